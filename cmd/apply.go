@@ -35,11 +35,16 @@ func NewApplyCmd(globalCfg *config.GlobalImpl) *cobra.Command {
 	f.StringArrayVar(&applyOptions.Set, "set", nil, "additional values to be merged into the helm command --set flag")
 	f.StringArrayVar(&applyOptions.Values, "values", nil, "additional value files to be merged into the helm command --values flag")
 	f.IntVar(&applyOptions.Concurrency, "concurrency", 0, "maximum number of concurrent helm processes to run, 0 is unlimited")
+	f.BoolVar(&applyOptions.LockstepBatches, "lockstep-batches", false, "process releases in lock-step groups, waiting for every release in a group before starting the next one, instead of starting each release as soon as its needs are done")
+	f.StringVar(&applyOptions.MetricsOutput, "metrics-output", "", `export metrics of the run, like the duration and result of each release, to this file in the Prometheus text format, e.g. for the textfile collector of node-exporter, or to the Pushgateway at this http(s) URL`)
+	f.BoolVar(&applyOptions.TimingReport, "timing-report", false, "print the time spent in each phase and release of the run, like repo updates, chart downloads, diff, hooks and waiting for needs, and the critical path through the needs of the releases")
+	f.BoolVar(&applyOptions.UI, "ui", false, "show the releases in a terminal ui, with their status, elapsed time and batch, and their helm and kubedog output when expanded, instead of the interleaved output of all of them. The plain output is kept when stdout is not a terminal")
 	f.BoolVar(&applyOptions.Validate, "validate", false, "validate your manifests against the Kubernetes cluster you are currently pointing at. Note that this requires access to a Kubernetes cluster to obtain information necessary for validating, like the list of available API versions")
 	f.IntVar(&applyOptions.Context, "context", 0, "output NUM lines of context around changes")
 	f.StringVar(&applyOptions.Output, "output", "", "output format for diff plugin")
 	f.BoolVar(&applyOptions.DetailedExitcode, "detailed-exitcode", false, "return a non-zero exit code 2 instead of 0 when there were changes detected AND the changes are synced successfully")
 	f.BoolVar(&applyOptions.StripTrailingCR, "strip-trailing-cr", false, "strip trailing carriage return on input")
+	f.BoolVar(&applyOptions.ApproveReleases, "approve-releases", false, "ask whether to apply each changed release after showing its diff, instead of once for all of them like --interactive, and apply only the approved ones. Releases needing a skipped release are skipped too")
 	f.StringVar(&applyOptions.DiffArgs, "diff-args", "", `Pass args to helm-diff`)
 	f.StringVar(&applyOptions.SyncArgs, "sync-args", "", `pass args to helm upgrade`)
 	f.StringVar(&globalCfg.GlobalOptions.Args, "args", "", "pass args to helm exec")
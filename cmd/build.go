@@ -32,6 +32,7 @@ func NewBuildCmd(globalCfg *config.GlobalImpl) *cobra.Command {
 
 	f := cmd.Flags()
 	f.BoolVar(&buildOptions.EmbedValues, "embed-values", false, "Read all the values files for every release and embed into the output helmfile.yaml")
+	f.BoolVar(&buildOptions.RenderTrace, "render-trace", false, "Print the template file and line each line of the rendered helmfile templates comes from to stderr, and the lines around the failing line when rendering fails")
 
 	return cmd
 }
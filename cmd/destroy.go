@@ -34,6 +34,10 @@ func NewDestroyCmd(globalCfg *config.GlobalImpl) *cobra.Command {
 	f.StringVar(&globalCfg.GlobalOptions.Args, "args", "", "pass args to helm exec")
 	f.StringVar(&destroyOptions.Cascade, "cascade", "", "pass cascade to helm exec, default: background")
 	f.IntVar(&destroyOptions.Concurrency, "concurrency", 0, "maximum number of concurrent helm processes to run, 0 is unlimited")
+	f.BoolVar(&destroyOptions.LockstepBatches, "lockstep-batches", false, "process releases in lock-step groups, waiting for every release in a group before starting the next one, instead of starting each release as soon as its needs are done")
+	f.StringVar(&destroyOptions.MetricsOutput, "metrics-output", "", `export metrics of the run, like the duration and result of each release, to this file in the Prometheus text format, e.g. for the textfile collector of node-exporter, or to the Pushgateway at this http(s) URL`)
+	f.BoolVar(&destroyOptions.TimingReport, "timing-report", false, "print the time spent in each phase and release of the run, like repo updates, chart downloads, diff, hooks and waiting for needs, and the critical path through the needs of the releases")
+	f.BoolVar(&destroyOptions.UI, "ui", false, "show the releases in a terminal ui, with their status, elapsed time and batch, and their helm and kubedog output when expanded, instead of the interleaved output of all of them. The plain output is kept when stdout is not a terminal")
 	f.BoolVar(&destroyOptions.SkipCharts, "skip-charts", false, "don't prepare charts when destroying releases")
 	f.BoolVar(&destroyOptions.DeleteWait, "deleteWait", false, `override helmDefaults.wait setting "helm uninstall --wait"`)
 	f.IntVar(&destroyOptions.DeleteTimeout, "deleteTimeout", 300, `time in seconds to wait for helm uninstall, default: 300`)
@@ -39,6 +39,7 @@ func NewDiffCmd(globalCfg *config.GlobalImpl) *cobra.Command {
 	f.BoolVar(&diffOptions.DetailedExitcode, "detailed-exitcode", false, "return a detailed exit code")
 	f.IntVar(&diffOptions.Context, "context", 0, "output NUM lines of context around changes")
 	f.StringVar(&diffOptions.Output, "output", "", "output format for diff plugin")
+	f.BoolVar(&diffOptions.LockstepBatches, "lockstep-batches", false, "diff all the releases at once, instead of diffing each release as soon as its needs are diffed")
 
 	return cmd
 }
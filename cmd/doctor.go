@@ -45,6 +45,23 @@ SECURITY: secrets are ALWAYS redacted before any byte leaves the process.
 secret-looking content from the diff before it is sent to the LLM. The redaction
 count is reported in the output footer so you can spot unexpected leaks.
 
+Extra redaction rules can be declared in helmfile.yaml:
+
+  llm:
+    redaction:
+      patterns: ['[a-z0-9-]+\.corp\.example\.com']  # regexes, every match redacted
+      keys: [customerId, billing.accountNumber]      # values of these keys redacted
+      allowlist: ['^sha256:[a-f0-9]{64}$']           # never redact these values
+
+With --source template, doctor renders manifests via ` + "`helmfile template`" + `
+instead of diffing against the cluster, and the LLM reviews them for issues
+such as missing resource limits, privileged pods, hostPath volumes and
+"latest" image tags. No cluster access is needed. Rendered Secret data is
+redacted in this mode even when no LLM is configured.
+
+Use --dump-payload FILE to write the exact prompt that would be sent, so
+auditors can verify nothing sensitive leaves.
+
 Exit codes:
   0  success, or only low/medium risks, or LLM call failed (degraded)
   2  at least one high-severity risk and --force not passed
@@ -104,6 +121,11 @@ swallowed: doctor's whole job is to react to changes.
 	f.StringVar(&doctorOptions.ReportFormat, "output", "",
 		`Doctor report format: "text" (markdown, default) or "json" (structured). The JSON "diff" field is always post-redaction.`)
 
+	f.StringVar(&doctorOptions.Source, "source", "diff",
+		`What to analyze: "diff" runs `+"`helmfile diff`"+` against the cluster; "template" reviews the full manifests rendered by `+"`helmfile template`"+` (no cluster access needed, suited to fresh installs).`)
+	f.StringVar(&doctorOptions.DumpPayload, "dump-payload", "",
+		"Write the exact post-redaction prompt (JSON) that is sent to the LLM to this file, for audit. Works without an LLM configured, in which case nothing is sent.")
+
 	// === Common diff surface (shared with `helmfile diff`) ===
 	bindCommonDiffFlags(f, diffOpts, &globalCfg.GlobalOptions.Args)
 
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/helmfile/helmfile/pkg/app"
+	"github.com/helmfile/helmfile/pkg/config"
+)
+
+// NewHistoryCmd returns the history subcommand
+func NewHistoryCmd(globalCfg *config.GlobalImpl) *cobra.Command {
+	historyOptions := config.NewHistoryOptions()
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Print the recent sync, apply and destroy runs recorded to the audit file",
+		Long: `Print the sync, apply and destroy runs recorded to the audit.file of the helmfiles, most recent first.
+Each run shows who ran it, the git commit of the helmfile, the environment, and what happened to each release.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			historyImpl := config.NewHistoryImpl(globalCfg, historyOptions)
+			err := config.NewCLIConfigImpl(historyImpl.GlobalImpl)
+			if err != nil {
+				return err
+			}
+
+			if err := historyImpl.ValidateConfig(); err != nil {
+				return err
+			}
+
+			a := app.New(historyImpl)
+			return toCLIError(historyImpl.GlobalImpl, a.History(historyImpl))
+		},
+	}
+
+	f := cmd.Flags()
+	f.IntVar(&historyOptions.Limit, "limit", 20, "maximum number of runs to print, 0 is unlimited")
+	f.StringVar(&historyOptions.Output, "output", "table", `output format, "json" or "table"`)
+
+	return cmd
+}
@@ -34,6 +34,8 @@ func NewListCmd(globalCfg *config.GlobalImpl) *cobra.Command {
 	f.BoolVar(&listOptions.KeepTempDir, "keep-temp-dir", false, "Keep temporary directory")
 	f.BoolVar(&listOptions.SkipCharts, "skip-charts", false, "don't prepare charts when listing releases")
 	f.StringVar(&listOptions.Output, "output", "", "output releases list as a json string")
+	f.BoolVar(&listOptions.Live, "live", false, `query the releases installed in the kube contexts and namespaces of the releases with "helm list", to show their deployed version, revision, status and last update,
+whether they diverge from the helmfiles, and the orphan releases installed there but not defined in any helmfile`)
 
 	return cmd
 }
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/helmfile/helmfile/pkg/app"
+	"github.com/helmfile/helmfile/pkg/config"
+)
+
+// NewPruneCmd returns the prune subcommand
+func NewPruneCmd(globalCfg *config.GlobalImpl) *cobra.Command {
+	pruneOptions := config.NewPruneOptions()
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete the releases owned by the helmfile project that are no longer declared",
+		Long: `Find the releases installed by the helmfile project and environment, identified by helmDefaults.project, that are no longer declared in any helmfile.
+The releases are only shown unless --confirm is set, which deletes them, after asking for confirmation with --interactive.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pruneImpl := config.NewPruneImpl(globalCfg, pruneOptions)
+			err := config.NewCLIConfigImpl(pruneImpl.GlobalImpl)
+			if err != nil {
+				return err
+			}
+
+			a := app.New(pruneImpl)
+			return toCLIError(pruneImpl.GlobalImpl, a.Prune(pruneImpl))
+		},
+	}
+
+	f := cmd.Flags()
+	f.BoolVar(&pruneOptions.Confirm, "confirm", false, "delete the releases found, instead of only showing them")
+
+	return cmd
+}
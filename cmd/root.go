@@ -17,6 +17,7 @@ import (
 	"github.com/helmfile/helmfile/pkg/errors"
 	"github.com/helmfile/helmfile/pkg/helmexec"
 	"github.com/helmfile/helmfile/pkg/runtime"
+	"github.com/helmfile/helmfile/pkg/tracing"
 )
 
 var logger *zap.SugaredLogger
@@ -75,7 +76,8 @@ func NewRootCmd(globalConfig *config.GlobalOptions) (*cobra.Command, error) {
 			}
 			logger = helmexec.NewLogger(logOut, logLevel)
 			globalConfig.SetLogger(logger)
-			return nil
+
+			return tracing.Start(globalImpl.TracingConfig(), c.CommandPath(), tracing.Environment.String(globalImpl.Env()))
 		},
 	}
 	flags := cmd.PersistentFlags()
@@ -99,6 +101,7 @@ func NewRootCmd(globalConfig *config.GlobalOptions) (*cobra.Command, error) {
 		NewCacheCmd(globalImpl),
 		NewDepsCmd(globalImpl),
 		NewDestroyCmd(globalImpl),
+		NewPruneCmd(globalImpl),
 		NewDiffCmd(globalImpl),
 		NewDoctorCmd(globalImpl),
 		NewFetchCmd(globalImpl),
@@ -108,11 +111,14 @@ func NewRootCmd(globalConfig *config.GlobalOptions) (*cobra.Command, error) {
 		NewWriteValuesCmd(globalImpl),
 		NewTestCmd(globalImpl),
 		NewUnittestCmd(globalImpl),
+		NewTestStateCmd(globalImpl),
 		NewTemplateCmd(globalImpl),
 		NewSyncCmd(globalImpl),
 		NewStatusCmd(globalImpl),
+		NewHistoryCmd(globalImpl),
 		NewShowDAGCmd(globalImpl),
 		NewPrintEnvCmd(globalImpl),
+		NewSchemaCmd(globalImpl),
 		extension.NewVersionCobraCmd(
 			versionOpts...,
 		),
@@ -163,6 +169,10 @@ It only applies for the Helm CLI commands, Stdout/Stderr for Hooks are still dis
 Useful when file order matters for dependencies (e.g., databases before applications).
 When processing multiple files, paths are resolved without changing the process working directory,
 so relative environment variables like KUBECONFIG work correctly.`)
+	fs.StringVar(&globalOptions.TraceEndpoint, "trace-endpoint", "", `Export OpenTelemetry spans of the run to this OTLP/HTTP endpoint, like "http://localhost:4318". Overrides "HELMFILE_TRACE_ENDPOINT" OS environment variable when specified`)
+	fs.StringVar(&globalOptions.TraceFile, "trace-file", "", `Write OpenTelemetry spans of the run to this file as JSON. Overrides "HELMFILE_TRACE_FILE" OS environment variable when specified`)
+	fs.BoolVar(&globalOptions.Strict, "strict", false, `Fail on every key of the helmfiles that isn't in the schema of helmfile.yaml, like a typo or a key misplaced under the wrong section,
+reporting the file and line of each of them. Run "helmfile schema" to print the schema.`)
 	// avoid 'pflag: help requested' error (#251)
 	fs.BoolP("help", "h", false, "help for helmfile")
 }
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/helmfile/helmfile/pkg/app"
+	"github.com/helmfile/helmfile/pkg/config"
+)
+
+// NewSchemaCmd returns the schema subcommand
+func NewSchemaCmd(globalCfg *config.GlobalImpl) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON schema of helmfile.yaml",
+		Long: `Print the JSON schema of helmfile.yaml, generated from the Go types helmfiles are decoded to.
+Point your editor to it to validate and complete helmfiles, like with the "# yaml-language-server: $schema=helmfile.schema.json" comment.
+The --strict flag checks helmfiles against the same schema while loading them.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := config.NewCLIConfigImpl(globalCfg)
+			if err != nil {
+				return err
+			}
+
+			a := app.New(globalCfg)
+			return toCLIError(globalCfg, a.PrintSchema())
+		},
+	}
+
+	return cmd
+}
@@ -28,5 +28,9 @@ func NewShowDAGCmd(globalCfg *config.GlobalImpl) *cobra.Command {
 			return toCLIError(showDAGImpl.GlobalImpl, a.PrintDAGState(showDAGImpl))
 		},
 	}
+
+	f := cmd.Flags()
+	f.StringVar(&showDAGOptions.Output, "output", "table", "output format: table, dot (Graphviz), mermaid or json. Every format but table prints a single graph of the releases of all the helmfiles")
+
 	return cmd
 }
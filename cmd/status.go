@@ -33,6 +33,7 @@ func NewStatusCmd(globalCfg *config.GlobalImpl) *cobra.Command {
 	f := cmd.Flags()
 	f.StringVar(&globalCfg.GlobalOptions.Args, "args", "", "pass args to helm exec")
 	f.IntVar(&statusOptions.Concurrency, "concurrency", 0, "maximum number of concurrent helm processes to run, 0 is unlimited")
+	f.StringVar(&statusOptions.Output, "output", "", `print the statuses of all the releases as "json" or a "table", instead of the output of "helm status" of each release`)
 
 	return cmd
 }
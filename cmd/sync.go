@@ -36,6 +36,10 @@ func NewSyncCmd(globalCfg *config.GlobalImpl) *cobra.Command {
 	f.StringArrayVar(&syncOptions.Set, "set", nil, "additional values to be merged into the helm command --set flag")
 	f.StringArrayVar(&syncOptions.Values, "values", nil, "additional value files to be merged into the helm command --values flag")
 	f.IntVar(&syncOptions.Concurrency, "concurrency", 0, "maximum number of concurrent helm processes to run, 0 is unlimited")
+	f.BoolVar(&syncOptions.LockstepBatches, "lockstep-batches", false, "process releases in lock-step groups, waiting for every release in a group before starting the next one, instead of starting each release as soon as its needs are done")
+	f.StringVar(&syncOptions.MetricsOutput, "metrics-output", "", `export metrics of the run, like the duration and result of each release, to this file in the Prometheus text format, e.g. for the textfile collector of node-exporter, or to the Pushgateway at this http(s) URL`)
+	f.BoolVar(&syncOptions.TimingReport, "timing-report", false, "print the time spent in each phase and release of the run, like repo updates, chart downloads, diff, hooks and waiting for needs, and the critical path through the needs of the releases")
+	f.BoolVar(&syncOptions.UI, "ui", false, "show the releases in a terminal ui, with their status, elapsed time and batch, and their helm and kubedog output when expanded, instead of the interleaved output of all of them. The plain output is kept when stdout is not a terminal")
 	f.BoolVar(&syncOptions.Validate, "validate", false, "validate your manifests against the Kubernetes cluster you are currently pointing at. Note that this requires access to a Kubernetes cluster to obtain information necessary for validating, like the sync of available API versions")
 	f.BoolVar(&syncOptions.SkipNeeds, "skip-needs", true, `do not automatically include releases from the target release's "needs" when --selector/-l flag is provided. Does nothing when --selector/-l flag is not provided. Defaults to true when --include-needs or --include-transitive-needs is not provided`)
 	f.BoolVar(&syncOptions.SkipCRDs, "skip-crds", false, "if set, no CRDs will be installed on sync. By default, CRDs are installed if not already present")
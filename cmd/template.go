@@ -51,6 +51,8 @@ func NewTemplateCmd(globalCfg *config.GlobalImpl) *cobra.Command {
 	f.BoolVar(&templateOptions.SkipSchemaValidation, "skip-schema-validation", false, `pass skip-schema-validation to "helm template" or "helm upgrade --install"`)
 	f.StringVar(&templateOptions.KubeVersion, "kube-version", "", `pass --kube-version to "helm template". Overrides kubeVersion in helmfile.yaml`)
 	f.StringArrayVar(&templateOptions.ShowOnly, "show-only", nil, `pass --show-only to "helm template"`)
+	f.StringVar(&templateOptions.SnapshotDir, "snapshot-dir", "", "write the normalized manifests of each release to a stable file under this directory, for golden-file testing")
+	f.BoolVar(&templateOptions.CheckSnapshots, "check-snapshots", false, "compare the rendered manifests against the files in --snapshot-dir instead of writing them, print a unified diff and exit non-zero on mismatch")
 	f.StringVar(&templateOptions.TemplateArgs, "template-args", "", `Pass extra args to "helm template" (e.g. --template-args="--dry-run=server" to enable the helm lookup function). Overrides helmDefaults.templateArgs.`)
 
 	return cmd
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/helmfile/helmfile/pkg/app"
+	"github.com/helmfile/helmfile/pkg/config"
+)
+
+// NewTestStateCmd returns test-state subcmd
+func NewTestStateCmd(globalCfg *config.GlobalImpl) *cobra.Command {
+	testStateOptions := config.NewTestStateOptions()
+
+	cmd := &cobra.Command{
+		Use:   "test-state",
+		Short: "Unit test the helmfile state itself, offline",
+		Long: `Unit test the helmfile state itself, offline.
+
+Each test file lists test cases. A test case loads the helmfile with the given
+environment and state values, then asserts on the resulting releases (existence,
+installed, condition, labels and other release fields) and on their merged values.
+Neither helm nor the cluster is called.
+
+Example test file:
+
+  tests:
+  - name: prod installs foo with the backend tier
+    environment: prod
+    stateValuesSet:
+    - foo.enabled=true
+    asserts:
+    - release: foo
+      installed: true
+      labels:
+        tier: backend
+      fields:
+        chart: charts/foo
+      values:
+        image.tag: "1.2.3"
+      hasValues:
+      - resources.limits
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			testStateImpl := config.NewTestStateImpl(globalCfg, testStateOptions)
+			err := config.NewCLIConfigImpl(testStateImpl.GlobalImpl)
+			if err != nil {
+				return err
+			}
+
+			if err := testStateImpl.ValidateConfig(); err != nil {
+				return err
+			}
+
+			a := app.New(testStateImpl)
+			return toCLIError(testStateImpl.GlobalImpl, a.TestState(testStateImpl))
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringArrayVar(&testStateOptions.Tests, "tests", nil, "state test files or glob patterns to run, e.g. tests/state/*.yaml")
+
+	return cmd
+}
@@ -38,6 +38,9 @@ func NewUnittestCmd(globalCfg *config.GlobalImpl) *cobra.Command {
 	f.BoolVar(&unittestOptions.FailFast, "fail-fast", false, "fail fast on the first test failure")
 	f.BoolVar(&unittestOptions.Color, "color", false, "enforce colored output even when stdout is not a tty (ignored on Helm 4 due to flag parsing issues)")
 	f.BoolVar(&unittestOptions.DebugPlugin, "debug-plugin", false, "enable verbose output from the helm-unittest plugin")
+	f.BoolVar(&unittestOptions.UpdateSnapshot, "update-snapshot", false, "update the snapshot files written next to each release's unitTests paths")
+	f.StringVar(&unittestOptions.ReportFile, "report-file", "", "write a combined test report across all releases to this file, with one test suite per release")
+	f.StringVar(&unittestOptions.ReportFormat, "report-format", "junit", "format of the combined test report: junit or json")
 	f.BoolVar(&unittestOptions.SkipNeeds, "skip-needs", true, `do not automatically include releases from the target release's "needs" when --selector/-l flag is provided. Does nothing when --selector/-l flag is not provided. Defaults to true when --include-needs or --include-transitive-needs is not provided`)
 	f.BoolVar(&unittestOptions.IncludeNeeds, "include-needs", false, `automatically include releases from the target release's "needs" when --selector/-l flag is provided. Does nothing when --selector/-l flag is not provided`)
 	f.BoolVar(&unittestOptions.IncludeTransitiveNeeds, "include-transitive-needs", false, `like --include-needs, but also includes transitive needs (needs of needs). Does nothing when --selector/-l flag is not provided. Overrides exclusions of other selectors and conditions.`)
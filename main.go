@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
@@ -9,6 +10,7 @@ import (
 	"github.com/helmfile/helmfile/pkg/app"
 	"github.com/helmfile/helmfile/pkg/config"
 	"github.com/helmfile/helmfile/pkg/errors"
+	"github.com/helmfile/helmfile/pkg/tracing"
 )
 
 func main() {
@@ -26,7 +28,9 @@ func main() {
 			return
 		}
 
-		errChan <- rootCmd.Execute()
+		err = rootCmd.Execute()
+		shutdownTracing(err)
+		errChan <- err
 	}()
 
 	select {
@@ -34,6 +38,7 @@ func main() {
 		if sig != nil {
 			app.Cancel()
 			app.CleanWaitGroup.Wait()
+			shutdownTracing(fmt.Errorf("received %s", sig))
 
 			// See http://tldp.org/LDP/abs/html/exitcodes.html
 			switch sig {
@@ -47,3 +52,10 @@ func main() {
 		errors.HandleExitCoder(err)
 	}
 }
+
+// shutdownTracing ends the root span of the run with err, and flushes the spans of the run.
+func shutdownTracing(err error) {
+	if shutdownErr := tracing.Shutdown(err); shutdownErr != nil {
+		fmt.Fprintf(os.Stderr, "failed to export the spans of the run: %v\n", shutdownErr)
+	}
+}
@@ -8,6 +8,9 @@ package doctor
 
 import (
 	goContext "context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"time"
 
 	"github.com/helmfile/helmfile/pkg/agent/llm"
@@ -34,6 +37,10 @@ type Result struct {
 	Model string
 	// Duration is how long the LLM call took.
 	Duration time.Duration
+	// PayloadErr is set when Options.PayloadWriter was given but the payload
+	// could not be written. The LLM is NOT called in that case: an audit
+	// trail that silently went missing is worse than no analysis.
+	PayloadErr error
 }
 
 // HasHighRisk delegates to Analysis.HasHighRisk when an analysis exists.
@@ -56,6 +63,14 @@ type Options struct {
 	// fine: its Redact method falls back to "<REDACTED>". Redaction is
 	// ALWAYS applied — there is no opt-out at this layer.
 	Redactor SecretRedactor
+	// PayloadWriter, when non-nil, receives the exact post-redaction prompt
+	// (as indented JSON) before the LLM is called. Used by --dump-payload so
+	// auditors can verify what leaves the process. The payload is written
+	// even when Client is nil, which lets users audit without sending.
+	PayloadWriter io.Writer
+	// Source tells the LLM whether diff is `helm diff` output or rendered
+	// manifests from `helmfile template`. The zero value means llm.SourceDiff.
+	Source llm.Source
 }
 
 // Analyze runs the full doctor pipeline against the given diff text.
@@ -73,6 +88,21 @@ func Analyze(ctx goContext.Context, diff string, opts Options) Result {
 	}
 
 	redacted, redactionCount := opts.Redactor.Redact(diff)
+	in := llm.AnalyzeInput{
+		Environment: opts.Environment,
+		Releases:    opts.Releases,
+		Source:      opts.Source,
+	}
+
+	if opts.PayloadWriter != nil {
+		if err := WritePayload(opts.PayloadWriter, llm.BuildPayload(opts.Model, redacted, in)); err != nil {
+			return Result{
+				RawDiff:         redacted,
+				SecretsRedacted: redactionCount,
+				PayloadErr:      err,
+			}
+		}
+	}
 
 	if opts.Client == nil {
 		return Result{
@@ -82,10 +112,7 @@ func Analyze(ctx goContext.Context, diff string, opts Options) Result {
 	}
 
 	start := time.Now()
-	a, err := opts.Client.Analyze(ctx, redacted, llm.AnalyzeInput{
-		Environment: opts.Environment,
-		Releases:    opts.Releases,
-	})
+	a, err := opts.Client.Analyze(ctx, redacted, in)
 	duration := time.Since(start)
 
 	if err != nil {
@@ -107,3 +134,15 @@ func Analyze(ctx goContext.Context, diff string, opts Options) Result {
 		Duration:        duration,
 	}
 }
+
+// WritePayload encodes p as indented JSON to w. HTML escaping is disabled so
+// the "<REDACTED>" placeholder stays greppable in the dump.
+func WritePayload(w io.Writer, p llm.Payload) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(p); err != nil {
+		return fmt.Errorf("doctor: failed to write llm payload: %w", err)
+	}
+	return nil
+}
@@ -1,8 +1,11 @@
 package doctor
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -108,3 +111,55 @@ func TestRoundDuration(t *testing.T) {
 		t.Errorf("got %q want 2.2s", got)
 	}
 }
+
+func TestAnalyze_WritesRedactedPayloadBeforeCall(t *testing.T) {
+	var buf bytes.Buffer
+	c := llm.NewMockClient(llm.Analysis{Summary: "ok"})
+
+	r := Analyze(context.Background(), "  + data.password: hunter2hunter2\n", Options{
+		Client:        c,
+		Model:         "gpt-4o",
+		Environment:   "prod",
+		Redactor:      NewSecretRedactor(),
+		PayloadWriter: &buf,
+	})
+	if r.PayloadErr != nil {
+		t.Fatalf("unexpected payload error: %v", r.PayloadErr)
+	}
+
+	var p llm.Payload
+	if err := json.Unmarshal(buf.Bytes(), &p); err != nil {
+		t.Fatalf("payload is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if p.Model != "gpt-4o" || len(p.Messages) != 2 {
+		t.Fatalf("unexpected payload: %+v", p)
+	}
+	if strings.Contains(buf.String(), "hunter2") {
+		t.Errorf("payload contains unredacted secret:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), RedactedPlaceholder) {
+		t.Errorf("payload should contain the literal placeholder:\n%s", buf.String())
+	}
+	sent, _ := c.LastCall()
+	if !strings.Contains(p.Messages[1].Content, sent) {
+		t.Errorf("payload user message does not contain the diff that was sent")
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestAnalyze_PayloadWriteFailureSkipsLLM(t *testing.T) {
+	c := llm.NewMockClient(llm.Analysis{Summary: "ok"})
+	r := Analyze(context.Background(), "diff", Options{Client: c, PayloadWriter: failingWriter{}})
+	if r.PayloadErr == nil {
+		t.Fatal("expected PayloadErr")
+	}
+	if r.Analysis != nil {
+		t.Error("LLM must not be called when the payload dump fails")
+	}
+	if sent, _ := c.LastCall(); sent != "" {
+		t.Errorf("LLM was called with %q", sent)
+	}
+}
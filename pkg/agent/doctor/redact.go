@@ -1,8 +1,11 @@
 package doctor
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/helmfile/helmfile/pkg/agent/llm"
 )
 
 // RedactedPlaceholder is the default string substituted in place of detected
@@ -29,6 +32,18 @@ const RedactedPlaceholder = "<REDACTED>"
 type SecretRedactor struct {
 	// Placeholder replaces detected secret values. Defaults to "<REDACTED>".
 	Placeholder string
+
+	// Patterns are user-supplied regexes (llm.redaction.patterns). Every
+	// match is replaced with Placeholder.
+	Patterns []*regexp.Regexp
+	// Keys are user-supplied key names or dotted key paths
+	// (llm.redaction.keys), lower-cased. Values of matching diff lines are
+	// replaced with Placeholder.
+	Keys []string
+	// Allowlist are user-supplied regexes (llm.redaction.allowlist) for
+	// values that the heuristic and pattern passes must leave alone.
+	// Secret data blocks ignore the allowlist: they are always redacted.
+	Allowlist []*regexp.Regexp
 }
 
 // NewSecretRedactor returns a SecretRedactor with the default placeholder.
@@ -36,6 +51,38 @@ func NewSecretRedactor() SecretRedactor {
 	return SecretRedactor{Placeholder: RedactedPlaceholder}
 }
 
+// NewSecretRedactorWithRules returns a SecretRedactor with the default
+// placeholder plus the user-supplied rules from the helmfile.yaml `llm:`
+// block. Invalid regexes are reported rather than skipped: silently dropping
+// a compliance rule would defeat its purpose.
+func NewSecretRedactorWithRules(rules llm.RedactionRules) (SecretRedactor, error) {
+	r := NewSecretRedactor()
+
+	for _, p := range rules.Patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return SecretRedactor{}, fmt.Errorf("invalid llm.redaction.patterns entry %q: %w", p, err)
+		}
+		r.Patterns = append(r.Patterns, re)
+	}
+	for _, k := range rules.Keys {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if k == "" {
+			continue
+		}
+		r.Keys = append(r.Keys, k)
+	}
+	for _, p := range rules.Allowlist {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return SecretRedactor{}, fmt.Errorf("invalid llm.redaction.allowlist entry %q: %w", p, err)
+		}
+		r.Allowlist = append(r.Allowlist, re)
+	}
+
+	return r, nil
+}
+
 // Redact applies all redaction patterns to diff and returns the sanitized
 // text plus a count of replacements made. The count is surfaced in the doctor
 // report footer so users can spot unexpected redaction (e.g. when a chart
@@ -55,16 +102,37 @@ func (r SecretRedactor) Redact(diff string) (string, int) {
 	count += n
 
 	// Pattern 2: Sensitive key/value lines (password, token, apiKey, ...).
-	out, n = redactSensitiveKeyValues(out, ph)
+	out, n = redactSensitiveKeyValues(out, ph, r.allowed)
+	count += n
+
+	// Pattern 3: User-supplied key names and key paths.
+	out, n = redactUserKeys(out, ph, r.Keys, r.allowed)
+	count += n
+
+	// Pattern 4: User-supplied regexes. Run before the base64 pass so a rule
+	// matching e.g. a hostname sees the original text, not a placeholder.
+	out, n = redactUserPatterns(out, ph, r.Patterns, r.allowed)
 	count += n
 
-	// Pattern 3: Free-form long base64 (>=40 chars) and JWT-shaped tokens.
-	out, n = redactLongBase64(out, ph)
+	// Pattern 5: Free-form long base64 (>=40 chars) and JWT-shaped tokens.
+	out, n = redactLongBase64(out, ph, r.allowed)
 	count += n
 
 	return out, count
 }
 
+// allowed reports whether value matches any allowlist entry and must
+// therefore be kept verbatim.
+func (r SecretRedactor) allowed(value string) bool {
+	v := stripANSI(strings.TrimSpace(value))
+	for _, re := range r.Allowlist {
+		if re.MatchString(v) {
+			return true
+		}
+	}
+	return false
+}
+
 // --- pattern implementations ------------------------------------------------
 
 // redactSecretBlocks walks the diff line by line, finds each `kind: Secret`
@@ -228,7 +296,7 @@ var reSensitiveKeyValue = regexp.MustCompile(
 	`(?im)^([+-]?\s+(?:[^:\n]*\.)?(?:password|passwd|pwd|secret|secrets|token|tokens|apikey|api[_-]?key|private[_-]?key|client[_-]?secret|access[_-]?token|refresh[_-]?token|bearer|credential|credentials|auth[_-]?token|session[_-]?token)(?:\.[A-Za-z_]+)?)(:\s*)(.+)$`,
 )
 
-func redactSensitiveKeyValues(diff, ph string) (string, int) {
+func redactSensitiveKeyValues(diff, ph string, allowed func(string) bool) (string, int) {
 	count := 0
 	out := reSensitiveKeyValue.ReplaceAllStringFunc(diff, func(line string) string {
 		m := reSensitiveKeyValue.FindStringSubmatch(line)
@@ -236,7 +304,7 @@ func redactSensitiveKeyValues(diff, ph string) (string, int) {
 			return line
 		}
 		val := stripANSI(m[3])
-		if val == ph || val == "***" || val == "<redacted>" {
+		if val == ph || val == "***" || val == "<redacted>" || allowed(val) {
 			return line
 		}
 		count++
@@ -245,6 +313,67 @@ func redactSensitiveKeyValues(diff, ph string) (string, int) {
 	return out, count
 }
 
+// redactUserKeys replaces the value of every "key: value" diff line whose key
+// matches one of keys. The key portion is the text before the first ": "
+// with the diff sign and indentation stripped, so both compact helm-diff
+// lines ("+ data.customerId: 42") and nested YAML ("+   customerId: 42")
+// are recognised. A rule matches when it equals the key or is a dotted
+// suffix of it.
+func redactUserKeys(diff, ph string, keys []string, allowed func(string) bool) (string, int) {
+	if len(keys) == 0 {
+		return diff, 0
+	}
+
+	lines := strings.Split(diff, "\n")
+	count := 0
+	for i, raw := range lines {
+		idx := strings.Index(raw, ": ")
+		if idx < 0 {
+			continue
+		}
+		key := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(raw[:idx]), "+-"))
+		key = strings.ToLower(strings.TrimPrefix(key, "- "))
+		if !matchesUserKey(key, keys) {
+			continue
+		}
+		if allowed(raw[idx+2:]) {
+			continue
+		}
+		redacted, didRedact := redactKeyValueLine(raw, ph)
+		if didRedact {
+			lines[i] = redacted
+			count++
+		}
+	}
+	return strings.Join(lines, "\n"), count
+}
+
+func matchesUserKey(key string, keys []string) bool {
+	for _, k := range keys {
+		if key == k || strings.HasSuffix(key, "."+k) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactUserPatterns replaces every match of the user-supplied regexes.
+// Matches that already equal the placeholder or hit the allowlist are kept.
+func redactUserPatterns(diff, ph string, patterns []*regexp.Regexp, allowed func(string) bool) (string, int) {
+	count := 0
+	out := diff
+	for _, re := range patterns {
+		out = re.ReplaceAllStringFunc(out, func(s string) string {
+			if s == ph || s == "" || allowed(s) {
+				return s
+			}
+			count++
+			return ph
+		})
+	}
+	return out, count
+}
+
 // reLongBase64 matches runs of base64 alphabet characters of length >= 40.
 // 40 is chosen because:
 //   - typical encoded secrets (16-byte token, 32-byte key) base64 to 24/44 chars,
@@ -262,11 +391,11 @@ var reLongBase64 = regexp.MustCompile(`[A-Za-z0-9+/=_-]{40,}`)
 // reLongBase64.
 var reJWT = regexp.MustCompile(`eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)
 
-func redactLongBase64(diff, ph string) (string, int) {
+func redactLongBase64(diff, ph string, allowed func(string) bool) (string, int) {
 	count := 0
 	// JWTs first (they contain dots so reLongBase64 would only catch segments).
 	out := reJWT.ReplaceAllStringFunc(diff, func(s string) string {
-		if s == ph {
+		if s == ph || allowed(s) {
 			return s
 		}
 		count++
@@ -274,7 +403,7 @@ func redactLongBase64(diff, ph string) (string, int) {
 	})
 	// Then free-form long base64.
 	out = reLongBase64.ReplaceAllStringFunc(out, func(s string) string {
-		if s == ph {
+		if s == ph || allowed(s) {
 			return s
 		}
 		count++
@@ -3,6 +3,8 @@ package doctor
 import (
 	"strings"
 	"testing"
+
+	"github.com/helmfile/helmfile/pkg/agent/llm"
 )
 
 func TestSecretRedactor_LeavesNonSecretDiffAlone(t *testing.T) {
@@ -205,3 +207,75 @@ func TestSecretRedactor_RealisticMixedDiff(t *testing.T) {
 		t.Errorf("expected `kind: Secret` to survive redaction; got:\n%s", out)
 	}
 }
+
+func TestNewSecretRedactorWithRules_UserPatternsAndKeys(t *testing.T) {
+	r, err := NewSecretRedactorWithRules(llm.RedactionRules{
+		Patterns: []string{`[a-z0-9-]+\.corp\.example\.com`},
+		Keys:     []string{"customerId", "billing.accountNumber"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	in := `  default, api, ConfigMap (v1) has changed:
+  - data.upstream: db-01.corp.example.com
+  + data.upstream: db-02.corp.example.com
+  + data.customerId: C-12345
+  + data.region: eu-west-1
+  +   billing:
+  +     accountNumber: 112233
+  + data.billing.accountNumber: 998877
+`
+	out, n := r.Redact(in)
+	for _, leak := range []string{"db-01.corp", "db-02.corp", "C-12345", "998877"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("value %q leaked into output:\n%s", leak, out)
+		}
+	}
+	if !strings.Contains(out, "data.region: eu-west-1") {
+		t.Errorf("unrelated key was redacted:\n%s", out)
+	}
+	if !strings.Contains(out, "accountNumber: 112233") {
+		t.Errorf("nested key without the full path should not match the dotted rule:\n%s", out)
+	}
+	// db-01, db-02, customerId, data.billing.accountNumber. The nested
+	// "accountNumber:" line only carries the last path segment and is not
+	// matched by the dotted rule.
+	if n != 4 {
+		t.Errorf("replacement count = %d, want 4; output:\n%s", n, out)
+	}
+}
+
+func TestNewSecretRedactorWithRules_AllowlistKeepsFalsePositives(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	r, err := NewSecretRedactorWithRules(llm.RedactionRules{
+		Allowlist: []string{`^sha256:[a-f0-9]{64}$`, `^[a-f0-9]{64}$`},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	in := "  + spec.template.spec.containers.0.image: nginx@" + digest + "\n"
+	out, n := r.Redact(in)
+	if out != in || n != 0 {
+		t.Errorf("allowlisted digest was redacted (n=%d):\n%s", n, out)
+	}
+
+	// Secret data ignores the allowlist.
+	secret := `+ kind: Secret
++ data:
++   checksum: ` + strings.Repeat("ab", 32) + "\n"
+	out, _ = r.Redact(secret)
+	if strings.Contains(out, strings.Repeat("ab", 32)) {
+		t.Errorf("Secret data must be redacted regardless of allowlist:\n%s", out)
+	}
+}
+
+func TestNewSecretRedactorWithRules_InvalidRegex(t *testing.T) {
+	if _, err := NewSecretRedactorWithRules(llm.RedactionRules{Patterns: []string{"("}}); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+	if _, err := NewSecretRedactorWithRules(llm.RedactionRules{Allowlist: []string{"["}}); err == nil {
+		t.Error("expected error for invalid allowlist entry")
+	}
+}
@@ -1,6 +1,10 @@
 package llm
 
-import goContext "context"
+import (
+	goContext "context"
+	"fmt"
+	"strings"
+)
 
 // Client is the abstraction used by `helmfile doctor` to talk to whatever
 // OpenAI-compatible backend the user configured. The default implementation
@@ -19,6 +23,33 @@ type AnalyzeInput struct {
 	Environment string
 	// Releases is the list of release names that appear in the diff.
 	Releases []string
+	// Source selects what the text handed to Analyze contains and therefore
+	// which prompt is used. The zero value means SourceDiff.
+	Source Source
+}
+
+// Source identifies the kind of text handed to Client.Analyze.
+type Source string
+
+const (
+	// SourceDiff is `helm diff` output produced by `helmfile diff`.
+	SourceDiff Source = "diff"
+	// SourceTemplate is the full set of rendered manifests produced by
+	// `helmfile template`. Used for fresh installs and for clusters doctor
+	// cannot reach, since rendering needs no cluster access.
+	SourceTemplate Source = "template"
+)
+
+// ParseSource maps a user-supplied --source value onto a Source. Returns an
+// error for anything other than "", "diff" or "template".
+func ParseSource(s string) (Source, error) {
+	switch Source(strings.ToLower(strings.TrimSpace(s))) {
+	case "", SourceDiff:
+		return SourceDiff, nil
+	case SourceTemplate:
+		return SourceTemplate, nil
+	}
+	return "", fmt.Errorf("unknown doctor source %q: must be one of %q or %q", s, SourceDiff, SourceTemplate)
 }
 
 // NewClient returns a Client backed by the OpenAI Chat Completions protocol.
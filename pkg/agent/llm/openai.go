@@ -38,8 +38,11 @@ func (o *openaiClient) Analyze(ctx goContext.Context, diff string, extras Analyz
 		return Analysis{Summary: "No changes detected by helm diff."}, nil
 	}
 
-	system := systemPrompt()
-	user := userPrompt(diff, extras)
+	payload := BuildPayload(o.cfg.Model, diff, extras)
+	messages := make([]openai.ChatCompletionMessage, 0, len(payload.Messages))
+	for _, m := range payload.Messages {
+		messages = append(messages, openai.ChatCompletionMessage{Role: m.Role, Content: m.Content})
+	}
 
 	ctx, cancel := goContext.WithTimeout(ctx, o.cfg.Timeout)
 	defer cancel()
@@ -54,10 +57,7 @@ func (o *openaiClient) Analyze(ctx goContext.Context, diff string, extras Analyz
 		ResponseFormat: &openai.ChatCompletionResponseFormat{
 			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
 		},
-		Messages: []openai.ChatCompletionMessage{
-			{Role: openai.ChatMessageRoleSystem, Content: system},
-			{Role: openai.ChatMessageRoleUser, Content: user},
-		},
+		Messages: messages,
 	}
 
 	resp, err := o.c.CreateChatCompletion(ctx, req)
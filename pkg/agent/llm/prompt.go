@@ -6,6 +6,37 @@ import (
 	"strings"
 )
 
+// Payload is the exact prompt the client sends to the Chat Completions
+// endpoint. It is exposed so `helmfile doctor --dump-payload` can write it
+// to disk for auditors to verify that nothing sensitive leaves the process.
+type Payload struct {
+	Model    string           `json:"model"`
+	Messages []PayloadMessage `json:"messages"`
+}
+
+// PayloadMessage is a single chat message inside a Payload.
+type PayloadMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// BuildPayload assembles the system+user messages for diff exactly as the
+// OpenAI client would send them. diff must already be redacted. The system
+// prompt is picked by extras.Source.
+func BuildPayload(model, diff string, extras AnalyzeInput) Payload {
+	system := systemPrompt()
+	if extras.Source == SourceTemplate {
+		system = manifestSystemPrompt()
+	}
+	return Payload{
+		Model: model,
+		Messages: []PayloadMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: userPrompt(diff, extras)},
+		},
+	}
+}
+
 // systemPrompt returns the system message that frames the model as a
 // Kubernetes/Helm reviewer and locks the output to a known JSON schema.
 func systemPrompt() string {
@@ -48,6 +79,51 @@ If the diff is empty, return {"summary":"No changes.","risks":[]}.
 If the diff is too large or unparseable, return {"error":"<short reason>"} and nothing else.`)
 }
 
+// manifestSystemPrompt is the counterpart of systemPrompt for
+// SourceTemplate. There is no "before" state, so instead of reviewing a
+// change the model audits the full rendered manifests for common
+// misconfigurations. The response schema is identical so report rendering
+// and the high-risk gate work unchanged.
+func manifestSystemPrompt() string {
+	return strings.TrimSpace(`You are a senior Kubernetes and Helm reviewer.
+
+You will be given the full set of Kubernetes manifests rendered by "helmfile template". They describe what will be installed; there is no previous state to compare against. Your job is to:
+
+1. Summarize what the manifests deploy, in one or two sentences a human operator can scan in under five seconds.
+2. Identify risks across these categories (skip categories that do not apply). Pay particular attention to:
+   - security: privileged containers, allowPrivilegeEscalation, added capabilities, hostPath volumes, hostNetwork/hostPID/hostIPC, running as root, missing securityContext, overly broad RBAC (wildcards, cluster-admin), plaintext credentials in ConfigMaps or env.
+   - performance: containers without resources.requests or resources.limits, unbounded replicas, missing HPA where one is expected.
+   - downtime: single replicas for user-facing workloads, missing readiness/liveness probes, missing PodDisruptionBudget.
+   - best-practice: images using the "latest" tag or no tag, imagePullPolicy Always with mutable tags, missing namespace, missing recommended labels.
+   - data-loss: stateful workloads without persistent storage, PVCs with a Delete reclaim policy.
+   - breaking-change: deprecated or removed apiVersions.
+3. For each risk, give an actionable mitigation step (values override, chart setting, or manifest change). Name the offending resource in the description.
+
+Risk levels:
+   - high: would cause a security exposure, data loss, or an outage. Must be reviewed by a human before installing.
+   - medium: likely causes degraded operation or requires follow-up fix.
+   - low: cosmetic or non-impacting, but worth noting.
+
+Respond with ONLY a single JSON object matching this schema (no prose outside JSON, no markdown fences):
+
+{
+  "summary": "<one or two sentences>",
+  "risks": [
+    {
+      "level": "low" | "medium" | "high",
+      "category": "data-loss" | "security" | "breaking-change" | "downtime" | "performance" | "best-practice",
+      "description": "<what the risk is, 1-3 sentences, concrete>",
+      "suggestion": "<actionable mitigation>"
+    }
+  ],
+  "affected_resources": ["Deployment/foo", "Service/bar"]
+}
+
+If there are no manifests, return {"summary":"Nothing rendered.","risks":[]}.
+
+If the input is too large or unparseable, return {"error":"<short reason>"} and nothing else.`)
+}
+
 // userPrompt assembles the user message containing the diff plus runtime
 // context (environment, release names) for grounding.
 //
@@ -57,13 +133,17 @@ If the diff is too large or unparseable, return {"error":"<short reason>"} and n
 // forms; using stdlib rather than a hand-rolled marshaler gives us RFC 8259
 // compliance and auditability.
 //
-// The diff body is delimited by the literal banner "helm diff output:" so the
-// model can tell where data begins. Large diffs are capped at 32KB as a
+// The diff body is delimited by the literal banner "helm diff output:" (or
+// "helmfile template output:" for SourceTemplate) so the model can tell
+// where data begins. Large diffs are capped at 32KB as a
 // defensive measure against blowing the model context window.
 func userPrompt(diff string, extras AnalyzeInput) string {
 	var b strings.Builder
 	if extras.Environment != "" || len(extras.Releases) > 0 {
-		ctxJSON, err := json.Marshal(promptContext(extras))
+		ctxJSON, err := json.Marshal(promptContext{
+			Environment: extras.Environment,
+			Releases:    extras.Releases,
+		})
 		if err != nil {
 			// Should never happen for a struct of strings. Skip the context
 			// block rather than fail — the diff alone is still useful.
@@ -74,20 +154,25 @@ func userPrompt(diff string, extras AnalyzeInput) string {
 			b.WriteString("\n\n")
 		}
 	}
-	b.WriteString("helm diff output:\n\n")
+	banner, noun := "helm diff output:", "diff"
+	if extras.Source == SourceTemplate {
+		banner, noun = "helmfile template output:", "manifests"
+	}
+	b.WriteString(banner)
+	b.WriteString("\n\n")
 	const maxDiffBytes = 32 * 1024
 	if len(diff) > maxDiffBytes {
 		b.WriteString(diff[:maxDiffBytes])
-		fmt.Fprintf(&b, "\n\n... [diff truncated: %d bytes total, only first %d sent] ...\n", len(diff), maxDiffBytes)
+		fmt.Fprintf(&b, "\n\n... [%s truncated: %d bytes total, only first %d sent] ...\n", noun, len(diff), maxDiffBytes)
 	} else {
 		b.WriteString(diff)
 	}
 	return b.String()
 }
 
-// promptContext mirrors AnalyzeInput for JSON marshaling. Exists ONLY so
-// json.Marshal produces a stable, predictable key order. Do not reuse outside
-// the prompt builder.
+// promptContext mirrors the free-form AnalyzeInput fields for JSON
+// marshaling. Exists ONLY so json.Marshal produces a stable, predictable key
+// order. Do not reuse outside the prompt builder.
 type promptContext struct {
 	Environment string   `json:"environment,omitempty"`
 	Releases    []string `json:"releases,omitempty"`
@@ -193,3 +193,49 @@ func TestUserPrompt_PromptInjectionDefense(t *testing.T) {
 		t.Errorf("injection payload was mangled: %q", parsed.Releases[1])
 	}
 }
+
+func TestBuildPayload_SelectsPromptBySource(t *testing.T) {
+	diff := BuildPayload("m", "BODY", AnalyzeInput{})
+	if diff.Messages[0].Content != systemPrompt() {
+		t.Error("zero Source must use the diff system prompt")
+	}
+	if !strings.Contains(diff.Messages[1].Content, "helm diff output:") {
+		t.Errorf("diff user prompt missing banner:\n%s", diff.Messages[1].Content)
+	}
+
+	tmpl := BuildPayload("m", "BODY", AnalyzeInput{Source: SourceTemplate})
+	if tmpl.Messages[0].Content != manifestSystemPrompt() {
+		t.Error("SourceTemplate must use the manifest system prompt")
+	}
+	for _, want := range []string{"hostPath", "latest", "resources.limits", "privileged"} {
+		if !strings.Contains(tmpl.Messages[0].Content, want) {
+			t.Errorf("manifest system prompt should mention %q", want)
+		}
+	}
+	if !strings.Contains(tmpl.Messages[1].Content, "helmfile template output:") {
+		t.Errorf("template user prompt missing banner:\n%s", tmpl.Messages[1].Content)
+	}
+}
+
+func TestParseSource(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Source
+		wantErr bool
+	}{
+		{in: "", want: SourceDiff},
+		{in: "diff", want: SourceDiff},
+		{in: "Template", want: SourceTemplate},
+		{in: "live", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSource(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSource(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSource(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
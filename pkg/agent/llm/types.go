@@ -39,6 +39,45 @@ type Config struct {
 	// Temperature controls generation randomness. Defaults to 0.2 when zero
 	// (deterministic-ish for risk analysis).
 	Temperature float32 `yaml:"temperature,omitempty"`
+
+	// Redaction holds user-supplied redaction rules applied on top of the
+	// built-in heuristics before any text is sent to the endpoint. Only the
+	// helmfile.yaml layer sets it; there are no env or flag equivalents.
+	Redaction RedactionRules `yaml:"redaction,omitempty"`
+}
+
+// RedactionRules extends doctor's built-in secret redaction with
+// organisation-specific rules, e.g. internal hostnames or customer IDs:
+//
+//	llm:
+//	  redaction:
+//	    patterns:
+//	    - '[a-z0-9-]+\.corp\.example\.com'
+//	    keys:
+//	    - customerId
+//	    - billing.accountNumber
+//	    allowlist:
+//	    - '^sha256:[a-f0-9]{64}$'
+type RedactionRules struct {
+	// Patterns are regular expressions (RE2 syntax). Every match is replaced
+	// with the redaction placeholder wherever it appears in the text.
+	Patterns []string `yaml:"patterns,omitempty"`
+
+	// Keys are YAML key names or dotted key paths. The value of any diff line
+	// whose key equals the rule, or ends with "."+rule, is redacted. Matching
+	// is case-insensitive.
+	Keys []string `yaml:"keys,omitempty"`
+
+	// Allowlist holds regular expressions for values that must never be
+	// redacted by the heuristic or pattern rules (false positives such as
+	// image digests). Values inside Kubernetes Secret data are always
+	// redacted regardless of the allowlist.
+	Allowlist []string `yaml:"allowlist,omitempty"`
+}
+
+// IsEmpty reports whether no custom redaction rule is configured.
+func (r RedactionRules) IsEmpty() bool {
+	return len(r.Patterns) == 0 && len(r.Keys) == 0 && len(r.Allowlist) == 0
 }
 
 // IsConfigured reports whether enough information is present to call the LLM.
@@ -105,6 +144,9 @@ func Merge(base, override Config) Config {
 	if override.Temperature != 0 {
 		out.Temperature = override.Temperature
 	}
+	if !override.Redaction.IsEmpty() {
+		out.Redaction = override.Redaction
+	}
 	return out
 }
 
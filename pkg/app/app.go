@@ -3,6 +3,7 @@ package app
 import (
 	"bytes"
 	goContext "context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -22,6 +23,9 @@ import (
 	"github.com/helmfile/helmfile/pkg/plugins"
 	"github.com/helmfile/helmfile/pkg/remote"
 	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/helmfile/helmfile/pkg/tmpl"
+	"github.com/helmfile/helmfile/pkg/tracing"
+	"github.com/helmfile/helmfile/pkg/tui"
 )
 
 var CleanWaitGroup sync.WaitGroup
@@ -40,6 +44,7 @@ type App struct {
 	RepoRetry                       int
 	DisableKubeVersionAutoDetection bool
 	SequentialHelmfiles             bool
+	Strict                          bool
 
 	Logger      *zap.SugaredLogger
 	Kubeconfig  string
@@ -63,6 +68,13 @@ type App struct {
 	helmsMutex sync.Mutex
 
 	ctx goContext.Context
+
+	// metrics collects the runs of the releases for the metrics of the command, when they are exported
+	metrics *runMetrics
+	// timing collects the spans and the releases for the timing report of the command, when it's printed
+	timing *runTiming
+	// ui is the terminal UI of the command, while it's shown
+	ui *runUI
 }
 
 type HelmRelease struct {
@@ -73,6 +85,17 @@ type HelmRelease struct {
 	Labels    string `json:"labels"`
 	Chart     string `json:"chart"`
 	Version   string `json:"version"`
+
+	// The fields below are only set by `list --live`, from the release installed in the cluster
+	DeployedVersion string `json:"deployedVersion,omitempty"`
+	Revision        string `json:"revision,omitempty"`
+	Status          string `json:"status,omitempty"`
+	Updated         string `json:"updated,omitempty"`
+	// Diverged is true when the installed release isn't what the helmfile describes:
+	// it isn't installed while it should be, or the other way around, or its chart version isn't the desired one
+	Diverged bool `json:"diverged,omitempty"`
+	// Orphan is true when the release is installed but not defined in any helmfile
+	Orphan bool `json:"orphan,omitempty"`
 }
 
 func New(conf ConfigProvider) *App {
@@ -90,6 +113,7 @@ func New(conf ConfigProvider) *App {
 		HelmOCIPlainHTTP:           conf.HelmOCIPlainHTTP(),
 		RepoRetry:                  conf.RepoRetry(),
 		SequentialHelmfiles:        conf.SequentialHelmfiles(),
+		Strict:                     conf.Strict(),
 		Logger:                     conf.Logger(),
 		Kubeconfig:                 conf.Kubeconfig(),
 		Env:                        conf.Env(),
@@ -233,6 +257,24 @@ func (a *App) Diff(c DiffConfigProvider) error {
 }
 
 func (a *App) Template(c TemplateConfigProvider) error {
+	// Sub-helmfiles are processed from within their own directories, so the
+	// snapshot directory has to be resolved before visiting any state.
+	snapshotDir := c.SnapshotDir()
+	var snapshotBaseDir string
+	if snapshotDir != "" {
+		abs, err := a.fs.Abs(snapshotDir)
+		if err != nil {
+			return err
+		}
+		snapshotDir = abs
+
+		// The snapshots of each state file are placed by its directory relative to this one
+		snapshotBaseDir, err = a.fs.Getwd()
+		if err != nil {
+			return err
+		}
+	}
+
 	return a.ForEachState(func(run *Run) (ok bool, errs []error) {
 		includeCRDs := c.IncludeCRDs()
 
@@ -259,7 +301,7 @@ func (a *App) Template(c TemplateConfigProvider) error {
 			HelmOCIPlainHTTP:       a.HelmOCIPlainHTTP,
 			TemplateArgs:           c.TemplateArgs(),
 		}, func() []error {
-			ok, errs = a.template(run, c)
+			ok, errs = a.template(run, c, snapshotDir, snapshotBaseDir)
 			return errs
 		})
 
@@ -363,6 +405,16 @@ func (a *App) Lint(c LintConfigProvider) error {
 func (a *App) Unittest(c UnittestConfigProvider) error {
 	var deferredUnittestErrors []error
 
+	var report *state.UnittestReport
+	if c.ReportFile() != "" {
+		switch c.ReportFormat() {
+		case "", "junit", "json":
+		default:
+			return fmt.Errorf("unsupported unittest report format %q: must be junit or json", c.ReportFormat())
+		}
+		report = &state.UnittestReport{}
+	}
+
 	err := a.ForEachState(func(run *Run) (ok bool, errs []error) {
 		var unittestErrs []error
 
@@ -376,7 +428,7 @@ func (a *App) Unittest(c UnittestConfigProvider) error {
 			Concurrency:            c.Concurrency(),
 			IncludeTransitiveNeeds: c.IncludeTransitiveNeeds(),
 		}, func() []error {
-			ok, unittestErrs, errs = a.unittest(run, c)
+			ok, unittestErrs, errs = a.unittest(run, c, report)
 			return append(errs, unittestErrs...)
 		})
 
@@ -391,6 +443,15 @@ func (a *App) Unittest(c UnittestConfigProvider) error {
 		return
 	}, c.IncludeNeeds())
 
+	if report != nil {
+		if writeErr := a.writeUnittestReport(report, c.ReportFile(), c.ReportFormat()); writeErr != nil {
+			if err == nil {
+				return writeErr
+			}
+			a.Logger.Warnf("%v", writeErr)
+		}
+	}
+
 	if err != nil {
 		return err
 	}
@@ -402,6 +463,26 @@ func (a *App) Unittest(c UnittestConfigProvider) error {
 	return nil
 }
 
+// writeUnittestReport writes the combined unittest report. It runs even when
+// tests failed, since that is when the report is most useful.
+func (a *App) writeUnittestReport(report *state.UnittestReport, path, format string) error {
+	var buf bytes.Buffer
+	var err error
+	if format == "json" {
+		err = report.WriteJSON(&buf)
+	} else {
+		err = report.WriteJUnit(&buf)
+	}
+	if err != nil {
+		return fmt.Errorf("rendering unittest report: %w", err)
+	}
+	if err := a.fs.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("writing unittest report to %s: %w", path, err)
+	}
+	a.Logger.Infof("Wrote unittest report to %s", path)
+	return nil
+}
+
 func (a *App) Fetch(c FetchConfigProvider) error {
 	if c.WriteOutput() && c.OutputDir() == "" {
 		return fmt.Errorf("--output-dir is required when --write-output is set")
@@ -498,6 +579,9 @@ func (a *App) Sync(c SyncConfigProvider) error {
 
 	mut := &sync.Mutex{}
 
+	a.startMetrics(c)
+	a.startTiming(c)
+	a.startUI(c, "sync")
 	err := a.ForEachState(func(run *Run) (ok bool, errs []error) {
 		includeCRDs := !c.SkipCRDs()
 
@@ -532,7 +616,10 @@ func (a *App) Sync(c SyncConfigProvider) error {
 		}
 
 		return
-	}, c.IncludeNeeds())
+	}, c.IncludeNeeds(), SetNeedsAcrossHelmfiles(true))
+	a.stopUI()
+	a.writeMetrics("sync", err)
+	a.printTiming(false, !c.NoColor())
 
 	if err != nil {
 		return err
@@ -554,8 +641,11 @@ func (a *App) Apply(c ApplyConfigProvider) error {
 
 	var opts []LoadOption
 
-	opts = append(opts, SetRetainValuesFiles(c.SkipCleanup()))
+	opts = append(opts, SetRetainValuesFiles(c.SkipCleanup()), SetNeedsAcrossHelmfiles(true))
 
+	a.startMetrics(c)
+	a.startTiming(c)
+	a.startUI(c, "apply")
 	err := a.ForEachState(func(run *Run) (ok bool, errs []error) {
 		includeCRDs := !c.SkipCRDs()
 
@@ -591,6 +681,9 @@ func (a *App) Apply(c ApplyConfigProvider) error {
 
 		return
 	}, c.IncludeNeeds(), opts...)
+	a.stopUI()
+	a.writeMetrics("apply", err)
+	a.printTiming(false, !c.NoColor())
 
 	if err != nil {
 		return err
@@ -606,13 +699,19 @@ func (a *App) Apply(c ApplyConfigProvider) error {
 }
 
 func (a *App) Status(c StatusesConfigProvider) error {
-	return a.ForEachState(func(run *Run) (ok bool, errs []error) {
+	// The statuses are collected to be printed at once with --output, instead of printing the output of each helm status
+	var statuses *releaseStatuses
+	if c.Output() != "" {
+		statuses = &releaseStatuses{}
+	}
+
+	err := a.ForEachState(func(run *Run) (ok bool, errs []error) {
 		err := run.WithPreparedCharts("status", state.ChartPrepareOptions{
 			SkipRepos:   true,
 			SkipDeps:    true,
 			Concurrency: c.Concurrency(),
 		}, func() []error {
-			ok, errs = a.status(run, c)
+			ok, errs = a.status(run, c, statuses)
 			return errs
 		})
 
@@ -622,10 +721,18 @@ func (a *App) Status(c StatusesConfigProvider) error {
 
 		return
 	}, false, SetFilter(true))
+
+	if err != nil || statuses == nil {
+		return err
+	}
+	return statuses.print(c.Output())
 }
 
 func (a *App) Destroy(c DestroyConfigProvider) error {
-	return a.ForEachState(func(run *Run) (ok bool, errs []error) {
+	a.startMetrics(c)
+	a.startTiming(c)
+	a.startUI(c, "destroy")
+	err := a.ForEachState(func(run *Run) (ok bool, errs []error) {
 		if !c.SkipCharts() {
 			err := run.WithPreparedCharts("destroy", state.ChartPrepareOptions{
 				SkipRepos:     c.SkipRefresh() || c.SkipDeps(),
@@ -645,7 +752,12 @@ func (a *App) Destroy(c DestroyConfigProvider) error {
 			ok, errs = a.delete(run, true, c)
 		}
 		return
-	}, false, SetReverse(true))
+	}, false, SetReverse(true), SetNeedsAcrossHelmfiles(true))
+	a.stopUI()
+	a.writeMetrics("destroy", err)
+	a.printTiming(true, !c.NoColor())
+
+	return err
 }
 
 func (a *App) Test(c TestConfigProvider) error {
@@ -675,24 +787,75 @@ func (a *App) Test(c TestConfigProvider) error {
 }
 
 func (a *App) PrintDAGState(c DAGConfigProvider) error {
-	var err error
-	return a.ForEachState(func(run *Run) (ok bool, errs []error) {
-		err = run.WithPreparedCharts("show-dag", state.ChartPrepareOptions{
+	var (
+		mu     sync.Mutex
+		graphs []*ReleaseGraph
+	)
+
+	// The graph outputs include the needs of the selected releases, so that
+	// they can be marked as transitive. The table is printed as before.
+	includeNeeds := c.Output() != "" && c.Output() != "table"
+
+	err := a.ForEachState(func(run *Run) (ok bool, errs []error) {
+		prepErr := run.WithPreparedCharts("show-dag", state.ChartPrepareOptions{
 			SkipRepos:   true,
 			SkipDeps:    true,
 			Concurrency: 2,
 		}, func() []error {
-			err = a.dag(run)
+			batches, err := a.dag(run)
 			if err != nil {
 				errs = append(errs, err)
+				return errs
+			}
+
+			switch c.Output() {
+			case "", "table":
+				fmt.Print(printDAG(batches))
+			default:
+				g, err := newReleaseGraph(run.state, batches)
+				if err != nil {
+					errs = append(errs, err)
+					return errs
+				}
+				mu.Lock()
+				graphs = append(graphs, g)
+				mu.Unlock()
 			}
 			return errs
 		})
+		if prepErr != nil {
+			errs = append(errs, prepErr)
+		}
 		return ok, errs
-	}, false, SetFilter(true))
+	}, includeNeeds, SetFilter(true))
+	if err != nil {
+		return err
+	}
+
+	graph := mergeReleaseGraphs(graphs)
+
+	switch c.Output() {
+	case "dot":
+		fmt.Print(graph.FormatAsDOT())
+	case "mermaid":
+		fmt.Print(graph.FormatAsMermaid())
+	case "json":
+		out, err := graph.FormatAsJSON()
+		if err != nil {
+			return err
+		}
+		fmt.Print(out)
+	}
+
+	return nil
 }
 
 func (a *App) PrintState(c StateConfigProvider) error {
+	opts := []LoadOption{SetFilter(true)}
+	if c.RenderTrace() {
+		opts = append(opts, SetRenderTrace(NewRenderTrace(os.Stderr)))
+	}
+
 	return a.ForEachState(func(run *Run) (_ bool, errs []error) {
 		err := run.WithPreparedCharts("build", state.ChartPrepareOptions{
 			SkipRepos:   true,
@@ -743,25 +906,23 @@ func (a *App) PrintState(c StateConfigProvider) error {
 		}
 
 		return false, errs
-	}, false, SetFilter(true))
+	}, false, opts...)
 }
 
-func (a *App) dag(r *Run) error {
+func (a *App) dag(r *Run) ([][]state.Release, error) {
 	st := r.state
 
-	batches, err := st.PlanReleases(state.PlanOptions{SelectedReleases: st.Releases, Reverse: false, SkipNeeds: false, IncludeNeeds: true, IncludeTransitiveNeeds: true})
-	if err != nil {
-		return err
-	}
-
-	fmt.Print(printDAG(batches))
-
-	return nil
+	return st.PlanReleases(state.PlanOptions{SelectedReleases: st.Releases, Reverse: false, SkipNeeds: false, IncludeNeeds: true, IncludeTransitiveNeeds: true})
 }
 
 func (a *App) ListReleases(c ListConfigProvider) error {
 	releasesChan := make(chan []*HelmRelease, 100)
 
+	var live *liveReleases
+	if c.Live() {
+		live = newLiveReleases()
+	}
+
 	err := a.ForEachState(func(run *Run) (_ bool, errs []error) {
 		var stateReleases []*HelmRelease
 		var listErr error
@@ -772,7 +933,7 @@ func (a *App) ListReleases(c ListConfigProvider) error {
 				SkipDeps:    true,
 				Concurrency: 2,
 			}, func() []error {
-				rel, err := a.list(run)
+				rel, err := a.list(run, live)
 				if err != nil {
 					errs = append(errs, err)
 					return []error{err}
@@ -784,7 +945,7 @@ func (a *App) ListReleases(c ListConfigProvider) error {
 				errs = append(errs, prepErr)
 			}
 		} else {
-			stateReleases, listErr = a.list(run)
+			stateReleases, listErr = a.list(run, live)
 			if listErr != nil {
 				errs = append(errs, listErr)
 			}
@@ -817,16 +978,24 @@ func (a *App) ListReleases(c ListConfigProvider) error {
 		return releases[i].Name < releases[j].Name
 	})
 
-	if c.Output() == "json" {
+	if live != nil {
+		releases = append(releases, live.orphans()...)
+	}
+
+	switch {
+	case c.Output() == "json":
 		err = FormatAsJson(releases)
-	} else {
+	case live != nil:
+		err = FormatLiveAsTable(releases)
+	default:
 		err = FormatAsTable(releases)
 	}
 
 	return err
 }
 
-func (a *App) list(run *Run) ([]*HelmRelease, error) {
+// list returns the releases of the state of run. With live, they are reconciled with the installed releases.
+func (a *App) list(run *Run, live *liveReleases) ([]*HelmRelease, error) {
 	var releases []*HelmRelease
 
 	resolvedState, err := run.state.ResolveDeps()
@@ -857,7 +1026,7 @@ func (a *App) list(run *Run) ([]*HelmRelease, error) {
 			return nil, err
 		}
 
-		releases = append(releases, &HelmRelease{
+		release := &HelmRelease{
 			Name:      r.Name,
 			Namespace: r.Namespace,
 			Installed: r.Desired(),
@@ -865,7 +1034,17 @@ func (a *App) list(run *Run) ([]*HelmRelease, error) {
 			Labels:    labels,
 			Chart:     r.Chart,
 			Version:   r.Version,
-		})
+		}
+		if live != nil {
+			if err := live.reconcile(run, &r, release); err != nil {
+				return nil, err
+			}
+		}
+		releases = append(releases, release)
+	}
+
+	if live != nil {
+		live.define(run.state)
 	}
 
 	return releases, nil
@@ -916,6 +1095,10 @@ func (a *App) loadDesiredStateFromYaml(file string, opts ...LoadOpts) (*state.He
 }
 
 func (a *App) loadDesiredStateFromYamlWithBaseDir(file string, baseDir string, opts ...LoadOpts) (*state.HelmState, error) {
+	return a.loadDesiredStateWithLogger(file, baseDir, a.Logger, opts...)
+}
+
+func (a *App) loadDesiredStateWithLogger(file string, baseDir string, logger *zap.SugaredLogger, opts ...LoadOpts) (*state.HelmState, error) {
 	var op LoadOpts
 	if len(opts) > 0 {
 		op = opts[0]
@@ -926,7 +1109,7 @@ func (a *App) loadDesiredStateFromYamlWithBaseDir(file string, baseDir string, o
 		env:       a.Env,
 		namespace: a.Namespace,
 		chart:     a.Chart,
-		logger:    a.Logger,
+		logger:    logger,
 		remote:    a.remote,
 		baseDir:   baseDir,
 
@@ -936,6 +1119,8 @@ func (a *App) loadDesiredStateFromYamlWithBaseDir(file string, baseDir string, o
 		enableLiveOutput:        a.EnableLiveOutput,
 		getHelm:                 a.getHelm,
 		valsRuntime:             a.valsRuntime,
+		renderTrace:             op.RenderTrace,
+		strict:                  a.Strict,
 	}
 
 	st, err := ld.Load(file, op)
@@ -1005,8 +1190,8 @@ func (a *App) getHelm(st *state.HelmState) (helmexec.Interface, error) {
 
 // processStateFileParallel processes a single helmfile state file in a goroutine.
 // It is used for parallel processing of multiple helmfile.d files.
-// Results are communicated via errChan (errors) and matchChan (whether file had matching releases).
-func (a *App) processStateFileParallel(relPath string, defOpts LoadOpts, converge func(*state.HelmState) (bool, []error), sharedCtx *Context, errChan chan error, matchChan chan bool) {
+// It returns whether the file or any of its nested helmfiles had matching releases.
+func (a *App) processStateFileParallel(relPath string, defOpts LoadOpts, converge func(*state.HelmState) (bool, []error), sharedCtx *Context) (bool, error) {
 	var file string
 	var dir string
 	if a.fs.DirectoryExistsAt(relPath) {
@@ -1019,8 +1204,7 @@ func (a *App) processStateFileParallel(relPath string, defOpts LoadOpts, converg
 
 	absd, errAbsDir := a.fs.Abs(dir)
 	if errAbsDir != nil {
-		errChan <- errAbsDir
-		return
+		return false, errAbsDir
 	}
 
 	opts := defOpts.DeepCopy()
@@ -1028,49 +1212,45 @@ func (a *App) processStateFileParallel(relPath string, defOpts LoadOpts, converg
 		opts.CalleePath = file
 	}
 
-	st, err := a.loadDesiredStateFromYamlWithBaseDir(file, absd, opts)
+	st, err := sharedCtx.helmfiles.load(filepath.Join(absd, file), opts, func() (*state.HelmState, error) {
+		return a.loadDesiredStateWithLogger(file, absd, a.Logger, opts)
+	})
 	if err != nil {
 		switch stateLoadErr := err.(type) {
 		case *state.StateLoadError:
 			switch stateLoadErr.Cause.(type) {
 			case *state.UndefinedEnvError:
-				return
+				return false, nil
 			default:
-				errChan <- appError(fmt.Sprintf("in %s/%s", dir, file), err)
-				return
+				return false, appError(fmt.Sprintf("in %s/%s", dir, file), err)
 			}
 		default:
-			errChan <- appError(fmt.Sprintf("in %s/%s", dir, file), err)
-			return
+			return false, appError(fmt.Sprintf("in %s/%s", dir, file), err)
 		}
 	}
 
 	if st == nil {
-		return
+		return false, nil
 	}
 
 	st.Selectors = opts.Selectors
 
 	// Track whether any releases matched across nested helmfiles and converge.
-	// Aggregate into a single send to matchChan to avoid overfilling the buffer
-	// (which is sized to len(desiredStateFiles), i.e. one send per goroutine).
 	anyMatched := false
 
 	if len(st.Helmfiles) > 0 && !opts.Reverse {
 		matched, err := a.processNestedHelmfiles(st, absd, file, defOpts, opts, converge, sharedCtx)
 		if err != nil {
-			errChan <- err
-			return
+			return false, err
 		}
 		if matched {
 			anyMatched = true
 		}
 	}
 
-	templated, err := st.ExecuteTemplates()
+	templated, err := sharedCtx.helmfiles.executeTemplates(st)
 	if err != nil {
-		errChan <- appError(fmt.Sprintf("in %s/%s: failed executing release templates in \"%s\"", dir, file, file), err)
-		return
+		return false, appError(fmt.Sprintf("in %s/%s: failed executing release templates in \"%s\"", dir, file, file), err)
 	}
 
 	var errs []error
@@ -1081,15 +1261,13 @@ func (a *App) processStateFileParallel(relPath string, defOpts LoadOpts, converg
 		cleanErr = context{app: a, st: templated, retainValues: defOpts.RetainValuesFiles}.clean(errs)
 	}()
 
-	processed, errs := converge(templated)
+	processed, errs := a.convergeHelmfile(filepath.Join(absd, file), templated, converge, sharedCtx)
 
 	if len(errs) > 0 {
-		errChan <- errs[0]
-		return
+		return false, errs[0]
 	}
 	if cleanErr != nil {
-		errChan <- cleanErr
-		return
+		return false, cleanErr
 	}
 
 	if processed {
@@ -1099,17 +1277,84 @@ func (a *App) processStateFileParallel(relPath string, defOpts LoadOpts, converg
 	if opts.Reverse && len(st.Helmfiles) > 0 {
 		matched, err := a.processNestedHelmfiles(st, absd, file, defOpts, opts, converge, sharedCtx)
 		if err != nil {
-			errChan <- err
-			return
+			return false, err
 		}
 		if matched {
 			anyMatched = true
 		}
 	}
 
-	if anyMatched {
-		matchChan <- true
+	return anyMatched, nil
+}
+
+// convergeHelmfile runs converge on the helmfile at path, whose releases wait
+// for the releases of other helmfiles they need, or only records its releases
+// while the helmfiles loaded by the command are being collected.
+func (a *App) convergeHelmfile(path string, templated *state.HelmState, converge func(*state.HelmState) (bool, []error), sharedCtx *Context) (bool, []error) {
+	g := sharedCtx.helmfiles
+	if g.isRecording() {
+		g.record(path, templated)
+		return true, nil
 	}
+
+	templated.ExternalReleases = g.externalReleases(path)
+	templated.UndefinedNeedsExternal = g == nil && sharedCtx.multipleHelmfiles
+
+	run := g.start(path, templated)
+
+	processed, errs := converge(templated)
+
+	run.finish(len(errs) == 0)
+
+	return processed, errs
+}
+
+// buildHelmfileGraph collects the releases of every helmfile visit loads, and
+// sets the resulting graph on sharedCtx until the returned function is called.
+func (a *App) buildHelmfileGraph(sharedCtx *Context, wd string, reverse bool, visit func(recCtx *Context) error) (func(), error) {
+	recCtx := NewContext()
+	recCtx.helmfiles = newHelmfileGraph(wd, reverse, true)
+
+	if err := visit(&recCtx); err != nil {
+		if _, noMatch := err.(*NoMatchingHelmfileError); !noMatch {
+			return nil, err
+		}
+	}
+
+	g := recCtx.helmfiles
+	if err := g.resolve(); err != nil {
+		return nil, err
+	}
+
+	g.recording = false
+	g.active = 1
+	sharedCtx.helmfiles = g
+
+	return func() { sharedCtx.helmfiles = nil }, nil
+}
+
+// sortHelmfilesByNeeds sorts the helmfiles processed one by one so that each
+// comes after the helmfiles defining the releases it needs.
+func (a *App) sortHelmfilesByNeeds(desiredStateFiles []string, g *helmfileGraph) ([]string, error) {
+	paths := make([]string, 0, len(desiredStateFiles))
+	for _, relPath := range desiredStateFiles {
+		file, dir := relPath, relPath
+		if !a.fs.DirectoryExistsAt(relPath) {
+			file = filepath.Base(relPath)
+			dir = filepath.Dir(relPath)
+		}
+		absd, err := a.fs.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, filepath.Join(absd, file))
+	}
+
+	sorted := make([]string, 0, len(desiredStateFiles))
+	for _, i := range g.order(paths) {
+		sorted = append(sorted, desiredStateFiles[i])
+	}
+	return sorted, nil
 }
 
 // processNestedHelmfiles processes sub-helmfiles referenced from a parent state.
@@ -1136,6 +1381,7 @@ func (a *App) processNestedHelmfiles(st *state.HelmState, absd, file string, def
 			Environment:       m.Environment,
 			Reverse:           defOpts.Reverse,
 			RetainValuesFiles: defOpts.RetainValuesFiles,
+			RenderTrace:       defOpts.RenderTrace,
 		}
 		if (m.Selectors == nil && !isExplicitSelectorInheritanceEnabled()) || m.SelectorsInherited {
 			optsForNestedState.Selectors = opts.Selectors
@@ -1154,6 +1400,12 @@ func (a *App) processNestedHelmfiles(st *state.HelmState, absd, file string, def
 			optsForNestedState.Inherited = inherited
 		}
 
+		// A sandboxed sub-helmfile is restricted by the sandboxes of its parents too.
+		optsForNestedState.Sandbox = st.Sandbox
+		if m.Sandbox != nil {
+			optsForNestedState.Sandbox = &tmpl.Sandbox{AllowedEnv: m.Sandbox.AllowedEnv, Parent: st.Sandbox}
+		}
+
 		if err := a.visitStatesWithContext(m.Path, optsForNestedState, converge, sharedCtx); err != nil {
 			switch err.(type) {
 			case *NoMatchingHelmfileError:
@@ -1188,11 +1440,36 @@ func subhelmfileSelectorsConflict(cliSelectors []string, m state.SubHelmfileSpec
 func (a *App) visitStatesWithContext(fileOrDir string, defOpts LoadOpts, converge func(*state.HelmState) (bool, []error), sharedCtx *Context) error {
 	noMatchInHelmfiles := true
 
-	desiredStateFiles, findErr := a.findDesiredStateFiles(fileOrDir, defOpts)
+	desiredStateFiles, findErr := a.findDesiredStateFiles(fileOrDir, defOpts, sharedCtx.helmfiles.logger(a.Logger))
 	if findErr != nil {
 		return appError("", findErr)
 	}
 
+	if len(desiredStateFiles) > 1 && !sharedCtx.multipleHelmfiles {
+		sharedCtx.multipleHelmfiles = true
+	}
+
+	// Releases may need releases defined in other helmfiles loaded by this
+	// command, so collect all of them first to order the releases accordingly.
+	var wd string
+	if sharedCtx.helmfiles == nil && defOpts.NeedsAcrossHelmfiles {
+		var err error
+		wd, err = a.fs.Getwd()
+		if err != nil {
+			return err
+		}
+
+		if len(desiredStateFiles) > 1 {
+			reset, err := a.buildHelmfileGraph(sharedCtx, wd, defOpts.Reverse, func(recCtx *Context) error {
+				return a.visitStatesWithContext(fileOrDir, defOpts, converge, recCtx)
+			})
+			if err != nil {
+				return err
+			}
+			defer reset()
+		}
+	}
+
 	// Process files in parallel if we have multiple files and parallel mode is enabled
 	shouldProcessInParallel := len(desiredStateFiles) > 1 && !a.SequentialHelmfiles
 
@@ -1202,11 +1479,19 @@ func (a *App) visitStatesWithContext(fileOrDir string, defOpts LoadOpts, converg
 		errChan := make(chan error, len(desiredStateFiles))
 		matchChan := make(chan bool, len(desiredStateFiles))
 
+		group := sharedCtx.helmfiles.fork(len(desiredStateFiles))
 		for _, relPath := range desiredStateFiles {
 			wg.Add(1)
 			go func(relPath string) {
 				defer wg.Done()
-				a.processStateFileParallel(relPath, defOpts, converge, sharedCtx, errChan, matchChan)
+				matched, err := a.processStateFileParallel(relPath, defOpts, converge, sharedCtx)
+				sharedCtx.helmfiles.exit(group, err)
+				if err != nil {
+					errChan <- err
+				}
+				if matched {
+					matchChan <- true
+				}
 			}(relPath)
 		}
 
@@ -1214,11 +1499,17 @@ func (a *App) visitStatesWithContext(fileOrDir string, defOpts LoadOpts, converg
 		close(errChan)
 		close(matchChan)
 
+		// Report the failure that made other helmfiles skipped, rather than
+		// one of the helmfiles it skipped.
+		var firstErr error
 		for err := range errChan {
-			if err != nil {
-				return err
+			if firstErr == nil || isHelmfileSkipError(firstErr) && !isHelmfileSkipError(err) {
+				firstErr = err
 			}
 		}
+		if firstErr != nil {
+			return firstErr
+		}
 
 		// Check if any files had matching releases
 		for range matchChan {
@@ -1234,6 +1525,14 @@ func (a *App) visitStatesWithContext(fileOrDir string, defOpts LoadOpts, converg
 		//   os.Chdir, which fixes relative env var paths like KUBECONFIG (#2409).
 		useBaseDir := len(desiredStateFiles) > 1
 
+		if sharedCtx.helmfiles != nil {
+			sorted, err := a.sortHelmfilesByNeeds(desiredStateFiles, sharedCtx.helmfiles)
+			if err != nil {
+				return err
+			}
+			desiredStateFiles = sorted
+		}
+
 		for _, relPath := range desiredStateFiles {
 			var file string
 			var dir string
@@ -1259,15 +1558,16 @@ func (a *App) visitStatesWithContext(fileOrDir string, defOpts LoadOpts, converg
 				var st *state.HelmState
 				var loadErr error
 
-				if useBaseDir {
-					// Multi-file sequential: use absolute baseDir for path resolution
-					// instead of os.Chdir to avoid breaking relative env var paths.
-					// Must use absd (absolute dir) to correctly resolve relative values/secrets paths.
-					st, loadErr = a.loadDesiredStateFromYamlWithBaseDir(file, absd, opts)
-				} else {
+				st, loadErr = sharedCtx.helmfiles.load(filepath.Join(absd, file), opts, func() (*state.HelmState, error) {
+					if useBaseDir {
+						// Multi-file sequential: use absolute baseDir for path resolution
+						// instead of os.Chdir to avoid breaking relative env var paths.
+						// Must use absd (absolute dir) to correctly resolve relative values/secrets paths.
+						return a.loadDesiredStateWithLogger(file, absd, a.Logger, opts)
+					}
 					// Single file: CWD is set by within(), load without baseDir
-					st, loadErr = a.loadDesiredStateFromYaml(file, opts)
-				}
+					return a.loadDesiredStateWithLogger(file, "", a.Logger, opts)
+				})
 
 				ctx := context{app: a, st: st, retainValues: defOpts.RetainValuesFiles}
 
@@ -1291,6 +1591,30 @@ func (a *App) visitStatesWithContext(fileOrDir string, defOpts LoadOpts, converg
 
 				st.Selectors = opts.Selectors
 
+				if len(st.Helmfiles) > 0 && !sharedCtx.multipleHelmfiles {
+					sharedCtx.multipleHelmfiles = true
+				}
+
+				// A single helmfile only needs ordering across helmfiles
+				// when it has sub-helmfiles.
+				if sharedCtx.helmfiles == nil && defOpts.NeedsAcrossHelmfiles && len(st.Helmfiles) > 0 {
+					reset, err := a.buildHelmfileGraph(sharedCtx, wd, defOpts.Reverse, func(recCtx *Context) error {
+						if _, err := a.processNestedHelmfiles(st, absd, file, defOpts, opts, converge, recCtx); err != nil {
+							return err
+						}
+						templated, err := recCtx.helmfiles.executeTemplates(st)
+						if err != nil {
+							return err
+						}
+						recCtx.helmfiles.record(filepath.Join(absd, file), templated)
+						return nil
+					})
+					if err != nil {
+						return err
+					}
+					defer reset()
+				}
+
 				if !opts.Reverse && len(st.Helmfiles) > 0 {
 					matched, err := a.processNestedHelmfiles(st, absd, file, defOpts, opts, converge, sharedCtx)
 					if err != nil {
@@ -1301,7 +1625,7 @@ func (a *App) visitStatesWithContext(fileOrDir string, defOpts LoadOpts, converg
 					}
 				}
 
-				templated, tmplErr := st.ExecuteTemplates()
+				templated, tmplErr := sharedCtx.helmfiles.executeTemplates(st)
 				if tmplErr != nil {
 					return appError(fmt.Sprintf("failed executing release templates in \"%s\"", file), tmplErr)
 				}
@@ -1322,7 +1646,7 @@ func (a *App) visitStatesWithContext(fileOrDir string, defOpts LoadOpts, converg
 					}
 				}()
 
-				processed, errs = converge(templated)
+				processed, errs = a.convergeHelmfile(filepath.Join(absd, file), templated, converge, sharedCtx)
 
 				if len(errs) > 0 {
 					return errs[0]
@@ -1382,6 +1706,18 @@ var (
 			o.Filter = f
 		}
 	}
+
+	SetRenderTrace = func(t *RenderTrace) func(o *LoadOpts) {
+		return func(o *LoadOpts) {
+			o.RenderTrace = t
+		}
+	}
+
+	SetNeedsAcrossHelmfiles = func(n bool) func(o *LoadOpts) {
+		return func(o *LoadOpts) {
+			o.NeedsAcrossHelmfiles = n
+		}
+	}
 )
 
 // ForEachState iterates over each loaded state file and invokes do.
@@ -1493,7 +1829,13 @@ func withBatches(purpose string, templated *state.HelmState, batches [][]state.R
 		batchSt := *templated
 		batchSt.Releases = targets
 
+		span := tracing.StartSpan("release group",
+			tracing.Helmfile.String(templated.FilePath),
+			tracing.Batch.Int(i+1),
+			tracing.Releases.StringSlice(releaseIds),
+		)
 		processed, errs := converge(&batchSt, helm)
+		tracing.End(span, errors.Join(errs...))
 
 		if len(errs) > 0 {
 			return false, errs
@@ -1505,6 +1847,224 @@ func withBatches(purpose string, templated *state.HelmState, batches [][]state.R
 	return any, nil
 }
 
+// withStreamingDAG plans the releases like withDAG, but unless lockstep is set
+// it runs them with withStreaming instead of withBatches, so that a release
+// doesn't wait for unrelated releases that happen to share its group.
+// skipped, when not nil, is called with the releases withStreaming skips.
+// external, when not nil, makes releases wait for the releases of other
+// helmfiles they depend on too.
+func withStreamingDAG(templated *state.HelmState, helm helmexec.Interface, logger *zap.SugaredLogger, opts state.PlanOptions, concurrency int, lockstep bool, skipped func(*state.ReleaseSpec, string), external *helmfileRun, converge func(*state.HelmState, helmexec.Interface) (bool, []error)) (bool, []error) {
+	batches, err := templated.PlanReleases(opts)
+	if err != nil {
+		return false, []error{err}
+	}
+
+	if lockstep {
+		return withBatches(opts.Purpose, templated, batches, helm, logger, external.waitForBatch(opts.Reverse, logger, converge))
+	}
+
+	return withStreaming(opts.Purpose, opts.Reverse, templated, batches, helm, logger, concurrency, skipped, external, converge)
+}
+
+// withStreaming runs converge once per release and starts each release as soon
+// as the releases it needs are done. With reverse, a release instead waits for
+// the releases that need it, which is the order releases are deleted in.
+//
+// At most concurrency releases are processed at a time, 0 being unlimited.
+// Releases that become ready together are started in the order of the plan, so
+// that concurrency 1 processes releases in the same order as withBatches.
+// A failed release skips everything that waits for it, while independent
+// releases keep going. skipped, when not nil, is called with each skipped
+// release and the ID of the failed release it waits for.
+//
+// external, when not nil, also makes releases wait for the releases of other
+// helmfiles they depend on, and is told when each release is done.
+func withStreaming(purpose string, reverse bool, templated *state.HelmState, batches [][]state.Release, helm helmexec.Interface, logger *zap.SugaredLogger, concurrency int, skipped func(*state.ReleaseSpec, string), external *helmfileRun, converge func(*state.HelmState, helmexec.Interface) (bool, []error)) (bool, []error) {
+	if purpose == "" {
+		purpose = "processing"
+	}
+
+	logger.Debugf("%s %d groups of releases in this order:\n%s", purpose, len(batches), printBatches(batches))
+
+	var releases []state.ReleaseSpec
+	// groups[i] is the group of the plan releases[i] is in, numbered from 1 like in the logs
+	var groups []int
+	for g, batch := range batches {
+		for _, marked := range batch {
+			releases = append(releases, marked.ReleaseSpec)
+			groups = append(groups, g+1)
+		}
+	}
+
+	ids := make([]string, len(releases))
+	idToIndices := map[string][]int{}
+	for i := range releases {
+		ids[i] = state.ReleaseToID(&releases[i])
+		idToIndices[ids[i]] = append(idToIndices[ids[i]], i)
+	}
+
+	// waiting[i] is the number of releases i still waits for, and unblocks[i]
+	// lists the releases that wait for i.
+	waiting := make([]int, len(releases))
+	unblocks := make([][]int, len(releases))
+	for i, r := range releases {
+		// Needs that aren't part of the plan, e.g. due to --skip-needs, are
+		// not waited for.
+		for _, need := range r.Needs {
+			for _, j := range idToIndices[need] {
+				first, then := j, i
+				if reverse {
+					first, then = i, j
+				}
+				waiting[then]++
+				unblocks[first] = append(unblocks[first], then)
+			}
+		}
+	}
+
+	// externals is the number of releases still waiting for releases of other helmfiles.
+	var externals int
+	if external.orders(reverse) {
+		for i := range releases {
+			if external.wait(i, ids[i]) {
+				waiting[i]++
+				externals++
+			}
+		}
+	}
+
+	var ready []int
+	for i := range releases {
+		if waiting[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	// blocked[i] is the ID of a failed release i waits for, directly or through skipped releases
+	blocked := make([]string, len(releases))
+
+	var done func(i int, failed string)
+
+	// unblock is called when release j waits for one release less, where
+	// failed is the ID of the failed release it waited for, if any.
+	unblock := func(j int, failed string) {
+		if blocked[j] == "" {
+			blocked[j] = failed
+		}
+		waiting[j]--
+		if waiting[j] > 0 {
+			return
+		}
+		if blocked[j] == ids[j] {
+			// Waiting for releases of other helmfiles failed.
+			done(j, blocked[j])
+			return
+		}
+		if blocked[j] != "" {
+			logger.Warnf("Skipping release %s, as release %s it waits for failed", ids[j], blocked[j])
+			if skipped != nil {
+				skipped(&releases[j], blocked[j])
+			}
+			done(j, blocked[j])
+			return
+		}
+		ready = append(ready, j)
+	}
+
+	// done is called when release i is done, where failed is the ID of the
+	// release that failed and made i fail or be skipped, if any.
+	done = func(i int, failed string) {
+		external.releaseDone(ids[i], failed == "")
+		for _, j := range unblocks[i] {
+			unblock(j, failed)
+		}
+		sort.Ints(ready)
+	}
+
+	var (
+		running   int
+		processed bool
+		errs      []error
+	)
+
+	// events handles the releases that no longer wait for releases of other helmfiles.
+	events := func(evs []releaseEvent) {
+		for _, ev := range evs {
+			externals--
+			failed := ev.failed
+			if ev.err != nil {
+				errs = append(errs, ev.err)
+				failed = ids[ev.index]
+			}
+			unblock(ev.index, failed)
+		}
+		sort.Ints(ready)
+	}
+
+	type result struct {
+		index     int
+		processed bool
+		errs      []error
+	}
+
+	results := make(chan result)
+
+	for len(ready) > 0 || running > 0 || externals > 0 {
+		for len(ready) > 0 && (concurrency <= 0 || running < concurrency) {
+			i := ready[0]
+			ready = ready[1:]
+
+			logger.Debugf("%s release %s", purpose, ids[i])
+
+			running++
+			go func() {
+				st := *templated
+				st.Releases = []state.ReleaseSpec{releases[i]}
+
+				span := tracing.StartSpan("release",
+					tracing.Helmfile.String(templated.FilePath),
+					tracing.Release.String(releases[i].Name),
+					tracing.Namespace.String(releases[i].Namespace),
+					tracing.KubeContext.String(releases[i].KubeContext),
+					tracing.Batch.Int(groups[i]),
+				)
+				p, es := converge(&st, helm)
+				tracing.End(span, errors.Join(es...))
+				results <- result{index: i, processed: p, errs: es}
+			}()
+		}
+
+		if running == 0 {
+			events(external.next())
+			continue
+		}
+
+		var res result
+		select {
+		case res = <-results:
+		case <-external.signalled():
+			events(external.take())
+			continue
+		}
+		running--
+
+		processed = processed || res.processed
+		errs = append(errs, res.errs...)
+
+		var failed string
+		if len(res.errs) > 0 {
+			failed = ids[res.index]
+		}
+		done(res.index, failed)
+	}
+
+	if len(errs) > 0 {
+		return false, errs
+	}
+
+	return processed, nil
+}
+
 type Opts struct {
 	DAGEnabled bool
 }
@@ -1625,13 +2185,13 @@ func (a *App) WrapWithoutSelector(converge func(*state.HelmState, helmexec.Inter
 	}
 }
 
-func (a *App) findDesiredStateFiles(specifiedPath string, opts LoadOpts) ([]string, error) {
+func (a *App) findDesiredStateFiles(specifiedPath string, opts LoadOpts, logger *zap.SugaredLogger) ([]string, error) {
 	path, err := a.remote.Locate(specifiedPath, "states")
 	if err != nil {
 		return nil, fmt.Errorf("locate: %v", err)
 	}
 	if specifiedPath != path {
-		a.Logger.Debugf("fetched remote \"%s\" to local cache \"%s\" and loading the latter...", specifiedPath, path)
+		logger.Debugf("fetched remote \"%s\" to local cache \"%s\" and loading the latter...", specifiedPath, path)
 	}
 	specifiedPath = path
 
@@ -1658,6 +2218,12 @@ func (a *App) findDesiredStateFiles(specifiedPath string, opts LoadOpts) ([]stri
 		case a.fs.FileExistsAt(DefaultGotmplHelmfile):
 			defaultFile = DefaultGotmplHelmfile
 		}
+		if a.fs.FileExistsAt(DefaultHCLHelmfile) {
+			if defaultFile != "" {
+				return []string{}, fmt.Errorf("both %s and %s exist. Please remove one of them", defaultFile, DefaultHCLHelmfile)
+			}
+			defaultFile = DefaultHCLHelmfile
+		}
 
 		switch {
 		case a.fs.DirectoryExistsAt(DefaultHelmfileDirectory):
@@ -1669,7 +2235,7 @@ func (a *App) findDesiredStateFiles(specifiedPath string, opts LoadOpts) ([]stri
 		case defaultFile != "":
 			return []string{defaultFile}, nil
 		default:
-			return []string{}, fmt.Errorf("no state file found. It must be named %s/*.{yaml,yml,yaml.gotmpl,yml.gotmpl}, %s, or %s, otherwise specified with the --file flag or %s environment variable", DefaultHelmfileDirectory, DefaultHelmfile, DefaultGotmplHelmfile, envvar.FilePath)
+			return []string{}, fmt.Errorf("no state file found. It must be named %s/*.{yaml,yml,yaml.gotmpl,yml.gotmpl,helmfile.hcl}, %s, %s, or %s, otherwise specified with the --file flag or %s environment variable", DefaultHelmfileDirectory, DefaultHelmfile, DefaultGotmplHelmfile, DefaultHCLHelmfile, envvar.FilePath)
 		}
 	}
 
@@ -1684,8 +2250,14 @@ func (a *App) findDesiredStateFiles(specifiedPath string, opts LoadOpts) ([]stri
 		return []string{}, err
 	}
 
+	hclFiles, err := a.fs.Glob(filepath.Join(helmfileDir, "*"+DefaultHCLHelmfileSuffix))
+	if err != nil {
+		return []string{}, err
+	}
+
 	files = append(files, ymlFiles...)
 	files = append(files, gotmplFiles...)
+	files = append(files, hclFiles...)
 
 	if opts.Reverse {
 		sort.Slice(files, func(i, j int) bool {
@@ -1697,7 +2269,7 @@ func (a *App) findDesiredStateFiles(specifiedPath string, opts LoadOpts) ([]stri
 		})
 	}
 
-	a.Logger.Debugf("found %d helmfile state files in %s: %s", len(ymlFiles)+len(gotmplFiles), helmfileDir, strings.Join(files, ", "))
+	logger.Debugf("found %d helmfile state files in %s: %s", len(files), helmfileDir, strings.Join(files, ", "))
 
 	return files, nil
 }
@@ -1830,6 +2402,7 @@ func (a *App) apply(r *Run, c ApplyConfigProvider) (bool, bool, []error) {
 	// Do build deps and prepare only on selected releases so that we won't waste time
 	// on running various helm commands on unnecessary releases
 	st.Releases = releasesWithNeeds
+	a.ui.queue(releasesWithNeeds)
 
 	// helm must be 2.11+ and helm-diff should be provided `--detailed-exitcode` in order for `helmfile apply` to work properly
 	detailedExitCode := true
@@ -1857,21 +2430,16 @@ func (a *App) apply(r *Run, c ApplyConfigProvider) (bool, bool, []error) {
 		ServerSide:                  c.ServerSide(),
 		DetectedKubeVersion:         detectedKubeVersion,
 	}
+	if c.ApproveReleases() || a.ui != nil {
+		// The diff of each release is shown again when asking whether to apply it, or with the release in the ui
+		diffOpts.Outputs = state.NewDiffOutputs()
+	}
 
 	infoMsg, releasesToUpdate, releasesToDelete, diffErrs := r.diff(false, detailedExitCode, c, diffOpts)
 	if len(diffErrs) > 0 {
 		return false, false, diffErrs
 	}
-
-	var toDelete []state.ReleaseSpec
-	for _, r := range releasesToDelete {
-		toDelete = append(toDelete, r)
-	}
-
-	var toUpdate []state.ReleaseSpec
-	for _, r := range releasesToUpdate {
-		toUpdate = append(toUpdate, r)
-	}
+	a.ui.diffs(releasesWithNeeds, diffOpts.Outputs)
 
 	releasesWithNoChange := map[string]state.ReleaseSpec{}
 	for _, r := range releasesWithNeeds {
@@ -1883,6 +2451,7 @@ func (a *App) apply(r *Run, c ApplyConfigProvider) (bool, bool, []error) {
 			releasesWithNoChange[id] = release
 		}
 	}
+	a.ui.unchanged(releasesWithNoChange)
 
 	infoMsgStr := ""
 	if infoMsg != nil {
@@ -1910,7 +2479,32 @@ Do you really want to apply?
 	}
 	affectedReleases := state.AffectedReleases{}
 
-	if !interactive || interactive && r.askForConfirmation(confMsg) {
+	confirmed := !interactive
+	if c.ApproveReleases() {
+		var skipped []state.ReleaseSpec
+		releasesToUpdate, releasesToDelete, skipped = a.approveReleases(r, releasesWithNeeds, releasesToUpdate, releasesToDelete, diffOpts.Outputs)
+		for _, r := range skipped {
+			release := r
+			releasesWithNoChange[state.ReleaseToID(&release)] = release
+		}
+		confirmed = len(releasesToUpdate) > 0 || len(releasesToDelete) > 0
+	} else if interactive {
+		confirmed = r.askForConfirmation(confMsg)
+	}
+
+	var toDelete []state.ReleaseSpec
+	for _, r := range releasesToDelete {
+		toDelete = append(toDelete, r)
+	}
+
+	var toUpdate []state.ReleaseSpec
+	for _, r := range releasesToUpdate {
+		toUpdate = append(toUpdate, r)
+	}
+
+	if confirmed {
+		rec := a.startRun(r, "apply")
+
 		if _, preapplyErrors := withDAG(st, helm, a.Logger, state.PlanOptions{Purpose: "invoking preapply hooks for", Reverse: true, SelectedReleases: releasesWithNeeds, SkipNeeds: true}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
 			for _, r := range subst.Releases {
 				release := r
@@ -1921,12 +2515,13 @@ Do you really want to apply?
 
 			return nil
 		})); len(preapplyErrors) > 0 {
+			rec.finish(&affectedReleases, preapplyErrors)
 			return true, false, preapplyErrors
 		}
 
 		// We deleted releases by traversing the DAG in reverse order
 		if len(releasesToDelete) > 0 {
-			_, deletionErrs := withDAG(st, helm, a.Logger, state.PlanOptions{Reverse: true, SelectedReleases: toDelete, SkipNeeds: true}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
+			_, deletionErrs := withStreamingDAG(st, helm, a.Logger, state.PlanOptions{Reverse: true, SelectedReleases: toDelete, SkipNeeds: true}, c.Concurrency(), c.LockstepBatches(), affectedReleases.Skip, r.external(), rec.notifyFailures(&affectedReleases, a.watch(tui.Deleting, releasesToDelete, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
 				var rs []state.ReleaseSpec
 
 				for _, r := range subst.Releases {
@@ -1939,7 +2534,7 @@ Do you really want to apply?
 				subst.Releases = rs
 
 				return subst.DeleteReleasesForSync(&affectedReleases, helm, c.Concurrency(), c.Cascade())
-			}))
+			}))))
 
 			if len(deletionErrs) > 0 {
 				errs = append(errs, deletionErrs...)
@@ -1948,7 +2543,7 @@ Do you really want to apply?
 
 		// We upgrade releases by traversing the DAG
 		if len(releasesToUpdate) > 0 {
-			_, updateErrs := withDAG(st, helm, a.Logger, state.PlanOptions{SelectedReleases: toUpdate, SkipNeeds: true, IncludeTransitiveNeeds: c.IncludeTransitiveNeeds()}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
+			_, updateErrs := withStreamingDAG(st, helm, a.Logger, state.PlanOptions{SelectedReleases: toUpdate, SkipNeeds: true, IncludeTransitiveNeeds: c.IncludeTransitiveNeeds()}, c.Concurrency(), c.LockstepBatches(), affectedReleases.Skip, r.external(), rec.notifyFailures(&affectedReleases, a.watch(tui.Syncing, releasesToUpdate, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
 				var rs []state.ReleaseSpec
 
 				for _, r := range subst.Releases {
@@ -1989,15 +2584,17 @@ Do you really want to apply?
 					NoColor:              c.NoColor(),
 				}
 				return subst.SyncReleases(&affectedReleases, helm, c.Values(), c.Concurrency(), syncOpts)
-			}))
+			}))))
 
 			if len(updateErrs) > 0 {
 				errs = append(errs, updateErrs...)
 			}
 		}
+
+		rec.finish(&affectedReleases, errs)
 	}
 
-	affectedReleases.DisplayAffectedReleases(c.Logger(), !c.NoColor())
+	affectedReleases.DisplayAffectedReleases(a.logger(c), !c.NoColor())
 
 	for id := range releasesWithNoChange {
 		r := releasesWithNoChange[id]
@@ -2037,6 +2634,7 @@ func (a *App) delete(r *Run, purge bool, c DestroyConfigProvider) (bool, []error
 		id := state.ReleaseToID(&release)
 		releasesToDelete[id] = release
 	}
+	a.ui.queue(toDelete)
 
 	releasesWithNoChange := map[string]state.ReleaseSpec{}
 	for _, r := range toSync {
@@ -2071,19 +2669,23 @@ Do you really want to delete?
 `, strings.Join(names, "\n"))
 	interactive := c.Interactive()
 	if !interactive || interactive && r.askForConfirmation(msg) {
+		rec := a.startRun(r, "destroy")
+
 		r.helm.SetExtraArgs(GetArgs(c.Args(), r.state)...)
 
 		if len(releasesToDelete) > 0 {
-			_, deletionErrs := withDAG(st, helm, a.Logger, state.PlanOptions{SelectedReleases: toDelete, Reverse: true, SkipNeeds: true}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
+			_, deletionErrs := withStreamingDAG(st, helm, a.Logger, state.PlanOptions{SelectedReleases: toDelete, Reverse: true, SkipNeeds: true}, c.Concurrency(), c.LockstepBatches(), affectedReleases.Skip, r.external(), rec.notifyFailures(&affectedReleases, a.watch(tui.Deleting, releasesToDelete, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
 				return subst.DeleteReleases(&affectedReleases, helm, c.Concurrency(), purge, c.Cascade())
-			}))
+			}))))
 
 			if len(deletionErrs) > 0 {
 				errs = append(errs, deletionErrs...)
 			}
 		}
+
+		rec.finish(&affectedReleases, errs)
 	}
-	affectedReleases.DisplayAffectedReleases(c.Logger(), !c.NoColor())
+	affectedReleases.DisplayAffectedReleases(a.logger(c), !c.NoColor())
 	return true, errs
 }
 
@@ -2196,7 +2798,7 @@ func (a *App) lint(r *Run, c LintConfigProvider) (bool, []error, []error) {
 	return ok, deferredLintErrs, errs
 }
 
-func (a *App) unittest(r *Run, c UnittestConfigProvider) (bool, []error, []error) {
+func (a *App) unittest(r *Run, c UnittestConfigProvider, report *state.UnittestReport) (bool, []error, []error) {
 	var deferredUnittestErrs []error
 
 	ok, errs := a.withNeeds(r, c, false, func(st *state.HelmState) []error {
@@ -2212,21 +2814,22 @@ func (a *App) unittest(r *Run, c UnittestConfigProvider) (bool, []error, []error
 		}
 
 		opts := &state.UnittestOpts{
-			Set:         c.Set(),
-			SkipCleanup: c.SkipCleanup(),
-			FailFast:    c.FailFast(),
-			Color:       c.Color(),
-			DebugPlugin: c.DebugPlugin(),
+			Set:            c.Set(),
+			SkipCleanup:    c.SkipCleanup(),
+			FailFast:       c.FailFast(),
+			Color:          c.Color(),
+			DebugPlugin:    c.DebugPlugin(),
+			UpdateSnapshot: c.UpdateSnapshot(),
+			Report:         report,
 		}
 		unittestErrs := st.UnittestReleases(helm, c.Values(), args, c.Concurrency(), opts)
-		if len(unittestErrs) == 1 {
-			if err, ok := unittestErrs[0].(helmexec.ExitError); ok {
-				if err.Code > 0 {
-					deferredUnittestErrs = append(deferredUnittestErrs, err)
 
-					return nil
-				}
-			}
+		// Failed tests (helm unittest exiting non-zero) are deferred so that
+		// the remaining helmfiles are still tested; anything else aborts.
+		if len(unittestErrs) > 0 && allUnittestFailures(unittestErrs) {
+			deferredUnittestErrs = append(deferredUnittestErrs, unittestErrs...)
+
+			return nil
 		}
 
 		return unittestErrs
@@ -2235,7 +2838,17 @@ func (a *App) unittest(r *Run, c UnittestConfigProvider) (bool, []error, []error
 	return ok, deferredUnittestErrs, errs
 }
 
-func (a *App) status(r *Run, c StatusesConfigProvider) (bool, []error) {
+func allUnittestFailures(errs []error) bool {
+	for _, e := range errs {
+		var exitErr helmexec.ExitError
+		if !errors.As(e, &exitErr) || exitErr.Code <= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *App) status(r *Run, c StatusesConfigProvider, statuses *releaseStatuses) (bool, []error) {
 	st := r.state
 	helm := r.helm
 
@@ -2276,7 +2889,12 @@ func (a *App) status(r *Run, c StatusesConfigProvider) (bool, []error) {
 
 	if len(toStatus) > 0 {
 		_, templateErrs := withDAG(st, helm, a.Logger, state.PlanOptions{SelectedReleases: toStatus, Reverse: false, SkipNeeds: true}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
-			return subst.ReleaseStatuses(helm, c.Concurrency())
+			if statuses == nil {
+				return subst.ReleaseStatuses(helm, c.Concurrency())
+			}
+			collected, errs := subst.CollectReleaseStatuses(helm, c.Concurrency())
+			statuses.add(collected)
+			return errs
 		}))
 
 		if len(templateErrs) > 0 {
@@ -2344,6 +2962,8 @@ func (a *App) SyncState(r *Run, c SyncConfigProvider) (bool, bool, []error) {
 			releasesWithNoChange[id] = release
 		}
 	}
+	a.ui.queue(releasesWithNeeds)
+	a.ui.unchanged(releasesWithNoChange)
 
 	names := []string{}
 	for _, r := range releasesToUpdate {
@@ -2422,9 +3042,11 @@ Do you really want to sync?
 	affectedReleases := state.AffectedReleases{}
 
 	if !interactive || interactive && r.askForConfirmation(confMsg) {
+		rec := a.startRun(r, "sync")
+
 		if len(releasesToDelete) > 0 {
 			operationsAttempted = true
-			_, deletionErrs := withDAG(st, helm, a.Logger, state.PlanOptions{Reverse: true, SelectedReleases: toDelete, SkipNeeds: true}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
+			_, deletionErrs := withStreamingDAG(st, helm, a.Logger, state.PlanOptions{Reverse: true, SelectedReleases: toDelete, SkipNeeds: true}, c.Concurrency(), c.LockstepBatches(), affectedReleases.Skip, r.external(), rec.notifyFailures(&affectedReleases, a.watch(tui.Deleting, releasesToDelete, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
 				var rs []state.ReleaseSpec
 
 				for _, r := range subst.Releases {
@@ -2437,7 +3059,7 @@ Do you really want to sync?
 				subst.Releases = rs
 
 				return subst.DeleteReleasesForSync(&affectedReleases, helm, c.Concurrency(), c.Cascade())
-			}))
+			}))))
 
 			if len(deletionErrs) > 0 {
 				errs = append(errs, deletionErrs...)
@@ -2446,7 +3068,7 @@ Do you really want to sync?
 
 		if len(releasesToUpdate) > 0 {
 			operationsAttempted = true
-			_, syncErrs := withDAG(st, helm, a.Logger, state.PlanOptions{SelectedReleases: toUpdate, SkipNeeds: true, IncludeTransitiveNeeds: c.IncludeTransitiveNeeds()}, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
+			_, syncErrs := withStreamingDAG(st, helm, a.Logger, state.PlanOptions{SelectedReleases: toUpdate, SkipNeeds: true, IncludeTransitiveNeeds: c.IncludeTransitiveNeeds()}, c.Concurrency(), c.LockstepBatches(), affectedReleases.Skip, r.external(), rec.notifyFailures(&affectedReleases, a.watch(tui.Syncing, releasesToUpdate, a.WrapWithoutSelector(func(subst *state.HelmState, helm helmexec.Interface) []error {
 				var rs []state.ReleaseSpec
 
 				for _, r := range subst.Releases {
@@ -2486,15 +3108,17 @@ Do you really want to sync?
 					NoColor:              c.NoColor(),
 				}
 				return subst.SyncReleases(&affectedReleases, helm, c.Values(), c.Concurrency(), syncOpts)
-			}))
+			}))))
 
 			if len(syncErrs) > 0 {
 				errs = append(errs, syncErrs...)
 			}
 		}
+
+		rec.finish(&affectedReleases, errs)
 	}
 
-	affectedReleases.DisplayAffectedReleases(c.Logger(), !c.NoColor())
+	affectedReleases.DisplayAffectedReleases(a.logger(c), !c.NoColor())
 
 	for id := range releasesWithNoChange {
 		r := releasesWithNoChange[id]
@@ -2508,7 +3132,7 @@ Do you really want to sync?
 	return true, changesApplied, errs
 }
 
-func (a *App) template(r *Run, c TemplateConfigProvider) (bool, []error) {
+func (a *App) template(r *Run, c TemplateConfigProvider, snapshotDir, snapshotBaseDir string) (bool, []error) {
 	return a.withNeeds(r, c, false, func(st *state.HelmState) []error {
 		helm := r.helm
 
@@ -2534,6 +3158,9 @@ func (a *App) template(r *Run, c TemplateConfigProvider) (bool, []error) {
 			ShowOnly:             c.ShowOnly(),
 			SkipSchemaValidation: c.SkipSchemaValidation(),
 			TemplateArgs:         c.TemplateArgs(),
+			SnapshotDir:          snapshotDir,
+			SnapshotBaseDir:      snapshotBaseDir,
+			CheckSnapshots:       c.CheckSnapshots(),
 		}
 		return st.TemplateReleases(helm, c.OutputDir(), c.Values(), args, c.Concurrency(), c.Validate(), opts)
 	})
@@ -3,6 +3,7 @@ package app
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"os"
 	"testing"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 
+	"github.com/helmfile/helmfile/pkg/exectest"
 	ffs "github.com/helmfile/helmfile/pkg/filesystem"
 	"github.com/helmfile/helmfile/pkg/helmexec"
 	"github.com/helmfile/helmfile/pkg/testhelper"
@@ -459,3 +461,138 @@ dependencies:
 	assert.Equal(t, "bitnami/nginx", nginx.Chart)
 	assert.Equal(t, "15.0.0", nginx.Version, "expected nginx version from second.lock")
 }
+
+func TestListLive(t *testing.T) {
+	files := map[string]string{
+		"/path/to/helmfile.yaml": `
+releases:
+- name: web
+  namespace: app
+  chart: stable/web
+  version: ~1.2.0
+  labels:
+    tier: frontend
+- name: api
+  namespace: app
+  chart: stable/api
+  version: 2.0.0
+- name: worker
+  namespace: app
+  chart: stable/worker
+- name: legacy
+  namespace: app
+  chart: stable/legacy
+  installed: false
+- name: cache
+  namespace: db
+  chart: stable/redis
+  labels:
+    tier: backend
+`,
+	}
+
+	helm := &exectest.Helm{
+		LiveReleases: map[string][]helmexec.ReleaseInfo{
+			"--kube-context default --namespace app --all --max 0": {
+				{Name: "web", Namespace: "app", Revision: "3", Updated: "2024-05-01 10:00:00 +0000 UTC", Status: "deployed", Chart: "web-1.2.5"},
+				{Name: "api", Namespace: "app", Revision: "7", Updated: "2024-05-02 10:00:00 +0000 UTC", Status: "failed", Chart: "api-1.9.0"},
+				{Name: "legacy", Namespace: "app", Revision: "1", Updated: "2023-01-01 10:00:00 +0000 UTC", Status: "deployed", Chart: "legacy-0.1.0"},
+				{Name: "old-web", Namespace: "app", Revision: "12", Updated: "2022-01-01 10:00:00 +0000 UTC", Status: "deployed", Chart: "my-web-chart-0.3.0-rc.1"},
+			},
+			"--kube-context default --namespace db --all --max 0": {
+				{Name: "cache", Namespace: "db", Revision: "1", Updated: "2024-05-03 10:00:00 +0000 UTC", Status: "pending-upgrade", Chart: "redis-17.0.7"},
+			},
+		},
+	}
+
+	run := func(t *testing.T, cfg configImpl) string {
+		t.Helper()
+
+		app := appWithFs(&App{
+			OverrideHelmBinary:              DefaultHelmBinary,
+			fs:                              ffs.DefaultFileSystem(),
+			OverrideKubeContext:             "default",
+			DisableKubeVersionAutoDetection: true,
+			Env:                             "default",
+			Logger:                          newAppTestLogger(),
+			helms: map[helmKey]helmexec.Interface{
+				createHelmKey(DefaultHelmBinary, "default"): helm,
+			},
+			Selectors: cfg.selectors,
+		}, files)
+
+		out, err := testutil.CaptureStdout(func() {
+			assert.NoError(t, app.ListReleases(cfg))
+		})
+		assert.NoError(t, err)
+		return out
+	}
+
+	t.Run("table", func(t *testing.T) {
+		out := run(t, configImpl{skipCharts: true, live: true})
+		expected := `NAME   	NAMESPACE	ENABLED	INSTALLED	LABELS                                          	CHART        	VERSION	DEPLOYED  	REVISION	STATUS         	UPDATED                      	DIVERGED
+api    	app      	true   	true     	chart:api,name:api,namespace:app                	stable/api   	2.0.0  	1.9.0     	7       	failed         	2024-05-02 10:00:00 +0000 UTC	true
+legacy 	app      	true   	false    	chart:legacy,name:legacy,namespace:app          	stable/legacy	       	0.1.0     	1       	deployed       	2023-01-01 10:00:00 +0000 UTC	true
+web    	app      	true   	true     	chart:web,name:web,namespace:app,tier:frontend  	stable/web   	~1.2.0 	1.2.5     	3       	deployed       	2024-05-01 10:00:00 +0000 UTC	false
+worker 	app      	true   	true     	chart:worker,name:worker,namespace:app          	stable/worker	       	          	        	not installed  	                             	true
+cache  	db       	true   	true     	chart:redis,name:cache,namespace:db,tier:backend	stable/redis 	       	17.0.7    	1       	pending-upgrade	2024-05-03 10:00:00 +0000 UTC	false
+old-web	app      	       	         	                                                	my-web-chart 	       	0.3.0-rc.1	12      	deployed       	2022-01-01 10:00:00 +0000 UTC	orphan
+`
+		assert.Equal(t, expected, out)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		out := run(t, configImpl{skipCharts: true, live: true, output: "json"})
+
+		var releases []HelmRelease
+		if err := json.Unmarshal([]byte(out), &releases); err != nil {
+			t.Fatalf("failed to parse JSON output: %v", err)
+		}
+
+		assert.Len(t, releases, 6)
+		assert.Equal(t, HelmRelease{
+			Name:            "old-web",
+			Namespace:       "app",
+			Chart:           "my-web-chart",
+			DeployedVersion: "0.3.0-rc.1",
+			Revision:        "12",
+			Status:          "deployed",
+			Updated:         "2022-01-01 10:00:00 +0000 UTC",
+			Orphan:          true,
+		}, releases[5])
+	})
+
+	t.Run("selector doesn't make orphans", func(t *testing.T) {
+		out := run(t, configImpl{skipCharts: true, live: true, selectors: []string{"name=web"}})
+		assert.Contains(t, out, "\nweb ")
+		assert.NotContains(t, out, "worker")
+		assert.NotContains(t, out, "legacy")
+		assert.Contains(t, out, "\nold-web\t")
+		assert.NotContains(t, out, "\napi ")
+	})
+
+	t.Run("helm list error", func(t *testing.T) {
+		helm.ListReleasesErrors = map[string]error{
+			"--kube-context default --namespace db --all --max 0": errors.New("Kubernetes cluster unreachable"),
+		}
+		defer func() {
+			helm.ListReleasesErrors = nil
+		}()
+
+		app := appWithFs(&App{
+			OverrideHelmBinary:              DefaultHelmBinary,
+			fs:                              ffs.DefaultFileSystem(),
+			OverrideKubeContext:             "default",
+			DisableKubeVersionAutoDetection: true,
+			Env:                             "default",
+			Logger:                          newAppTestLogger(),
+			helms: map[helmKey]helmexec.Interface{
+				createHelmKey(DefaultHelmBinary, "default"): helm,
+			},
+		}, files)
+
+		err := app.ListReleases(configImpl{skipCharts: true, live: true})
+		assert.ErrorContains(t, err, "listing the releases installed in")
+		assert.ErrorContains(t, err, "Kubernetes cluster unreachable")
+	})
+}
@@ -2375,6 +2375,7 @@ type configImpl struct {
 	includeTransitiveNeeds   bool
 	enforceNeedsAreInstalled bool
 	skipCharts               bool
+	live                     bool
 	kubeVersion              string
 	postRenderer             string
 	postRendererArgs         []string
@@ -2452,14 +2453,26 @@ func (c configImpl) Concurrency() int {
 	return 1
 }
 
+func (c configImpl) LockstepBatches() bool {
+	return false
+}
+
 func (c configImpl) EmbedValues() bool {
 	return false
 }
 
+func (c configImpl) RenderTrace() bool {
+	return false
+}
+
 func (c configImpl) Output() string {
 	return c.output
 }
 
+func (c configImpl) Live() bool {
+	return c.live
+}
+
 func (c configImpl) SkipCharts() bool {
 	return c.skipCharts
 }
@@ -2489,6 +2502,14 @@ func (c configImpl) TemplateArgs() string {
 	return ""
 }
 
+func (c configImpl) SnapshotDir() string {
+	return ""
+}
+
+func (c configImpl) CheckSnapshots() bool {
+	return false
+}
+
 func (c configImpl) EnforceNeedsAreInstalled() bool {
 	return c.enforceNeedsAreInstalled
 }
@@ -2521,10 +2542,18 @@ type applyConfig struct {
 	color                    bool
 	failFast                 bool
 	debugPlugin              bool
+	updateSnapshot           bool
+	reportFile               string
+	reportFormat             string
 	context                  int
 	diffOutput               string
 	concurrency              int
+	lockstepBatches          bool
+	metricsOutput            string
+	timingReport             bool
+	ui                       bool
 	detailedExitcode         bool
+	approveReleases          bool
 	stripTrailingCR          bool
 	interactive              bool
 	skipDiffOnInstall        bool
@@ -2661,6 +2690,18 @@ func (a applyConfig) DebugPlugin() bool {
 	return a.debugPlugin
 }
 
+func (a applyConfig) UpdateSnapshot() bool {
+	return a.updateSnapshot
+}
+
+func (a applyConfig) ReportFile() string {
+	return a.reportFile
+}
+
+func (a applyConfig) ReportFormat() string {
+	return a.reportFormat
+}
+
 func (a applyConfig) NoColor() bool {
 	return a.noColor
 }
@@ -2677,10 +2718,30 @@ func (a applyConfig) Concurrency() int {
 	return a.concurrency
 }
 
+func (a applyConfig) LockstepBatches() bool {
+	return a.lockstepBatches
+}
+
+func (a applyConfig) MetricsOutput() string {
+	return a.metricsOutput
+}
+
+func (a applyConfig) TimingReport() bool {
+	return a.timingReport
+}
+
+func (a applyConfig) UI() bool {
+	return a.ui
+}
+
 func (a applyConfig) DetailedExitcode() bool {
 	return a.detailedExitcode
 }
 
+func (a applyConfig) ApproveReleases() bool {
+	return a.approveReleases
+}
+
 func (a applyConfig) StripTrailingCR() bool {
 	return a.stripTrailingCR
 }
@@ -2762,6 +2823,14 @@ func (a applyConfig) TemplateArgs() string {
 	return ""
 }
 
+func (a applyConfig) SnapshotDir() string {
+	return ""
+}
+
+func (a applyConfig) CheckSnapshots() bool {
+	return false
+}
+
 func (a applyConfig) HideNotes() bool {
 	return a.hideNotes
 }
@@ -2926,6 +2995,14 @@ func (helm *mockHelmExec) List(context helmexec.HelmContext, filter string, flag
 	return "", nil
 }
 
+func (helm *mockHelmExec) ReleaseStatusInfo(context helmexec.HelmContext, name string, flags ...string) (*helmexec.ReleaseStatusInfo, error) {
+	return nil, nil
+}
+
+func (helm *mockHelmExec) ListReleases(context helmexec.HelmContext, flags ...string) ([]helmexec.ReleaseInfo, error) {
+	return nil, nil
+}
+
 func (helm *mockHelmExec) DecryptSecret(context helmexec.HelmContext, name string, flags ...string) (string, error) {
 	return "", nil
 }
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/helmfile/helmfile/pkg/state"
+)
+
+// approveReleases asks whether to apply the changes to each release to be updated or deleted,
+// after showing its diff, and returns the releases to update and delete that were approved,
+// and the ones that were skipped.
+//
+// releases are the releases of the run in the order they are planned, which the releases to
+// delete are asked about in reverse, like they are deleted. A release isn't asked about but
+// skipped when it needs a release whose update is skipped, or when it's to be deleted and
+// a release needing it is to be deleted too but is skipped, as the order of needs can't be kept.
+func (a *App) approveReleases(r *Run, releases []state.ReleaseSpec, toUpdate, toDelete map[string]state.ReleaseSpec, diffs *state.DiffOutputs) (map[string]state.ReleaseSpec, map[string]state.ReleaseSpec, []state.ReleaseSpec) {
+	needs := map[string][]string{}
+	neededBy := map[string][]string{}
+	for _, rel := range releases {
+		id := state.ReleaseToID(&rel)
+		needs[id] = rel.Needs
+		for _, n := range rel.Needs {
+			neededBy[n] = append(neededBy[n], id)
+		}
+	}
+
+	approvedUpdates := map[string]state.ReleaseSpec{}
+	approvedDeletes := map[string]state.ReleaseSpec{}
+	var skipped []state.ReleaseSpec
+
+	skippedDeletes := map[string]bool{}
+	for i := len(releases) - 1; i >= 0; i-- {
+		id := state.ReleaseToID(&releases[i])
+		rel, ok := toDelete[id]
+		if !ok {
+			continue
+		}
+
+		if dependent := reachable(id, neededBy, skippedDeletes); dependent != "" {
+			a.Logger.Infof("Skipping the deletion of release %s, as %s, which needs it, is not deleted", id, dependent)
+		} else if r.askForConfirmation(approvalMessage(id, rel, "DELETED", diffs) + fmt.Sprintf("Do you want to delete release %s?", id)) {
+			approvedDeletes[id] = rel
+			continue
+		}
+		skippedDeletes[id] = true
+		skipped = append(skipped, rel)
+	}
+
+	skippedUpdates := map[string]bool{}
+	for i := range releases {
+		id := state.ReleaseToID(&releases[i])
+		rel, ok := toUpdate[id]
+		if !ok {
+			continue
+		}
+
+		if need := reachable(id, needs, skippedUpdates); need != "" {
+			a.Logger.Infof("Skipping release %s, as it needs %s, whose changes are not applied", id, need)
+		} else if r.askForConfirmation(approvalMessage(id, rel, "UPDATED", diffs) + fmt.Sprintf("Do you want to apply the changes to release %s?", id)) {
+			approvedUpdates[id] = rel
+			continue
+		}
+		skippedUpdates[id] = true
+		skipped = append(skipped, rel)
+	}
+
+	return approvedUpdates, approvedDeletes, skipped
+}
+
+// approvalMessage shows what's to be done to the release with id, and its diff.
+func approvalMessage(id string, rel state.ReleaseSpec, action string, diffs *state.DiffOutputs) string {
+	msg := fmt.Sprintf("\nRelease %s (%s) will be %s", id, rel.Chart, action)
+	diff, _ := diffs.Get(id)
+	if diff = strings.TrimSpace(diff); diff != "" {
+		msg += ":\n\n" + diff
+	}
+	return msg + "\n\n"
+}
+
+// reachable returns the first release in targets that's reachable from the release with id
+// through edges, not including the release itself, or "" if there's none.
+func reachable(id string, edges map[string][]string, targets map[string]bool) string {
+	visited := map[string]bool{id: true}
+	queue := []string{id}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		for _, e := range edges[next] {
+			if visited[e] {
+				continue
+			}
+			if targets[e] {
+				return e
+			}
+			visited[e] = true
+			queue = append(queue, e)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,192 @@
+package app
+
+import (
+	"bytes"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/helmfile/vals"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/helmfile/helmfile/pkg/exectest"
+	ffs "github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/state"
+)
+
+func TestApplyApproveReleases(t *testing.T) {
+	files := map[string]string{
+		"/path/to/helmfile.yaml": `
+releases:
+- name: db
+  chart: stable/db
+  namespace: default
+- name: api
+  chart: stable/api
+  namespace: default
+  needs:
+  - db
+- name: web
+  chart: stable/web
+  namespace: default
+  needs:
+  - api
+- name: docs
+  chart: stable/docs
+  namespace: default
+- name: legacy
+  chart: stable/legacy
+  namespace: default
+  installed: false
+- name: old
+  chart: stable/old
+  namespace: default
+  installed: false
+  needs:
+  - legacy
+`,
+	}
+
+	diffFlags := "--kube-context default --namespace default --reset-values --detailed-exitcode"
+	listed := func(name string) string {
+		return "NAME\tREVISION\tUPDATED\tSTATUS\tCHART\tAPP VERSION\tNAMESPACE\n" + name + "\t1\tFri Nov  1 08:40:07 2019\tDEPLOYED\t" + name + "-1.0.0\t1.0.0\tdefault\n"
+	}
+	helm := &exectest.Helm{
+		Diffs: map[exectest.DiffKey]error{
+			{Name: "db", Chart: "stable/db", Flags: diffFlags}:     helmexec.ExitError{Code: 2},
+			{Name: "api", Chart: "stable/api", Flags: diffFlags}:   helmexec.ExitError{Code: 2},
+			{Name: "web", Chart: "stable/web", Flags: diffFlags}:   helmexec.ExitError{Code: 2},
+			{Name: "docs", Chart: "stable/docs", Flags: diffFlags}: helmexec.ExitError{Code: 2},
+		},
+		Lists: map[exectest.ListKey]string{
+			{Filter: "^legacy$", Flags: listFlags("default", "default")}: listed("legacy"),
+			{Filter: "^old$", Flags: listFlags("default", "default")}:    listed("old"),
+		},
+		DiffMutex:     &sync.Mutex{},
+		ChartsMutex:   &sync.Mutex{},
+		ReleasesMutex: &sync.Mutex{},
+	}
+
+	valsRuntime, err := vals.New(vals.Options{CacheSize: 32})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	logger := helmexec.NewLogger(&buf, "info")
+	app := appWithFs(&App{
+		OverrideHelmBinary:              DefaultHelmBinary,
+		fs:                              ffs.DefaultFileSystem(),
+		OverrideKubeContext:             "default",
+		DisableKubeVersionAutoDetection: true,
+		Env:                             "default",
+		Logger:                          logger,
+		helms: map[helmKey]helmexec.Interface{
+			createHelmKey(DefaultHelmBinary, "default"): helm,
+		},
+		valsRuntime: valsRuntime,
+	}, files)
+
+	var asked []string
+	release := regexp.MustCompile(`release (\S+)\?$`)
+	err = app.ForEachState(func(run *Run) (bool, []error) {
+		run.Ask = func(msg string) bool {
+			id := release.FindStringSubmatch(msg)[1]
+			asked = append(asked, id)
+			return id != "default/default/db" && id != "default/default/old"
+		}
+		ok, _, errs := app.apply(run, applyConfig{approveReleases: true, skipNeeds: true, logger: logger})
+		return ok, errs
+	}, false)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"default/default/db", "default/default/docs", "default/default/old"}, asked, "releases needing skipped ones aren't asked about")
+	assert.Equal(t, "default/default/old", asked[0], "deletions are asked about first")
+
+	var upgraded []string
+	for _, r := range helm.Releases {
+		upgraded = append(upgraded, r.Name)
+	}
+	assert.Equal(t, []string{"docs"}, upgraded)
+	assert.Empty(t, helm.Deleted)
+
+	out := buf.String()
+	assert.Contains(t, out, "Skipping release default/default/api, as it needs default/default/db, whose changes are not applied")
+	assert.Contains(t, out, "Skipping release default/default/web, as it needs default/default/api, whose changes are not applied")
+	assert.Contains(t, out, "Skipping the deletion of release default/default/legacy, as default/default/old, which needs it, is not deleted")
+}
+
+// The releases are diffed concurrently, each one setting its output. Run with -race.
+func TestDiffReleasesOutputs(t *testing.T) {
+	files := map[string]string{
+		"/path/to/helmfile.yaml": `
+releases:
+- name: db
+  chart: stable/db
+  namespace: default
+- name: api
+  chart: stable/api
+  namespace: default
+- name: web
+  chart: stable/web
+  namespace: default
+- name: docs
+  chart: stable/docs
+  namespace: default
+`,
+	}
+
+	diffFlags := "--kube-context default --namespace default --reset-values --detailed-exitcode"
+	helm := &exectest.Helm{
+		Diffs: map[exectest.DiffKey]error{
+			{Name: "db", Chart: "stable/db", Flags: diffFlags}:     helmexec.ExitError{Code: 2},
+			{Name: "api", Chart: "stable/api", Flags: diffFlags}:   helmexec.ExitError{Code: 2},
+			{Name: "web", Chart: "stable/web", Flags: diffFlags}:   helmexec.ExitError{Code: 2},
+			{Name: "docs", Chart: "stable/docs", Flags: diffFlags}: helmexec.ExitError{Code: 2},
+		},
+		DiffMutex:     &sync.Mutex{},
+		ChartsMutex:   &sync.Mutex{},
+		ReleasesMutex: &sync.Mutex{},
+	}
+
+	valsRuntime, err := vals.New(vals.Options{CacheSize: 32})
+	require.NoError(t, err)
+
+	app := appWithFs(&App{
+		OverrideHelmBinary:              DefaultHelmBinary,
+		fs:                              ffs.DefaultFileSystem(),
+		OverrideKubeContext:             "default",
+		DisableKubeVersionAutoDetection: true,
+		Env:                             "default",
+		Logger:                          newAppTestLogger(),
+		helms: map[helmKey]helmexec.Interface{
+			createHelmKey(DefaultHelmBinary, "default"): helm,
+		},
+		valsRuntime: valsRuntime,
+	}, files)
+
+	diffOpts := &state.DiffOpts{Outputs: state.NewDiffOutputs()}
+	var changed []state.ReleaseSpec
+	err = app.ForEachState(func(run *Run) (bool, []error) {
+		changed, _ = run.diffReleases(false, true, diffConfig{concurrency: 4, detailedExitcode: true, logger: app.Logger}, diffOpts)
+		return true, nil
+	}, false)
+	require.NoError(t, err)
+
+	assert.Len(t, changed, 4)
+	for _, id := range []string{"default/default/db", "default/default/api", "default/default/web", "default/default/docs"} {
+		_, ok := diffOpts.Outputs.Get(id)
+		assert.True(t, ok, "missing the diff output of %s", id)
+	}
+}
+
+func TestReachable(t *testing.T) {
+	needs := map[string][]string{
+		"web": {"api"},
+		"api": {"db", "cache"},
+	}
+	assert.Equal(t, "db", reachable("web", needs, map[string]bool{"db": true}))
+	assert.Equal(t, "api", reachable("web", needs, map[string]bool{"api": true, "db": true}))
+	assert.Equal(t, "", reachable("web", needs, map[string]bool{"web": true, "docs": true}))
+	assert.Equal(t, "", reachable("db", needs, map[string]bool{"web": true}))
+}
@@ -0,0 +1,194 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosuri/uitable"
+
+	"github.com/helmfile/helmfile/pkg/audit"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/notify"
+	"github.com/helmfile/helmfile/pkg/state"
+)
+
+// runRecorder reports a sync, apply or destroy run of the releases of a helmfile
+// to the audit sinks and the notifications of the helmfile, if any, and to the
+// metrics and the timing report of the command.
+type runRecorder struct {
+	a       *App
+	run     *Run
+	command string
+	start   time.Time
+	// commit is the commit the helmfile is checked out at, resolved once for the run
+	commit string
+
+	mu sync.Mutex
+	// failed are the releases whose failure was notified, keyed by `kubecontext/namespace/name`
+	failed map[string]bool
+}
+
+// startRun notifies the start of command on the releases of r.
+func (a *App) startRun(r *Run, command string) *runRecorder {
+	rec := &runRecorder{a: a, run: r, command: command, start: time.Now(), failed: map[string]bool{}}
+	// Only the audit log and the notifications show the commit, which takes running git
+	if r.state.AuditConfig().IsConfigured() || len(r.state.Notifications) > 0 {
+		rec.commit = r.state.GitCommit()
+	}
+	if len(r.state.Notifications) > 0 {
+		a.notify(r, notify.NewEvent(notify.EventStart, rec.record(&state.AffectedReleases{}, nil)))
+	}
+	return rec
+}
+
+// notifyFailures wraps converge, which syncs or deletes releases of the run into affected,
+// to notify each failed release as soon as the releases converged with it are processed.
+func (rec *runRecorder) notifyFailures(affected *state.AffectedReleases, converge func(*state.HelmState, helmexec.Interface) (bool, []error)) func(*state.HelmState, helmexec.Interface) (bool, []error) {
+	wanted := false
+	for _, c := range rec.run.state.Notifications {
+		wanted = wanted || c.Wants(notify.EventReleaseFailed)
+	}
+	if !wanted {
+		return converge
+	}
+
+	return func(st *state.HelmState, helm helmexec.Interface) (bool, []error) {
+		processed, errs := converge(st, helm)
+		if len(errs) == 0 {
+			return processed, errs
+		}
+
+		// The releases are converged concurrently, so the failures of the others may be recorded already
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		for _, e := range notify.NewEvent(notify.EventComplete, rec.record(affected, nil)).ReleaseFailedEvents() {
+			key := e.Release.KubeContext + "/" + e.Release.Namespace + "/" + e.Release.Name
+			if rec.failed[key] {
+				continue
+			}
+			rec.failed[key] = true
+			rec.a.notify(rec.run, e)
+		}
+		return processed, errs
+	}
+}
+
+// finish records the run, and notifies its completion.
+// Failing to record or notify the run is only a warning, not to fail the releases that were deployed.
+func (rec *runRecorder) finish(affected *state.AffectedReleases, errs []error) {
+	rec.a.timing.add(rec.run.state.FilePath, rec.run.state.ReleaseTimings(affected))
+
+	c := rec.run.state.AuditConfig()
+	if !c.IsConfigured() && len(rec.run.state.Notifications) == 0 && rec.a.metrics == nil {
+		return
+	}
+
+	record := rec.record(affected, errs)
+	rec.a.metrics.add(record)
+	if c.IsConfigured() {
+		if err := audit.Write(c, record); err != nil {
+			rec.a.Logger.Warnf("warn: failed to record the %s run to the audit log: %v", rec.command, err)
+		}
+	}
+
+	rec.a.notify(rec.run, notify.NewEvent(notify.EventComplete, record))
+}
+
+func (rec *runRecorder) record(affected *state.AffectedReleases, errs []error) audit.Record {
+	record := rec.run.state.AuditRecord(rec.command, affected)
+	record.Time = rec.start.UTC()
+	record.User = audit.CurrentUser()
+	record.Commit = rec.commit
+	record.Selectors = rec.a.Selectors
+	record.DurationSeconds = time.Since(rec.start).Seconds()
+	for _, err := range errs {
+		record.Errors = append(record.Errors, err.Error())
+	}
+	return record
+}
+
+// History prints the runs recorded to the audit files of the helmfiles, most recent first.
+func (a *App) History(c HistoryConfigProvider) error {
+	var files []string
+	seen := map[string]bool{}
+	err := a.ForEachState(func(run *Run) (bool, []error) {
+		if f := run.state.AuditConfig().File; f != "" && !seen[f] {
+			seen[f] = true
+			files = append(files, f)
+		}
+		return false, nil
+	}, false)
+	// History doesn't need releases, as it's reading the runs recorded for them
+	if _, ok := err.(*NoMatchingHelmfileError); !ok && err != nil {
+		return err
+	}
+
+	if len(files) == 0 {
+		return errors.New("no helmfile sets audit.file, which the runs are recorded to")
+	}
+
+	var records []audit.Record
+	for _, f := range files {
+		rs, err := audit.ReadFile(f)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("reading audit file: %w", err)
+		}
+		records = append(records, rs...)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.After(records[j].Time)
+	})
+	if limit := c.Limit(); limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+
+	if c.Output() == "json" {
+		if records == nil {
+			records = []audit.Record{}
+		}
+		bs, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error generating json: %v", err)
+		}
+		fmt.Println(string(bs))
+		return nil
+	}
+
+	table := uitable.New()
+	table.AddRow("TIME", "COMMAND", "USER", "ENVIRONMENT", "HELMFILE", "COMMIT", "RELEASES", "DURATION", "RESULT")
+	for _, r := range records {
+		result := "ok"
+		if r.Failed() {
+			result = "failed"
+		}
+		table.AddRow(r.Time.Format("2006-01-02 15:04:05 -0700"), r.Command, r.User, r.Environment, r.Helmfile, shortCommit(r.Commit), formatAuditReleases(r.Releases), (time.Duration(r.DurationSeconds * float64(time.Second))).Round(time.Second), result)
+	}
+	fmt.Println(trimTrailingWhitespace(table.String()))
+	return nil
+}
+
+// formatAuditReleases summarizes what a run did to each release, like `web (upgraded), db (deleted)`.
+func formatAuditReleases(releases []audit.Release) string {
+	summary := make([]string, len(releases))
+	for i, r := range releases {
+		summary[i] = fmt.Sprintf("%s (%s)", r.Name, r.Action)
+	}
+	return strings.Join(summary, ", ")
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
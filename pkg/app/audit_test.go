@@ -0,0 +1,155 @@
+package app
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/helmfile/vals"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/helmfile/helmfile/pkg/audit"
+	"github.com/helmfile/helmfile/pkg/exectest"
+	ffs "github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/testutil"
+)
+
+type historyConfig struct {
+	limit  int
+	output string
+	logger *zap.SugaredLogger
+}
+
+func (c historyConfig) Limit() int {
+	return c.limit
+}
+
+func (c historyConfig) Output() string {
+	return c.output
+}
+
+func (c historyConfig) Logger() *zap.SugaredLogger {
+	return c.logger
+}
+
+func TestAuditAndHistory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "audit.jsonl")
+	files := map[string]string{
+		"/path/to/helmfile.yaml": `
+audit:
+  file: ` + file + `
+releases:
+- name: web
+  namespace: app
+  chart: stable/web
+  labels:
+    tier: frontend
+- name: old
+  namespace: app
+  chart: stable/old
+  installed: false
+  labels:
+    tier: frontend
+- name: db
+  namespace: db
+  chart: stable/db
+`,
+	}
+
+	valsRuntime, err := vals.New(vals.Options{CacheSize: 32})
+	require.NoError(t, err)
+
+	newApp := func(helm helmexec.Interface) *App {
+		return appWithFs(&App{
+			OverrideHelmBinary:              DefaultHelmBinary,
+			fs:                              ffs.DefaultFileSystem(),
+			OverrideKubeContext:             "default",
+			DisableKubeVersionAutoDetection: true,
+			Env:                             "default",
+			Logger:                          newAppTestLogger(),
+			Selectors:                       []string{"tier=frontend"},
+			helms: map[helmKey]helmexec.Interface{
+				createHelmKey(DefaultHelmBinary, "default"): helm,
+			},
+			valsRuntime: valsRuntime,
+		}, files)
+	}
+
+	helm := &exectest.Helm{
+		DiffMutex:     &sync.Mutex{},
+		ChartsMutex:   &sync.Mutex{},
+		ReleasesMutex: &sync.Mutex{},
+	}
+	app := newApp(helm)
+	require.NoError(t, app.Sync(applyConfig{concurrency: 1, skipNeeds: true, logger: app.Logger}))
+	require.NoError(t, app.Destroy(destroyConfig{concurrency: 1, logger: app.Logger}))
+
+	history := func(t *testing.T, c historyConfig) string {
+		t.Helper()
+
+		app := newApp(helm)
+		c.logger = app.Logger
+		var historyErr error
+		out, err := testutil.CaptureStdout(func() {
+			historyErr = app.History(c)
+		})
+		require.NoError(t, err)
+		require.NoError(t, historyErr)
+		return out
+	}
+
+	var records []audit.Record
+	require.NoError(t, json.Unmarshal([]byte(history(t, historyConfig{output: "json"})), &records))
+	require.Len(t, records, 2)
+	for i, command := range []string{"destroy", "sync"} {
+		r := records[i]
+		assert.Equal(t, command, r.Command)
+		assert.Equal(t, "helmfile.yaml", r.Helmfile)
+		assert.Equal(t, "default", r.Environment)
+		assert.Equal(t, []string{"tier=frontend"}, r.Selectors)
+		assert.NotZero(t, r.Time)
+		assert.Empty(t, r.Errors)
+	}
+	assert.False(t, records[0].Time.Before(records[1].Time))
+
+	actions := func(r audit.Record) map[string]string {
+		m := map[string]string{}
+		for _, rel := range r.Releases {
+			assert.Equal(t, "default", rel.KubeContext)
+			m[rel.Name] = rel.Action
+		}
+		return m
+	}
+	assert.Equal(t, map[string]string{"web": audit.ActionUpgraded, "old": audit.ActionDeleted}, actions(records[1]))
+	assert.Equal(t, map[string]string{"web": audit.ActionDeleted, "old": audit.ActionDeleted}, actions(records[0]))
+
+	limited := history(t, historyConfig{limit: 1, output: "table"})
+	assert.Contains(t, limited, "TIME")
+	assert.Contains(t, limited, "web (deleted)")
+	assert.NotContains(t, limited, "upgraded")
+}
+
+func TestHistoryWithoutAuditFile(t *testing.T) {
+	app := appWithFs(&App{
+		OverrideHelmBinary:              DefaultHelmBinary,
+		fs:                              ffs.DefaultFileSystem(),
+		OverrideKubeContext:             "default",
+		DisableKubeVersionAutoDetection: true,
+		Env:                             "default",
+		Logger:                          newAppTestLogger(),
+	}, map[string]string{
+		"/path/to/helmfile.yaml": `
+releases:
+- name: web
+  chart: stable/web
+`,
+	})
+	expectNoCallsToHelm(app)
+
+	err := app.History(historyConfig{logger: app.Logger})
+	require.EqualError(t, err, "no helmfile sets audit.file, which the runs are recorded to")
+}
@@ -19,6 +19,7 @@ type ConfigProvider interface {
 	SkipDeps() bool
 	SkipRefresh() bool
 	SequentialHelmfiles() bool
+	Strict() bool
 
 	FileOrDir() string
 	KubeContext() string
@@ -77,6 +78,7 @@ type ApplyConfigProvider interface {
 
 	DetailedExitcode() bool
 	StripTrailingCR() bool
+	ApproveReleases() bool
 
 	Color() bool
 	NoColor() bool
@@ -106,6 +108,10 @@ type ApplyConfigProvider interface {
 	Description() string
 
 	concurrencyConfig
+	schedulingConfig
+	metricsConfig
+	timingReportConfig
+	uiConfig
 	interactive
 	loggingConfig
 	valuesControlMode
@@ -156,6 +162,10 @@ type SyncConfigProvider interface {
 	DAGConfig
 
 	concurrencyConfig
+	schedulingConfig
+	metricsConfig
+	timingReportConfig
+	uiConfig
 	interactive
 	loggingConfig
 	valuesControlMode
@@ -199,6 +209,7 @@ type DiffConfigProvider interface {
 	ServerSide() string
 
 	concurrencyConfig
+	schedulingConfig
 	valuesControlMode
 }
 
@@ -223,6 +234,14 @@ type DoctorConfigProvider interface {
 	// DoctorOutput to avoid colliding with DiffConfigProvider.DiffOutput
 	// which is the helm-diff plugin output format.
 	DoctorOutput() string
+
+	// DumpPayload returns the file path the post-redaction LLM prompt is
+	// written to, or "" when --dump-payload was not passed.
+	DumpPayload() string
+
+	// DoctorSource returns what doctor analyzes: "diff" (default) or
+	// "template" for rendered manifests that need no cluster access.
+	DoctorSource() string
 }
 
 type DestroyConfigProvider interface {
@@ -239,6 +258,10 @@ type DestroyConfigProvider interface {
 	interactive
 	loggingConfig
 	concurrencyConfig
+	schedulingConfig
+	metricsConfig
+	timingReportConfig
+	uiConfig
 }
 
 type TestConfigProvider interface {
@@ -275,6 +298,9 @@ type UnittestConfigProvider interface {
 	FailFast() bool
 	Color() bool
 	DebugPlugin() bool
+	UpdateSnapshot() bool
+	ReportFile() string
+	ReportFormat() string
 	SkipDeps() bool
 	SkipRefresh() bool
 	SkipCleanup() bool
@@ -314,6 +340,8 @@ type TemplateConfigProvider interface {
 	KubeVersion() string
 	ShowOnly() []string
 	TemplateArgs() string
+	SnapshotDir() string
+	CheckSnapshots() bool
 
 	DAGConfig
 
@@ -341,20 +369,43 @@ type WriteValuesConfigProvider interface {
 
 type StatusesConfigProvider interface {
 	Args() string
+	Output() string
 
 	concurrencyConfig
 }
 
 type StateConfigProvider interface {
 	EmbedValues() bool
+	RenderTrace() bool
 }
 
-type DAGConfigProvider any
+type DAGConfigProvider interface {
+	Output() string
+}
 
 type concurrencyConfig interface {
 	Concurrency() int
 }
 
+type schedulingConfig interface {
+	LockstepBatches() bool
+}
+
+type metricsConfig interface {
+	MetricsOutput() string
+}
+
+type timingReportConfig interface {
+	TimingReport() bool
+}
+
+type uiConfig interface {
+	UI() bool
+	NoColor() bool
+
+	interactive
+}
+
 type loggingConfig interface {
 	Logger() *zap.SugaredLogger
 }
@@ -363,9 +414,24 @@ type interactive interface {
 	Interactive() bool
 }
 
+type HistoryConfigProvider interface {
+	Limit() int
+	Output() string
+
+	loggingConfig
+}
+
+type PruneConfigProvider interface {
+	Confirm() bool
+
+	interactive
+	loggingConfig
+}
+
 type ListConfigProvider interface {
 	Output() string
 	SkipCharts() bool
+	Live() bool
 }
 
 type CacheConfigProvider any
@@ -391,3 +457,8 @@ type valuesControlMode interface {
 	ReuseValues() bool
 	ResetValues() bool
 }
+
+type TestStateConfigProvider interface {
+	// Tests returns the state test files or glob patterns to run.
+	Tests() []string
+}
@@ -9,7 +9,9 @@ import (
 
 const (
 	DefaultHelmfile              = "helmfile.yaml"
+	DefaultHCLHelmfile           = "helmfile.hcl"
 	DefaultHelmfileDirectory     = "helmfile.d"
+	DefaultHCLHelmfileSuffix     = ".helmfile.hcl"                 // helmfiles written in HCL in a helmfile directory, as other .hcl files may be environment values files
 	ExperimentalSelectorExplicit = "explicit-selector-inheritance" // value to remove default selector inheritance to sub-helmfiles and use the explicit one
 )
 
@@ -9,6 +9,12 @@ import (
 type Context struct {
 	updatedRepos map[string]bool
 	mu           sync.Mutex
+
+	// helmfiles orders the helmfiles loaded by the command by the needs
+	// across them. It is nil when the command loads a single helmfile.
+	helmfiles *helmfileGraph
+	// multipleHelmfiles is true when the command loads more than one helmfile.
+	multipleHelmfiles bool
 }
 
 func NewContext() Context {
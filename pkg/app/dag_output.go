@@ -0,0 +1,277 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/helmfile/helmfile/pkg/state"
+)
+
+// ReleaseGraph is the dependency graph of releases printed by show-dag.
+type ReleaseGraph struct {
+	Nodes []ReleaseNode `json:"nodes"`
+	// Edges point from a release to a release it needs.
+	Edges []ReleaseEdge `json:"edges"`
+}
+
+// ReleaseNode is a release in a ReleaseGraph.
+type ReleaseNode struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace,omitempty"`
+	KubeContext string            `json:"kubeContext,omitempty"`
+	Chart       string            `json:"chart"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Helmfile    string            `json:"helmfile"`
+	// Group is the group the release is processed in within its helmfile, starting from 1.
+	Group int `json:"group"`
+	// Transitive is true when the release is only included as a need of the selected releases.
+	Transitive bool `json:"transitive"`
+	// Disabled is true when the release has installed: false.
+	Disabled bool `json:"disabled"`
+}
+
+// ReleaseEdge is a need of a release in a ReleaseGraph.
+type ReleaseEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// newReleaseGraph returns the graph of the planned releases of st.
+func newReleaseGraph(st *state.HelmState, batches [][]state.Release) (*ReleaseGraph, error) {
+	marked, err := st.SelectReleases(false)
+	if err != nil {
+		return nil, err
+	}
+	transitive := map[string]bool{}
+	for _, r := range marked {
+		transitive[state.ReleaseToID(&r.ReleaseSpec)] = r.Filtered
+	}
+
+	g := &ReleaseGraph{}
+	for i, batch := range batches {
+		for _, r := range batch {
+			id := state.ReleaseToID(&r.ReleaseSpec)
+			g.Nodes = append(g.Nodes, ReleaseNode{
+				ID:          id,
+				Name:        r.Name,
+				Namespace:   r.Namespace,
+				KubeContext: r.KubeContext,
+				Chart:       r.Chart,
+				Labels:      r.Labels,
+				Helmfile:    st.FilePath,
+				Group:       i + 1,
+				Transitive:  transitive[id],
+				Disabled:    !r.Desired(),
+			})
+			for _, need := range r.Needs {
+				g.Edges = append(g.Edges, ReleaseEdge{From: id, To: need})
+			}
+		}
+	}
+	return g, nil
+}
+
+// mergeReleaseGraphs merges the graphs of multiple helmfiles, ordered by helmfile.
+func mergeReleaseGraphs(graphs []*ReleaseGraph) *ReleaseGraph {
+	sort.SliceStable(graphs, func(i, j int) bool {
+		return helmfileOf(graphs[i]) < helmfileOf(graphs[j])
+	})
+
+	merged := &ReleaseGraph{Nodes: []ReleaseNode{}, Edges: []ReleaseEdge{}}
+	for _, g := range graphs {
+		merged.Nodes = append(merged.Nodes, g.Nodes...)
+		merged.Edges = append(merged.Edges, g.Edges...)
+	}
+	return merged
+}
+
+func helmfileOf(g *ReleaseGraph) string {
+	if len(g.Nodes) == 0 {
+		return ""
+	}
+	return g.Nodes[0].Helmfile
+}
+
+// helmfiles returns the helmfiles of the releases in g, in the order they first appear.
+func (g *ReleaseGraph) helmfiles() []string {
+	var helmfiles []string
+	seen := map[string]bool{}
+	for _, n := range g.Nodes {
+		if !seen[n.Helmfile] {
+			seen[n.Helmfile] = true
+			helmfiles = append(helmfiles, n.Helmfile)
+		}
+	}
+	return helmfiles
+}
+
+func (n ReleaseNode) labelLines() []string {
+	lines := []string{n.Name}
+	if n.Namespace != "" {
+		lines = append(lines, "namespace: "+n.Namespace)
+	}
+	if n.KubeContext != "" {
+		lines = append(lines, "kubeContext: "+n.KubeContext)
+	}
+	lines = append(lines, "chart: "+n.Chart)
+
+	// The name, namespace and chart labels every release has are shown above
+	var labels []string
+	for k, v := range n.Labels {
+		if k != "name" && k != "namespace" && k != "chart" {
+			labels = append(labels, k+"="+v)
+		}
+	}
+	if len(labels) > 0 {
+		sort.Strings(labels)
+		lines = append(lines, "labels: "+strings.Join(labels, ", "))
+	}
+	return lines
+}
+
+// FormatAsJSON returns g as indented JSON.
+func (g *ReleaseGraph) FormatAsJSON() (string, error) {
+	bs, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error generating json: %v", err)
+	}
+	return string(bs) + "\n", nil
+}
+
+// FormatAsDOT returns g in the Graphviz DOT language.
+// Releases of each helmfile are grouped in a cluster when there are more than one helmfile.
+func (g *ReleaseGraph) FormatAsDOT() string {
+	var b strings.Builder
+
+	b.WriteString("digraph releases {\n")
+	b.WriteString("  node [shape=box];\n")
+
+	node := func(indent string, n ReleaseNode) {
+		var lines []string
+		for _, l := range n.labelLines() {
+			lines = append(lines, dotEscape(l))
+		}
+		attrs := []string{fmt.Sprintf(`label="%s"`, strings.Join(lines, `\n`))}
+		if n.Disabled {
+			attrs = append(attrs, `style="dashed"`)
+		}
+		if n.Transitive {
+			attrs = append(attrs, `color="gray50"`, `fontcolor="gray50"`)
+		}
+		fmt.Fprintf(&b, "%s\"%s\" [%s];\n", indent, dotEscape(n.ID), strings.Join(attrs, ", "))
+	}
+
+	helmfiles := g.helmfiles()
+	if len(helmfiles) > 1 {
+		for i, h := range helmfiles {
+			fmt.Fprintf(&b, "\n  subgraph \"cluster_%d\" {\n", i+1)
+			fmt.Fprintf(&b, "    label=\"%s\";\n", dotEscape(h))
+			for _, n := range g.Nodes {
+				if n.Helmfile == h {
+					node("    ", n)
+				}
+			}
+			b.WriteString("  }\n")
+		}
+	} else if len(g.Nodes) > 0 {
+		b.WriteString("\n")
+		for _, n := range g.Nodes {
+			node("  ", n)
+		}
+	}
+
+	if len(g.Edges) > 0 {
+		b.WriteString("\n")
+		for _, e := range g.Edges {
+			fmt.Fprintf(&b, "  \"%s\" -> \"%s\";\n", dotEscape(e.From), dotEscape(e.To))
+		}
+	}
+
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+// FormatAsMermaid returns g as a Mermaid flowchart.
+// Releases of each helmfile are grouped in a subgraph when there are more than one helmfile.
+func (g *ReleaseGraph) FormatAsMermaid() string {
+	var b strings.Builder
+
+	b.WriteString("flowchart TD\n")
+
+	ids := map[string]string{}
+	nodeID := func(id string) string {
+		if _, ok := ids[id]; !ok {
+			ids[id] = fmt.Sprintf("r%d", len(ids)+1)
+		}
+		return ids[id]
+	}
+
+	node := func(indent string, n ReleaseNode) {
+		var lines []string
+		for _, l := range n.labelLines() {
+			lines = append(lines, mermaidEscape(l))
+		}
+		fmt.Fprintf(&b, "%s%s[\"%s\"]\n", indent, nodeID(n.ID), strings.Join(lines, "<br/>"))
+	}
+
+	helmfiles := g.helmfiles()
+	if len(helmfiles) > 1 {
+		for i, h := range helmfiles {
+			fmt.Fprintf(&b, "  subgraph h%d[\"%s\"]\n", i+1, mermaidEscape(h))
+			for _, n := range g.Nodes {
+				if n.Helmfile == h {
+					node("    ", n)
+				}
+			}
+			b.WriteString("  end\n")
+		}
+	} else {
+		for _, n := range g.Nodes {
+			node("  ", n)
+		}
+	}
+
+	// Needs on releases outside the graph, like releases of helmfiles that
+	// were not selected, are shown with their IDs.
+	for _, e := range g.Edges {
+		if _, ok := ids[e.To]; !ok {
+			fmt.Fprintf(&b, "  %s[\"%s\"]\n", nodeID(e.To), mermaidEscape(e.To))
+		}
+	}
+
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s --> %s\n", nodeID(e.From), nodeID(e.To))
+	}
+
+	var disabled, transitive []string
+	for _, n := range g.Nodes {
+		if n.Disabled {
+			disabled = append(disabled, nodeID(n.ID))
+		}
+		if n.Transitive {
+			transitive = append(transitive, nodeID(n.ID))
+		}
+	}
+	if len(disabled) > 0 {
+		b.WriteString("  classDef disabled stroke-dasharray: 5 5\n")
+		fmt.Fprintf(&b, "  class %s disabled\n", strings.Join(disabled, ","))
+	}
+	if len(transitive) > 0 {
+		b.WriteString("  classDef transitive color:#808080,stroke:#808080\n")
+		fmt.Fprintf(&b, "  class %s transitive\n", strings.Join(transitive, ","))
+	}
+
+	return b.String()
+}
+
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
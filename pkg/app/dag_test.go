@@ -1,14 +1,24 @@
 package app
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/helmfile/vals"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
+	"github.com/helmfile/helmfile/pkg/exectest"
 	ffs "github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/state"
 	"github.com/helmfile/helmfile/pkg/testhelper"
 	"github.com/helmfile/helmfile/pkg/testutil"
 )
@@ -19,6 +29,7 @@ func testDAG(t *testing.T, cfg configImpl) {
 		ns          string
 		error       string
 		selectors   []string
+		output      string
 		expected    string
 	}
 
@@ -103,6 +114,8 @@ releases:
 				app.Selectors = tc.selectors
 			}
 
+			cfg.output = tc.output
+
 			var dagErr error
 			out, err := testutil.CaptureStdout(func() {
 				dagErr = app.PrintDAGState(cfg)
@@ -136,6 +149,125 @@ releases:
 3     default//test3                                  default//test2
 4     default/default/my-release                      default/default/external-secrets
 4     default//test4                                  default//test2, default//test3
+`,
+		}, cfg)
+	})
+
+	t.Run("DAG table lists only the selected releases", func(t *testing.T) {
+		check(t, testcase{
+			environment: "default",
+			selectors:   []string{"namespace=kube-system"},
+			expected: `GROUP RELEASE                                         DEPENDENCIES
+1     default/kube-system/logging
+1     default/kube-system/disabled
+2     default/kube-system/kubernetes-external-secrets default/kube-system/logging
+`,
+		}, cfg)
+	})
+
+	t.Run("DAG as DOT", func(t *testing.T) {
+		check(t, testcase{
+			environment: "default",
+			selectors:   []string{"name=test3"},
+			output:      "dot",
+			expected: `digraph releases {
+  node [shape=box];
+
+  "default/kube-system/disabled" [label="disabled\nnamespace: kube-system\nkubeContext: default\nchart: incubator/raw", style="dashed", color="gray50", fontcolor="gray50"];
+  "default//test2" [label="test2\nkubeContext: default\nchart: incubator/raw", color="gray50", fontcolor="gray50"];
+  "default//test3" [label="test3\nkubeContext: default\nchart: incubator/raw"];
+
+  "default//test2" -> "default/kube-system/disabled";
+  "default//test3" -> "default//test2";
+}
+`,
+		}, cfg)
+	})
+
+	t.Run("DAG as Mermaid", func(t *testing.T) {
+		check(t, testcase{
+			environment: "default",
+			selectors:   []string{"name=test3"},
+			output:      "mermaid",
+			expected: `flowchart TD
+  r1["disabled<br/>namespace: kube-system<br/>kubeContext: default<br/>chart: incubator/raw"]
+  r2["test2<br/>kubeContext: default<br/>chart: incubator/raw"]
+  r3["test3<br/>kubeContext: default<br/>chart: incubator/raw"]
+  r2 --> r1
+  r3 --> r2
+  classDef disabled stroke-dasharray: 5 5
+  class r1 disabled
+  classDef transitive color:#808080,stroke:#808080
+  class r1,r2 transitive
+`,
+		}, cfg)
+	})
+
+	t.Run("DAG as JSON", func(t *testing.T) {
+		check(t, testcase{
+			environment: "default",
+			selectors:   []string{"name=test3"},
+			output:      "json",
+			expected: `{
+  "nodes": [
+    {
+      "id": "default/kube-system/disabled",
+      "name": "disabled",
+      "namespace": "kube-system",
+      "kubeContext": "default",
+      "chart": "incubator/raw",
+      "labels": {
+        "chart": "raw",
+        "name": "disabled",
+        "namespace": "kube-system"
+      },
+      "helmfile": "helmfile.yaml",
+      "group": 1,
+      "transitive": true,
+      "disabled": true
+    },
+    {
+      "id": "default//test2",
+      "name": "test2",
+      "kubeContext": "default",
+      "chart": "incubator/raw",
+      "labels": {
+        "chart": "raw",
+        "name": "test2",
+        "namespace": ""
+      },
+      "helmfile": "helmfile.yaml",
+      "group": 2,
+      "transitive": true,
+      "disabled": false
+    },
+    {
+      "id": "default//test3",
+      "name": "test3",
+      "kubeContext": "default",
+      "chart": "incubator/raw",
+      "labels": {
+        "chart": "raw",
+        "name": "test3",
+        "namespace": ""
+      },
+      "helmfile": "helmfile.yaml",
+      "group": 3,
+      "transitive": false,
+      "disabled": false
+    }
+  ],
+  "edges": [
+    {
+      "from": "default//test2",
+      "to": "default/kube-system/disabled"
+    },
+    {
+      "from": "default//test3",
+      "to": "default//test2"
+    }
+  ]
+}
 `,
 		}, cfg)
 	})
@@ -146,3 +278,217 @@ func TestDAG(t *testing.T) {
 		testDAG(t, configImpl{})
 	})
 }
+
+func streamingTestBatches(releases ...state.ReleaseSpec) [][]state.Release {
+	var batch []state.Release
+	for _, r := range releases {
+		batch = append(batch, state.Release{ReleaseSpec: r})
+	}
+	return [][]state.Release{batch}
+}
+
+func TestWithStreaming_StartsReleasesOnceTheirNeedsAreDone(t *testing.T) {
+	// slow is in the first group together with fast, and dependent only needs fast,
+	// so dependent must not wait for slow.
+	batches := [][]state.Release{
+		{{ReleaseSpec: state.ReleaseSpec{Name: "slow"}}, {ReleaseSpec: state.ReleaseSpec{Name: "fast"}}},
+		{{ReleaseSpec: state.ReleaseSpec{Name: "dependent", Needs: []string{"fast"}}}},
+	}
+
+	dependentDone := make(chan struct{})
+
+	processed, errs := withStreaming("", false, &state.HelmState{}, batches, nil, zap.NewNop().Sugar(), 0, nil, nil, func(st *state.HelmState, _ helmexec.Interface) (bool, []error) {
+		switch st.Releases[0].Name {
+		case "slow":
+			select {
+			case <-dependentDone:
+			case <-time.After(10 * time.Second):
+				return false, []error{errors.New("dependent waited for slow")}
+			}
+		case "dependent":
+			close(dependentDone)
+		}
+		return true, nil
+	})
+
+	require.Empty(t, errs)
+	assert.True(t, processed)
+}
+
+func TestWithStreaming_Order(t *testing.T) {
+	releases := []state.Release{
+		{ReleaseSpec: state.ReleaseSpec{Name: "a"}},
+		{ReleaseSpec: state.ReleaseSpec{Name: "b", Needs: []string{"a"}}},
+		{ReleaseSpec: state.ReleaseSpec{Name: "c"}},
+		{ReleaseSpec: state.ReleaseSpec{Name: "d", Needs: []string{"b", "c"}}},
+	}
+
+	// With concurrency 1 the releases must be processed in the same order as
+	// the lock-step groups.
+	run := func(reverse bool) []string {
+		batches, err := state.SortedReleaseGroups(releases, state.PlanOptions{Reverse: reverse})
+		require.NoError(t, err)
+
+		var order []string
+		_, errs := withStreaming("", reverse, &state.HelmState{}, batches, nil, zap.NewNop().Sugar(), 1, nil, nil, func(st *state.HelmState, _ helmexec.Interface) (bool, []error) {
+			order = append(order, st.Releases[0].Name)
+			return true, nil
+		})
+		require.Empty(t, errs)
+		return order
+	}
+
+	assert.Equal(t, []string{"a", "c", "b", "d"}, run(false))
+	assert.Equal(t, []string{"d", "b", "a", "c"}, run(true))
+}
+
+func TestWithStreaming_FailureSkipsDependents(t *testing.T) {
+	batches := streamingTestBatches(
+		state.ReleaseSpec{Name: "broken"},
+		state.ReleaseSpec{Name: "independent"},
+		state.ReleaseSpec{Name: "child", Needs: []string{"broken"}},
+		state.ReleaseSpec{Name: "grandchild", Needs: []string{"child"}},
+	)
+
+	var (
+		mu      sync.Mutex
+		started []string
+		logs    bytes.Buffer
+	)
+	skipped := map[string]string{}
+
+	processed, errs := withStreaming("", false, &state.HelmState{}, batches, nil, helmexec.NewLogger(&logs, "warn"), 0, func(r *state.ReleaseSpec, failed string) {
+		skipped[r.Name] = failed
+	}, nil, func(st *state.HelmState, _ helmexec.Interface) (bool, []error) {
+		name := st.Releases[0].Name
+
+		mu.Lock()
+		started = append(started, name)
+		mu.Unlock()
+
+		if name == "broken" {
+			return true, []error{errors.New("boom")}
+		}
+		return true, nil
+	})
+
+	assert.False(t, processed)
+	require.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "boom")
+	assert.ElementsMatch(t, []string{"broken", "independent"}, started)
+	assert.Equal(t, map[string]string{"child": "broken", "grandchild": "broken"}, skipped)
+	assert.Contains(t, logs.String(), "Skipping release grandchild, as release broken it waits for failed")
+}
+
+func TestWithStreaming_Concurrency(t *testing.T) {
+	var releases []state.ReleaseSpec
+	for i := 0; i < 10; i++ {
+		releases = append(releases, state.ReleaseSpec{Name: fmt.Sprintf("r%d", i)})
+	}
+
+	var running, maxRunning atomic.Int32
+
+	_, errs := withStreaming("", false, &state.HelmState{}, streamingTestBatches(releases...), nil, zap.NewNop().Sugar(), 3, nil, nil, func(st *state.HelmState, _ helmexec.Interface) (bool, []error) {
+		n := running.Add(1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+		return true, nil
+	})
+
+	require.Empty(t, errs)
+	assert.LessOrEqual(t, maxRunning.Load(), int32(3))
+}
+
+func TestReleaseGraph_MultipleHelmfiles(t *testing.T) {
+	graph := mergeReleaseGraphs([]*ReleaseGraph{
+		{
+			Nodes: []ReleaseNode{{ID: "app/api", Name: "api", Namespace: "app", Chart: "charts/api", Labels: map[string]string{"name": "api", "namespace": "app", "chart": "api", "tier": "backend", "app": "shop"}, Helmfile: "helmfile.d/20-app.yaml", Group: 1}},
+			Edges: []ReleaseEdge{{From: "app/api", To: "db/postgres"}, {From: "app/api", To: "cache/redis"}},
+		},
+		{
+			Nodes: []ReleaseNode{{ID: "db/postgres", Name: "postgres", Namespace: "db", Chart: "charts/postgres", Helmfile: "helmfile.d/10-db.yaml", Group: 1}},
+		},
+	})
+
+	assert.Equal(t, `digraph releases {
+  node [shape=box];
+
+  subgraph "cluster_1" {
+    label="helmfile.d/10-db.yaml";
+    "db/postgres" [label="postgres\nnamespace: db\nchart: charts/postgres"];
+  }
+
+  subgraph "cluster_2" {
+    label="helmfile.d/20-app.yaml";
+    "app/api" [label="api\nnamespace: app\nchart: charts/api\nlabels: app=shop, tier=backend"];
+  }
+
+  "app/api" -> "db/postgres";
+  "app/api" -> "cache/redis";
+}
+`, graph.FormatAsDOT())
+
+	assert.Equal(t, `flowchart TD
+  subgraph h1["helmfile.d/10-db.yaml"]
+    r1["postgres<br/>namespace: db<br/>chart: charts/postgres"]
+  end
+  subgraph h2["helmfile.d/20-app.yaml"]
+    r2["api<br/>namespace: app<br/>chart: charts/api<br/>labels: app=shop, tier=backend"]
+  end
+  r3["cache/redis"]
+  r2 --> r1
+  r2 --> r3
+`, graph.FormatAsMermaid())
+}
+
+func TestSync_SkippedReleasesAreListed(t *testing.T) {
+	files := map[string]string{
+		"/path/to/helmfile.yaml": `
+releases:
+- name: error-db
+  namespace: db
+  chart: charts/db
+- name: api
+  namespace: app
+  chart: charts/api
+  needs:
+  - db/error-db
+`,
+	}
+
+	valsRuntime, err := vals.New(vals.Options{CacheSize: 32})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	logger := helmexec.NewLogger(&buf, "debug")
+	helm := &exectest.Helm{
+		DiffMutex:     &sync.Mutex{},
+		ChartsMutex:   &sync.Mutex{},
+		ReleasesMutex: &sync.Mutex{},
+	}
+	app := appWithFs(&App{
+		OverrideHelmBinary:              DefaultHelmBinary,
+		fs:                              ffs.DefaultFileSystem(),
+		OverrideKubeContext:             "default",
+		DisableKubeVersionAutoDetection: true,
+		Env:                             "default",
+		Logger:                          logger,
+		helms: map[helmKey]helmexec.Interface{
+			createHelmKey(DefaultHelmBinary, "default"): helm,
+		},
+		valsRuntime: valsRuntime,
+	}, files)
+
+	require.Error(t, app.Sync(applyConfig{concurrency: 1, logger: logger}))
+
+	out := buf.String()
+	assert.Contains(t, out, "Skipping release default/app/api, as release default/db/error-db it waits for failed")
+	assert.Regexp(t, `Skipped Releases[\s\S]*NAME\s+NAMESPACE\s+FAILED DEPENDENCY\s+api\s+app\s+default/db/error-db`, out)
+	assert.Empty(t, helm.Releases)
+}
@@ -19,6 +19,8 @@ import (
 	"github.com/helmfile/helmfile/pkg/policy"
 	"github.com/helmfile/helmfile/pkg/remote"
 	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/helmfile/helmfile/pkg/tmpl"
+	"github.com/helmfile/helmfile/pkg/tracing"
 )
 
 const (
@@ -45,6 +47,19 @@ type desiredStateLoader struct {
 	valsRuntime vals.Evaluator
 
 	lockFilePath string
+
+	// sandbox restricts the template functions of the helmfiles being loaded
+	sandbox *tmpl.Sandbox
+
+	// renderTrace receives the render traces of the helmfile templates, if set
+	renderTrace *RenderTrace
+	// includeChain is the helmfile being loaded, preceded by the helmfiles it is a base of
+	includeChain []string
+
+	// strict makes loading fail on the keys that aren't in the schema of helmfile.yaml
+	strict bool
+	// renderedLines are the lines of the last rendered template along with their template lines, in strict mode
+	renderedLines []tmpl.TracedLine
 }
 
 func (ld *desiredStateLoader) Load(f string, opts LoadOpts) (*state.HelmState, error) {
@@ -101,6 +116,20 @@ func (ld *desiredStateLoader) Load(f string, opts LoadOpts) (*state.HelmState, e
 		file = filepath.Base(f)
 	}
 
+	if opts.Sandbox != nil {
+		sandbox := *opts.Sandbox
+		if sandbox.Dir == "" {
+			absDir, err := ld.fs.Abs(dir)
+			if err != nil {
+				return nil, err
+			}
+			sandbox.Dir = absDir
+		}
+		sandboxed := *ld
+		sandboxed.sandbox = &sandbox
+		ld = &sandboxed
+	}
+
 	// environments inheritance must be injected pre-load: environment values are
 	// baked into RenderedValues during ParseAndLoad (see create.go), so merging
 	// them post-load would leave stale values. Passing the parent's resolved
@@ -185,6 +214,7 @@ func (ld *desiredStateLoader) loadFileWithOverrides(inheritedEnv, overrodeEnv *e
 		return nil, err
 	}
 
+	span := tracing.StartSpan("load helmfile", tracing.Helmfile.String(f))
 	self, err := ld.load(
 		inheritedEnv,
 		overrodeEnv,
@@ -193,6 +223,7 @@ func (ld *desiredStateLoader) loadFileWithOverrides(inheritedEnv, overrodeEnv *e
 		fileBytes,
 		evaluateBases,
 	)
+	tracing.End(span, err)
 
 	if err != nil {
 		return nil, err
@@ -213,6 +244,7 @@ func (ld *desiredStateLoader) loadFileWithOverrides(inheritedEnv, overrodeEnv *e
 func (a *desiredStateLoader) underlying() *state.StateCreator {
 	c := state.NewCreator(a.logger, a.fs, a.valsRuntime, a.getHelm, a.overrideHelmBinary, a.overrideKustomizeBinary, a.remote, a.enableLiveOutput, a.lockFilePath)
 	c.LoadFile = a.loadFile
+	c.Sandbox = a.sandbox
 	return c
 }
 
@@ -239,47 +271,86 @@ func (a *desiredStateLoader) rawLoad(yaml []byte, baseDir, file string, evaluate
 }
 
 func (ld *desiredStateLoader) load(env, overrodeEnv *environment.Environment, baseDir, filename string, content []byte, evaluateBases bool) (*state.HelmState, error) {
+	ld.includeChain = append(ld.includeChain, filename)
+	defer func() {
+		ld.includeChain = ld.includeChain[:len(ld.includeChain)-1]
+	}()
+
 	// Allows part-splitting to work with CLRF-ed content
 	normalizedContent := bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
-	isStrict, err := policy.Checker(filename, normalizedContent)
-	if err != nil {
-		if isStrict {
-			return nil, err
+
+	var parts [][]byte
+	isHCL := filepath.Ext(filename) == state.DefaultHCLFileExtension
+	if isHCL {
+		// A helmfile written in HCL is loaded like a YAML helmfile whose environments
+		// are in the first part: the first part is rendered to the environments, and
+		// the second part to the rest of the helmfile with the environment values.
+		parts = [][]byte{normalizedContent, normalizedContent}
+	} else {
+		isStrict, err := policy.Checker(filename, normalizedContent)
+		if err != nil {
+			if isStrict {
+				return nil, err
+			}
+			ld.logger.Warnf("WARNING: %v", err)
 		}
-		ld.logger.Warnf("WARNING: %v", err)
+		parts = bytes.Split(normalizedContent, []byte("\n---\n"))
 	}
-	parts := bytes.Split(normalizedContent, []byte("\n---\n"))
 
 	hasEnv := env != nil || overrodeEnv != nil
 	var finalState *state.HelmState
 
+	firstLine := 1
 	for i, part := range parts {
 		id := fmt.Sprintf("%s.part.%d", filename, i)
+		if i > 0 && !isHCL {
+			// The previous part and the `---` separating it from this part
+			firstLine += bytes.Count(parts[i-1], []byte("\n")) + 2
+		}
 
 		var rawContent []byte
+		ld.renderedLines = nil
 
 		shouldRender := filepath.Ext(filename) == ".gotmpl" || os.Getenv(envvar.RenderYaml) == "true"
 
-		if shouldRender {
+		if isHCL {
+			yamlBuf, err := ld.renderHCLToYaml(filename, part, i, env, overrodeEnv)
+			if err != nil {
+				return nil, fmt.Errorf("error during %s parsing: %v", id, err)
+			}
+			rawContent = yamlBuf.Bytes()
+		} else if shouldRender {
 			var yamlBuf *bytes.Buffer
 			var err error
 
+			span := tracing.StartSpan("render helmfile template", tracing.Helmfile.String(id))
 			if env == nil && overrodeEnv == nil {
 				yamlBuf, err = ld.renderTemplatesToYaml(baseDir, id, part)
-				if err != nil {
-					return nil, fmt.Errorf("error during %s parsing: %v", id, err)
-				}
 			} else {
-				yamlBuf, err = ld.renderTemplatesToYamlWithEnv(baseDir, id, part, env, overrodeEnv)
-				if err != nil {
-					return nil, fmt.Errorf("error during %s parsing: %v", id, err)
-				}
+				yamlBuf, err = ld.renderTemplatesToYamlWithEnv(baseDir, id, firstLine, part, env, overrodeEnv)
+			}
+			tracing.End(span, err)
+			if err != nil {
+				return nil, fmt.Errorf("error during %s parsing: %v", id, err)
 			}
 			rawContent = yamlBuf.Bytes()
 		} else {
 			rawContent = part
 		}
 
+		if ld.strict && !isHCL {
+			keys, err := ld.findUnknownKeys(filename, firstLine, rawContent)
+			if err != nil {
+				return nil, fmt.Errorf("error during %s parsing: %v", id, err)
+			}
+			if len(keys) > 0 {
+				return nil, &state.StateLoadError{
+					Msg:   fmt.Sprintf("failed to read %s", filename),
+					Cause: &state.UnknownKeysError{Keys: keys},
+				}
+			}
+		}
+
 		currentState, err := ld.rawLoad(
 			rawContent,
 			baseDir,
@@ -358,6 +429,38 @@ func (ld *desiredStateLoader) load(env, overrodeEnv *environment.Environment, ba
 		}
 	}
 
+	// Validate the notifications, not to find a typo in them only once the releases are deployed
+	for i, n := range finalState.Notifications {
+		if err := n.Validate(); err != nil {
+			return nil, &state.StateLoadError{
+				Msg:   fmt.Sprintf("failed to read %s", finalState.FilePath),
+				Cause: fmt.Errorf("notifications[%d]: %w", i, err),
+			}
+		}
+	}
+
 	finalState.OrginReleases = finalState.Releases
 	return finalState, nil
 }
+
+// findUnknownKeys returns the keys of the rendered part content of filename that aren't in the schema of helmfile.yaml,
+// with the lines of the template they were rendered from. The part starts at firstLine of filename.
+func (ld *desiredStateLoader) findUnknownKeys(filename string, firstLine int, content []byte) ([]state.UnknownKey, error) {
+	keys, err := state.FindUnknownKeys(content)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, k := range keys {
+		keys[i].File = filename
+		switch {
+		case ld.renderedLines == nil:
+			keys[i].Line = firstLine + k.Line - 1
+		case k.Line <= len(ld.renderedLines):
+			src := ld.renderedLines[k.Line-1].Source
+			keys[i].File, keys[i].Line = src.File, src.Line
+		}
+	}
+
+	return keys, nil
+}
@@ -36,6 +36,10 @@ type destroyConfig struct {
 	args                   string
 	cascade                string
 	concurrency            int
+	lockstepBatches        bool
+	metricsOutput          string
+	timingReport           bool
+	ui                     bool
 	interactive            bool
 	skipDeps               bool
 	skipRefresh            bool
@@ -70,6 +74,22 @@ func (d destroyConfig) Concurrency() int {
 	return d.concurrency
 }
 
+func (d destroyConfig) LockstepBatches() bool {
+	return d.lockstepBatches
+}
+
+func (d destroyConfig) MetricsOutput() string {
+	return d.metricsOutput
+}
+
+func (d destroyConfig) TimingReport() bool {
+	return d.timingReport
+}
+
+func (d destroyConfig) UI() bool {
+	return d.ui
+}
+
 func (d destroyConfig) SkipDeps() bool {
 	return d.skipDeps
 }
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"bytes"
 	"sync"
 	"testing"
 
@@ -39,6 +40,7 @@ type diffConfig struct {
 	context                  int
 	diffOutput               string
 	concurrency              int
+	lockstepBatches          bool
 	detailedExitcode         bool
 	stripTrailingCR          bool
 	interactive              bool
@@ -143,6 +145,10 @@ func (a diffConfig) Concurrency() int {
 	return a.concurrency
 }
 
+func (a diffConfig) LockstepBatches() bool {
+	return a.lockstepBatches
+}
+
 func (a diffConfig) DetailedExitcode() bool {
 	return a.detailedExitcode
 }
@@ -1262,3 +1268,81 @@ releases:
 		})
 	}
 }
+
+func TestDiffStreaming(t *testing.T) {
+	files := map[string]string{
+		"/path/to/helmfile.yaml": `
+releases:
+- name: front
+  chart: charts/front
+  needs:
+  - back
+- name: back
+  chart: charts/back
+- name: other
+  chart: charts/other
+`,
+	}
+
+	diff := func(t *testing.T, c diffConfig, diffs map[exectest.DiffKey]error) ([]string, string, error) {
+		t.Helper()
+
+		valsRuntime, err := vals.New(vals.Options{CacheSize: 32})
+		if err != nil {
+			t.Fatalf("unexpected error creating vals runtime: %v", err)
+		}
+
+		var buf bytes.Buffer
+		logger := helmexec.NewLogger(&buf, "debug")
+		helm := &exectest.Helm{
+			Diffs:         diffs,
+			DiffMutex:     &sync.Mutex{},
+			ChartsMutex:   &sync.Mutex{},
+			ReleasesMutex: &sync.Mutex{},
+		}
+		app := appWithFs(&App{
+			OverrideHelmBinary:              DefaultHelmBinary,
+			fs:                              ffs.DefaultFileSystem(),
+			OverrideKubeContext:             "default",
+			DisableKubeVersionAutoDetection: true,
+			Env:                             "default",
+			Logger:                          logger,
+			helms: map[helmKey]helmexec.Interface{
+				createHelmKey("helm", "default"): helm,
+			},
+			valsRuntime: valsRuntime,
+		}, files)
+
+		c.concurrency = 1
+		c.logger = logger
+		err = app.Diff(c)
+
+		var diffed []string
+		for _, r := range helm.Diffed {
+			diffed = append(diffed, r.Name)
+		}
+		return diffed, buf.String(), err
+	}
+
+	t.Run("releases are diffed once their needs are diffed", func(t *testing.T) {
+		diffed, _, err := diff(t, diffConfig{}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"back", "other", "front"}, diffed)
+	})
+
+	t.Run("lockstep-batches diffs the releases at once", func(t *testing.T) {
+		diffed, log, err := diff(t, diffConfig{lockstepBatches: true}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"back", "other", "front"}, diffed)
+		assert.NotContains(t, log, "diffing release")
+	})
+
+	t.Run("a failed diff skips the releases that need it", func(t *testing.T) {
+		diffed, log, err := diff(t, diffConfig{}, map[exectest.DiffKey]error{
+			{Name: "back", Chart: "charts/back", Flags: "--kube-context default --reset-values"}: helmexec.ExitError{Code: 1},
+		})
+		assert.Error(t, err)
+		assert.Equal(t, []string{"back", "other"}, diffed)
+		assert.Contains(t, log, "Skipping release default//front, as release default//back it waits for failed")
+	})
+}
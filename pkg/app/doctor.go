@@ -29,9 +29,20 @@ import (
 // SecretRedactor strips residual secret-looking content. The redaction
 // count is surfaced in the report footer.
 //
+// With --source template, `helmfile template` output is analyzed instead of
+// `helmfile diff`. No cluster access is needed, which makes doctor usable for
+// first-time installs and unreachable clusters. Rendered manifests carry
+// Secret data verbatim, so in this mode the output is always routed through
+// the redactor, even when no LLM is configured.
+//
 // See the `helmfile doctor --help` long description for the full user-facing
 // documentation including exit codes.
 func (a *App) Doctor(c DoctorConfigProvider) error {
+	source, err := llm.ParseSource(c.DoctorSource())
+	if err != nil {
+		return err
+	}
+
 	// Wrap c so that ShowSecrets() is forced to false regardless of user
 	// flags. This is the primary secret-safety mechanism: it makes helm-diff
 	// itself redact secret values with "<REDACTED>" placeholders.
@@ -46,20 +57,31 @@ func (a *App) Doctor(c DoctorConfigProvider) error {
 	// safeCfg in so ShowSecrets() is forced false here too: even when no LLM
 	// is configured, doctor itself must never echo raw secrets to stdout
 	// (a user might have piped doctor into a CI log by mistake).
-	if !finalLLM.IsConfigured() {
+	//
+	// --dump-payload is the one exception: auditors may want to inspect the
+	// prompt without ever configuring an endpoint, so we capture the diff,
+	// write the payload, and then print the diff unchanged.
+	if !finalLLM.IsConfigured() && c.DumpPayload() == "" && source == llm.SourceDiff {
 		a.Logger.Debug("doctor: llm not configured, behaving as `helmfile diff` with ShowSecrets forced off")
 		return a.Diff(safeCfg)
 	}
 
-	// Capture stdout while running diff. helmfile writes the rendered diff
-	// to os.Stdout via fmt.Print (see pkg/state/state.go DiffReleases).
+	// Shared redactor — single source of truth for failure and success paths.
+	redactor, err := doctor.NewSecretRedactorWithRules(finalLLM.Redaction)
+	if err != nil {
+		return fmt.Errorf("doctor: %w", err)
+	}
+
+	// Capture stdout while running diff (or template). helmfile writes the
+	// rendered diff to os.Stdout via fmt.Print (see pkg/state/state.go
+	// DiffReleases) and helm template output via helmexec's write.
 	diffText, diffErr := captureStdout(func() error {
+		if source == llm.SourceTemplate {
+			return a.Template(doctorTemplateConfig{DoctorConfigProvider: safeCfg})
+		}
 		return a.Diff(safeCfg)
 	})
 
-	// Shared redactor — single source of truth for failure and success paths.
-	redactor := doctor.NewSecretRedactor()
-
 	// A "detected changes" exit (code 2 from helm-diff) is expected here and
 	// must NOT short-circuit the analysis; doctor exists to react to changes.
 	if diffErr != nil && !isDetectedChanges(diffErr) {
@@ -73,16 +95,57 @@ func (a *App) Doctor(c DoctorConfigProvider) error {
 		return diffErr
 	}
 
+	var payloadWriter io.Writer
+	if path := c.DumpPayload(); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("doctor: failed to create payload dump file: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		payloadWriter = f
+	}
+
+	if !finalLLM.IsConfigured() {
+		result := doctor.Analyze(a.ctx, diffText, doctor.Options{
+			Environment:   a.Env,
+			Releases:      releases,
+			Model:         finalLLM.Model,
+			Redactor:      redactor,
+			PayloadWriter: payloadWriter,
+			Source:        source,
+		})
+		if result.PayloadErr != nil {
+			return result.PayloadErr
+		}
+		if payloadWriter != nil {
+			a.Logger.Infof("doctor: llm not configured; payload written to %s without being sent", c.DumpPayload())
+		}
+		// helm-diff already redacted Secret values (ShowSecrets is forced
+		// off), so the diff is printed byte-for-byte. Rendered manifests
+		// have no such protection and are printed post-redaction.
+		if source == llm.SourceTemplate {
+			_, _ = fmt.Fprint(os.Stdout, result.RawDiff)
+			return nil
+		}
+		_, _ = fmt.Fprint(os.Stdout, diffText)
+		return diffErr
+	}
+
 	// Invoke the LLM. doctor.Analyze applies the shared redactor before the
 	// diff ever leaves the process boundary.
 	client := llm.NewClient(finalLLM)
 	result := doctor.Analyze(a.ctx, diffText, doctor.Options{
-		Client:      client,
-		Environment: a.Env,
-		Releases:    releases,
-		Model:       finalLLM.Model,
-		Redactor:    redactor,
+		Client:        client,
+		Environment:   a.Env,
+		Releases:      releases,
+		Model:         finalLLM.Model,
+		Redactor:      redactor,
+		PayloadWriter: payloadWriter,
+		Source:        source,
 	})
+	if result.PayloadErr != nil {
+		return result.PayloadErr
+	}
 	if result.SecretsRedacted > 0 {
 		a.Logger.Infof("doctor: %d secrets redacted before LLM transmission", result.SecretsRedacted)
 	}
@@ -164,6 +227,47 @@ type secretSafeDoctorConfig struct {
 // values. doctor's own SecretRedactor handles any residual leaks.
 func (s secretSafeDoctorConfig) ShowSecrets() bool { return false }
 
+// doctorTemplateConfig adapts a DoctorConfigProvider to the
+// TemplateConfigProvider surface for `doctor --source template`. Shared
+// flags (values, set, selectors, concurrency, needs handling, post-renderer)
+// pass through; template-only knobs are pinned so the rendered manifests are
+// streamed to stdout where captureStdout can pick them up.
+type doctorTemplateConfig struct {
+	DoctorConfigProvider
+}
+
+// OutputDir is always empty: manifests must go to stdout for capture.
+func (c doctorTemplateConfig) OutputDir() string { return "" }
+
+// OutputDirTemplate is always empty for the same reason as OutputDir.
+func (c doctorTemplateConfig) OutputDirTemplate() string { return "" }
+
+// SnapshotDir is always empty: snapshots write manifests to files, not stdout.
+func (c doctorTemplateConfig) SnapshotDir() string { return "" }
+
+// CheckSnapshots is off because SnapshotDir is.
+func (c doctorTemplateConfig) CheckSnapshots() bool { return false }
+
+// Validate is forced off: --validate makes helm contact the cluster, and
+// template mode exists precisely for clusters doctor cannot reach.
+func (c doctorTemplateConfig) Validate() bool { return false }
+
+// IncludeCRDs is on so CRDs shipped by charts are reviewed too.
+func (c doctorTemplateConfig) IncludeCRDs() bool { return true }
+
+// SkipCleanup removes temporary values files as `helmfile template` does by
+// default.
+func (c doctorTemplateConfig) SkipCleanup() bool { return false }
+
+// SkipTests drops chart test hooks, which are not installed resources.
+func (c doctorTemplateConfig) SkipTests() bool { return true }
+
+// KubeVersion defers to helm's default.
+func (c doctorTemplateConfig) KubeVersion() string { return "" }
+
+// ShowOnly renders every template.
+func (c doctorTemplateConfig) ShowOnly() []string { return nil }
+
 // peekDoctorContext walks the helmfile(s) once to harvest the first configured
 // `llm:` block plus the full set of release names that match the current
 // selector. Used by Doctor to drive config precedence without running helm.
@@ -172,6 +276,10 @@ func (s secretSafeDoctorConfig) ShowSecrets() bool { return false }
 // base inheritance). It is NOT a cheap YAML-only peek — see the performance
 // note in App.Doctor for why we accept the double-load cost.
 //
+// Redaction rules are harvested independently of the endpoint settings so a
+// helmfile.yaml can declare `llm.redaction` while the API key and model come
+// from env or flags.
+//
 // Returns (zero-value llm.Config, nil, nil) when no `llm:` block exists.
 // Returns the ForEachState error verbatim so callers can decide whether to
 // warn or fail.
@@ -187,7 +295,14 @@ func (a *App) peekDoctorContext(c DoctorConfigProvider) (llm.Config, []string, e
 		st := run.State()
 		if st != nil {
 			if !yamlLLM.IsConfigured() && st.LLM.IsConfigured() {
+				rules := yamlLLM.Redaction
 				yamlLLM = st.LLM
+				if yamlLLM.Redaction.IsEmpty() {
+					yamlLLM.Redaction = rules
+				}
+			}
+			if yamlLLM.Redaction.IsEmpty() && !st.LLM.Redaction.IsEmpty() {
+				yamlLLM.Redaction = st.LLM.Redaction
 			}
 			for _, r := range st.Releases {
 				if r.Desired() {
@@ -20,12 +20,16 @@ type doctorStubConfig struct {
 	force       bool
 	reportFmt   string
 	showSecrets bool
+	dumpPayload string
+	source      string
 }
 
 func (d doctorStubConfig) FlagLLMConfig() llm.Config { return d.flagLLM }
 func (d doctorStubConfig) Force() bool               { return d.force }
 func (d doctorStubConfig) DoctorOutput() string      { return d.reportFmt }
 func (d doctorStubConfig) ShowSecrets() bool         { return d.showSecrets }
+func (d doctorStubConfig) DumpPayload() string       { return d.dumpPayload }
+func (d doctorStubConfig) DoctorSource() string      { return d.source }
 
 // TestSecretSafeDoctorConfig_PassthroughAllOtherMethods is a regression guard
 // against a subtle Go pitfall: when wrapping an interface in a struct and
@@ -57,9 +61,11 @@ func TestSecretSafeDoctorConfig_PassthroughAllOtherMethods(t *testing.T) {
 			skipCRDs:    true,
 			skipDeps:    true,
 		},
-		flagLLM:   llm.Config{BaseURL: "x", APIKey: "y", Model: "z"},
-		force:     true,
-		reportFmt: "json",
+		flagLLM:     llm.Config{BaseURL: "x", APIKey: "y", Model: "z"},
+		force:       true,
+		reportFmt:   "json",
+		dumpPayload: "payload.json",
+		source:      "template",
 	}
 	wrapped := secretSafeDoctorConfig{DoctorConfigProvider: inner}
 
@@ -90,6 +96,8 @@ func TestSecretSafeDoctorConfig_PassthroughAllOtherMethods(t *testing.T) {
 		// DoctorConfigProvider surface
 		{"Force", wrapped.Force(), inner.Force()},
 		{"DoctorOutput", wrapped.DoctorOutput(), inner.DoctorOutput()},
+		{"DumpPayload", wrapped.DumpPayload(), inner.DumpPayload()},
+		{"DoctorSource", wrapped.DoctorSource(), inner.DoctorSource()},
 	}
 	for _, c := range passThroughChecks {
 		if c.got != c.want {
@@ -107,6 +115,36 @@ func TestSecretSafeDoctorConfig_PassthroughAllOtherMethods(t *testing.T) {
 	}
 }
 
+// TestDoctorTemplateConfig_PinsTemplateKnobs guards the --source template
+// adapter: manifests must stream to stdout and helm must never be asked to
+// validate against the cluster, while shared flags keep passing through.
+func TestDoctorTemplateConfig_PinsTemplateKnobs(t *testing.T) {
+	inner := doctorStubConfig{
+		diffConfig: diffConfig{
+			concurrency: 3,
+			validate:    true,
+			values:      []string{"prod.yaml"},
+		},
+	}
+	var c TemplateConfigProvider = doctorTemplateConfig{DoctorConfigProvider: inner}
+
+	if c.Validate() {
+		t.Error("Validate() must be false in template mode (no cluster access)")
+	}
+	if c.OutputDir() != "" || c.OutputDirTemplate() != "" || c.SnapshotDir() != "" {
+		t.Error("output dir must be empty so manifests go to stdout")
+	}
+	if !c.IncludeCRDs() {
+		t.Error("IncludeCRDs() should be true so CRDs are reviewed")
+	}
+	if c.Concurrency() != 3 {
+		t.Errorf("Concurrency() = %d, want passthrough 3", c.Concurrency())
+	}
+	if !slices.Equal(c.Values(), []string{"prod.yaml"}) {
+		t.Errorf("Values() = %v, want passthrough", c.Values())
+	}
+}
+
 func TestCaptureStdout_CapturesPrint(t *testing.T) {
 	got, err := captureStdout(func() error {
 		fmt.Println("hello")
@@ -36,6 +36,25 @@ func FormatAsTable(releases []*HelmRelease) error {
 	return nil
 }
 
+// FormatLiveAsTable prints releases along with the releases installed in the cluster, as listed by `list --live`.
+func FormatLiveAsTable(releases []*HelmRelease) error {
+	table := uitable.New()
+	table.AddRow("NAME", "NAMESPACE", "ENABLED", "INSTALLED", "LABELS", "CHART", "VERSION", "DEPLOYED", "REVISION", "STATUS", "UPDATED", "DIVERGED")
+
+	for _, r := range releases {
+		enabled, installed, diverged := fmt.Sprintf("%t", r.Enabled), fmt.Sprintf("%t", r.Installed), fmt.Sprintf("%t", r.Diverged)
+		if r.Orphan {
+			enabled, installed, diverged = "", "", "orphan"
+		}
+		table.AddRow(r.Name, r.Namespace, enabled, installed, r.Labels, r.Chart, r.Version, r.DeployedVersion, r.Revision, r.Status, r.Updated, diverged)
+	}
+
+	output := trimTrailingWhitespace(table.String())
+	fmt.Println(output)
+
+	return nil
+}
+
 func FormatAsJson(releases []*HelmRelease) error {
 	output, err := json.Marshal(releases)
 
@@ -0,0 +1,772 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/state"
+)
+
+// helmfileGraph is the graph of the releases of every helmfile loaded by a
+// single command, linked by their needs, so that releases can need releases
+// defined in other helmfiles.
+//
+// It is built by a recording pass over every helmfile the command loads, before
+// any of them is processed. Helmfiles are then processed as usual, reusing the
+// states loaded by the recording pass instead of loading them again, and each
+// release only starts once the releases of other helmfiles it needs are done,
+// or, when releases are deleted, once the releases of other helmfiles needing
+// it are done. Releases that don't depend on each other keep being processed
+// in parallel, wherever they are defined.
+//
+// Since sub-helmfiles are processed by their parent's goroutine, some orderings
+// can't be satisfied, like a sub-helmfile needing a release of its parent.
+// helmfileGraph tracks the goroutines that are still running to report those
+// as an error instead of waiting forever.
+type helmfileGraph struct {
+	// reverse is true when releases are deleted, so that a release waits for
+	// the releases of other helmfiles that need it instead.
+	reverse bool
+	// recording is true during the recording pass.
+	recording bool
+	// wd is the directory paths are shown relative to.
+	wd string
+
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	helmfiles map[string]*helmfileNode
+	releases  map[string]*releaseNode
+	// runs maps the states being processed to the helmfiles they were loaded from.
+	runs map[*state.HelmState]*helmfileRun
+
+	// active is the number of goroutines processing helmfiles that are not waiting.
+	active int
+	// failures is the number of goroutines that ended with an error.
+	failures int
+	waiting  map[*releaseWaiter]struct{}
+	err      error
+
+	// loaded are the states loaded during the recording pass, by load key.
+	// Each is taken by the first helmfile loaded with the same key afterwards.
+	loaded map[string][]*state.HelmState
+	// templated maps the states whose release templates were executed during
+	// the recording pass to the resulting states.
+	templated map[*state.HelmState]*state.HelmState
+}
+
+type helmfileNode struct {
+	path    string
+	display string
+
+	// releases maps the IDs of the releases defined in the helmfile to their needs.
+	releases map[string][]string
+	// after maps the IDs of the releases defined in the helmfile to the
+	// releases of other helmfiles they wait for.
+	after map[string][]releaseEdge
+}
+
+type releaseNode struct {
+	id string
+	// pending is the number of times the release is yet to be processed, as
+	// the same release can be defined by several helmfiles.
+	pending int
+	failed  bool
+}
+
+// releaseEdge is a release of another helmfile that a release waits for.
+type releaseEdge struct {
+	node     *releaseNode
+	helmfile *helmfileNode
+}
+
+func (e releaseEdge) String() string {
+	return fmt.Sprintf("release %q of %s", e.node.id, e.helmfile.display)
+}
+
+// helmfileRun is a helmfile being processed, whose releases wait for releases
+// of other helmfiles.
+type helmfileRun struct {
+	g     *helmfileGraph
+	st    *state.HelmState
+	node  *helmfileNode
+	ended bool
+	// done are the releases of the helmfile that are done.
+	done map[string]bool
+	// idle is true while the goroutine processing the helmfile has nothing to
+	// do but wait for releases of other helmfiles.
+	idle   bool
+	events []releaseEvent
+	signal chan struct{}
+}
+
+// releaseEvent tells that a release, identified by the index given to
+// helmfileRun.wait, no longer waits for releases of other helmfiles.
+type releaseEvent struct {
+	index int
+	// failed is the ID of a failed release it waits for.
+	failed string
+	err    error
+}
+
+type releaseWaiter struct {
+	run      *helmfileRun
+	index    int
+	releases []string
+	edges    []releaseEdge
+}
+
+// helmfileGroup tracks the goroutines started to process helmfiles in parallel.
+type helmfileGroup struct {
+	remaining int
+}
+
+// HelmfileSkipError is returned for releases that were not processed because
+// a release they wait for failed, or for a helmfile that was not processed
+// because of a failure in another helmfile.
+type HelmfileSkipError struct {
+	msg string
+}
+
+func (e *HelmfileSkipError) Error() string {
+	return e.msg
+}
+
+func newHelmfileGraph(wd string, reverse, recording bool) *helmfileGraph {
+	g := &helmfileGraph{
+		reverse:   reverse,
+		recording: recording,
+		wd:        wd,
+		helmfiles: map[string]*helmfileNode{},
+		releases:  map[string]*releaseNode{},
+		runs:      map[*state.HelmState]*helmfileRun{},
+		waiting:   map[*releaseWaiter]struct{}{},
+		loaded:    map[string][]*state.HelmState{},
+		templated: map[*state.HelmState]*state.HelmState{},
+	}
+	g.cond = sync.NewCond(&g.mu)
+	return g
+}
+
+func (g *helmfileGraph) isRecording() bool {
+	return g != nil && g.recording
+}
+
+// logger returns the logger to find helmfiles with, which discards everything
+// during the recording pass as the helmfiles are found again afterwards.
+func (g *helmfileGraph) logger(logger *zap.SugaredLogger) *zap.SugaredLogger {
+	if g.isRecording() {
+		return zap.NewNop().Sugar()
+	}
+	return logger
+}
+
+// load calls load to load the helmfile at path with opts, keeping its state
+// during the recording pass, or returns the state kept for it if any.
+func (g *helmfileGraph) load(path string, opts LoadOpts, load func() (*state.HelmState, error)) (*state.HelmState, error) {
+	if g == nil {
+		return load()
+	}
+
+	key := fmt.Sprintf("%s\n%s\n%q\n%+v", path, opts.CalleePath, opts.Selectors, opts.Environment)
+
+	g.mu.Lock()
+	if kept := g.loaded[key]; !g.recording && len(kept) > 0 {
+		g.loaded[key] = kept[1:]
+		g.mu.Unlock()
+		return kept[0], nil
+	}
+	recording := g.recording
+	g.mu.Unlock()
+
+	st, err := load()
+	if err == nil && st != nil && recording {
+		g.mu.Lock()
+		g.loaded[key] = append(g.loaded[key], st)
+		g.mu.Unlock()
+	}
+	return st, err
+}
+
+// executeTemplates executes the release templates of st, or returns the state
+// they were executed into during the recording pass if any.
+func (g *helmfileGraph) executeTemplates(st *state.HelmState) (*state.HelmState, error) {
+	if g == nil {
+		return st.ExecuteTemplates()
+	}
+
+	g.mu.Lock()
+	templated, ok := g.templated[st]
+	if ok && !g.recording {
+		delete(g.templated, st)
+	}
+	recording := g.recording
+	g.mu.Unlock()
+	if ok {
+		return templated, nil
+	}
+
+	templated, err := st.ExecuteTemplates()
+	if err == nil && recording {
+		g.mu.Lock()
+		g.templated[st] = templated
+		g.mu.Unlock()
+	}
+	return templated, err
+}
+
+// record adds the releases of the helmfile at path to the graph.
+func (g *helmfileGraph) record(path string, st *state.HelmState) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	n, ok := g.helmfiles[path]
+	if !ok {
+		display := path
+		if rel, err := filepath.Rel(g.wd, path); err == nil {
+			display = rel
+		}
+		n = &helmfileNode{path: path, display: display, releases: map[string][]string{}, after: map[string][]releaseEdge{}}
+		g.helmfiles[path] = n
+	}
+
+	seen := map[string]bool{}
+	for _, r := range st.Releases {
+		spec := r
+		st.ApplyOverrides(&spec)
+		id := state.ReleaseToID(&spec)
+		n.releases[id] = append(n.releases[id], spec.Needs...)
+
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		rn, ok := g.releases[id]
+		if !ok {
+			rn = &releaseNode{id: id}
+			g.releases[id] = rn
+		}
+		rn.pending++
+	}
+}
+
+// resolve computes the releases of other helmfiles each release waits for,
+// and returns an error when needs across helmfiles form a cycle.
+func (g *helmfileGraph) resolve() error {
+	owners := map[string][]*helmfileNode{}
+	for _, n := range g.sortedHelmfiles() {
+		for id := range n.releases {
+			owners[id] = append(owners[id], n)
+		}
+	}
+
+	for _, n := range g.sortedHelmfiles() {
+		for _, id := range sortedKeys(n.releases) {
+			for _, need := range n.releases[id] {
+				if _, local := n.releases[need]; local {
+					continue
+				}
+				for _, dep := range owners[need] {
+					if g.reverse {
+						dep.after[need] = appendEdge(dep.after[need], releaseEdge{node: g.releases[id], helmfile: n})
+					} else {
+						n.after[id] = appendEdge(n.after[id], releaseEdge{node: g.releases[need], helmfile: dep})
+					}
+				}
+			}
+		}
+	}
+
+	// Releases are checked for cycles as defined in each helmfile. Cycles
+	// within a single helmfile are left to the planning of its releases.
+	type vertex struct {
+		helmfile *helmfileNode
+		release  string
+	}
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	marks := map[vertex]int{}
+	var path []vertex
+	var visit func(v vertex) error
+	visit = func(v vertex) error {
+		marks[v] = visiting
+		path = append(path, v)
+		for _, need := range v.helmfile.releases[v.release] {
+			var next []vertex
+			if _, local := v.helmfile.releases[need]; local {
+				next = append(next, vertex{v.helmfile, need})
+			} else {
+				for _, dep := range owners[need] {
+					next = append(next, vertex{dep, need})
+				}
+			}
+			for _, w := range next {
+				switch marks[w] {
+				case visiting:
+					var start int
+					for i := range path {
+						if path[i] == w {
+							start = i
+						}
+					}
+					cycle := append(append([]vertex{}, path[start:]...), w)
+					across := false
+					var hops []string
+					for _, c := range cycle {
+						across = across || c.helmfile != w.helmfile
+						hops = append(hops, fmt.Sprintf("%q in %s", c.release, c.helmfile.display))
+					}
+					if across {
+						return fmt.Errorf("needs across helmfiles form a cycle: %s", strings.Join(hops, " -> "))
+					}
+				case unvisited:
+					if err := visit(w); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		marks[v] = visited
+		return nil
+	}
+	for _, n := range g.sortedHelmfiles() {
+		for _, id := range sortedKeys(n.releases) {
+			if v := (vertex{n, id}); marks[v] == unvisited {
+				if err := visit(v); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func appendEdge(edges []releaseEdge, e releaseEdge) []releaseEdge {
+	for _, x := range edges {
+		if x.node == e.node && x.helmfile == e.helmfile {
+			return edges
+		}
+	}
+	return append(edges, e)
+}
+
+func (g *helmfileGraph) sortedHelmfiles() []*helmfileNode {
+	var nodes []*helmfileNode
+	for _, p := range sortedKeys(g.helmfiles) {
+		nodes = append(nodes, g.helmfiles[p])
+	}
+	return nodes
+}
+
+// externalReleases returns the releases needed by the helmfile at path that
+// are defined by other helmfiles, mapped to the helmfile defining each of them.
+func (g *helmfileGraph) externalReleases(path string) map[string]string {
+	if g == nil || g.recording {
+		return nil
+	}
+
+	n, ok := g.helmfiles[path]
+	if !ok {
+		return nil
+	}
+
+	external := map[string]string{}
+	for _, needs := range n.releases {
+		for _, need := range needs {
+			if _, local := n.releases[need]; local {
+				continue
+			}
+			for _, p := range sortedKeys(g.helmfiles) {
+				if _, ok := g.helmfiles[p].releases[need]; ok {
+					external[need] = g.helmfiles[p].display
+					break
+				}
+			}
+		}
+	}
+	return external
+}
+
+// start is called before the helmfile at path is processed from st.
+// The returned run is nil when the helmfile wasn't recorded.
+func (g *helmfileGraph) start(path string, st *state.HelmState) *helmfileRun {
+	if g == nil || g.recording {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	n, ok := g.helmfiles[path]
+	if !ok {
+		return nil
+	}
+
+	r := &helmfileRun{g: g, st: st, node: n, done: map[string]bool{}, signal: make(chan struct{}, 1)}
+	g.runs[st] = r
+	return r
+}
+
+// run returns the run of the helmfile st is being processed for, if any.
+func (g *helmfileGraph) run(st *state.HelmState) *helmfileRun {
+	if g == nil || g.recording {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.runs[st]
+}
+
+// finish is called once the helmfile is processed. The releases of the
+// helmfile that weren't processed, like releases without changes or not
+// matching the selectors, are done too, and failed when ok is false.
+func (r *helmfileRun) finish(ok bool) {
+	if r == nil {
+		return
+	}
+
+	g := r.g
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for id := range r.node.releases {
+		r.markDone(id, ok)
+	}
+	r.ended = true
+	delete(g.runs, r.st)
+	g.release()
+}
+
+// orders reports whether releases processed in the given direction wait for
+// releases of other helmfiles.
+func (r *helmfileRun) orders(reverse bool) bool {
+	return r != nil && r.g.reverse == reverse
+}
+
+// wait makes the releases ids wait for the releases of other helmfiles they
+// depend on. It returns false when there is nothing to wait for, and otherwise
+// an event with index is delivered once they no longer wait.
+func (r *helmfileRun) wait(index int, ids ...string) bool {
+	if r == nil {
+		return false
+	}
+
+	g := r.g
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var edges []releaseEdge
+	for _, id := range ids {
+		for _, e := range r.node.after[id] {
+			edges = appendEdge(edges, e)
+		}
+	}
+	if len(edges) == 0 {
+		return false
+	}
+
+	g.waiting[&releaseWaiter{run: r, index: index, releases: ids, edges: edges}] = struct{}{}
+	g.release()
+	return true
+}
+
+// releaseDone is called once the release id of the helmfile is done.
+func (r *helmfileRun) releaseDone(id string, ok bool) {
+	if r == nil {
+		return
+	}
+
+	g := r.g
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	r.markDone(id, ok)
+	g.release()
+}
+
+func (r *helmfileRun) markDone(id string, ok bool) {
+	if r.ended || r.done[id] {
+		return
+	}
+	if _, defined := r.node.releases[id]; !defined {
+		return
+	}
+	r.done[id] = true
+
+	n := r.g.releases[id]
+	n.pending--
+	if !ok {
+		n.failed = true
+	}
+}
+
+// signalled returns a channel that receives when events are delivered.
+func (r *helmfileRun) signalled() <-chan struct{} {
+	if r == nil {
+		return nil
+	}
+	return r.signal
+}
+
+// take returns the events delivered so far.
+func (r *helmfileRun) take() []releaseEvent {
+	g := r.g
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	events := r.events
+	r.events = nil
+	return events
+}
+
+// next blocks until events are delivered, when the goroutine processing the
+// helmfile has nothing else to do.
+func (r *helmfileRun) next() []releaseEvent {
+	g := r.g
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(r.events) == 0 {
+		r.idle = true
+		g.active--
+		g.stalled()
+		for len(r.events) == 0 {
+			g.cond.Wait()
+		}
+	}
+
+	events := r.events
+	r.events = nil
+	return events
+}
+
+// waitForBatch wraps converge to make each group of releases processed with
+// withBatches wait for the releases of other helmfiles they depend on.
+func (r *helmfileRun) waitForBatch(reverse bool, logger *zap.SugaredLogger, converge func(*state.HelmState, helmexec.Interface) (bool, []error)) func(*state.HelmState, helmexec.Interface) (bool, []error) {
+	if !r.orders(reverse) {
+		return converge
+	}
+
+	return func(subst *state.HelmState, helm helmexec.Interface) (bool, []error) {
+		var ids []string
+		for i := range subst.Releases {
+			ids = append(ids, state.ReleaseToID(&subst.Releases[i]))
+		}
+
+		done := func(ok bool) {
+			for _, id := range ids {
+				r.releaseDone(id, ok)
+			}
+		}
+
+		if r.wait(0, ids...) {
+			ev := r.next()[0]
+			if ev.err != nil {
+				done(false)
+				return false, []error{ev.err}
+			}
+			if ev.failed != "" {
+				logger.Warnf("Skipping releases %s, as release %s they wait for failed", strings.Join(ids, ", "), ev.failed)
+				done(false)
+				return false, []error{&HelmfileSkipError{msg: fmt.Sprintf("skipping releases %s as release %s they wait for failed", strings.Join(ids, ", "), ev.failed)}}
+			}
+		}
+
+		processed, errs := converge(subst, helm)
+		done(len(errs) == 0)
+		return processed, errs
+	}
+}
+
+// fork is called before starting n goroutines that process helmfiles while
+// the calling goroutine waits for them.
+func (g *helmfileGraph) fork(n int) *helmfileGroup {
+	if g == nil || g.recording {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.active += n - 1
+	return &helmfileGroup{remaining: n}
+}
+
+// exit is called when a goroutine started after fork ends.
+// The last one hands its slot back to the goroutine that called fork.
+func (g *helmfileGraph) exit(grp *helmfileGroup, err error) {
+	if g == nil || grp == nil {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err != nil && !isHelmfileSkipError(err) {
+		g.failures++
+	}
+	grp.remaining--
+	if grp.remaining > 0 {
+		g.active--
+	}
+	g.release()
+}
+
+// ready returns whether the waiter can proceed, and the ID of a failed
+// release it waits for.
+func (g *helmfileGraph) ready(w *releaseWaiter) (bool, string) {
+	for _, e := range w.edges {
+		if e.node.failed {
+			return true, e.node.id
+		}
+	}
+	for _, e := range w.edges {
+		if e.node.pending > 0 {
+			return false, ""
+		}
+	}
+	return true, ""
+}
+
+// notify delivers the event of w to its helmfile.
+func (g *helmfileGraph) notify(w *releaseWaiter, failed string, err error) {
+	delete(g.waiting, w)
+
+	r := w.run
+	r.events = append(r.events, releaseEvent{index: w.index, failed: failed, err: err})
+	if r.idle {
+		r.idle = false
+		g.active++
+	}
+	select {
+	case r.signal <- struct{}{}:
+	default:
+	}
+	g.cond.Broadcast()
+}
+
+// release delivers the events of the waiting releases that can proceed.
+func (g *helmfileGraph) release() {
+	for w := range g.waiting {
+		if g.err != nil {
+			g.notify(w, "", g.err)
+			continue
+		}
+		if ready, failed := g.ready(w); ready {
+			g.notify(w, failed, nil)
+		}
+	}
+	g.stalled()
+}
+
+// stalled fails the remaining waiting releases when none of the goroutines
+// processing helmfiles is running anymore, as nothing could ever wake them up.
+func (g *helmfileGraph) stalled() {
+	if g.err != nil || g.active > 0 || len(g.waiting) == 0 {
+		return
+	}
+
+	var waits []string
+	for w := range g.waiting {
+		for _, e := range w.edges {
+			if e.node.pending > 0 {
+				waits = append(waits, fmt.Sprintf("%s of %s waits for %s", quoteReleases(w.releases), w.run.node.display, e))
+				break
+			}
+		}
+	}
+	sort.Strings(waits)
+
+	if g.failures > 0 {
+		g.err = &HelmfileSkipError{msg: fmt.Sprintf("skipping releases waiting for releases of a helmfile that failed: %s", strings.Join(waits, ", "))}
+	} else {
+		g.err = fmt.Errorf("needs across helmfiles can't be satisfied in the order the helmfiles are processed: %s. "+
+			"Sub-helmfiles are processed before their parent helmfile, or after it when releases are deleted, "+
+			"and --sequential-helmfiles processes the helmfiles of a directory one by one in order", strings.Join(waits, ", "))
+	}
+
+	for w := range g.waiting {
+		g.notify(w, "", g.err)
+	}
+}
+
+func quoteReleases(ids []string) string {
+	quoted := make([]string, len(ids))
+	for i, id := range ids {
+		quoted[i] = fmt.Sprintf("%q", id)
+	}
+	if len(quoted) == 1 {
+		return "release " + quoted[0]
+	}
+	return "releases " + strings.Join(quoted, ", ")
+}
+
+// order returns the indices of paths in their original order, except that
+// every helmfile comes after the helmfiles among paths whose releases it waits for.
+func (g *helmfileGraph) order(paths []string) []int {
+	index := map[*helmfileNode]int{}
+	for i, p := range paths {
+		if n, ok := g.helmfiles[p]; ok {
+			index[n] = i
+		}
+	}
+
+	placed := make([]bool, len(paths))
+	var order []int
+	for len(order) < len(paths) {
+		next := -1
+		for i, p := range paths {
+			if placed[i] {
+				continue
+			}
+			if next < 0 {
+				next = i
+			}
+			blocked := false
+			if n, ok := g.helmfiles[p]; ok {
+				for _, edges := range n.after {
+					for _, e := range edges {
+						if j, ok := index[e.helmfile]; ok && j != i && !placed[j] {
+							blocked = true
+						}
+					}
+				}
+			}
+			if !blocked {
+				next = i
+				break
+			}
+		}
+		placed[next] = true
+		order = append(order, next)
+	}
+	return order
+}
+
+func isHelmfileSkipError(err error) bool {
+	switch e := err.(type) {
+	case *HelmfileSkipError:
+		return true
+	case *Error:
+		for _, err := range e.Errors {
+			if isHelmfileSkipError(err) {
+				return true
+			}
+		}
+	}
+	return false
+}
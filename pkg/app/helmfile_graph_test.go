@@ -0,0 +1,345 @@
+package app
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/helmfile/vals"
+	"go.uber.org/zap"
+
+	"github.com/helmfile/helmfile/pkg/exectest"
+	ffs "github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/testhelper"
+)
+
+func TestNeedsAcrossHelmfiles(t *testing.T) {
+	type testcase struct {
+		fileOrDir  string
+		files      map[string]string
+		sequential bool
+		lockstep   bool
+		destroy    bool
+		diff       bool
+		lists      map[exectest.ListKey]string
+		error      string
+		upgraded   []string
+		deleted    []string
+	}
+
+	check := func(t *testing.T, tc testcase) {
+		t.Helper()
+
+		var helm = &exectest.Helm{
+			Lists:         tc.lists,
+			DiffMutex:     &sync.Mutex{},
+			ChartsMutex:   &sync.Mutex{},
+			ReleasesMutex: &sync.Mutex{},
+		}
+
+		_ = runWithLogCapture(t, "debug", func(t *testing.T, logger *zap.SugaredLogger) {
+			t.Helper()
+
+			valsRuntime, err := vals.New(vals.Options{CacheSize: 32})
+			if err != nil {
+				t.Errorf("unexpected error creating vals runtime: %v", err)
+			}
+
+			fs := testhelper.NewTestFs(tc.files)
+			app := injectFs(&App{
+				OverrideHelmBinary:              DefaultHelmBinary,
+				fs:                              ffs.DefaultFileSystem(),
+				OverrideKubeContext:             "default",
+				DisableKubeVersionAutoDetection: true,
+				Env:                             "default",
+				Logger:                          logger,
+				FileOrDir:                       tc.fileOrDir,
+				SequentialHelmfiles:             tc.sequential,
+				helms: map[helmKey]helmexec.Interface{
+					createHelmKey("helm", "default"): helm,
+				},
+				valsRuntime: valsRuntime,
+			}, fs)
+
+			var gotErr error
+			switch {
+			case tc.diff:
+				gotErr = app.Diff(diffConfig{concurrency: 1, logger: logger})
+			case tc.destroy:
+				gotErr = app.Destroy(destroyConfig{concurrency: 1, lockstepBatches: tc.lockstep, logger: logger})
+			default:
+				gotErr = app.Sync(applyConfig{concurrency: 1, lockstepBatches: tc.lockstep, logger: logger})
+			}
+
+			var got string
+			if gotErr != nil {
+				got = gotErr.Error()
+			}
+			if d := cmp.Diff(tc.error, got); d != "" {
+				t.Fatalf("unexpected error: want (-), got (+): %s", d)
+			}
+
+			var upgraded, deleted []string
+			for _, r := range helm.Releases {
+				upgraded = append(upgraded, r.Name)
+			}
+			for _, r := range helm.Deleted {
+				deleted = append(deleted, r.Name)
+			}
+			if d := cmp.Diff(tc.upgraded, upgraded); d != "" {
+				t.Errorf("unexpected upgrades: want (-), got (+): %s", d)
+			}
+			if d := cmp.Diff(tc.deleted, deleted); d != "" {
+				t.Errorf("unexpected deletes: want (-), got (+): %s", d)
+			}
+
+			// The helmfiles loaded by the recording pass are not loaded again
+			reads := map[string]int{}
+			for _, f := range fs.SuccessfulReads() {
+				reads[f]++
+			}
+			for f, n := range reads {
+				if n > 1 {
+					t.Errorf("%s was read %d times", f, n)
+				}
+			}
+		})
+	}
+
+	helmfileD := map[string]string{
+		"/path/to/helmfile.d/10-app.yaml": `
+releases:
+- name: api
+  chart: incubator/raw
+  namespace: app
+  needs:
+  - db/postgres
+- name: web
+  chart: incubator/raw
+  namespace: app
+  needs:
+  - api
+`,
+		"/path/to/helmfile.d/20-db.yaml": `
+releases:
+- name: postgres
+  chart: incubator/raw
+  namespace: db
+`,
+	}
+
+	t.Run("sync waits for the helmfiles defining the needed releases", func(t *testing.T) {
+		check(t, testcase{
+			fileOrDir: "/path/to/helmfile.d",
+			files:     helmfileD,
+			upgraded:  []string{"postgres", "api", "web"},
+		})
+	})
+
+	t.Run("diff leaves needs on releases of other helmfiles out", func(t *testing.T) {
+		check(t, testcase{
+			fileOrDir: "/path/to/helmfile.d",
+			files:     helmfileD,
+			diff:      true,
+		})
+	})
+
+	t.Run("lockstep batches wait for the needed releases", func(t *testing.T) {
+		check(t, testcase{
+			fileOrDir: "/path/to/helmfile.d",
+			files:     helmfileD,
+			lockstep:  true,
+			upgraded:  []string{"postgres", "api", "web"},
+		})
+	})
+
+	t.Run("releases wait for releases, not for whole helmfiles", func(t *testing.T) {
+		check(t, testcase{
+			fileOrDir: "/path/to/helmfile.d",
+			files: map[string]string{
+				"/path/to/helmfile.d/10-app.yaml": `
+releases:
+- name: cache
+  chart: incubator/raw
+  namespace: app
+- name: api
+  chart: incubator/raw
+  namespace: app
+  needs:
+  - db/postgres
+`,
+				"/path/to/helmfile.d/20-db.yaml": `
+releases:
+- name: postgres
+  chart: incubator/raw
+  namespace: db
+  needs:
+  - app/cache
+`,
+			},
+			upgraded: []string{"cache", "postgres", "api"},
+		})
+	})
+
+	t.Run("a failed release skips the releases of other helmfiles needing it", func(t *testing.T) {
+		check(t, testcase{
+			fileOrDir: "/path/to/helmfile.d",
+			files: map[string]string{
+				"/path/to/helmfile.d/10-app.yaml": `
+releases:
+- name: api
+  chart: incubator/raw
+  namespace: app
+  needs:
+  - db/postgres-error
+- name: worker
+  chart: incubator/raw
+  namespace: app
+`,
+				"/path/to/helmfile.d/20-db.yaml": `
+releases:
+- name: postgres-error
+  chart: incubator/raw
+  namespace: db
+`,
+			},
+			error:    "failed processing release postgres-error: error",
+			upgraded: []string{"worker"},
+		})
+	})
+
+	t.Run("a helmfile failing before its releases are processed skips the releases of other helmfiles waiting on it", func(t *testing.T) {
+		check(t, testcase{
+			fileOrDir: "/path/to/helmfile.d",
+			files: map[string]string{
+				"/path/to/helmfile.d/10-app.yaml": `
+releases:
+- name: api
+  chart: incubator/raw
+  namespace: app
+  needs:
+  - db/postgres
+- name: worker
+  chart: incubator/raw
+  namespace: app
+`,
+				"/path/to/helmfile.d/20-db.yaml": `
+releases:
+- name: postgres
+  chart: incubator/raw
+  namespace: db
+  values:
+  - missing.yaml
+`,
+			},
+			error:    `failed processing release postgres: values file matching "/path/to/helmfile.d/missing.yaml" does not exist in "/path/to/helmfile.d"`,
+			upgraded: []string{"worker"},
+		})
+	})
+
+	t.Run("sequential helmfiles are sorted by needs", func(t *testing.T) {
+		check(t, testcase{
+			fileOrDir:  "/path/to/helmfile.d",
+			files:      helmfileD,
+			sequential: true,
+			upgraded:   []string{"postgres", "api", "web"},
+		})
+	})
+
+	t.Run("destroy waits for the helmfiles needing the deleted releases", func(t *testing.T) {
+		check(t, testcase{
+			fileOrDir: "/path/to/helmfile.d",
+			files:     helmfileD,
+			destroy:   true,
+			lists: map[exectest.ListKey]string{
+				{Filter: "^api$", Flags: listFlags("app", "default")}: `NAME	REVISION	UPDATED                 	STATUS  	CHART        	APP VERSION	NAMESPACE
+api 	4       	Fri Nov  1 08:40:07 2019	DEPLOYED	raw-3.1.0	3.1.0      	app
+`,
+				{Filter: "^web$", Flags: listFlags("app", "default")}: `NAME	REVISION	UPDATED                 	STATUS  	CHART        	APP VERSION	NAMESPACE
+web 	4       	Fri Nov  1 08:40:07 2019	DEPLOYED	raw-3.1.0	3.1.0      	app
+`,
+				{Filter: "^postgres$", Flags: listFlags("db", "default")}: `NAME	REVISION	UPDATED                 	STATUS  	CHART        	APP VERSION	NAMESPACE
+postgres 	4       	Fri Nov  1 08:40:07 2019	DEPLOYED	raw-3.1.0	3.1.0      	db
+`,
+			},
+			deleted: []string{"web", "api", "postgres"},
+		})
+	})
+
+	t.Run("cycle across helmfiles", func(t *testing.T) {
+		check(t, testcase{
+			fileOrDir: "/path/to/helmfile.d",
+			files: map[string]string{
+				"/path/to/helmfile.d/10-app.yaml": `
+releases:
+- name: api
+  chart: incubator/raw
+  namespace: app
+  needs:
+  - db/postgres
+`,
+				"/path/to/helmfile.d/20-db.yaml": `
+releases:
+- name: postgres
+  chart: incubator/raw
+  namespace: db
+  needs:
+  - app/api
+`,
+			},
+			error: `needs across helmfiles form a cycle: "default/app/api" in helmfile.d/10-app.yaml -> "default/db/postgres" in helmfile.d/20-db.yaml -> "default/app/api" in helmfile.d/10-app.yaml`,
+		})
+	})
+
+	t.Run("parent helmfile needs a release of its sub-helmfile", func(t *testing.T) {
+		check(t, testcase{
+			fileOrDir: "/path/to/helmfile.yaml",
+			files: map[string]string{
+				"/path/to/helmfile.yaml": `
+helmfiles:
+- db.yaml
+releases:
+- name: api
+  chart: incubator/raw
+  namespace: app
+  needs:
+  - db/postgres
+`,
+				"/path/to/db.yaml": `
+releases:
+- name: postgres
+  chart: incubator/raw
+  namespace: db
+`,
+			},
+			upgraded: []string{"postgres", "api"},
+		})
+	})
+
+	t.Run("sub-helmfile needs a release of its parent helmfile", func(t *testing.T) {
+		check(t, testcase{
+			fileOrDir: "/path/to/helmfile.yaml",
+			files: map[string]string{
+				"/path/to/helmfile.yaml": `
+helmfiles:
+- app.yaml
+releases:
+- name: postgres
+  chart: incubator/raw
+  namespace: db
+`,
+				"/path/to/app.yaml": `
+releases:
+- name: api
+  chart: incubator/raw
+  namespace: app
+  needs:
+  - db/postgres
+`,
+			},
+			error: `in /path/to/helmfile.yaml: in .helmfiles[0]: in /path/to/app.yaml: needs across helmfiles can't be satisfied in the order the helmfiles are processed: release "default/app/api" of app.yaml waits for release "default/db/postgres" of helmfile.yaml. ` +
+				`Sub-helmfiles are processed before their parent helmfile, or after it when releases are deleted, and --sequential-helmfiles processes the helmfiles of a directory one by one in order`,
+		})
+	})
+}
@@ -0,0 +1,164 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/state"
+)
+
+// chartVersionRegexp splits the chart column of `helm list`, like `nginx-1.2.3`, into the chart name and version
+var chartVersionRegexp = regexp.MustCompile(`^(.+?)-(v?\d+\.\d+\.\d+\S*)$`)
+
+// liveReleases reconciles the releases of the helmfiles with the ones installed in the cluster, for `list --live`.
+// The installed releases are listed once per kube context and namespace, across all the helmfiles.
+type liveReleases struct {
+	mu sync.Mutex
+	// installed are the releases installed in each scope
+	installed map[state.LiveReleaseScope][]helmexec.ReleaseInfo
+	// defined are the releases defined in the helmfiles, before filtering them by selectors
+	defined map[state.LiveReleaseScope]map[string]bool
+}
+
+func newLiveReleases() *liveReleases {
+	return &liveReleases{
+		installed: map[state.LiveReleaseScope][]helmexec.ReleaseInfo{},
+		defined:   map[state.LiveReleaseScope]map[string]bool{},
+	}
+}
+
+func (l *liveReleases) list(run *Run, scope state.LiveReleaseScope) ([]helmexec.ReleaseInfo, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if installed, ok := l.installed[scope]; ok {
+		return installed, nil
+	}
+	installed, err := run.state.ListLiveReleases(run.helm, scope)
+	if err != nil {
+		return nil, fmt.Errorf("listing the releases installed in %s: %w", formatLiveReleaseScope(scope), err)
+	}
+	l.installed[scope] = installed
+	return installed, nil
+}
+
+// reconcile sets the live fields of row from the installed release r is deployed as, if any.
+func (l *liveReleases) reconcile(run *Run, r *state.ReleaseSpec, row *HelmRelease) error {
+	installed, err := l.list(run, run.state.LiveReleaseScope(r))
+	if err != nil {
+		return err
+	}
+
+	var live *helmexec.ReleaseInfo
+	for i := range installed {
+		if installed[i].Name == r.Name {
+			live = &installed[i]
+			break
+		}
+	}
+
+	if live == nil {
+		row.Status = "not installed"
+		row.Diverged = r.Desired()
+		return nil
+	}
+
+	_, row.DeployedVersion = splitChartVersion(live.Chart)
+	row.Revision = live.Revision
+	row.Status = live.Status
+	row.Updated = live.Updated
+	row.Diverged = !r.Desired() || !versionSatisfies(row.DeployedVersion, r.Version)
+	return nil
+}
+
+// define records the releases of st, including the ones filtered out by selectors, not to report them as orphans.
+func (l *liveReleases) define(st *state.HelmState) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, r := range st.OrginReleases {
+		st.ApplyOverrides(&r)
+		scope := st.LiveReleaseScope(&r)
+		if l.defined[scope] == nil {
+			l.defined[scope] = map[string]bool{}
+		}
+		l.defined[scope][r.Name] = true
+	}
+}
+
+// orphans returns the releases installed in the scopes listed so far that aren't defined in any helmfile.
+func (l *liveReleases) orphans() []*HelmRelease {
+	var orphans []*HelmRelease
+	for scope, installed := range l.installed {
+		for _, live := range installed {
+			if l.defined[scope][live.Name] {
+				continue
+			}
+			chart, version := splitChartVersion(live.Chart)
+			orphans = append(orphans, &HelmRelease{
+				Name:            live.Name,
+				Namespace:       live.Namespace,
+				Chart:           chart,
+				DeployedVersion: version,
+				Revision:        live.Revision,
+				Status:          live.Status,
+				Updated:         live.Updated,
+				Orphan:          true,
+			})
+		}
+	}
+	sort.Slice(orphans, func(i, j int) bool {
+		if orphans[i].Namespace != orphans[j].Namespace {
+			return orphans[i].Namespace < orphans[j].Namespace
+		}
+		return orphans[i].Name < orphans[j].Name
+	})
+	return orphans
+}
+
+func formatLiveReleaseScope(scope state.LiveReleaseScope) string {
+	ns := scope.Namespace
+	if ns == "" {
+		ns = "the default namespace"
+	} else {
+		ns = fmt.Sprintf("namespace %q", ns)
+	}
+	if scope.KubeContext == "" {
+		return ns
+	}
+	return fmt.Sprintf("%s of kube context %q", ns, scope.KubeContext)
+}
+
+// splitChartVersion splits the chart column of `helm list` into the chart name and version.
+func splitChartVersion(chart string) (string, string) {
+	m := chartVersionRegexp.FindStringSubmatch(chart)
+	if m == nil {
+		return chart, ""
+	}
+	return m[1], m[2]
+}
+
+// versionSatisfies returns whether the deployed chart version is the desired one, which can be a semver constraint.
+// Any version is the desired one when the release doesn't pin its version.
+func versionSatisfies(deployed, desired string) bool {
+	if desired == "" {
+		return true
+	}
+	if deployed == desired {
+		return true
+	}
+	c, err := semver.NewConstraint(desired)
+	if err != nil {
+		return false
+	}
+	v, err := semver.NewVersion(deployed)
+	if err != nil {
+		return false
+	}
+	return c.Check(v)
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/helmfile/helmfile/pkg/tmpl"
 	"github.com/helmfile/helmfile/pkg/yaml"
 )
 
@@ -18,6 +19,10 @@ type LoadOpts struct {
 
 	Filter bool
 
+	// NeedsAcrossHelmfiles orders releases by their needs on releases of the
+	// other helmfiles loaded by the command, which are all loaded upfront for that.
+	NeedsAcrossHelmfiles bool
+
 	// Inherited carries parent-helmfile config that this sub-helmfile opts into
 	// via `inherits:`. See state.InheritedConfig and state.MergeInherited.
 	Inherited *state.InheritedConfig
@@ -27,6 +32,13 @@ type LoadOpts struct {
 	// inherits:). It is used by the "did you mean inherits: [repositories]?"
 	// footgun warning (state.WarnUninheritedRepos).
 	ParentRepoNames []string `yaml:"parentRepoNames,omitempty"`
+
+	// Sandbox restricts the template functions of the helmfile being loaded.
+	// Its Dir is set to the directory of the helmfile when empty.
+	Sandbox *tmpl.Sandbox `yaml:"-"`
+
+	// RenderTrace receives the render traces of the helmfile templates, if set.
+	RenderTrace *RenderTrace `yaml:"-"`
 }
 
 func (o LoadOpts) DeepCopy() LoadOpts {
@@ -64,5 +76,9 @@ func (o LoadOpts) DeepCopy() LoadOpts {
 		new.Inherited.Env = &e
 	}
 
+	// Sandboxes are never modified once created, so sharing is fine.
+	new.Sandbox = o.Sandbox
+	new.RenderTrace = o.RenderTrace
+
 	return new
 }
@@ -0,0 +1,60 @@
+package app
+
+import (
+	"sync"
+	"time"
+
+	"github.com/helmfile/helmfile/pkg/audit"
+	"github.com/helmfile/helmfile/pkg/metrics"
+)
+
+// runMetrics collects the records of the runs of the releases of each helmfile
+// of a sync, apply or destroy command, for the metrics of the command.
+type runMetrics struct {
+	output string
+	start  time.Time
+
+	mu      sync.Mutex
+	records []audit.Record
+}
+
+func (m *runMetrics) add(r audit.Record) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, r)
+}
+
+// startMetrics starts collecting the metrics of the command, when c sets where they are exported to.
+func (a *App) startMetrics(c metricsConfig) {
+	if c.MetricsOutput() == "" {
+		return
+	}
+	a.metrics = &runMetrics{output: c.MetricsOutput(), start: time.Now()}
+}
+
+// writeMetrics exports the metrics of command, which failed with err when it's not nil.
+// Failing to export them is only a warning, not to fail the releases that were deployed.
+func (a *App) writeMetrics(command string, err error) {
+	m := a.metrics
+	if m == nil {
+		return
+	}
+	a.metrics = nil
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run := metrics.Run{
+		Command:     command,
+		Environment: a.Env,
+		Start:       m.start,
+		Duration:    time.Since(m.start),
+		Records:     m.records,
+		Err:         err,
+	}
+	if err := metrics.Write(m.output, run); err != nil {
+		a.Logger.Warnf("warn: failed to export the metrics of the %s run: %v", command, err)
+	}
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/helmfile/vals"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/helmfile/helmfile/pkg/exectest"
+	ffs "github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+)
+
+func TestMetricsOutput(t *testing.T) {
+	files := map[string]string{
+		"/path/to/helmfile.yaml": `
+releases:
+- name: web
+  namespace: app
+  chart: stable/web
+- name: old
+  namespace: app
+  chart: stable/old
+  installed: false
+`,
+	}
+
+	valsRuntime, err := vals.New(vals.Options{CacheSize: 32})
+	require.NoError(t, err)
+
+	app := appWithFs(&App{
+		OverrideHelmBinary:              DefaultHelmBinary,
+		fs:                              ffs.DefaultFileSystem(),
+		OverrideKubeContext:             "default",
+		DisableKubeVersionAutoDetection: true,
+		Env:                             "default",
+		Logger:                          newAppTestLogger(),
+		helms: map[helmKey]helmexec.Interface{
+			createHelmKey(DefaultHelmBinary, "default"): &exectest.Helm{
+				DiffMutex:     &sync.Mutex{},
+				ChartsMutex:   &sync.Mutex{},
+				ReleasesMutex: &sync.Mutex{},
+			},
+		},
+		valsRuntime: valsRuntime,
+	}, files)
+
+	output := filepath.Join(t.TempDir(), "helmfile.prom")
+	require.NoError(t, app.Sync(applyConfig{concurrency: 1, skipNeeds: true, metricsOutput: output, logger: app.Logger}))
+
+	bs, err := os.ReadFile(output)
+	require.NoError(t, err)
+	out := string(bs)
+
+	assert.Contains(t, out, `helmfile_run_success{command="sync",environment="default"} 1`)
+	assert.Contains(t, out, `helmfile_releases{command="sync",environment="default",result="upgraded"} 1`)
+	assert.Contains(t, out, `helmfile_releases{command="sync",environment="default",result="deleted"} 1`)
+	assert.Contains(t, out, `helmfile_release_duration_seconds{chart="stable/web",command="sync",environment="default",helmfile="helmfile.yaml",kube_context="default",namespace="app",release="web",result="upgraded"}`)
+	assert.Nil(t, app.metrics)
+}
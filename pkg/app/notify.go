@@ -0,0 +1,18 @@
+package app
+
+import (
+	"github.com/helmfile/helmfile/pkg/notify"
+)
+
+// notify sends e to the notifications of the helmfile of r that want it.
+// Delivery failures are only warnings, not to fail the run for an unavailable endpoint.
+func (a *App) notify(r *Run, e notify.Event) {
+	for i, c := range r.state.Notifications {
+		if !c.Wants(e.Type) {
+			continue
+		}
+		if err := notify.Send(c, e); err != nil {
+			a.Logger.Warnf("warn: failed to send the %s notification to notifications[%d]: %v", e.Type, i, err)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/helmfile/vals"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/helmfile/helmfile/pkg/exectest"
+	ffs "github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/state"
+)
+
+func TestNotifications(t *testing.T) {
+	var mu sync.Mutex
+	var events []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bs, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		var event map[string]any
+		require.NoError(t, json.Unmarshal(bs, &event))
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+
+	files := map[string]string{
+		"/path/to/helmfile.yaml": `
+notifications:
+- url: ` + server.URL + `
+  messages:
+    complete: "{{ len .Upgraded }} upgraded"
+- format: slack
+  url: ` + unavailable.URL + `
+  events: [complete]
+releases:
+- name: web
+  namespace: app
+  chart: stable/web
+`,
+	}
+
+	valsRuntime, err := vals.New(vals.Options{CacheSize: 32})
+	require.NoError(t, err)
+
+	logs := runWithLogCapture(t, "warn", func(t *testing.T, logger *zap.SugaredLogger) {
+		t.Helper()
+
+		app := appWithFs(&App{
+			OverrideHelmBinary:              DefaultHelmBinary,
+			fs:                              ffs.DefaultFileSystem(),
+			OverrideKubeContext:             "default",
+			DisableKubeVersionAutoDetection: true,
+			Env:                             "default",
+			Logger:                          logger,
+			helms: map[helmKey]helmexec.Interface{
+				createHelmKey(DefaultHelmBinary, "default"): &exectest.Helm{
+					DiffMutex:     &sync.Mutex{},
+					ChartsMutex:   &sync.Mutex{},
+					ReleasesMutex: &sync.Mutex{},
+				},
+			},
+			valsRuntime: valsRuntime,
+		}, files)
+
+		require.NoError(t, app.Sync(applyConfig{concurrency: 1, skipNeeds: true, logger: logger}))
+	})
+
+	require.Len(t, events, 2)
+	assert.Equal(t, "start", events[0]["event"])
+	assert.Equal(t, "sync", events[0]["command"])
+	assert.Equal(t, "helmfile sync of helmfile.yaml started in environment default by "+events[0]["user"].(string), events[0]["message"])
+	assert.Equal(t, "complete", events[1]["event"])
+	assert.Equal(t, true, events[1]["succeeded"])
+	assert.Equal(t, "1 upgraded", events[1]["message"])
+	assert.Equal(t, "web", events[1]["upgraded"].([]any)[0].(map[string]any)["name"])
+
+	assert.Contains(t, logs.String(), "failed to send the complete notification to notifications[1]: unexpected status 503 Service Unavailable")
+}
+
+// Failed releases are notified as soon as they fail, not once the run completes.
+func TestNotifications_ReleaseFailed(t *testing.T) {
+	var mu sync.Mutex
+	var events []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	files := map[string]string{
+		"/path/to/helmfile.yaml": `
+notifications:
+- url: ` + server.URL + `
+  events: [releaseFailed, complete]
+releases:
+- name: web
+  namespace: app
+  chart: stable/web
+- name: api
+  namespace: app
+  chart: stable/api
+`,
+	}
+
+	app := appWithFs(&App{
+		OverrideHelmBinary:              DefaultHelmBinary,
+		fs:                              ffs.DefaultFileSystem(),
+		OverrideKubeContext:             "default",
+		DisableKubeVersionAutoDetection: true,
+		Env:                             "default",
+		Logger:                          newAppTestLogger(),
+		helms: map[helmKey]helmexec.Interface{
+			createHelmKey(DefaultHelmBinary, "default"): &exectest.Helm{},
+		},
+	}, files)
+
+	var run *Run
+	require.NoError(t, app.ForEachState(func(r *Run) (bool, []error) {
+		run = r
+		return true, nil
+	}, false))
+
+	affected := &state.AffectedReleases{}
+	rec := app.startRun(run, "sync")
+	converge := rec.notifyFailures(affected, func(st *state.HelmState, helm helmexec.Interface) (bool, []error) {
+		affected.Failed = append(affected.Failed, &st.Releases[0])
+		return true, []error{errors.New("timed out waiting for the condition")}
+	})
+
+	_, errs := converge(&state.HelmState{ReleaseSetSpec: state.ReleaseSetSpec{Releases: run.state.Releases[:1]}}, nil)
+	require.Len(t, errs, 1)
+	require.Len(t, events, 1)
+	assert.Equal(t, "releaseFailed", events[0]["event"])
+	assert.Equal(t, "web", events[0]["release"].(map[string]any)["name"])
+
+	// The failures already notified aren't notified again along with the next ones
+	_, _ = converge(&state.HelmState{ReleaseSetSpec: state.ReleaseSetSpec{Releases: run.state.Releases[1:]}}, nil)
+	require.Len(t, events, 2)
+	assert.Equal(t, "api", events[1]["release"].(map[string]any)["name"])
+
+	rec.finish(affected, nil)
+	require.Len(t, events, 3)
+	assert.Equal(t, "complete", events[2]["event"])
+	assert.Equal(t, false, events[2]["succeeded"])
+}
+
+func TestNotifications_Invalid(t *testing.T) {
+	files := map[string]string{
+		"/path/to/helmfile.yaml": `
+notifications:
+- url: https://hooks.slack.com/services/T000/B000/XXXX
+  format: slack
+  events: [compelte]
+releases:
+- name: web
+  chart: stable/web
+`,
+	}
+
+	app := appWithFs(&App{
+		OverrideHelmBinary:              DefaultHelmBinary,
+		fs:                              ffs.DefaultFileSystem(),
+		OverrideKubeContext:             "default",
+		DisableKubeVersionAutoDetection: true,
+		Env:                             "default",
+		Logger:                          newAppTestLogger(),
+		helms: map[helmKey]helmexec.Interface{
+			createHelmKey(DefaultHelmBinary, "default"): &exectest.Helm{},
+		},
+	}, files)
+
+	err := app.ForEachState(func(r *Run) (bool, []error) {
+		return true, nil
+	}, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `notifications[0]: unknown notification event "compelte"`)
+}
@@ -0,0 +1,176 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gosuri/uitable"
+
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/state"
+)
+
+// pruneTarget is a kube context the releases of a helmfile project and environment are installed in.
+type pruneTarget struct {
+	Project     string
+	Environment string
+	KubeContext string
+}
+
+// prunableRelease is a release owned by a helmfile project that no helmfile of the project declares anymore.
+type prunableRelease struct {
+	target    pruneTarget
+	run       *Run
+	installed helmexec.ReleaseInfo
+}
+
+// pruner collects the releases the helmfiles declare, to find the releases their projects installed but no longer declare.
+type pruner struct {
+	mu sync.Mutex
+	// runs are the helmfiles to list and delete the releases of each target with
+	runs map[pruneTarget]*Run
+	// declared are the releases of each target, keyed by `namespace/name`. The empty namespace matches any namespace.
+	declared map[pruneTarget]map[string]bool
+}
+
+func newPruner() *pruner {
+	return &pruner{
+		runs:     map[pruneTarget]*Run{},
+		declared: map[pruneTarget]map[string]bool{},
+	}
+}
+
+// add records the releases run declares, including the ones filtered out by selectors.
+func (p *pruner) add(run *Run) {
+	st := run.state
+	if st.HelmDefaults.Project == "" {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// target applies the overrides, like --namespace, to r and returns the target of r
+	target := func(r *state.ReleaseSpec) pruneTarget {
+		st.ApplyOverrides(r)
+		return pruneTarget{
+			Project:     st.HelmDefaults.Project,
+			Environment: st.Env.Name,
+			KubeContext: st.LiveReleaseScope(r).KubeContext,
+		}
+	}
+
+	// The default kube context is listed even when no release is left in the helmfile
+	targets := []pruneTarget{target(&state.ReleaseSpec{})}
+	for _, r := range st.OrginReleases {
+		t := target(&r)
+		targets = append(targets, t)
+		if p.declared[t] == nil {
+			p.declared[t] = map[string]bool{}
+		}
+		p.declared[t][r.Namespace+"/"+r.Name] = true
+	}
+	for _, t := range targets {
+		if _, ok := p.runs[t]; !ok {
+			p.runs[t] = run
+		}
+	}
+}
+
+func (p *pruner) isDeclared(t pruneTarget, r helmexec.ReleaseInfo) bool {
+	return p.declared[t][r.Namespace+"/"+r.Name] || p.declared[t]["/"+r.Name]
+}
+
+// prunable lists the releases installed in each target, and returns the ones no helmfile declares.
+func (p *pruner) prunable() ([]prunableRelease, error) {
+	targets := make([]pruneTarget, 0, len(p.runs))
+	for t := range p.runs {
+		targets = append(targets, t)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return fmt.Sprint(targets[i]) < fmt.Sprint(targets[j])
+	})
+
+	var prunable []prunableRelease
+	for _, t := range targets {
+		run := p.runs[t]
+		installed, err := run.state.ListOwnedReleases(run.helm, t.KubeContext)
+		if err != nil {
+			return nil, fmt.Errorf("listing the releases of project %q and environment %q: %w", t.Project, t.Environment, err)
+		}
+		for _, r := range installed {
+			if !p.isDeclared(t, r) {
+				prunable = append(prunable, prunableRelease{target: t, run: run, installed: r})
+			}
+		}
+	}
+	return prunable, nil
+}
+
+// Prune deletes the releases installed by the helmfile projects that no helmfile declares anymore.
+func (a *App) Prune(c PruneConfigProvider) error {
+	return a.prune(c, AskForConfirmation)
+}
+
+func (a *App) prune(c PruneConfigProvider, ask func(string) bool) error {
+	p := newPruner()
+	err := a.ForEachState(func(run *Run) (bool, []error) {
+		p.add(run)
+		return false, nil
+	}, false)
+	// Prune doesn't need releases, as it's looking for the ones removed from the helmfiles
+	if _, ok := err.(*NoMatchingHelmfileError); !ok && err != nil {
+		return err
+	}
+
+	if len(p.runs) == 0 {
+		return errors.New("no helmfile sets helmDefaults.project, which identifies the releases to prune")
+	}
+
+	prunable, err := p.prunable()
+	if err != nil {
+		return err
+	}
+	if len(prunable) == 0 {
+		c.Logger().Infof("No releases to prune")
+		return nil
+	}
+
+	table := uitable.New()
+	table.AddRow("NAME", "NAMESPACE", "KUBECONTEXT", "CHART", "STATUS", "UPDATED", "PROJECT", "ENVIRONMENT")
+	names := make([]string, len(prunable))
+	for i, r := range prunable {
+		table.AddRow(r.installed.Name, r.installed.Namespace, r.target.KubeContext, r.installed.Chart, r.installed.Status, r.installed.Updated, r.target.Project, r.target.Environment)
+		names[i] = fmt.Sprintf("  %s (%s)", r.installed.Name, r.installed.Namespace)
+	}
+	fmt.Println(trimTrailingWhitespace(table.String()))
+
+	if !c.Confirm() {
+		c.Logger().Infof("The releases above are no longer declared in any helmfile. Run again with --confirm to delete them")
+		return nil
+	}
+
+	msg := fmt.Sprintf(`Releases to prune are:
+%s
+
+Do you really want to delete?
+  Helmfile will delete the releases above, which are no longer declared in any helmfile.
+
+`, strings.Join(names, "\n"))
+	if c.Interactive() && !ask(msg) {
+		return nil
+	}
+
+	var errs []error
+	for _, r := range prunable {
+		if err := r.run.state.DeleteOwnedRelease(r.run.helm, r.target.KubeContext, r.installed); err != nil {
+			errs = append(errs, fmt.Errorf("deleting release %q in namespace %q: %w", r.installed.Name, r.installed.Namespace, err))
+			continue
+		}
+		c.Logger().Infof("Deleted release %q in namespace %q", r.installed.Name, r.installed.Namespace)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,159 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/helmfile/helmfile/pkg/exectest"
+	ffs "github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/testutil"
+)
+
+type pruneConfig struct {
+	confirm     bool
+	interactive bool
+	logger      *zap.SugaredLogger
+}
+
+func (c pruneConfig) Confirm() bool {
+	return c.confirm
+}
+
+func (c pruneConfig) Interactive() bool {
+	return c.interactive
+}
+
+func (c pruneConfig) Logger() *zap.SugaredLogger {
+	return c.logger
+}
+
+func TestPrune(t *testing.T) {
+	files := map[string]string{
+		"/path/to/helmfile.d/a.yaml": `
+helmDefaults:
+  project: platform
+releases:
+- name: web
+  namespace: app
+  chart: stable/web
+- name: api
+  chart: stable/api
+`,
+		"/path/to/helmfile.d/b.yaml": `
+helmDefaults:
+  project: platform
+releases:
+- name: db
+  namespace: db
+  chart: stable/postgres
+  installed: false
+`,
+	}
+
+	const ownedFlags = "--kube-context default --all-namespaces --all --max 0 --selector helmfile.sh/environment=default,helmfile.sh/project=platform"
+
+	var namespace string
+	installed := []helmexec.ReleaseInfo{
+		{Name: "web", Namespace: "app", Status: "deployed", Chart: "web-1.0.0"},
+		{Name: "api", Namespace: "backend", Status: "deployed", Chart: "api-1.0.0"},
+		{Name: "db", Namespace: "db", Status: "deployed", Chart: "postgres-12.0.0"},
+		{Name: "old-web", Namespace: "app", Status: "failed", Chart: "web-0.9.0", Updated: "2022-01-01 10:00:00 +0000 UTC"},
+	}
+
+	run := func(t *testing.T, c pruneConfig, confirm bool) (*exectest.Helm, string, error) {
+		t.Helper()
+
+		helm := &exectest.Helm{
+			LiveReleases: map[string][]helmexec.ReleaseInfo{
+				ownedFlags: installed,
+			},
+		}
+		app := appWithFs(&App{
+			OverrideHelmBinary:              DefaultHelmBinary,
+			fs:                              ffs.DefaultFileSystem(),
+			OverrideKubeContext:             "default",
+			Namespace:                       namespace,
+			DisableKubeVersionAutoDetection: true,
+			Env:                             "default",
+			Logger:                          newAppTestLogger(),
+			helms: map[helmKey]helmexec.Interface{
+				createHelmKey(DefaultHelmBinary, "default"): helm,
+			},
+		}, files)
+
+		c.logger = app.Logger
+		var pruneErr error
+		out, err := testutil.CaptureStdout(func() {
+			pruneErr = app.prune(c, func(string) bool { return confirm })
+		})
+		require.NoError(t, err)
+		return helm, out, pruneErr
+	}
+
+	expectedOut := `NAME   	NAMESPACE	KUBECONTEXT	CHART    	STATUS	UPDATED                      	PROJECT 	ENVIRONMENT
+old-web	app      	default    	web-0.9.0	failed	2022-01-01 10:00:00 +0000 UTC	platform	default
+`
+
+	t.Run("without confirm", func(t *testing.T) {
+		helm, out, err := run(t, pruneConfig{}, true)
+		require.NoError(t, err)
+		assert.Equal(t, expectedOut, out)
+		assert.Empty(t, helm.Deleted)
+	})
+
+	t.Run("with confirm", func(t *testing.T) {
+		helm, out, err := run(t, pruneConfig{confirm: true}, false)
+		require.NoError(t, err)
+		assert.Equal(t, expectedOut, out)
+		assert.Equal(t, []exectest.Release{
+			{Name: "old-web", Flags: []string{"--kube-context", "default", "--namespace", "app"}},
+		}, helm.Deleted)
+	})
+
+	t.Run("interactive and declined", func(t *testing.T) {
+		helm, _, err := run(t, pruneConfig{confirm: true, interactive: true}, false)
+		require.NoError(t, err)
+		assert.Empty(t, helm.Deleted)
+	})
+
+	t.Run("interactive and accepted", func(t *testing.T) {
+		helm, _, err := run(t, pruneConfig{confirm: true, interactive: true}, true)
+		require.NoError(t, err)
+		assert.Len(t, helm.Deleted, 1)
+	})
+
+	t.Run("with --namespace", func(t *testing.T) {
+		namespace = "staging"
+		installed = []helmexec.ReleaseInfo{
+			{Name: "web", Namespace: "staging", Status: "deployed", Chart: "web-1.0.0"},
+			{Name: "api", Namespace: "staging", Status: "deployed", Chart: "api-1.0.0"},
+			{Name: "db", Namespace: "staging", Status: "deployed", Chart: "postgres-12.0.0"},
+			{Name: "web", Namespace: "app", Status: "deployed", Chart: "web-1.0.0"},
+		}
+		defer func() {
+			namespace = ""
+		}()
+
+		helm, _, err := run(t, pruneConfig{confirm: true}, true)
+		require.NoError(t, err)
+		assert.Equal(t, []exectest.Release{
+			{Name: "web", Flags: []string{"--kube-context", "default", "--namespace", "app"}},
+		}, helm.Deleted)
+	})
+
+	t.Run("without project", func(t *testing.T) {
+		files = map[string]string{
+			"/path/to/helmfile.yaml": `
+releases:
+- name: web
+  chart: stable/web
+`,
+		}
+		_, _, err := run(t, pruneConfig{confirm: true}, true)
+		require.EqualError(t, err, "no helmfile sets helmDefaults.project, which identifies the releases to prune")
+	})
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/helmfile/helmfile/pkg/tmpl"
+)
+
+// renderTraceErrorContext is the number of lines printed before and after the line a rendering failed at.
+const renderTraceErrorContext = 3
+
+// RenderTrace writes the traces of the rendered helmfile templates, for `helmfile build --render-trace`.
+type RenderTrace struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewRenderTrace returns a RenderTrace writing to w.
+func NewRenderTrace(w io.Writer) *RenderTrace {
+	return &RenderTrace{w: w}
+}
+
+// writeRendered writes the rendered lines of a part of file along with the template lines they were rendered from.
+// includeChain is the helmfiles file was included from, through bases.
+func (t *RenderTrace) writeRendered(includeChain []string, file string, trace *tmpl.RenderTrace) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Render trace: %s\n", file)
+	writeIncludeChain(&b, includeChain)
+
+	width := 0
+	for _, l := range trace.Lines {
+		width = max(width, len(l.Source.String()))
+	}
+	for _, l := range trace.Lines {
+		fmt.Fprintf(&b, "%-*s | %s\n", width, l.Source, l.Text)
+	}
+
+	t.write(b.String())
+}
+
+// writeError writes the lines of content around the line the rendering of file failed at.
+// content is the part of file that was rendered, starting at firstLine.
+func (t *RenderTrace) writeError(includeChain []string, file string, content []byte, firstLine int, trace *tmpl.RenderTrace, err error) {
+	var b strings.Builder
+	if trace != nil && trace.ErrorLine > 0 {
+		fmt.Fprintf(&b, "# Render error: %s:%d\n", file, trace.ErrorLine)
+	} else {
+		fmt.Fprintf(&b, "# Render error: %s\n", file)
+	}
+	writeIncludeChain(&b, includeChain)
+
+	if trace != nil && trace.ErrorLine > 0 {
+		lines := strings.Split(string(content), "\n")
+		lastLine := firstLine + len(lines) - 1
+		from := max(firstLine, trace.ErrorLine-renderTraceErrorContext)
+		to := min(lastLine, trace.ErrorLine+renderTraceErrorContext)
+		width := len(fmt.Sprint(to))
+		for n := from; n <= to; n++ {
+			marker := " "
+			if n == trace.ErrorLine {
+				marker = ">"
+			}
+			fmt.Fprintf(&b, "%s %*d | %s\n", marker, width, n, lines[n-firstLine])
+		}
+	}
+	fmt.Fprintf(&b, "# %v\n", err)
+
+	t.write(b.String())
+}
+
+func writeIncludeChain(b *strings.Builder, includeChain []string) {
+	if len(includeChain) > 0 {
+		fmt.Fprintf(b, "# Included from: %s\n", strings.Join(includeChain, " -> "))
+	}
+}
+
+func (t *RenderTrace) write(s string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, _ = io.WriteString(t.w, s)
+}
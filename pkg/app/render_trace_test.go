@@ -0,0 +1,112 @@
+package app
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/helmfile/helmfile/pkg/testhelper"
+)
+
+func TestRenderTrace(t *testing.T) {
+	run := func(t *testing.T, files map[string]string) (string, error) {
+		t.Helper()
+
+		app := injectFs(&App{
+			OverrideHelmBinary:              DefaultHelmBinary,
+			OverrideKubeContext:             "default",
+			DisableKubeVersionAutoDetection: true,
+			Env:                             "default",
+			FileOrDir:                       "helmfile.yaml.gotmpl",
+			Logger:                          newAppTestLogger(),
+		}, testhelper.NewTestFs(files))
+		expectNoCallsToHelm(app)
+
+		var out bytes.Buffer
+		noop := func(run *Run) (bool, []error) {
+			return false, []error{}
+		}
+		err := app.ForEachState(noop, false, SetFilter(true), SetRenderTrace(NewRenderTrace(&out)))
+		return out.String(), err
+	}
+
+	t.Run("parts and bases", func(t *testing.T) {
+		got, err := run(t, map[string]string{
+			"/path/to/helmfile.yaml.gotmpl": `bases:
+- base.yaml.gotmpl
+---
+releases:
+{{- range $i := list 1 2 }}
+- name: app-{{ $i }}
+  chart: stable/{{ $.Values.chart }}
+{{- end }}
+`,
+			"/path/to/base.yaml.gotmpl": `environments:
+  default:
+    values:
+    - chart: {{ "nginx" }}
+`,
+		})
+		require.NoError(t, err)
+
+		want := `# Render trace: helmfile.yaml.gotmpl
+helmfile.yaml.gotmpl:1 | bases:
+helmfile.yaml.gotmpl:2 | - base.yaml.gotmpl
+# Render trace: base.yaml.gotmpl
+# Included from: helmfile.yaml.gotmpl
+base.yaml.gotmpl:1 | environments:
+base.yaml.gotmpl:2 |   default:
+base.yaml.gotmpl:3 |     values:
+base.yaml.gotmpl:4 |     - chart: nginx
+# Render trace: helmfile.yaml.gotmpl
+helmfile.yaml.gotmpl:4 | releases:
+helmfile.yaml.gotmpl:6 | - name: app-1
+helmfile.yaml.gotmpl:7 |   chart: stable/nginx
+helmfile.yaml.gotmpl:6 | - name: app-2
+helmfile.yaml.gotmpl:7 |   chart: stable/nginx
+`
+		if d := cmp.Diff(want, got); d != "" {
+			t.Errorf("unexpected render trace: want (-), got (+):\n%s", d)
+		}
+	})
+
+	t.Run("error in a base", func(t *testing.T) {
+		got, err := run(t, map[string]string{
+			"/path/to/helmfile.yaml.gotmpl": `bases:
+- base.yaml.gotmpl
+`,
+			"/path/to/base.yaml.gotmpl": `environments:
+  default:
+    values:
+    - a: 1
+    - b: 2
+    - c: {{ required "c is required" .Values.c }}
+    - d: 4
+    - e: 5
+    - f: 6
+    - g: 7
+`,
+		})
+		require.Error(t, err)
+
+		want := `# Render trace: helmfile.yaml.gotmpl
+helmfile.yaml.gotmpl:1 | bases:
+helmfile.yaml.gotmpl:2 | - base.yaml.gotmpl
+# Render error: base.yaml.gotmpl:6
+# Included from: helmfile.yaml.gotmpl
+  3 |     values:
+  4 |     - a: 1
+  5 |     - b: 2
+> 6 |     - c: {{ required "c is required" .Values.c }}
+  7 |     - d: 4
+  8 |     - e: 5
+  9 |     - f: 6
+# template: base.yaml.gotmpl:6:44: executing "base.yaml.gotmpl" at <.Values.c>: map has no entry for key "c"
+`
+		if d := cmp.Diff(want, got); d != "" {
+			t.Errorf("unexpected render trace: want (-), got (+):\n%s", d)
+		}
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"slices"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/fatih/color"
 
@@ -35,6 +36,15 @@ func NewRun(st *state.HelmState, helm helmexec.Interface, ctx *Context) (*Run, e
 	return &Run{state: st, helm: helm, ctx: ctx}, nil
 }
 
+// external returns the run of the helmfile in the graph of the releases
+// across helmfiles, if the command orders releases across helmfiles.
+func (r *Run) external() *helmfileRun {
+	if r.ctx == nil {
+		return nil
+	}
+	return r.ctx.helmfiles.run(r.state)
+}
+
 func (r *Run) askForConfirmation(msg string) bool {
 	if r.Ask != nil {
 		return r.Ask(msg)
@@ -185,7 +195,7 @@ func (r *Run) diff(triggerCleanupEvent bool, detailedExitCode bool, c DiffConfig
 
 	// TODO Better way to detect diff on only filtered releases
 	{
-		changedReleases, planningErrs = st.DiffReleases(helm, c.Values(), c.Concurrency(), detailedExitCode, c.StripTrailingCR(), c.IncludeTests(), c.Suppress(), c.SuppressSecrets(), c.ShowSecrets(), c.NoHooks(), c.SuppressDiff(), triggerCleanupEvent, diffOpts)
+		changedReleases, planningErrs = r.diffReleases(triggerCleanupEvent, detailedExitCode, c, diffOpts)
 
 		var err error
 		deletingReleases, err = st.DetectReleasesToBeDeletedForSync(helm, st.Releases)
@@ -261,6 +271,59 @@ func (r *Run) diff(triggerCleanupEvent bool, detailedExitCode bool, c DiffConfig
 	return &infoMsg, releasesToBeUpdated, releasesToBeDeleted, nil
 }
 
+// diffReleases diffs the releases of the state, each one as soon as the releases it needs are diffed,
+// like they are synced. With --lockstep-batches, all of them are diffed at once instead.
+//
+// The releases aren't planned like for sync, as they already are the selected releases and their needs.
+// Needs on releases that aren't diffed aren't waited for.
+func (r *Run) diffReleases(triggerCleanupEvent bool, detailedExitCode bool, c DiffConfigProvider, diffOpts *state.DiffOpts) ([]state.ReleaseSpec, []error) {
+	diff := func(st *state.HelmState, helm helmexec.Interface) ([]state.ReleaseSpec, []error) {
+		return st.DiffReleases(helm, c.Values(), c.Concurrency(), detailedExitCode, c.StripTrailingCR(), c.IncludeTests(), c.Suppress(), c.SuppressSecrets(), c.ShowSecrets(), c.NoHooks(), c.SuppressDiff(), triggerCleanupEvent, diffOpts)
+	}
+
+	st := r.state
+	if c.LockstepBatches() {
+		return diff(st, r.helm)
+	}
+
+	var (
+		mu      sync.Mutex
+		changed []state.ReleaseSpec
+		// changes are the errors of the releases that have changes, which only fail the diff with --detailed-exitcode
+		changes []error
+	)
+
+	// The releases to be uninstalled aren't diffed
+	var releases []state.Release
+	for _, r := range st.Releases {
+		if r.Desired() {
+			releases = append(releases, state.Release{ReleaseSpec: r})
+		}
+	}
+	if len(releases) == 0 {
+		return nil, nil
+	}
+
+	_, errs := withStreaming("diffing", false, st, [][]state.Release{releases}, r.helm, st.Logger(), c.Concurrency(), nil, nil, func(subst *state.HelmState, helm helmexec.Interface) (bool, []error) {
+		rs, es := diff(subst, helm)
+
+		var failed []error
+		mu.Lock()
+		defer mu.Unlock()
+		changed = append(changed, rs...)
+		for _, e := range es {
+			if releaseErr, ok := e.(*state.ReleaseError); ok && releaseErr.Code == 2 {
+				changes = append(changes, e)
+				continue
+			}
+			failed = append(failed, e)
+		}
+		return len(rs) > 0, failed
+	})
+
+	return changed, append(errs, changes...)
+}
+
 func (r *Run) State() *state.HelmState {
 	return r.state
 }
@@ -0,0 +1,225 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/helmfile/helmfile/pkg/testhelper"
+)
+
+func TestSubhelmfileSandbox(t *testing.T) {
+	newApp := func() *App {
+		return &App{
+			OverrideHelmBinary:              DefaultHelmBinary,
+			OverrideKubeContext:             "default",
+			DisableKubeVersionAutoDetection: true,
+			Env:                             "default",
+			FileOrDir:                       "helmfile.yaml.gotmpl",
+			Logger:                          newAppTestLogger(),
+		}
+	}
+
+	load := func(t *testing.T, files map[string]string) (map[string]*state.HelmState, error) {
+		t.Helper()
+		app := injectFs(newApp(), testhelper.NewTestFs(files))
+		expectNoCallsToHelm(app)
+
+		states := map[string]*state.HelmState{}
+		noop := func(run *Run) (bool, []error) {
+			states[run.state.FilePath] = run.state
+			return false, []error{}
+		}
+		return states, app.ForEachState(noop, false, SetFilter(true))
+	}
+
+	t.Run("root helmfile keeps full access", func(t *testing.T) {
+		t.Setenv("TEAM_A_REGION", "eu-west-1")
+		t.Setenv("CLUSTER_TOKEN", "secret")
+
+		states, err := load(t, map[string]string{
+			"/path/to/helmfile.yaml.gotmpl": `
+helmfiles:
+- path: team-a/team-a.yaml.gotmpl
+  sandbox:
+    allowedEnv:
+    - TEAM_A_*
+releases:
+- name: {{ env "CLUSTER_TOKEN" }}
+  chart: stable/{{ readFile "team-b/chart.txt" }}
+`,
+			"/path/to/team-b/chart.txt": `mysql`,
+			"/path/to/team-a/team-a.yaml.gotmpl": `
+releases:
+- name: app-{{ env "TEAM_A_REGION" }}
+  chart: stable/{{ readFile "chart.txt" }}
+`,
+			"/path/to/team-a/chart.txt": `nginx`,
+		})
+		require.NoError(t, err)
+
+		require.Len(t, states["helmfile.yaml.gotmpl"].Releases, 1)
+		assert.Equal(t, "secret", states["helmfile.yaml.gotmpl"].Releases[0].Name)
+		assert.Equal(t, "stable/mysql", states["helmfile.yaml.gotmpl"].Releases[0].Chart)
+		require.Len(t, states["team-a.yaml.gotmpl"].Releases, 1)
+		assert.Equal(t, "app-eu-west-1", states["team-a.yaml.gotmpl"].Releases[0].Name)
+		assert.Equal(t, "stable/nginx", states["team-a.yaml.gotmpl"].Releases[0].Chart)
+	})
+
+	for _, tc := range []struct {
+		name     string
+		template string
+		error    string
+	}{
+		{
+			name:     "exec",
+			template: `{{ exec "echo" (list "foo") }}`,
+			error:    "exec is not allowed in sandboxed helmfiles",
+		},
+		{
+			name:     "readFile outside of the sub-helmfile directory",
+			template: `{{ readFile "../team-b/chart.txt" }}`,
+			error:    "../team-b/chart.txt is outside of the sandbox directory /path/to/team-a",
+		},
+		{
+			name:     "env var not in the allowlist",
+			template: `{{ env "CLUSTER_TOKEN" }}`,
+			error:    "env var `CLUSTER_TOKEN` is not allowed in the sandbox",
+		},
+		{
+			name:     "ref+file:// outside of the sub-helmfile directory",
+			template: `{{ fetchSecretValue "ref+file:///path/to/team-b/chart.txt" }}`,
+			error:    "/path/to/team-b/chart.txt is outside of the sandbox directory /path/to/team-a",
+		},
+		{
+			name:     "ref+envsubst://",
+			template: `{{ fetchSecretValue "ref+envsubst://$CLUSTER_TOKEN" }}`,
+			error:    "ref+envsubst:// is not allowed in sandboxed helmfiles",
+		},
+	} {
+		t.Run(tc.name+" is denied", func(t *testing.T) {
+			_, err := load(t, map[string]string{
+				"/path/to/helmfile.yaml.gotmpl": `
+helmfiles:
+- path: team-a/team-a.yaml.gotmpl
+  sandbox:
+    allowedEnv:
+    - TEAM_A_*
+`,
+				"/path/to/team-b/chart.txt": `mysql`,
+				"/path/to/team-a/team-a.yaml.gotmpl": `
+releases:
+- name: app
+  chart: stable/` + tc.template + `
+`,
+			})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.error)
+		})
+	}
+
+	for _, tc := range []struct {
+		name     string
+		helmfile string
+	}{
+		{
+			name: "bases",
+			helmfile: `
+bases:
+- ../team-b/base.yaml
+`,
+		},
+		{
+			name: "environment values",
+			helmfile: `
+environments:
+  default:
+    values:
+    - ../team-b/values.yaml
+`,
+		},
+		{
+			name: "environment secrets",
+			helmfile: `
+environments:
+  default:
+    secrets:
+    - ../team-b/values.yaml
+`,
+		},
+	} {
+		t.Run(tc.name+" outside of the sub-helmfile directory are denied", func(t *testing.T) {
+			_, err := load(t, map[string]string{
+				"/path/to/helmfile.yaml.gotmpl": `
+helmfiles:
+- path: team-a/team-a.yaml.gotmpl
+  sandbox: {}
+`,
+				"/path/to/team-b/base.yaml":          `releases: []`,
+				"/path/to/team-b/values.yaml":        `token: secret`,
+				"/path/to/team-a/team-a.yaml.gotmpl": tc.helmfile,
+			})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "is outside of the sandbox directory /path/to/team-a")
+		})
+	}
+
+	t.Run("nested sub-helmfiles inherit the sandbox", func(t *testing.T) {
+		_, err := load(t, map[string]string{
+			"/path/to/helmfile.yaml.gotmpl": `
+helmfiles:
+- path: team-a/team-a.yaml.gotmpl
+  sandbox: {}
+`,
+			"/path/to/team-a/team-a.yaml.gotmpl": `
+helmfiles:
+- path: apps/apps.yaml.gotmpl
+`,
+			"/path/to/team-a/apps/apps.yaml.gotmpl": `
+releases:
+- name: app
+  chart: stable/{{ exec "echo" (list "foo") }}
+`,
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "exec is not allowed in sandboxed helmfiles")
+	})
+
+	t.Run("hooks are denied", func(t *testing.T) {
+		states, err := load(t, map[string]string{
+			"/path/to/helmfile.yaml.gotmpl": `
+helmfiles:
+- path: team-a/team-a.yaml.gotmpl
+  sandbox: {}
+`,
+			"/path/to/team-a/team-a.yaml.gotmpl": `
+releases:
+- name: app
+  chart: stable/nginx
+  hooks:
+  - events: ["preapply"]
+    command: echo
+    args: ["foo"]
+`,
+		})
+		require.NoError(t, err)
+
+		st := states["team-a.yaml.gotmpl"]
+		require.NotNil(t, st.Sandbox)
+		_, err = st.TriggerPreapplyEvent(&st.Releases[0], "apply")
+		require.EqualError(t, err, "running hooks is not allowed in sandboxed helmfiles")
+	})
+
+	t.Run("sandbox without path", func(t *testing.T) {
+		_, err := load(t, map[string]string{
+			"/path/to/helmfile.yaml.gotmpl": `
+helmfiles:
+- sandbox: {}
+`,
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "found 'sandbox' definition without path")
+	})
+}
@@ -0,0 +1,18 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/helmfile/helmfile/pkg/state"
+)
+
+// PrintSchema prints the JSON schema of helmfile.yaml, for editors to validate and complete helmfiles.
+func (a *App) PrintSchema() error {
+	bs, err := json.MarshalIndent(state.JSONSchema(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal the schema to JSON: %w", err)
+	}
+	fmt.Println(string(bs))
+	return nil
+}
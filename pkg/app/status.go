@@ -0,0 +1,123 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosuri/uitable"
+
+	"github.com/helmfile/helmfile/pkg/state"
+)
+
+// ReleaseStatus is the status of a release, as printed by `status --output`.
+type ReleaseStatus struct {
+	Name         string `json:"name"`
+	KubeContext  string `json:"kubeContext"`
+	Namespace    string `json:"namespace"`
+	Revision     int    `json:"revision"`
+	Status       string `json:"status"`
+	Chart        string `json:"chart"`
+	ChartVersion string `json:"chartVersion"`
+	AppVersion   string `json:"appVersion"`
+	LastDeployed string `json:"lastDeployed"`
+	Notes        string `json:"notes"`
+}
+
+// releaseStatuses collects the statuses of the releases of all the helmfiles, to print them at once.
+type releaseStatuses struct {
+	mu       sync.Mutex
+	statuses []ReleaseStatus
+}
+
+func (s *releaseStatuses) add(statuses []state.ReleaseStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, st := range statuses {
+		status := ReleaseStatus{
+			Name:        st.Release.Name,
+			KubeContext: st.KubeContext,
+			Namespace:   st.Release.Namespace,
+			Status:      "not installed",
+			Chart:       st.Release.Chart,
+		}
+		if info := st.Info; info != nil {
+			status.Namespace = info.Namespace
+			status.Revision = info.Revision
+			status.Status = info.Info.Status
+			status.Chart = info.Chart.Metadata.Name
+			status.ChartVersion = info.Chart.Metadata.Version
+			status.AppVersion = info.Chart.Metadata.AppVersion
+			status.LastDeployed = info.Info.LastDeployed
+			status.Notes = info.Info.Notes
+		}
+		s.statuses = append(s.statuses, status)
+	}
+}
+
+// sorted returns the statuses sorted by kube context, namespace and name, regardless of the order they were collected.
+func (s *releaseStatuses) sorted() []ReleaseStatus {
+	statuses := append([]ReleaseStatus{}, s.statuses...)
+	sort.Slice(statuses, func(i, j int) bool {
+		a, b := statuses[i], statuses[j]
+		if a.KubeContext != b.KubeContext {
+			return a.KubeContext < b.KubeContext
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return statuses
+}
+
+func (s *releaseStatuses) print(output string) error {
+	statuses := s.sorted()
+
+	if output == "json" {
+		bs, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error generating json: %v", err)
+		}
+		fmt.Println(string(bs))
+		return nil
+	}
+
+	table := uitable.New()
+	table.AddRow("NAME", "KUBECONTEXT", "NAMESPACE", "REVISION", "STATUS", "CHART", "APP VERSION", "LAST DEPLOYED", "NOTES")
+	for _, st := range statuses {
+		revision, chart := "", st.Chart
+		if st.Revision > 0 {
+			revision = fmt.Sprintf("%d", st.Revision)
+		}
+		if st.ChartVersion != "" {
+			chart = st.Chart + "-" + st.ChartVersion
+		}
+		table.AddRow(st.Name, st.KubeContext, st.Namespace, revision, st.Status, chart, st.AppVersion, formatLastDeployed(st.LastDeployed), firstLine(st.Notes))
+	}
+	fmt.Println(trimTrailingWhitespace(table.String()))
+	return nil
+}
+
+// formatLastDeployed formats the RFC 3339 time helm reports to be read at a glance.
+func formatLastDeployed(t string) string {
+	parsed, err := time.Parse(time.RFC3339Nano, t)
+	if err != nil {
+		return t
+	}
+	return parsed.Format("2006-01-02 15:04:05 -0700")
+}
+
+// firstLine returns the first non-empty line of s, to summarize the notes of releases in a table.
+func firstLine(s string) string {
+	for _, l := range strings.Split(s, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			return l
+		}
+	}
+	return ""
+}
@@ -0,0 +1,146 @@
+package app
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/helmfile/helmfile/pkg/exectest"
+	ffs "github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/testutil"
+)
+
+type statusConfig struct {
+	output      string
+	concurrency int
+}
+
+func (c statusConfig) Args() string {
+	return ""
+}
+
+func (c statusConfig) Output() string {
+	return c.output
+}
+
+func (c statusConfig) Concurrency() int {
+	return c.concurrency
+}
+
+func TestStatusOutput(t *testing.T) {
+	files := map[string]string{
+		"/path/to/helmfile.yaml": `
+releases:
+- name: web
+  namespace: app
+  chart: stable/web
+- name: api
+  namespace: app
+  chart: stable/api
+- name: cache
+  namespace: db
+  chart: stable/redis
+- name: legacy
+  namespace: app
+  chart: stable/legacy
+  installed: false
+`,
+	}
+
+	status := func(name, ns, st, chart, version, appVersion, lastDeployed, notes string, revision int) *helmexec.ReleaseStatusInfo {
+		s := &helmexec.ReleaseStatusInfo{Name: name, Namespace: ns, Revision: revision}
+		s.Info.Status = st
+		s.Info.LastDeployed = lastDeployed
+		s.Info.Notes = notes
+		s.Chart.Metadata.Name = chart
+		s.Chart.Metadata.Version = version
+		s.Chart.Metadata.AppVersion = appVersion
+		return s
+	}
+
+	run := func(t *testing.T, output string) string {
+		t.Helper()
+
+		helm := &exectest.Helm{
+			Statuses: map[string]*helmexec.ReleaseStatusInfo{
+				"web":   status("web", "app", "deployed", "web", "1.2.3", "2.0.0", "2024-05-01T10:00:00.123456+02:00", "\n1. Visit http://web\n2. Enjoy\n", 4),
+				"cache": status("cache", "db", "failed", "redis", "17.0.7", "7.0.0", "2024-05-03T10:00:00Z", "", 2),
+			},
+			ReleasesMutex: &sync.Mutex{},
+		}
+		app := appWithFs(&App{
+			OverrideHelmBinary:              DefaultHelmBinary,
+			fs:                              ffs.DefaultFileSystem(),
+			OverrideKubeContext:             "default",
+			DisableKubeVersionAutoDetection: true,
+			Env:                             "default",
+			Logger:                          newAppTestLogger(),
+			helms: map[helmKey]helmexec.Interface{
+				createHelmKey(DefaultHelmBinary, "default"): helm,
+			},
+		}, files)
+
+		var statusErr error
+		out, err := testutil.CaptureStdout(func() {
+			statusErr = app.Status(statusConfig{output: output, concurrency: 2})
+		})
+		require.NoError(t, err)
+		require.NoError(t, statusErr)
+		return out
+	}
+
+	t.Run("table", func(t *testing.T) {
+		expected := `NAME 	KUBECONTEXT	NAMESPACE	REVISION	STATUS       	CHART       	APP VERSION	LAST DEPLOYED            	NOTES
+api  	default    	app      	        	not installed	stable/api
+web  	default    	app      	4       	deployed     	web-1.2.3   	2.0.0      	2024-05-01 10:00:00 +0200	1. Visit http://web
+cache	default    	db       	2       	failed       	redis-17.0.7	7.0.0      	2024-05-03 10:00:00 +0000
+`
+		assert.Equal(t, expected, run(t, "table"))
+	})
+
+	t.Run("json", func(t *testing.T) {
+		expected := `[
+  {
+    "name": "api",
+    "kubeContext": "default",
+    "namespace": "app",
+    "revision": 0,
+    "status": "not installed",
+    "chart": "stable/api",
+    "chartVersion": "",
+    "appVersion": "",
+    "lastDeployed": "",
+    "notes": ""
+  },
+  {
+    "name": "web",
+    "kubeContext": "default",
+    "namespace": "app",
+    "revision": 4,
+    "status": "deployed",
+    "chart": "web",
+    "chartVersion": "1.2.3",
+    "appVersion": "2.0.0",
+    "lastDeployed": "2024-05-01T10:00:00.123456+02:00",
+    "notes": "\n1. Visit http://web\n2. Enjoy\n"
+  },
+  {
+    "name": "cache",
+    "kubeContext": "default",
+    "namespace": "db",
+    "revision": 2,
+    "status": "failed",
+    "chart": "redis",
+    "chartVersion": "17.0.7",
+    "appVersion": "7.0.0",
+    "lastDeployed": "2024-05-03T10:00:00Z",
+    "notes": ""
+  }
+]
+`
+		assert.Equal(t, expected, run(t, "json"))
+	})
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/helmfile/helmfile/pkg/testhelper"
+)
+
+func TestStrict(t *testing.T) {
+	run := func(t *testing.T, strict bool, files map[string]string) error {
+		t.Helper()
+
+		app := injectFs(&App{
+			OverrideHelmBinary:              DefaultHelmBinary,
+			OverrideKubeContext:             "default",
+			DisableKubeVersionAutoDetection: true,
+			Env:                             "default",
+			FileOrDir:                       "helmfile.yaml.gotmpl",
+			Logger:                          newAppTestLogger(),
+			Strict:                          strict,
+		}, testhelper.NewTestFs(files))
+		expectNoCallsToHelm(app)
+
+		noop := func(run *Run) (bool, []error) {
+			return false, []error{}
+		}
+		return app.ForEachState(noop, false, SetFilter(true))
+	}
+
+	files := map[string]string{
+		"/path/to/helmfile.yaml.gotmpl": `bases:
+- base.yaml
+---
+releases:
+{{- range $i := list 1 2 }}
+- name: app-{{ $i }}
+  chart: stable/app
+  atomc: true
+{{- end }}
+`,
+		"/path/to/base.yaml": `helmDefaults:
+  wait: true
+---
+repositories:
+- name: stable
+  url: https://charts.example.com
+`,
+	}
+
+	require.ErrorContains(t, run(t, false, files), "line 4: field atomc not found in type state.ReleaseSpec")
+	require.EqualError(t, run(t, true, files), `in ./helmfile.yaml.gotmpl: failed to read helmfile.yaml.gotmpl: found 2 unknown or misplaced keys:
+  helmfile.yaml.gotmpl:8: releases[0].atomc: unknown key, did you mean "atomic"?
+  helmfile.yaml.gotmpl:8: releases[1].atomc: unknown key, did you mean "atomic"?`)
+
+	files["/path/to/base.yaml"] = `helmDefaults:
+  wait: true
+---
+repositories:
+- name: stable
+  url: https://charts.example.com
+  wait: true
+`
+	require.EqualError(t, run(t, true, files), `in ./helmfile.yaml.gotmpl: failed to read base.yaml: found 1 unknown or misplaced keys:
+  base.yaml:7: repositories[0].wait: misplaced key, it is only allowed under helmDefaults, releases[], templates.<name>`)
+}
@@ -0,0 +1,419 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/maputil"
+	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/helmfile/helmfile/pkg/yaml"
+)
+
+// stateTestFile is the content of a `helmfile test-state` test file.
+type stateTestFile struct {
+	Tests []stateTestCase `yaml:"tests"`
+}
+
+// stateTestCase loads the helmfile once with its own inputs and checks the
+// resulting releases against Asserts.
+type stateTestCase struct {
+	Name string `yaml:"name"`
+	// Environment overrides --environment for this test case.
+	Environment string `yaml:"environment,omitempty"`
+	// StateValuesSet is merged over --state-values-set and uses the same key=value syntax.
+	StateValuesSet []string         `yaml:"stateValuesSet,omitempty"`
+	Asserts        []stateAssertion `yaml:"asserts"`
+}
+
+// stateAssertion selects a single release by name, optionally narrowed by
+// namespace and kubeContext, and checks it. Unset checks are skipped.
+type stateAssertion struct {
+	Release     string `yaml:"release"`
+	Namespace   string `yaml:"namespace,omitempty"`
+	KubeContext string `yaml:"kubeContext,omitempty"`
+
+	// Exists asserts whether the release is defined at all. Defaults to true.
+	Exists *bool `yaml:"exists,omitempty"`
+	// Installed asserts the release's `installed` field.
+	Installed *bool `yaml:"installed,omitempty"`
+	// Enabled asserts the result of the release's `condition`.
+	Enabled *bool `yaml:"enabled,omitempty"`
+	// Labels must all be present on the release with the given values.
+	Labels map[string]string `yaml:"labels,omitempty"`
+	// Fields maps dotted paths into the release spec, e.g. `chart` or `set[0].name`, to their expected values.
+	Fields map[string]any `yaml:"fields,omitempty"`
+	// Values maps dotted paths into the merged release values to their expected values.
+	Values map[string]any `yaml:"values,omitempty"`
+	// HasValues lists dotted paths that must be present in the merged release values.
+	HasValues []string `yaml:"hasValues,omitempty"`
+}
+
+type stateTestRelease struct {
+	st      *state.HelmState
+	release state.ReleaseSpec
+}
+
+// TestState runs the helmfile state tests found in the files given by --tests.
+// Every test case loads the helmfile with its own environment and state values
+// and asserts on the resulting releases and their merged values. The state is
+// loaded and rendered entirely offline: neither helm nor the cluster is called.
+func (a *App) TestState(c TestStateConfigProvider) error {
+	files, err := a.stateTestFiles(c.Tests())
+	if err != nil {
+		return err
+	}
+
+	var passed, failed int
+	for _, file := range files {
+		tests, err := a.loadStateTestFile(file)
+		if err != nil {
+			return err
+		}
+
+		for i, tc := range tests {
+			name := tc.Name
+			if name == "" {
+				name = fmt.Sprintf("tests[%d]", i)
+			}
+
+			failures := a.runStateTest(tc)
+			if len(failures) == 0 {
+				passed++
+				fmt.Printf("PASS: %s: %s\n", file, name)
+				continue
+			}
+
+			failed++
+			fmt.Printf("FAIL: %s: %s\n", file, name)
+			for _, f := range failures {
+				fmt.Printf("    %s\n", f)
+			}
+		}
+	}
+
+	fmt.Printf("\n%d passed, %d failed\n", passed, failed)
+
+	if failed > 0 {
+		return helmexec.ExitError{Message: fmt.Sprintf("%d of %d state tests failed", failed, passed+failed), Code: 1}
+	}
+
+	return nil
+}
+
+func (a *App) stateTestFiles(patterns []string) ([]string, error) {
+	if len(patterns) == 0 {
+		return nil, errors.New("no state test files specified: pass one or more --tests")
+	}
+
+	var files []string
+	for _, p := range patterns {
+		matches, err := a.fs.Glob(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to glob %q: %w", p, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no state test files matched %q", p)
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+
+	return files, nil
+}
+
+func (a *App) loadStateTestFile(file string) ([]stateTestCase, error) {
+	bs, err := a.fs.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var f stateTestFile
+	if err := yaml.NewDecoder(bs, true)(&f); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse state test file %s: %w", file, err)
+	}
+	if len(f.Tests) == 0 {
+		return nil, fmt.Errorf("state test file %s contains no tests", file)
+	}
+
+	return f.Tests, nil
+}
+
+// runStateTest loads the helmfile for tc and returns the failed assertions,
+// or the load error as the only failure.
+func (a *App) runStateTest(tc stateTestCase) []string {
+	set, err := parseStateValuesSet(tc.StateValuesSet)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	// The loader reads the environment and state values from the App, so
+	// swap them in for the duration of this test case only.
+	env, globalSet := a.Env, a.Set
+	defer func() {
+		a.Env, a.Set = env, globalSet
+	}()
+	if tc.Environment != "" {
+		a.Env = tc.Environment
+	}
+	a.Set = maputil.MergeMaps(globalSet, set)
+
+	var (
+		mu       sync.Mutex
+		releases []stateTestRelease
+	)
+
+	ctx := NewContext()
+	err = a.visitStatesWithSelectorsAndRemoteSupportWithContext(a.FileOrDir, func(st *state.HelmState) (bool, []error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		for _, r := range st.Releases {
+			releases = append(releases, stateTestRelease{st: st, release: r})
+		}
+
+		return len(st.Releases) > 0, nil
+	}, false, &ctx)
+
+	var noMatch *NoMatchingHelmfileError
+	if err != nil && !errors.As(err, &noMatch) {
+		return []string{err.Error()}
+	}
+
+	var failures []string
+	for _, as := range tc.Asserts {
+		failures = append(failures, as.check(releases)...)
+	}
+
+	return failures
+}
+
+func (as stateAssertion) check(releases []stateTestRelease) []string {
+	id := as.Release
+	if as.Namespace != "" {
+		id = as.Namespace + "/" + id
+	}
+	if as.KubeContext != "" {
+		id = as.KubeContext + "/" + id
+	}
+
+	var matched []stateTestRelease
+	for _, r := range releases {
+		if r.release.Name != as.Release {
+			continue
+		}
+		if as.Namespace != "" && r.release.Namespace != as.Namespace {
+			continue
+		}
+		if as.KubeContext != "" && r.release.KubeContext != as.KubeContext {
+			continue
+		}
+		matched = append(matched, r)
+	}
+
+	exists := as.Exists == nil || *as.Exists
+	switch {
+	case !exists && len(matched) == 0:
+		return nil
+	case !exists:
+		return []string{fmt.Sprintf("release %q: expected not to exist, but it is defined", id)}
+	case len(matched) == 0:
+		return []string{fmt.Sprintf("release %q: not found", id)}
+	case len(matched) > 1:
+		return []string{fmt.Sprintf("release %q: matches %d releases, set namespace or kubeContext to select one", id, len(matched))}
+	}
+
+	st, r := matched[0].st, matched[0].release
+
+	var failures []string
+	fail := func(format string, args ...any) {
+		failures = append(failures, fmt.Sprintf("release %q: ", id)+fmt.Sprintf(format, args...))
+	}
+
+	if as.Installed != nil && r.Desired() != *as.Installed {
+		fail("installed: expected %v, got %v", *as.Installed, r.Desired())
+	}
+
+	if as.Enabled != nil {
+		enabled, err := state.ConditionEnabled(r, st.Values())
+		if err != nil {
+			fail("enabled: %v", err)
+		} else if enabled != *as.Enabled {
+			fail("enabled: expected %v, got %v", *as.Enabled, enabled)
+		}
+	}
+
+	for _, k := range sortedKeys(as.Labels) {
+		got, ok := r.Labels[k]
+		switch {
+		case !ok:
+			fail("labels.%s: not set", k)
+		case got != as.Labels[k]:
+			fail("labels.%s: expected %q, got %q", k, as.Labels[k], got)
+		}
+	}
+
+	if len(as.Fields) > 0 {
+		spec, err := toYAMLValue(r)
+		if err != nil {
+			fail("fields: %v", err)
+		} else {
+			for _, k := range sortedKeys(as.Fields) {
+				if msg := compareAtPath(spec, k, as.Fields[k]); msg != "" {
+					fail("%s: %s", k, msg)
+				}
+			}
+		}
+	}
+
+	if len(as.Values) == 0 && len(as.HasValues) == 0 {
+		return failures
+	}
+
+	values, err := st.ResolveReleaseValues(&r)
+	if err != nil {
+		fail("values: %v", err)
+		return failures
+	}
+
+	for _, k := range sortedKeys(as.Values) {
+		if msg := compareAtPath(values, k, as.Values[k]); msg != "" {
+			fail("values.%s: %s", k, msg)
+		}
+	}
+
+	for _, k := range as.HasValues {
+		if _, ok := lookupPath(values, k); !ok {
+			fail("values.%s: not set", k)
+		}
+	}
+
+	return failures
+}
+
+// parseStateValuesSet parses key=value pairs the same way as --state-values-set.
+func parseStateValuesSet(sets []string) (map[string]any, error) {
+	set := map[string]any{}
+	for _, s := range sets {
+		for _, op := range strings.Split(s, ",") {
+			kv := strings.SplitN(op, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("invalid stateValuesSet entry %q: expected key=value", op)
+			}
+			maputil.Set(set, maputil.ParseKey(kv[0]), kv[1], false)
+		}
+	}
+	return set, nil
+}
+
+// compareAtPath returns a description of the mismatch between the value at
+// path and want, or "" when they are equal.
+func compareAtPath(v any, path string, want any) string {
+	got, ok := lookupPath(v, path)
+	if !ok {
+		return "not set"
+	}
+
+	// Round-trip both sides through YAML so that e.g. int and int64 or
+	// map[any]any and map[string]any compare equal.
+	normGot, err := toYAMLValue(got)
+	if err != nil {
+		return err.Error()
+	}
+	normWant, err := toYAMLValue(want)
+	if err != nil {
+		return err.Error()
+	}
+
+	if !reflect.DeepEqual(normGot, normWant) {
+		return fmt.Sprintf("expected %s, got %s", formatStateTestValue(normWant), formatStateTestValue(normGot))
+	}
+	return ""
+}
+
+// lookupPath walks a dotted path such as `image.tag` or `ports[0].name`
+// through nested maps and lists. Dots in keys can be escaped with a backslash.
+func lookupPath(v any, path string) (any, bool) {
+	cur := v
+	for _, key := range maputil.ParseKey(path) {
+		name, indices := key, []int(nil)
+		for strings.HasSuffix(name, "]") {
+			open := strings.LastIndex(name, "[")
+			if open < 0 {
+				break
+			}
+			i, err := strconv.Atoi(name[open+1 : len(name)-1])
+			if err != nil {
+				break
+			}
+			indices = append([]int{i}, indices...)
+			name = name[:open]
+		}
+
+		if name != "" {
+			switch m := cur.(type) {
+			case map[string]any:
+				next, ok := m[name]
+				if !ok {
+					return nil, false
+				}
+				cur = next
+			case map[any]any:
+				next, ok := m[name]
+				if !ok {
+					return nil, false
+				}
+				cur = next
+			default:
+				return nil, false
+			}
+		}
+
+		for _, i := range indices {
+			l, ok := cur.([]any)
+			if !ok || i < 0 || i >= len(l) {
+				return nil, false
+			}
+			cur = l[i]
+		}
+	}
+	return cur, true
+}
+
+func toYAMLValue(v any) (any, error) {
+	bs, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	if err := yaml.Unmarshal(bs, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func formatStateTestValue(v any) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	bs, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return strings.TrimSpace(string(bs))
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
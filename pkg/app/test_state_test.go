@@ -0,0 +1,177 @@
+package app
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/testutil"
+)
+
+type testStateConfig struct {
+	tests []string
+}
+
+func (c testStateConfig) Tests() []string {
+	return c.tests
+}
+
+const testStateHelmfile = `
+environments:
+  default:
+    values:
+    - foo:
+        enabled: false
+  prod:
+    values:
+    - foo:
+        enabled: true
+      bar:
+        enabled: false
+---
+releases:
+- name: foo
+  namespace: backend
+  chart: charts/foo
+  installed: {{ .Values.foo.enabled }}
+  labels:
+    tier: backend
+  values:
+  - image:
+      tag: {{ .Values | get "tag" "latest" }}
+    replicas: 2
+  - values/foo.yaml
+- name: bar
+  chart: charts/bar
+  condition: bar.enabled
+`
+
+func TestTestState(t *testing.T) {
+	files := map[string]string{
+		"/path/to/helmfile.yaml.gotmpl": testStateHelmfile,
+		"/path/to/values/foo.yaml": `
+resources:
+  limits:
+    cpu: 100m
+`,
+		"/path/to/tests/state.yaml": `
+tests:
+- name: default disables foo
+  asserts:
+  - release: foo
+    installed: false
+  - release: baz
+    exists: false
+- name: prod installs foo with the backend tier
+  environment: prod
+  stateValuesSet:
+  - tag=1.2.3
+  asserts:
+  - release: foo
+    namespace: backend
+    installed: true
+    labels:
+      tier: backend
+    fields:
+      chart: charts/foo
+    values:
+      image.tag: 1.2.3
+      replicas: 2
+    hasValues:
+    - resources.limits.cpu
+  - release: bar
+    enabled: false
+`,
+	}
+
+	app := createTestApp(t, files, "default")
+	app.FileOrDir = "helmfile.yaml.gotmpl"
+
+	out, err := testutil.CaptureStdout(func() {
+		require.NoError(t, app.TestState(testStateConfig{tests: []string{"tests/*.yaml"}}))
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, out, "PASS: /path/to/tests/state.yaml: default disables foo")
+	assert.Contains(t, out, "PASS: /path/to/tests/state.yaml: prod installs foo with the backend tier")
+	assert.Contains(t, out, "2 passed, 0 failed")
+	assert.Equal(t, "default", app.Env, "the environment must be restored after each test case")
+}
+
+func TestTestState_Failures(t *testing.T) {
+	files := map[string]string{
+		"/path/to/helmfile.yaml.gotmpl": testStateHelmfile,
+		"/path/to/values/foo.yaml":      `{}`,
+		"/path/to/tests/state.yaml": `
+tests:
+- name: wrong expectations
+  environment: prod
+  asserts:
+  - release: foo
+    installed: false
+    labels:
+      tier: frontend
+    values:
+      image.tag: 1.2.3
+    hasValues:
+    - resources
+  - release: missing
+`,
+	}
+
+	app := createTestApp(t, files, "default")
+	app.FileOrDir = "helmfile.yaml.gotmpl"
+
+	var runErr error
+	out, err := testutil.CaptureStdout(func() {
+		runErr = app.TestState(testStateConfig{tests: []string{"tests/state.yaml"}})
+	})
+	require.NoError(t, err)
+
+	var exitErr helmexec.ExitError
+	require.True(t, errors.As(runErr, &exitErr), "expected ExitError, got %v", runErr)
+	assert.Equal(t, 1, exitErr.Code)
+
+	assert.Contains(t, out, "FAIL: /path/to/tests/state.yaml: wrong expectations")
+	assert.Contains(t, out, `release "foo": installed: expected false, got true`)
+	assert.Contains(t, out, `release "foo": labels.tier: expected "frontend", got "backend"`)
+	assert.Contains(t, out, `release "foo": values.image.tag: expected "1.2.3", got "latest"`)
+	assert.Contains(t, out, `release "foo": values.resources: not set`)
+	assert.Contains(t, out, `release "missing": not found`)
+	assert.Contains(t, out, "0 passed, 1 failed")
+}
+
+func TestTestState_NoTests(t *testing.T) {
+	app := createTestApp(t, map[string]string{"/path/to/helmfile.yaml": "releases: []\n"}, "default")
+
+	err := app.TestState(testStateConfig{})
+	require.EqualError(t, err, "no state test files specified: pass one or more --tests")
+}
+
+func TestLookupPath(t *testing.T) {
+	v := map[string]any{
+		"image": map[string]any{"tag": "1.0"},
+		"ports": []any{map[any]any{"name": "http"}},
+		"a.b":   1,
+	}
+
+	got, ok := lookupPath(v, "image.tag")
+	require.True(t, ok)
+	assert.Equal(t, "1.0", got)
+
+	got, ok = lookupPath(v, "ports[0].name")
+	require.True(t, ok)
+	assert.Equal(t, "http", got)
+
+	got, ok = lookupPath(v, `a\.b`)
+	require.True(t, ok)
+	assert.Equal(t, 1, got)
+
+	_, ok = lookupPath(v, "ports[1]")
+	assert.False(t, ok)
+	_, ok = lookupPath(v, "image.digest")
+	assert.False(t, ok)
+}
@@ -0,0 +1,410 @@
+package app
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tatsushid/go-prettytable"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/helmfile/helmfile/pkg/kubedog"
+	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/helmfile/helmfile/pkg/tracing"
+)
+
+// Phases of the timing report
+const (
+	phaseLoading      = "loading"
+	phaseRepos        = "repo updates"
+	phasePrepare      = "chart preparation"
+	phaseDependencies = "dependency builds"
+	phaseDownloads    = "chart downloads"
+	phaseDiff         = "diff"
+	phaseSync         = "sync"
+	phaseDelete       = "delete"
+	phaseHooks        = "hooks"
+	phaseTracking     = "kubedog tracking"
+	phaseWaiting      = "waiting for needs"
+)
+
+var timingPhases = []string{
+	phaseLoading,
+	phaseRepos,
+	phasePrepare,
+	phaseDependencies,
+	phaseDownloads,
+	phaseDiff,
+	phaseSync,
+	phaseDelete,
+	phaseHooks,
+	phaseTracking,
+}
+
+// spanPhase returns the phase of the run the span named name times, if any.
+// Spans wrapping others of the same phase, like `sync repos`, aren't counted not to count the time twice.
+func spanPhase(name string) string {
+	switch {
+	case name == "load helmfile":
+		return phaseLoading
+	case strings.HasPrefix(name, "helm repo ") || strings.HasPrefix(name, "helm registry "):
+		return phaseRepos
+	case name == "prepare chart":
+		return phasePrepare
+	case strings.HasPrefix(name, "helm dependency "):
+		return phaseDependencies
+	case name == "helm pull" || name == "helm fetch":
+		return phaseDownloads
+	case strings.HasPrefix(name, "helm diff "):
+		return phaseDiff
+	case name == "helm upgrade" || name == "helm install":
+		return phaseSync
+	case name == "helm uninstall" || name == "helm delete":
+		return phaseDelete
+	case strings.HasPrefix(name, "hook "):
+		return phaseHooks
+	case name == "kubedog track":
+		return phaseTracking
+	}
+	return ""
+}
+
+// helmfileTiming is the timings of the releases of a helmfile.
+type helmfileTiming struct {
+	file     string
+	releases []state.ReleaseTiming
+}
+
+// runTiming collects the spans of a sync, apply or destroy command, and the timings
+// of the releases of each of its helmfiles, for the timing report of the command.
+type runTiming struct {
+	start time.Time
+	stop  func() []sdktrace.ReadOnlySpan
+
+	mu        sync.Mutex
+	helmfiles []helmfileTiming
+}
+
+func (t *runTiming) add(file string, releases []state.ReleaseTiming) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.helmfiles = append(t.helmfiles, helmfileTiming{file: file, releases: releases})
+}
+
+// startTiming starts collecting the timings of the command, when c enables the timing report.
+func (a *App) startTiming(c timingReportConfig) {
+	if !c.TimingReport() {
+		return
+	}
+	a.timing = &runTiming{start: time.Now(), stop: tracing.Collect()}
+}
+
+// printTiming prints the timing report of the command. The needs of the releases
+// are reversed for destroy, which deletes the releases that need others first.
+func (a *App) printTiming(reverse, useColor bool) {
+	t := a.timing
+	if t == nil {
+		return
+	}
+	a.timing = nil
+
+	spans := t.stop()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	a.Logger.Info(timingReport(time.Since(t.start), spans, t.helmfiles, reverse, useColor))
+}
+
+// interval is the time a span took, from its start to its end.
+type interval struct {
+	start, end time.Time
+}
+
+// wallTime returns the time during which any of the intervals ran.
+func wallTime(intervals []interval) time.Duration {
+	slices.SortFunc(intervals, func(a, b interval) int { return a.start.Compare(b.start) })
+
+	var total time.Duration
+	var current interval
+	for i, in := range intervals {
+		switch {
+		case i == 0:
+			current = in
+		case in.start.After(current.end):
+			total += current.end.Sub(current.start)
+			current = in
+		case in.end.After(current.end):
+			current.end = in.end
+		}
+	}
+	if len(intervals) > 0 {
+		total += current.end.Sub(current.start)
+	}
+	return total
+}
+
+func spanAttributes(s sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range s.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+// spanOfRelease reports whether the span with attrs times work on the release r.
+// The kube context only tells releases apart when the span and r both have one.
+func spanOfRelease(attrs map[attribute.Key]attribute.Value, r state.ReleaseTiming) bool {
+	if attrs[tracing.Release].AsString() != r.Name {
+		return false
+	}
+	if ns := attrs[tracing.Namespace].AsString(); ns != "" && r.Namespace != "" && ns != r.Namespace {
+		return false
+	}
+	if kc := attrs[tracing.KubeContext].AsString(); kc != "" && r.KubeContext != "" && kc != r.KubeContext {
+		return false
+	}
+	return true
+}
+
+// releaseTiming is the time spent on a release, by phase.
+type releaseTiming struct {
+	state.ReleaseTiming
+	phases map[string]time.Duration
+	waited time.Duration
+}
+
+// timingReport returns the time the run spent in each phase and on each release, and the
+// critical path through the needs of the releases of each helmfile.
+func timingReport(total time.Duration, spans []sdktrace.ReadOnlySpan, helmfiles []helmfileTiming, reverse, useColor bool) string {
+	var releases [][]*releaseTiming
+	for _, h := range helmfiles {
+		var rs []*releaseTiming
+		for _, r := range h.releases {
+			rs = append(rs, &releaseTiming{ReleaseTiming: r, phases: map[string]time.Duration{}})
+		}
+		releases = append(releases, rs)
+	}
+
+	intervals := map[string][]interval{}
+	counts := map[string]int{}
+	sums := map[string]time.Duration{}
+	// firstStarts are the times the first release, or group of releases, of each helmfile started at
+	firstStarts := map[string]time.Time{}
+	attributes := make([]map[attribute.Key]attribute.Value, len(spans))
+	for i, s := range spans {
+		attrs := spanAttributes(s)
+		attributes[i] = attrs
+		if s.Name() == "release" || s.Name() == "release group" {
+			file := attrs[tracing.Helmfile].AsString()
+			if first, ok := firstStarts[file]; !ok || s.StartTime().Before(first) {
+				firstStarts[file] = s.StartTime()
+			}
+		}
+
+		phase := spanPhase(s.Name())
+		if phase == "" {
+			continue
+		}
+		d := s.EndTime().Sub(s.StartTime())
+		intervals[phase] = append(intervals[phase], interval{s.StartTime(), s.EndTime()})
+		counts[phase]++
+		sums[phase] += d
+
+		if attrs[tracing.Release].AsString() == "" {
+			continue
+		}
+		for _, rs := range releases {
+			for _, r := range rs {
+				if spanOfRelease(attrs, r.ReleaseTiming) {
+					r.phases[phase] += d
+				}
+			}
+		}
+	}
+
+	// A release waited from the start of the first release of its helmfile until it,
+	// or its group of releases with --lockstep-batches, started
+	for i, h := range helmfiles {
+		first, ok := firstStarts[h.file]
+		if !ok {
+			continue
+		}
+		for _, r := range releases[i] {
+			for j, s := range spans {
+				attrs := attributes[j]
+				if attrs[tracing.Helmfile].AsString() != h.file {
+					continue
+				}
+				started := s.Name() == "release" && spanOfRelease(attrs, r.ReleaseTiming) ||
+					s.Name() == "release group" && slices.Contains(attrs[tracing.Releases].AsStringSlice(), r.ID)
+				if started {
+					r.waited = s.StartTime().Sub(first)
+					counts[phaseWaiting]++
+					sums[phaseWaiting] += r.waited
+					break
+				}
+			}
+		}
+	}
+
+	var sb strings.Builder
+
+	phases, _ := prettytable.NewTable(
+		prettytable.Column{Header: "PHASE"},
+		prettytable.Column{Header: "WALL", AlignRight: true},
+		prettytable.Column{Header: "TOTAL", AlignRight: true},
+		prettytable.Column{Header: "COUNT", AlignRight: true},
+	)
+	phases.Separator = "   "
+	for _, phase := range timingPhases {
+		if counts[phase] == 0 {
+			continue
+		}
+		_ = phases.AddRow(phase, roundDuration(wallTime(intervals[phase])), roundDuration(sums[phase]), counts[phase])
+	}
+	if counts[phaseWaiting] > 0 {
+		_ = phases.AddRow(phaseWaiting, "-", roundDuration(sums[phaseWaiting]), counts[phaseWaiting])
+	}
+	_ = phases.AddRow("run", roundDuration(total), "-", "-")
+	writeTimingTable(&sb, "Timing by Phase", phases.String(), useColor)
+
+	var all []*releaseTiming
+	for _, rs := range releases {
+		all = append(all, rs...)
+	}
+	if len(all) == 0 {
+		return sb.String()
+	}
+	slices.SortStableFunc(all, func(a, b *releaseTiming) int { return cmp.Compare(b.Duration, a.Duration) })
+
+	byRelease, _ := prettytable.NewTable(
+		prettytable.Column{Header: "RELEASE"},
+		prettytable.Column{Header: "PREPARE", AlignRight: true},
+		prettytable.Column{Header: "DIFF", AlignRight: true},
+		prettytable.Column{Header: "HOOKS", AlignRight: true},
+		prettytable.Column{Header: "HELM", AlignRight: true},
+		prettytable.Column{Header: "TRACKING", AlignRight: true},
+		prettytable.Column{Header: "WAITED", AlignRight: true},
+		prettytable.Column{Header: "DURATION", AlignRight: true},
+	)
+	byRelease.Separator = "   "
+	for _, r := range all {
+		_ = byRelease.AddRow(r.ID,
+			roundDuration(r.phases[phasePrepare]),
+			roundDuration(r.phases[phaseDiff]),
+			roundDuration(r.phases[phaseHooks]),
+			roundDuration(r.phases[phaseSync]+r.phases[phaseDelete]),
+			roundDuration(r.phases[phaseTracking]),
+			roundDuration(r.waited),
+			roundDuration(r.Duration),
+		)
+	}
+	writeTimingTable(&sb, "Timing by Release", byRelease.String(), useColor)
+
+	paths, _ := prettytable.NewTable(
+		prettytable.Column{Header: "HELMFILE"},
+		prettytable.Column{Header: "CRITICAL PATH"},
+		prettytable.Column{Header: "DURATION", AlignRight: true},
+	)
+	paths.Separator = "   "
+	for _, h := range helmfiles {
+		path, d := criticalPath(h.releases, reverse)
+		if len(path) == 0 {
+			continue
+		}
+		steps := make([]string, len(path))
+		for j, r := range path {
+			steps[j] = fmt.Sprintf("%s (%s)", r.ID, roundDuration(r.Duration))
+		}
+		_ = paths.AddRow(h.file, strings.Join(steps, " -> "), roundDuration(d))
+	}
+	writeTimingTable(&sb, "Critical Path", paths.String(), useColor)
+
+	return sb.String()
+}
+
+func writeTimingTable(sb *strings.Builder, title, table string, useColor bool) {
+	sb.WriteString("\n")
+	sb.WriteString(kubedog.HeaderDividerCenteredStyled(title, kubedog.TableVisualWidth(table), useColor))
+	sb.WriteString("\n")
+	sb.WriteString(table)
+}
+
+// criticalPath returns the chain of releases, through their needs, that took the longest
+// in total, in the order they ran, and how long it took. A release runs after the releases
+// it needs, unless reverse is true, when it runs after the releases needing it.
+func criticalPath(releases []state.ReleaseTiming, reverse bool) ([]state.ReleaseTiming, time.Duration) {
+	byID := map[string]state.ReleaseTiming{}
+	for _, r := range releases {
+		byID[r.ID] = r
+	}
+
+	// after are the IDs of the releases each release runs after
+	after := map[string][]string{}
+	for _, r := range releases {
+		for _, n := range r.Needs {
+			if _, ok := byID[n]; !ok {
+				continue
+			}
+			if reverse {
+				after[n] = append(after[n], r.ID)
+			} else {
+				after[r.ID] = append(after[r.ID], n)
+			}
+		}
+	}
+
+	// finish is how long the longest chain ending with each release took, and prev the release before it in the chain
+	finish := map[string]time.Duration{}
+	prev := map[string]string{}
+	var visit func(id string, visiting map[string]bool) time.Duration
+	visit = func(id string, visiting map[string]bool) time.Duration {
+		if d, ok := finish[id]; ok {
+			return d
+		}
+		// The needs were validated to have no cycle while planning the run
+		if visiting[id] {
+			return 0
+		}
+		visiting[id] = true
+		var longest time.Duration
+		for _, a := range after[id] {
+			if d := visit(a, visiting); d > longest || prev[id] == "" {
+				longest = d
+				prev[id] = a
+			}
+		}
+		finish[id] = longest + byID[id].Duration
+		return finish[id]
+	}
+
+	var last string
+	for _, r := range releases {
+		if d := visit(r.ID, map[string]bool{}); last == "" || d > finish[last] {
+			last = r.ID
+		}
+	}
+	if last == "" {
+		return nil, 0
+	}
+
+	var path []state.ReleaseTiming
+	for id := last; id != ""; id = prev[id] {
+		path = append([]state.ReleaseTiming{byID[id]}, path...)
+	}
+	return path, finish[last]
+}
+
+// roundDuration rounds d for the timing report, to the millisecond below a second and to a tenth of a second otherwise.
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(100 * time.Millisecond)
+}
@@ -0,0 +1,168 @@
+package app
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/helmfile/vals"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/helmfile/helmfile/pkg/exectest"
+	ffs "github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/helmfile/helmfile/pkg/tracing"
+)
+
+var timings = []state.ReleaseTiming{
+	{ID: "app/web", Name: "web", Namespace: "app", Needs: []string{"app/api"}, Duration: 30 * time.Second},
+	{ID: "app/api", Name: "api", Namespace: "app", Needs: []string{"db/db", "app/cache"}, Duration: 40 * time.Second},
+	{ID: "db/db", Name: "db", Namespace: "db", Duration: 20 * time.Second},
+	{ID: "app/cache", Name: "cache", Namespace: "app", Duration: 5 * time.Second},
+	{ID: "app/docs", Name: "docs", Namespace: "app", Needs: []string{"app/external"}, Duration: 50 * time.Second},
+}
+
+func pathIDs(path []state.ReleaseTiming) []string {
+	var ids []string
+	for _, r := range path {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func TestCriticalPath(t *testing.T) {
+	path, d := criticalPath(timings, false)
+	assert.Equal(t, []string{"db/db", "app/api", "app/web"}, pathIDs(path))
+	assert.Equal(t, 90*time.Second, d)
+
+	path, d = criticalPath(timings, true)
+	assert.Equal(t, []string{"app/web", "app/api", "db/db"}, pathIDs(path), "destroy deletes the releases needing others first")
+	assert.Equal(t, 90*time.Second, d)
+
+	path, d = criticalPath(nil, false)
+	assert.Empty(t, path)
+	assert.Zero(t, d)
+}
+
+func TestWallTime(t *testing.T) {
+	at := func(s int) time.Time { return time.Unix(int64(s), 0) }
+	assert.Equal(t, 22*time.Second, wallTime([]interval{
+		{at(20), at(30)},
+		{at(0), at(10)},
+		{at(5), at(12)},
+		{at(25), at(28)},
+	}))
+	assert.Zero(t, wallTime(nil))
+}
+
+func TestTimingReport(t *testing.T) {
+	at := func(s int) time.Time { return time.Unix(1714557600+int64(s), 0) }
+	span := func(name string, start, end int, attrs ...attribute.KeyValue) sdktrace.ReadOnlySpan {
+		return tracetest.SpanStub{Name: name, StartTime: at(start), EndTime: at(end), Attributes: attrs}.Snapshot()
+	}
+	web := []attribute.KeyValue{tracing.Release.String("web"), tracing.Namespace.String("app")}
+	db := []attribute.KeyValue{tracing.Release.String("db"), tracing.Namespace.String("db")}
+	file := tracing.Helmfile.String("helmfile.yaml")
+
+	spans := []sdktrace.ReadOnlySpan{
+		span("load helmfile", 0, 2, file),
+		span("helm repo add", 2, 4),
+		span("prepare chart", 4, 6, web...),
+		span("prepare chart", 4, 7, db...),
+		span("helm dependency build", 5, 6),
+		span("release", 8, 28, append(db, file)...),
+		span("helm upgrade", 9, 25, db...),
+		span("kubedog track", 25, 27, db...),
+		span("release", 28, 60, append(web, file)...),
+		span("hook presync", 28, 29, web...),
+		span("helm upgrade", 29, 58, append(web, tracing.KubeContext.String("prod"))...),
+		// web in another kube context isn't the release of the helmfile
+		span("helm upgrade", 0, 1, append(web, tracing.KubeContext.String("staging"))...),
+	}
+	helmfiles := []helmfileTiming{{
+		file: "helmfile.yaml",
+		releases: []state.ReleaseTiming{
+			{ID: "db/db", Name: "db", Namespace: "db", Duration: 20 * time.Second},
+			{ID: "app/web", Name: "web", Namespace: "app", KubeContext: "prod", Needs: []string{"db/db"}, Duration: 30 * time.Second},
+		},
+	}}
+
+	out := timingReport(time.Minute, spans, helmfiles, false, false)
+
+	for _, row := range []string{
+		`loading\s+2s\s+2s\s+1\n`,
+		`repo updates\s+2s\s+2s\s+1\n`,
+		`chart preparation\s+3s\s+5s\s+2\n`,
+		`dependency builds\s+1s\s+1s\s+1\n`,
+		`sync\s+46s\s+46s\s+3\n`,
+		`hooks\s+1s\s+1s\s+1\n`,
+		`kubedog tracking\s+2s\s+2s\s+1\n`,
+		`waiting for needs\s+-\s+20s\s+2\n`,
+		`run\s+1m0s\s+-\s+-\n`,
+		`app/web\s+2s\s+0s\s+1s\s+29s\s+0s\s+20s\s+30s\n`,
+		`db/db\s+3s\s+0s\s+0s\s+16s\s+2s\s+0s\s+20s\n`,
+		`helmfile.yaml\s+db/db \(20s\) -> app/web \(30s\)\s+50s\n`,
+	} {
+		assert.Regexp(t, row, out)
+	}
+	assert.NotContains(t, out, "chart downloads", "phases without spans aren't reported")
+	assert.Less(t, strings.Index(out, "app/web "), strings.Index(out, "db/db "), "the releases are sorted by duration")
+}
+
+func TestTimingReportOfSync(t *testing.T) {
+	files := map[string]string{
+		"/path/to/helmfile.yaml": `
+releases:
+- name: db
+  namespace: app
+  chart: stable/db
+- name: web
+  namespace: app
+  chart: stable/web
+  needs:
+  - db
+`,
+	}
+
+	valsRuntime, err := vals.New(vals.Options{CacheSize: 32})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	logger := helmexec.NewLogger(&buf, "info")
+	app := appWithFs(&App{
+		OverrideHelmBinary:              DefaultHelmBinary,
+		fs:                              ffs.DefaultFileSystem(),
+		OverrideKubeContext:             "default",
+		DisableKubeVersionAutoDetection: true,
+		Env:                             "default",
+		Logger:                          logger,
+		helms: map[helmKey]helmexec.Interface{
+			createHelmKey(DefaultHelmBinary, "default"): &exectest.Helm{
+				DiffMutex:     &sync.Mutex{},
+				ChartsMutex:   &sync.Mutex{},
+				ReleasesMutex: &sync.Mutex{},
+			},
+		},
+		valsRuntime: valsRuntime,
+	}, files)
+
+	require.NoError(t, app.Sync(applyConfig{timingReport: true, noColor: true, logger: logger}))
+
+	out := buf.String()
+	assert.Contains(t, out, "Timing by Phase")
+	assert.Contains(t, out, "waiting for needs")
+	assert.Contains(t, out, "Timing by Release")
+	assert.Contains(t, out, "Critical Path")
+	assert.Regexp(t, `helmfile.yaml\s+default/app/db \([^)]+\) -> default/app/web`, out)
+	assert.Nil(t, app.timing)
+
+	// The spans are only collected while the report is on
+	assert.False(t, tracing.StartSpan("helm upgrade").SpanContext().IsValid())
+}
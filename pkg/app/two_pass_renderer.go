@@ -6,8 +6,10 @@ import (
 	"strings"
 
 	"github.com/helmfile/helmfile/pkg/environment"
+	"github.com/helmfile/helmfile/pkg/hcllang"
 	"github.com/helmfile/helmfile/pkg/state"
 	"github.com/helmfile/helmfile/pkg/tmpl"
+	"github.com/helmfile/helmfile/pkg/yaml"
 )
 
 func prependLineNumbers(text string) string {
@@ -25,14 +27,15 @@ type RenderOpts struct {
 func (r *desiredStateLoader) renderTemplatesToYaml(baseDir, filename string, content []byte) (*bytes.Buffer, error) {
 	env := &environment.Environment{Name: r.env, Values: map[string]any(nil)}
 
-	return r.renderTemplatesToYamlWithEnv(baseDir, filename, content, env, nil)
+	return r.renderTemplatesToYamlWithEnv(baseDir, filename, 1, content, env, nil)
 }
 
-func (r *desiredStateLoader) renderTemplatesToYamlWithEnv(baseDir, filename string, content []byte, inherited, overrode *environment.Environment) (*bytes.Buffer, error) {
-	return r.twoPassRenderTemplateToYaml(inherited, overrode, baseDir, filename, content)
+// renderTemplatesToYamlWithEnv renders content, which starts at firstLine of the helmfile being loaded.
+func (r *desiredStateLoader) renderTemplatesToYamlWithEnv(baseDir, filename string, firstLine int, content []byte, inherited, overrode *environment.Environment) (*bytes.Buffer, error) {
+	return r.twoPassRenderTemplateToYaml(inherited, overrode, baseDir, filename, firstLine, content)
 }
 
-func (r *desiredStateLoader) twoPassRenderTemplateToYaml(inherited, overrode *environment.Environment, baseDir, filename string, content []byte) (*bytes.Buffer, error) {
+func (r *desiredStateLoader) twoPassRenderTemplateToYaml(inherited, overrode *environment.Environment, baseDir, filename string, firstLine int, content []byte) (*bytes.Buffer, error) {
 	var phase string
 	r.logger.Debugf("%srendering starting for \"%s\": inherited=%v, overrode=%v", phase, filename, inherited, overrode)
 
@@ -59,6 +62,10 @@ func (r *desiredStateLoader) twoPassRenderTemplateToYaml(inherited, overrode *en
 
 	tmplData := state.NewEnvironmentTemplateData(*finalEnv, r.namespace, vals)
 	renderer := tmpl.NewFileRenderer(r.fs, baseDir, tmplData)
+	renderer.Context.SetSandbox(r.sandbox)
+	if r.renderTrace != nil || r.strict {
+		return r.renderTemplateWithTrace(renderer, firstLine, content)
+	}
 	yamlBuf, err := renderer.RenderTemplateContentToBuffer(content)
 	if err != nil {
 		r.logger.Debugf("%srendering failed, input of \"%s\":\n%s", renderingPhase, filename, prependLineNumbers(string(content)))
@@ -67,3 +74,66 @@ func (r *desiredStateLoader) twoPassRenderTemplateToYaml(inherited, overrode *en
 	r.logger.Debugf("%srendering result of \"%s\":\n%s", renderingPhase, filename, prependLineNumbers(yamlBuf.String()))
 	return yamlBuf, nil
 }
+
+// renderTemplateWithTrace renders content like twoPassRenderTemplateToYaml, and writes
+// the render trace, or the lines around the failing line on error, to the render trace if any.
+// The rendered lines are kept for the strict mode to report the template lines of the unknown keys.
+func (r *desiredStateLoader) renderTemplateWithTrace(renderer *tmpl.FileRenderer, firstLine int, content []byte) (*bytes.Buffer, error) {
+	file := r.includeChain[len(r.includeChain)-1]
+	includedFrom := r.includeChain[:len(r.includeChain)-1]
+
+	yamlBuf, trace, err := renderer.Context.RenderTemplateToBufferWithTrace(string(content), file, firstLine, renderer.Data)
+	if err != nil {
+		if r.renderTrace != nil {
+			r.renderTrace.writeError(includedFrom, file, content, firstLine, trace, err)
+		}
+		return nil, err
+	}
+	if r.renderTrace != nil {
+		r.renderTrace.writeRendered(includedFrom, file, trace)
+	}
+	r.renderedLines = trace.Lines
+	return yamlBuf, nil
+}
+
+// renderHCLToYaml renders the given part of a helmfile written in HCL to YAML.
+// Part 0 is what the environment values are loaded from, and part 1 is the rest
+// of the helmfile, rendered with the environment values.
+func (r *desiredStateLoader) renderHCLToYaml(filename string, content []byte, part int, inherited, overrode *environment.Environment) (*bytes.Buffer, error) {
+	hctx := hcllang.HelmfileContext{Environment: r.env, Namespace: r.namespace}
+
+	if part > 0 {
+		finalEnv, err := inherited.Merge(overrode)
+		if err != nil {
+			return nil, err
+		}
+
+		vals, err := finalEnv.GetMergedValues()
+		if err != nil {
+			return nil, err
+		}
+		if vals == nil {
+			vals = map[string]any{}
+		}
+
+		hctx.Environment = finalEnv.Name
+		hctx.Values = vals
+	}
+
+	hl := hcllang.NewHCLLoader(r.fs, r.logger)
+	hl.SetSandbox(r.sandbox)
+	doc, err := hl.HelmfileRender(filename, content, hctx)
+	if err != nil {
+		return nil, err
+	}
+	if part > 0 {
+		doc = hcllang.WithoutEnvValuesKeys(doc)
+	}
+
+	bs, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	r.logger.Debugf("rendering result of part %d of \"%s\":\n%s", part, filename, prependLineNumbers(string(bs)))
+	return bytes.NewBuffer(bs), nil
+}
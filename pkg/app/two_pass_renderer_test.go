@@ -5,6 +5,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+
 	"github.com/helmfile/helmfile/pkg/helmexec"
 	"github.com/helmfile/helmfile/pkg/remote"
 	"github.com/helmfile/helmfile/pkg/state"
@@ -188,3 +190,93 @@ releases:
 		t.Fatalf("wanted error, none returned")
 	}
 }
+
+func TestLoad_HCLHelmfile(t *testing.T) {
+	files := map[string]string{
+		"/path/to/helmfile.hcl": `
+environment "prod" {
+  values = ["prod.yaml"]
+}
+
+release "api" {
+  chart     = "charts/api"
+  namespace = "app"
+  needs     = [release.postgres]
+  values = [{
+    replicas = values.replicas
+  }]
+}
+
+release "postgres" {
+  chart     = "bitnami/postgresql"
+  namespace = "db"
+  installed = values.db.enabled
+}
+`,
+		"/path/to/prod.yaml": `
+replicas: 3
+db:
+  enabled: true
+`,
+	}
+
+	r, _, _ := makeLoader(files, "prod")
+	r.namespace = ""
+	st, err := r.Load("/path/to/helmfile.hcl", LoadOpts{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(st.Releases) != 2 {
+		t.Fatalf("expected 2 releases, got %d", len(st.Releases))
+	}
+	api, postgres := st.Releases[0], st.Releases[1]
+	if api.Name != "api" || postgres.Name != "postgres" {
+		t.Errorf("unexpected releases: %s, %s", api.Name, postgres.Name)
+	}
+	if d := cmp.Diff([]string{"db/postgres"}, api.Needs); d != "" {
+		t.Errorf("unexpected needs: want (-), got (+): %s", d)
+	}
+	if d := cmp.Diff([]any{map[string]any{"replicas": 3}}, api.Values); d != "" {
+		t.Errorf("unexpected values: want (-), got (+): %s", d)
+	}
+	if !postgres.Desired() {
+		t.Errorf("expected postgres to be installed")
+	}
+	if st.Env.Name != "prod" {
+		t.Errorf("unexpected environment: %s", st.Env.Name)
+	}
+}
+
+func TestLoad_HCLHelmfileWithNamespace(t *testing.T) {
+	files := map[string]string{
+		"/path/to/helmfile.hcl": `
+release "api" {
+  chart = "charts/api"
+  needs = [release.postgres]
+}
+
+release "postgres" {
+  chart = "bitnami/postgresql"
+}
+`,
+	}
+
+	r, _, _ := makeLoader(files, "default")
+	r.namespace = "app"
+	st, err := r.Load("/path/to/helmfile.hcl", LoadOpts{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The release needed gets the namespace of --namespace, like the release needing it
+	if d := cmp.Diff([]string{"postgres"}, st.Releases[0].Needs); d != "" {
+		t.Errorf("unexpected needs: want (-), got (+): %s", d)
+	}
+	api, postgres := st.Releases[0], st.Releases[1]
+	st.ApplyOverrides(&api)
+	st.ApplyOverrides(&postgres)
+	if d := cmp.Diff([]string{state.ReleaseToID(&postgres)}, api.Needs); d != "" {
+		t.Errorf("unexpected needs: want (-), got (+): %s", d)
+	}
+}
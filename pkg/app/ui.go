@@ -0,0 +1,163 @@
+package app
+
+import (
+	"errors"
+
+	"go.uber.org/zap"
+
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/helmfile/helmfile/pkg/tui"
+)
+
+// runUI is the terminal UI of a sync, apply or destroy command, while it's shown.
+type runUI struct {
+	ui *tui.UI
+	// logger is the logger of the app, which is replaced with the one of the log of the UI
+	logger  *zap.SugaredLogger
+	stopped chan struct{}
+}
+
+func uiRelease(r *state.ReleaseSpec) tui.Release {
+	return tui.Release{ID: state.ReleaseToID(r), Name: r.Name, Namespace: r.Namespace, KubeContext: r.KubeContext}
+}
+
+// queue shows the releases as queued.
+func (u *runUI) queue(releases []state.ReleaseSpec) {
+	if u == nil {
+		return
+	}
+	for i := range releases {
+		u.ui.Queue(uiRelease(&releases[i]))
+	}
+}
+
+// unchanged shows the releases as unchanged, as they have nothing to sync or delete.
+func (u *runUI) unchanged(releases map[string]state.ReleaseSpec) {
+	if u == nil {
+		return
+	}
+	for _, r := range releases {
+		u.ui.Set(uiRelease(&r), tui.Unchanged)
+	}
+}
+
+// diffs adds the diffs of the releases, by release ID, to their output.
+func (u *runUI) diffs(releases []state.ReleaseSpec, diffs *state.DiffOutputs) {
+	if u == nil {
+		return
+	}
+	for i := range releases {
+		r := uiRelease(&releases[i])
+		if diff, ok := diffs.Get(r.ID); ok {
+			_, _ = u.ui.Output(r).Write([]byte(diff))
+		}
+	}
+}
+
+// startUI shows the terminal UI of command instead of its plain output, when c enables it.
+// The plain output is kept when stdout isn't a terminal, and when confirmations are asked for.
+func (a *App) startUI(c uiConfig, command string) {
+	if !c.UI() {
+		return
+	}
+	if !tui.Enabled() {
+		a.Logger.Debug("Not showing the ui, as stdout isn't a terminal")
+		return
+	}
+	if approve, ok := c.(interface{ ApproveReleases() bool }); c.Interactive() || ok && approve.ApproveReleases() {
+		a.Logger.Warn("Not showing the ui, as confirmations are asked for")
+		return
+	}
+
+	ui := tui.New(command, !c.NoColor())
+	if err := ui.Start(); err != nil {
+		a.Logger.Warnf("Not showing the ui: %v", err)
+		return
+	}
+	a.ui = &runUI{ui: ui, logger: a.Logger, stopped: make(chan struct{})}
+	a.Logger = helmexec.NewLogger(ui.Log(), a.Logger.Level().String())
+
+	// The terminal is restored when helmfile is interrupted, before it exits
+	if a.ctx != nil {
+		CleanWaitGroup.Add(1)
+		go func(stopped chan struct{}) {
+			defer CleanWaitGroup.Done()
+			select {
+			case <-a.ctx.Done():
+				ui.Stop()
+			case <-stopped:
+			}
+		}(a.ui.stopped)
+	}
+}
+
+// stopUI stops showing the terminal UI, which prints the log of the run and a summary of the releases instead.
+func (a *App) stopUI() {
+	u := a.ui
+	if u == nil {
+		return
+	}
+	a.ui = nil
+
+	u.ui.Stop()
+	close(u.stopped)
+	a.Logger = u.logger
+}
+
+// logger returns the logger of c, or the one of the log of the terminal UI while it's shown.
+func (a *App) logger(c loggingConfig) *zap.SugaredLogger {
+	if a.ui != nil {
+		return a.Logger
+	}
+	return c.Logger()
+}
+
+// watch wraps converge, which syncs or deletes the releases in releases with status, to show
+// them in the terminal UI while they are converged. The output of a release converged on its own
+// is shown with the release, while the one of a group of releases, in lockstep mode, goes to the log.
+func (a *App) watch(status tui.Status, releases map[string]state.ReleaseSpec, converge func(*state.HelmState, helmexec.Interface) (bool, []error)) func(*state.HelmState, helmexec.Interface) (bool, []error) {
+	u := a.ui
+	if u == nil {
+		return converge
+	}
+
+	return func(st *state.HelmState, helm helmexec.Interface) (bool, []error) {
+		var watched []tui.Release
+		for i := range st.Releases {
+			r := uiRelease(&st.Releases[i])
+			if _, ok := releases[r.ID]; ok {
+				watched = append(watched, r)
+				u.ui.Set(r, status)
+			}
+		}
+
+		// st is a copy of the state for the release, whose logger can be replaced
+		if len(watched) == 1 && len(st.Releases) == 1 {
+			logger := helmexec.NewLogger(u.ui.Output(watched[0]), a.Logger.Level().String())
+			st.SetLogger(logger)
+			if swapper, ok := helm.(helmexec.LoggerSwapper); ok {
+				helm = swapper.WithLogger(logger)
+			}
+		}
+
+		processed, errs := converge(st, helm)
+
+		failed := map[string]error{}
+		for _, err := range errs {
+			var releaseErr *state.ReleaseError
+			if errors.As(err, &releaseErr) && releaseErr.ReleaseSpec != nil {
+				failed[state.ReleaseToID(releaseErr.ReleaseSpec)] = err
+			}
+		}
+		for _, r := range watched {
+			err, ok := failed[r.ID]
+			if !ok && len(failed) == 0 {
+				// The errors aren't about a single release
+				err = errors.Join(errs...)
+			}
+			u.ui.Finish(r, err)
+		}
+		return processed, errs
+	}
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/helmfile/vals"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/helmfile/helmfile/pkg/exectest"
+	ffs "github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+)
+
+func TestSyncUIWithoutTerminal(t *testing.T) {
+	files := map[string]string{
+		"/path/to/helmfile.yaml": `
+releases:
+- name: web
+  namespace: app
+  chart: stable/web
+`,
+	}
+
+	valsRuntime, err := vals.New(vals.Options{CacheSize: 32})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	logger := helmexec.NewLogger(&buf, "debug")
+	helm := &exectest.Helm{
+		DiffMutex:     &sync.Mutex{},
+		ChartsMutex:   &sync.Mutex{},
+		ReleasesMutex: &sync.Mutex{},
+	}
+	app := appWithFs(&App{
+		OverrideHelmBinary:              DefaultHelmBinary,
+		fs:                              ffs.DefaultFileSystem(),
+		OverrideKubeContext:             "default",
+		DisableKubeVersionAutoDetection: true,
+		Env:                             "default",
+		Logger:                          logger,
+		helms: map[helmKey]helmexec.Interface{
+			createHelmKey(DefaultHelmBinary, "default"): helm,
+		},
+		valsRuntime: valsRuntime,
+	}, files)
+
+	require.NoError(t, app.Sync(applyConfig{ui: true, skipNeeds: true, logger: logger}))
+
+	// The output of the tests isn't a terminal, where the plain output is kept
+	out := buf.String()
+	assert.Contains(t, out, "Not showing the ui, as stdout isn't a terminal")
+	assert.Contains(t, out, "Updated Releases")
+	assert.Len(t, helm.Releases, 1)
+	assert.Nil(t, app.ui)
+	assert.Same(t, logger, app.Logger)
+}
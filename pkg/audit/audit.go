@@ -0,0 +1,188 @@
+// Package audit records the sync, apply and destroy runs of helmfiles to the
+// sinks configured in the `audit:` block of helmfile.yaml, and reads them back
+// for `helmfile history`:
+//
+//	audit:
+//	  file: .helmfile/audit.jsonl
+//	  webhook:
+//	    url: https://audit.example.com/helmfile
+//	    headers:
+//	      Authorization: Bearer {{ env "AUDIT_TOKEN" }}
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/helmfile/helmfile/pkg/webhook"
+)
+
+// Release actions, as recorded in Release.Action
+const (
+	ActionUpgraded     = "upgraded"
+	ActionReinstalled  = "reinstalled"
+	ActionDeleted      = "deleted"
+	ActionFailed       = "failed"
+	ActionDeleteFailed = "deleteFailed"
+)
+
+// Config is the `audit:` block of helmfile.yaml. Runs are recorded to every sink that is set.
+type Config struct {
+	// File is the JSON Lines file runs are appended to, relative to the helmfile.
+	File string `yaml:"file,omitempty"`
+
+	// Webhook is the HTTP endpoint each run is posted to as JSON.
+	Webhook *webhook.Endpoint `yaml:"webhook,omitempty"`
+}
+
+// IsConfigured reports whether runs are recorded anywhere.
+func (c Config) IsConfigured() bool {
+	return c.File != "" || c.Webhook != nil && c.Webhook.URL != ""
+}
+
+// Record is a sync, apply or destroy run of a helmfile.
+type Record struct {
+	Time            time.Time `json:"time"`
+	Command         string    `json:"command"`
+	User            string    `json:"user"`
+	Helmfile        string    `json:"helmfile"`
+	Commit          string    `json:"commit,omitempty"`
+	Environment     string    `json:"environment"`
+	Selectors       []string  `json:"selectors,omitempty"`
+	Releases        []Release `json:"releases"`
+	DurationSeconds float64   `json:"durationSeconds"`
+	Errors          []string  `json:"errors,omitempty"`
+}
+
+// Release is what a run did to a release.
+type Release struct {
+	Name            string  `json:"name"`
+	Namespace       string  `json:"namespace,omitempty"`
+	KubeContext     string  `json:"kubeContext,omitempty"`
+	Chart           string  `json:"chart,omitempty"`
+	Version         string  `json:"version,omitempty"`
+	Action          string  `json:"action"`
+	DurationSeconds float64 `json:"durationSeconds"`
+
+	// Tracking is what kubedog tracked of the release, when it was tracked with kubedog.
+	Tracking *Tracking `json:"tracking,omitempty"`
+}
+
+// Tracking is what kubedog tracked of a release after it was synced.
+type Tracking struct {
+	Resources int `json:"resources"`
+	// ChangedResources is the number of resources that were observed changing,
+	// unless kubedog tracked the release without comparing it with the resources before the sync.
+	ChangedResources *int    `json:"changedResources,omitempty"`
+	DurationSeconds  float64 `json:"durationSeconds"`
+}
+
+// Failed reports whether the run ended with errors.
+func (r Record) Failed() bool {
+	return len(r.Errors) > 0
+}
+
+// fileMu serializes the writes to the audit files, which may be shared by the helmfiles of a run.
+var fileMu sync.Mutex
+
+// Write records r to every sink of c.
+func Write(c Config, r Record) error {
+	var errs []error
+	if c.File != "" {
+		if err := appendFile(c.File, r); err != nil {
+			errs = append(errs, fmt.Errorf("writing audit file %s: %w", c.File, err))
+		}
+	}
+	if c.Webhook != nil && c.Webhook.URL != "" {
+		if err := post(*c.Webhook, r); err != nil {
+			errs = append(errs, fmt.Errorf("posting to audit webhook: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func appendFile(path string, r Record) error {
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	fileMu.Lock()
+	defer fileMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(bs, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func post(e webhook.Endpoint, r Record) error {
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return e.Post(bs)
+}
+
+// ReadFile reads the runs recorded in the audit file at path, in the order they were recorded.
+func ReadFile(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var records []Record
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; s.Scan(); line++ {
+		if len(bytes.TrimSpace(s.Bytes())) == 0 {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal(s.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		records = append(records, r)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// CurrentUser returns the name of the user running helmfile.
+func CurrentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// GitCommit returns the commit checked out in the git repository containing dir,
+// or an empty string when dir isn't in a git repository.
+func GitCommit(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/helmfile/helmfile/pkg/webhook"
+)
+
+func TestWrite(t *testing.T) {
+	var posted []Record
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		bs, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		var record Record
+		require.NoError(t, json.Unmarshal(bs, &record))
+		posted = append(posted, record)
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), ".helmfile", "audit.jsonl")
+	c := Config{
+		File: file,
+		Webhook: &webhook.Endpoint{
+			URL:     server.URL,
+			Headers: map[string]string{"Authorization": "Bearer token"},
+		},
+	}
+
+	records := []Record{
+		{
+			Time:        time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+			Command:     "sync",
+			User:        "alice",
+			Helmfile:    "helmfile.yaml",
+			Environment: "prod",
+			Releases: []Release{
+				{Name: "web", Namespace: "app", Chart: "stable/web", Version: "1.2.3", Action: ActionUpgraded, DurationSeconds: 3},
+			},
+			DurationSeconds: 4,
+		},
+		{
+			Time:        time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC),
+			Command:     "destroy",
+			User:        "bob",
+			Helmfile:    "helmfile.yaml",
+			Environment: "prod",
+			Releases: []Release{
+				{Name: "web", Namespace: "app", Action: ActionDeleteFailed, DurationSeconds: 1},
+			},
+			DurationSeconds: 1,
+			Errors:          []string{"release web failed"},
+		},
+	}
+	for _, r := range records {
+		require.NoError(t, Write(c, r))
+	}
+
+	read, err := ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, records, read)
+	assert.Equal(t, records, posted)
+	assert.Equal(t, "Bearer token", auth)
+	assert.False(t, read[0].Failed())
+	assert.True(t, read[1].Failed())
+}
+
+func TestWriteWebhookError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "audit.jsonl")
+	err := Write(Config{File: file, Webhook: &webhook.Endpoint{URL: server.URL}}, Record{Command: "sync"})
+	require.EqualError(t, err, "posting to audit webhook: unexpected status 403 Forbidden")
+
+	// The file sink records the run regardless of the webhook
+	read, err := ReadFile(file)
+	require.NoError(t, err)
+	require.Len(t, read, 1)
+
+	// The token in the url isn't leaked into the error
+	err = Write(Config{Webhook: &webhook.Endpoint{URL: "http://127.0.0.1:1/audit?token=SECRET"}}, Record{Command: "sync"})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "SECRET")
+}
+
+func TestReadFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "audit.jsonl")
+	require.NoError(t, os.WriteFile(file, []byte("{\"command\":\"sync\"}\n\nnot json\n"), 0o644))
+
+	_, err := ReadFile(file)
+	require.ErrorContains(t, err, file+":3: ")
+}
@@ -13,6 +13,14 @@ type ApplyOptions struct {
 	Values []string
 	// Concurrency is the maximum number of concurrent helm processes to run
 	Concurrency int
+	// LockstepBatches is true if each group of releases should wait for the previous group to finish entirely
+	LockstepBatches bool
+	// MetricsOutput is the file or Pushgateway URL the metrics of the run are exported to
+	MetricsOutput string
+	// TimingReport prints the time spent in each phase and release of the run, and the critical path through the needs of the releases
+	TimingReport bool
+	// UI shows the releases of the run in a terminal UI, instead of their interleaved output, when stdout is a terminal
+	UI bool
 	// Validate is validate your manifests against the Kubernetes cluster you are currently pointing at. Note that this requires access to a Kubernetes cluster to obtain information necessary for validating, like the list of available API versions
 	Validate bool
 	// Context is the number of lines of context to show around changes
@@ -23,6 +31,8 @@ type ApplyOptions struct {
 	DetailedExitcode bool
 	// StripTrailingCR is true if trailing carriage returns should be stripped during diffing
 	StripTrailingCR bool
+	// ApproveReleases asks whether to apply each changed release, showing its diff, instead of once for all of them
+	ApproveReleases bool
 	// SkipCleanup is true if the cleanup of temporary values files should be skipped
 	SkipCleanup bool
 	// SkipCRDs is true if the CRDs should be skipped
@@ -136,6 +146,26 @@ func (a *ApplyImpl) Concurrency() int {
 	return a.ApplyOptions.Concurrency
 }
 
+// LockstepBatches returns the lockstep batches flag.
+func (a *ApplyImpl) LockstepBatches() bool {
+	return a.ApplyOptions.LockstepBatches
+}
+
+// MetricsOutput returns the metrics output
+func (a *ApplyImpl) MetricsOutput() string {
+	return a.ApplyOptions.MetricsOutput
+}
+
+// TimingReport returns the timing report flag
+func (a *ApplyImpl) TimingReport() bool {
+	return a.ApplyOptions.TimingReport
+}
+
+// UI returns the ui flag
+func (a *ApplyImpl) UI() bool {
+	return a.ApplyOptions.UI
+}
+
 // Context returns the context.
 func (a *ApplyImpl) Context() int {
 	return a.ApplyOptions.Context
@@ -151,6 +181,11 @@ func (a *ApplyImpl) StripTrailingCR() bool {
 	return a.ApplyOptions.StripTrailingCR
 }
 
+// ApproveReleases returns the approve releases flag
+func (a *ApplyImpl) ApproveReleases() bool {
+	return a.ApplyOptions.ApproveReleases
+}
+
 // DiffOutput returns the diff output.
 func (a *ApplyImpl) DiffOutput() string {
 	return a.Output
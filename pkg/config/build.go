@@ -4,6 +4,8 @@ package config
 type BuildOptions struct {
 	// EmbedValues is true if the values should be embedded
 	EmbedValues bool
+	// RenderTrace is true if the template line of each rendered line of the helmfiles should be printed
+	RenderTrace bool
 }
 
 // NewBuildOptions creates a new Apply
@@ -29,3 +31,8 @@ func NewBuildImpl(g *GlobalImpl, b *BuildOptions) *BuildImpl {
 func (b *BuildImpl) EmbedValues() bool {
 	return b.BuildOptions.EmbedValues
 }
+
+// RenderTrace returns the render trace.
+func (b *BuildImpl) RenderTrace() bool {
+	return b.BuildOptions.RenderTrace
+}
@@ -4,6 +4,14 @@ package config
 type DestroyOptions struct {
 	// Concurrency is the maximum number of concurrent helm processes to run, 0 is unlimited
 	Concurrency int
+	// LockstepBatches makes Destroy wait for each group of releases to be deleted entirely before starting the next
+	LockstepBatches bool
+	// MetricsOutput is the file or Pushgateway URL the metrics of the run are exported to
+	MetricsOutput string
+	// TimingReport prints the time spent in each phase and release of the run, and the critical path through the needs of the releases
+	TimingReport bool
+	// UI shows the releases of the run in a terminal UI, instead of their interleaved output, when stdout is a terminal
+	UI bool
 	// SkipCharts makes Destroy skip `withPreparedCharts`
 	SkipCharts bool
 	// Cascade '--cascade' to helmv3 delete, available values: background, foreground, or orphan, default: background
@@ -38,6 +46,26 @@ func (c *DestroyImpl) Concurrency() int {
 	return c.DestroyOptions.Concurrency
 }
 
+// LockstepBatches returns the lockstepBatches flag
+func (c *DestroyImpl) LockstepBatches() bool {
+	return c.DestroyOptions.LockstepBatches
+}
+
+// MetricsOutput returns the metrics output
+func (c *DestroyImpl) MetricsOutput() string {
+	return c.DestroyOptions.MetricsOutput
+}
+
+// TimingReport returns the timing report flag
+func (c *DestroyImpl) TimingReport() bool {
+	return c.DestroyOptions.TimingReport
+}
+
+// UI returns the ui flag
+func (c *DestroyImpl) UI() bool {
+	return c.DestroyOptions.UI
+}
+
 // SkipCharts returns skipCharts flag
 func (c *DestroyImpl) SkipCharts() bool {
 	return c.DestroyOptions.SkipCharts
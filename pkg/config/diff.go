@@ -34,6 +34,8 @@ type DiffOptions struct {
 	SuppressSecrets bool
 	// Concurrency is the concurrency flag
 	Concurrency int
+	// LockstepBatches is true if all the releases should be diffed at once, instead of each one as soon as its needs are diffed
+	LockstepBatches bool
 	// Validate is the validate flag
 	Validate bool
 	// Context is the context flag
@@ -86,6 +88,11 @@ func (t *DiffImpl) Concurrency() int {
 	return t.DiffOptions.Concurrency
 }
 
+// LockstepBatches returns the lockstep batches flag
+func (t *DiffImpl) LockstepBatches() bool {
+	return t.DiffOptions.LockstepBatches
+}
+
 // IncludeNeeds returns the include needs
 func (t *DiffImpl) IncludeNeeds() bool {
 	return t.DiffOptions.IncludeNeeds || t.IncludeTransitiveNeeds()
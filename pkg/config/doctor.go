@@ -34,6 +34,15 @@ type DoctorOptions struct {
 	// Named ReportFormat (not Output) to avoid shadowing DiffOptions.Output
 	// which is the helm-diff plugin format.
 	ReportFormat string
+
+	// DumpPayload is the path the exact post-redaction LLM prompt is written
+	// to. Empty disables the dump.
+	DumpPayload string
+
+	// Source selects the analyzed input: "diff" (default) runs
+	// `helmfile diff`, "template" runs `helmfile template` and reviews the
+	// full rendered manifests without contacting the cluster.
+	Source string
 }
 
 // NewDoctorOptions creates a new DoctorOptions.
@@ -91,3 +100,13 @@ func (t *DoctorImpl) Force() bool {
 func (t *DoctorImpl) DoctorOutput() string {
 	return t.DoctorOptions.ReportFormat
 }
+
+// DumpPayload returns the --dump-payload file path.
+func (t *DoctorImpl) DumpPayload() string {
+	return t.DoctorOptions.DumpPayload
+}
+
+// DoctorSource returns the --source value ("diff" or "template").
+func (t *DoctorImpl) DoctorSource() string {
+	return t.DoctorOptions.Source
+}
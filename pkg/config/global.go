@@ -13,6 +13,7 @@ import (
 	"github.com/helmfile/helmfile/pkg/envvar"
 	"github.com/helmfile/helmfile/pkg/maputil"
 	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/helmfile/helmfile/pkg/tracing"
 )
 
 // GlobalOptions is the global configuration for the Helmfile CLI.
@@ -82,6 +83,12 @@ type GlobalOptions struct {
 	LogOutput io.Writer
 	// SequentialHelmfiles is true if helmfile.d files should be processed sequentially instead of in parallel.
 	SequentialHelmfiles bool
+	// Strict is true if loading helmfiles should fail on the keys that aren't in the schema of helmfile.yaml.
+	Strict bool
+	// TraceEndpoint is the URL of the OTLP/HTTP endpoint the spans of the run are exported to.
+	TraceEndpoint string
+	// TraceFile is the file the spans of the run are written to as JSON.
+	TraceFile string
 }
 
 // Logger returns the logger to use.
@@ -304,6 +311,26 @@ func (g *GlobalImpl) SequentialHelmfiles() bool {
 	return g.GlobalOptions.SequentialHelmfiles
 }
 
+// Strict returns whether to fail on the keys of helmfiles that aren't in the schema of helmfile.yaml
+func (g *GlobalImpl) Strict() bool {
+	return g.GlobalOptions.Strict
+}
+
+// TracingConfig returns where the spans of the run are exported to
+func (g *GlobalImpl) TracingConfig() tracing.Config {
+	c := tracing.Config{
+		Endpoint: g.GlobalOptions.TraceEndpoint,
+		File:     g.GlobalOptions.TraceFile,
+	}
+	if c.Endpoint == "" {
+		c.Endpoint = os.Getenv(envvar.TraceEndpoint)
+	}
+	if c.File == "" {
+		c.File = os.Getenv(envvar.TraceFile)
+	}
+	return c
+}
+
 // Logger returns the logger
 func (g *GlobalImpl) Logger() *zap.SugaredLogger {
 	return g.logger
@@ -0,0 +1,51 @@
+package config
+
+import "fmt"
+
+// HistoryOptions is the options for the history command
+type HistoryOptions struct {
+	// Limit is the maximum number of runs to print, 0 is unlimited
+	Limit int
+	// Output is the output format, json or table
+	Output string
+}
+
+// NewHistoryOptions creates a new HistoryOptions
+func NewHistoryOptions() *HistoryOptions {
+	return &HistoryOptions{}
+}
+
+// HistoryImpl is impl for HistoryOptions
+type HistoryImpl struct {
+	*GlobalImpl
+	*HistoryOptions
+}
+
+// NewHistoryImpl creates a new HistoryImpl
+func NewHistoryImpl(g *GlobalImpl, h *HistoryOptions) *HistoryImpl {
+	return &HistoryImpl{
+		GlobalImpl:     g,
+		HistoryOptions: h,
+	}
+}
+
+// Limit returns the maximum number of runs to print
+func (c *HistoryImpl) Limit() int {
+	return c.HistoryOptions.Limit
+}
+
+// Output returns the output format
+func (c *HistoryImpl) Output() string {
+	return c.HistoryOptions.Output
+}
+
+// ValidateConfig validates the history configuration
+func (c *HistoryImpl) ValidateConfig() error {
+	if c.HistoryOptions.Output != "" && c.HistoryOptions.Output != "json" && c.HistoryOptions.Output != "table" {
+		return fmt.Errorf("invalid output format %q: must be 'json' or 'table'", c.HistoryOptions.Output)
+	}
+	if c.HistoryOptions.Limit < 0 {
+		return fmt.Errorf("invalid limit %d: must be 0 or more", c.HistoryOptions.Limit)
+	}
+	return c.GlobalImpl.ValidateConfig()
+}
@@ -8,6 +8,8 @@ type ListOptions struct {
 	KeepTempDir bool
 	// SkipCharts makes List skip `withPreparedCharts`
 	SkipCharts bool
+	// Live makes List reconcile the releases with the ones installed in the cluster
+	Live bool
 }
 
 // NewListOptions creates a new Apply
@@ -38,3 +40,8 @@ func (c *ListImpl) Output() string {
 func (c *ListImpl) SkipCharts() bool {
 	return c.ListOptions.SkipCharts
 }
+
+// Live returns the live flag
+func (c *ListImpl) Live() bool {
+	return c.ListOptions.Live
+}
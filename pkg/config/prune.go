@@ -0,0 +1,31 @@
+package config
+
+// PruneOptions is the options for the prune command
+type PruneOptions struct {
+	// Confirm makes Prune delete the releases it finds, instead of only showing them
+	Confirm bool
+}
+
+// NewPruneOptions creates a new PruneOptions
+func NewPruneOptions() *PruneOptions {
+	return &PruneOptions{}
+}
+
+// PruneImpl is impl for PruneOptions
+type PruneImpl struct {
+	*GlobalImpl
+	*PruneOptions
+}
+
+// NewPruneImpl creates a new PruneImpl
+func NewPruneImpl(g *GlobalImpl, p *PruneOptions) *PruneImpl {
+	return &PruneImpl{
+		GlobalImpl:   g,
+		PruneOptions: p,
+	}
+}
+
+// Confirm returns the confirm flag
+func (c *PruneImpl) Confirm() bool {
+	return c.PruneOptions.Confirm
+}
@@ -1,7 +1,11 @@
 package config
 
+import "fmt"
+
 // ShowDAGOptions is the options for the build command
 type ShowDAGOptions struct {
+	// Output is the output format: table, dot, mermaid or json
+	Output string
 }
 
 // NewShowDAGOptions creates a new ShowDAGOptions
@@ -22,3 +26,20 @@ func NewShowDAGImpl(g *GlobalImpl, b *ShowDAGOptions) *ShowDAGImpl {
 		ShowDAGOptions: b,
 	}
 }
+
+// Output returns the output format
+func (c *ShowDAGImpl) Output() string {
+	return c.ShowDAGOptions.Output
+}
+
+// ValidateConfig validates the show-dag configuration
+func (c *ShowDAGImpl) ValidateConfig() error {
+	if err := c.GlobalImpl.ValidateConfig(); err != nil {
+		return err
+	}
+	switch c.ShowDAGOptions.Output {
+	case "", "table", "dot", "mermaid", "json":
+		return nil
+	}
+	return fmt.Errorf("invalid output format %q: must be one of table, dot, mermaid or json", c.ShowDAGOptions.Output)
+}
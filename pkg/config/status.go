@@ -1,9 +1,13 @@
 package config
 
+import "fmt"
+
 // StatusOptions is the options for the build command
 type StatusOptions struct {
 	// Concurrency is the concurrent flag
 	Concurrency int
+	// Output is the output format, json or table, instead of the output of helm status
+	Output string
 }
 
 // NewStatusOptions creates a new Apply
@@ -34,3 +38,16 @@ func (s *StatusImpl) IncludeTransitiveNeeds() bool {
 func (s *StatusImpl) Concurrency() int {
 	return s.StatusOptions.Concurrency
 }
+
+// Output returns the output format
+func (s *StatusImpl) Output() string {
+	return s.StatusOptions.Output
+}
+
+// ValidateConfig validates the status configuration
+func (s *StatusImpl) ValidateConfig() error {
+	if s.StatusOptions.Output != "" && s.StatusOptions.Output != "json" && s.StatusOptions.Output != "table" {
+		return fmt.Errorf("invalid output format %q: must be 'json' or 'table'", s.StatusOptions.Output)
+	}
+	return s.GlobalImpl.ValidateConfig()
+}
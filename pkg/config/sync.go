@@ -13,6 +13,14 @@ type SyncOptions struct {
 	Values []string
 	// Concurrency is the concurrency flag
 	Concurrency int
+	// LockstepBatches is the lockstep batches flag
+	LockstepBatches bool
+	// MetricsOutput is the file or Pushgateway URL the metrics of the run are exported to
+	MetricsOutput string
+	// TimingReport prints the time spent in each phase and release of the run, and the critical path through the needs of the releases
+	TimingReport bool
+	// UI shows the releases of the run in a terminal UI, instead of their interleaved output, when stdout is a terminal
+	UI bool
 	// Validate is the validate flag
 	Validate bool
 	// IncludeCRDs is the include crds flag
@@ -115,6 +123,26 @@ func (t *SyncImpl) Concurrency() int {
 	return t.SyncOptions.Concurrency
 }
 
+// LockstepBatches returns the lockstep batches flag
+func (t *SyncImpl) LockstepBatches() bool {
+	return t.SyncOptions.LockstepBatches
+}
+
+// MetricsOutput returns the metrics output
+func (t *SyncImpl) MetricsOutput() string {
+	return t.SyncOptions.MetricsOutput
+}
+
+// TimingReport returns the timing report flag
+func (t *SyncImpl) TimingReport() bool {
+	return t.SyncOptions.TimingReport
+}
+
+// UI returns the ui flag
+func (t *SyncImpl) UI() bool {
+	return t.SyncOptions.UI
+}
+
 // IncludeNeeds returns the include needs
 func (t *SyncImpl) IncludeNeeds() bool {
 	return t.SyncOptions.IncludeNeeds || t.IncludeTransitiveNeeds()
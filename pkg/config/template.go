@@ -48,6 +48,10 @@ type TemplateOptions struct {
 	ShowOnly []string
 	// TemplateArgs are extra args appended to "helm template" (e.g. "--dry-run=server")
 	TemplateArgs string
+	// SnapshotDir is the directory per-release snapshots of the rendered manifests are written to
+	SnapshotDir string
+	// CheckSnapshots compares fresh renders against the snapshots in SnapshotDir instead of writing them
+	CheckSnapshots bool
 }
 
 // NewTemplateOptions creates a new Apply
@@ -172,3 +176,24 @@ func (t *TemplateImpl) TemplateArgs() string {
 func (t *TemplateImpl) EnforceNeedsAreInstalled() bool {
 	return t.TemplateOptions.EnforceNeedsAreInstalled
 }
+
+// SnapshotDir returns the snapshot dir
+func (t *TemplateImpl) SnapshotDir() string {
+	return t.TemplateOptions.SnapshotDir
+}
+
+// CheckSnapshots returns the check snapshots flag
+func (t *TemplateImpl) CheckSnapshots() bool {
+	return t.TemplateOptions.CheckSnapshots
+}
+
+// ValidateConfig validates the template configuration
+func (t *TemplateImpl) ValidateConfig() error {
+	if t.TemplateOptions.CheckSnapshots && t.TemplateOptions.SnapshotDir == "" {
+		return fmt.Errorf("--check-snapshots requires --snapshot-dir")
+	}
+	if t.TemplateOptions.SnapshotDir != "" && (t.TemplateOptions.OutputDir != "" || t.TemplateOptions.OutputDirTemplate != "") {
+		return fmt.Errorf("--snapshot-dir cannot be combined with --output-dir or --output-dir-template")
+	}
+	return t.GlobalImpl.ValidateConfig()
+}
@@ -0,0 +1,31 @@
+package config
+
+// TestStateOptions is the options for the test-state command
+type TestStateOptions struct {
+	// Tests is the list of state test files or glob patterns
+	Tests []string
+}
+
+// NewTestStateOptions creates a new TestStateOptions
+func NewTestStateOptions() *TestStateOptions {
+	return &TestStateOptions{}
+}
+
+// TestStateImpl is impl for TestStateOptions
+type TestStateImpl struct {
+	*GlobalImpl
+	*TestStateOptions
+}
+
+// NewTestStateImpl creates a new TestStateImpl
+func NewTestStateImpl(g *GlobalImpl, t *TestStateOptions) *TestStateImpl {
+	return &TestStateImpl{
+		GlobalImpl:       g,
+		TestStateOptions: t,
+	}
+}
+
+// Tests returns the state test files or glob patterns
+func (t *TestStateImpl) Tests() []string {
+	return t.TestStateOptions.Tests
+}
@@ -20,6 +20,12 @@ type UnittestOptions struct {
 	IncludeNeeds bool
 	// IncludeTransitiveNeeds is the include transitive needs flag
 	IncludeTransitiveNeeds bool
+	// UpdateSnapshot causes helm-unittest to update snapshot files next to the tests
+	UpdateSnapshot bool
+	// ReportFile is the path of the combined report written across all releases
+	ReportFile string
+	// ReportFormat is the format of the combined report: junit or json
+	ReportFormat string
 }
 
 // NewUnittestOptions creates a new UnittestOptions
@@ -71,6 +77,21 @@ func (u *UnittestImpl) DebugPlugin() bool {
 	return u.UnittestOptions.DebugPlugin
 }
 
+// UpdateSnapshot returns the update snapshot flag
+func (u *UnittestImpl) UpdateSnapshot() bool {
+	return u.UnittestOptions.UpdateSnapshot
+}
+
+// ReportFile returns the combined report file path
+func (u *UnittestImpl) ReportFile() string {
+	return u.UnittestOptions.ReportFile
+}
+
+// ReportFormat returns the combined report format
+func (u *UnittestImpl) ReportFormat() string {
+	return u.UnittestOptions.ReportFormat
+}
+
 // SkipCleanup returns the skip clean up
 func (u *UnittestImpl) SkipCleanup() bool {
 	return false
@@ -27,6 +27,8 @@ const (
 	Interactive           = "HELMFILE_INTERACTIVE"
 	RepoRetry             = "HELMFILE_REPO_RETRIES"
 	RenderYaml            = "HELMFILE_RENDER_YAML" // force helmfile.yaml to be rendered as template regardless of extension, expecting "true" lower case
+	TraceEndpoint         = "HELMFILE_TRACE_ENDPOINT"
+	TraceFile             = "HELMFILE_TRACE_FILE"
 
 	// AWSSDKLogLevel controls AWS SDK logging level
 	// Valid values: "off" (default), "minimal", "standard", "verbose", or custom (e.g., "request,response")
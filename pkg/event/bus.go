@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 
 	"github.com/helmfile/helmfile/pkg/environment"
@@ -14,6 +15,7 @@ import (
 	"github.com/helmfile/helmfile/pkg/filesystem"
 	"github.com/helmfile/helmfile/pkg/helmexec"
 	"github.com/helmfile/helmfile/pkg/tmpl"
+	"github.com/helmfile/helmfile/pkg/tracing"
 )
 
 type Hook struct {
@@ -42,6 +44,12 @@ type Bus struct {
 	Env environment.Environment
 	Fs  *filesystem.FileSystem
 
+	// Sandbox denies hooks, which run commands, in sandboxed helmfiles
+	Sandbox *tmpl.Sandbox
+
+	// SpanAttributes are added to the spans of the hooks, like the release the hooks are of
+	SpanAttributes []attribute.KeyValue
+
 	Logger *zap.SugaredLogger
 }
 
@@ -58,6 +66,10 @@ func (bus *Bus) Trigger(evt string, evtErr error, context map[string]any) (bool,
 		return false, fmt.Errorf("%s is active, hooks are disabled", envvar.DisableHooks)
 	}
 
+	if bus.Sandbox != nil && len(bus.Hooks) > 0 {
+		return false, bus.Sandbox.Deny("running hooks")
+	}
+
 	if bus.Runner == nil {
 		bus.Runner = helmexec.ShellRunner{
 			Dir:    bus.BasePath,
@@ -137,7 +149,9 @@ func (bus *Bus) Trigger(evt string, evtErr error, context map[string]any) (bool,
 			}
 		}
 
+		span := tracing.StartSpan("hook "+name, append([]attribute.KeyValue{tracing.Hook.String(name), tracing.Event.String(evt)}, bus.SpanAttributes...)...)
 		bytes, err := bus.Runner.Execute(command, args, map[string]string{}, false)
+		tracing.End(span, err)
 		bus.Logger.Debugf("hook[%s]: %s\n", name, string(bytes))
 		if hook.ShowLogs {
 			prefix := fmt.Sprintf("\nhook[%s] logs | ", evt)
@@ -50,6 +50,14 @@ type Helm struct {
 
 	UpdateDepsCallbacks map[string]func(string) error
 
+	// LiveReleases are the releases ListReleases returns, keyed by the flags joined with spaces
+	LiveReleases map[string][]helmexec.ReleaseInfo
+	// ListReleasesErrors are the errors ListReleases returns, keyed by the flags joined with spaces
+	ListReleasesErrors map[string]error
+	// Statuses are the statuses ReleaseStatusInfo returns, keyed by the release name.
+	// The releases missing in it are not found.
+	Statuses map[string]*helmexec.ReleaseStatusInfo
+
 	DiffMutex     *sync.Mutex
 	ChartsMutex   *sync.Mutex
 	ReleasesMutex *sync.Mutex
@@ -200,6 +208,27 @@ func (helm *Helm) List(context helmexec.HelmContext, filter string, flags ...str
 	}
 	return res, nil
 }
+
+func (helm *Helm) ReleaseStatusInfo(context helmexec.HelmContext, name string, flags ...string) (*helmexec.ReleaseStatusInfo, error) {
+	if strings.Contains(name, "error") {
+		return nil, errors.New("error")
+	}
+	helm.sync(helm.ReleasesMutex, func() {
+		helm.Releases = append(helm.Releases, Release{Name: name, Flags: flags})
+	})
+	status, ok := helm.Statuses[name]
+	if !ok {
+		return nil, errors.New("Error: release: not found")
+	}
+	return status, nil
+}
+func (helm *Helm) ListReleases(context helmexec.HelmContext, flags ...string) ([]helmexec.ReleaseInfo, error) {
+	key := strings.Join(flags, " ")
+	if err, ok := helm.ListReleasesErrors[key]; ok {
+		return nil, err
+	}
+	return helm.LiveReleases[key], nil
+}
 func (helm *Helm) DecryptSecret(context helmexec.HelmContext, name string, flags ...string) (string, error) {
 	return "", nil
 }
@@ -217,7 +246,9 @@ func (helm *Helm) Unittest(name, chart string, flags ...string) error {
 	if strings.Contains(name, "error") {
 		return errors.New("error")
 	}
-	helm.Unittested = append(helm.Unittested, Release{Name: name, Flags: flags})
+	helm.sync(helm.ReleasesMutex, func() {
+		helm.Unittested = append(helm.Unittested, Release{Name: name, Flags: flags})
+	})
 	return nil
 }
 func (helm *Helm) Lint(name, chart string, flags ...string) error {
@@ -30,6 +30,7 @@ type FileSystem struct {
 	WriteFile         func(string, []byte, fs.FileMode) error
 	DeleteFile        func(string) error
 	MkdirTemp         func(string, string) (string, error)
+	MkdirAll          func(string, fs.FileMode) error
 	RemoveAll         func(string) error
 	FileExists        func(string) (bool, error)
 	Glob              func(string) ([]string, error)
@@ -50,6 +51,7 @@ func DefaultFileSystem() *FileSystem {
 		WriteFile:    os.WriteFile,
 		DeleteFile:   os.Remove,
 		MkdirTemp:    os.MkdirTemp,
+		MkdirAll:     os.MkdirAll,
 		RemoveAll:    os.RemoveAll,
 		Glob:         filepath.Glob,
 		Getwd:        os.Getwd,
@@ -119,6 +121,9 @@ func FromFileSystem(params FileSystem) *FileSystem {
 	if params.MkdirTemp != nil {
 		dfs.MkdirTemp = params.MkdirTemp
 	}
+	if params.MkdirAll != nil {
+		dfs.MkdirAll = params.MkdirAll
+	}
 	if params.RemoveAll != nil {
 		dfs.RemoveAll = params.RemoveAll
 	}
@@ -1,14 +1,28 @@
 package hcllang
 
 import (
-	"dario.cat/mergo"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+
 	"github.com/hashicorp/go-cty-funcs/cidr"
+	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/ext/tryfunc"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	ctyyaml "github.com/zclconf/go-cty-yaml"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
 	"github.com/zclconf/go-cty/cty/function"
 	"github.com/zclconf/go-cty/cty/function/stdlib"
+
+	"github.com/helmfile/helmfile/pkg/plugins"
+	"github.com/helmfile/helmfile/pkg/tmpl"
 )
 
+// insecureFunctionsDisabled returns true when the insecure functions like readfile are disabled
+var insecureFunctionsDisabled = tmpl.InsecureFunctionsDisabled
+
 func HCLFunctions(additionnalFunctions map[string]function.Function) (map[string]function.Function, error) {
 	var hclFunctions = map[string]function.Function{
 		"abs":             stdlib.AbsoluteFunc,
@@ -77,11 +91,177 @@ func HCLFunctions(additionnalFunctions map[string]function.Function) (map[string
 		"zipmap":          stdlib.ZipmapFunc,
 	}
 
-	if additionnalFunctions != nil {
-		err := mergo.MapWithOverwrite(hclFunctions, additionnalFunctions)
+	maps.Copy(hclFunctions, additionnalFunctions)
+	return hclFunctions, nil
+}
+
+// functions returns the functions to evaluate the expressions of the file filename with.
+// Relative paths given to the functions reading files are resolved from the directory of filename.
+func (hl *HCLLoader) functions(filename string) (map[string]function.Function, error) {
+	dir := filepath.Dir(filename)
+	if fns, ok := hl.functionsByDir[dir]; ok {
+		return fns, nil
+	}
+
+	fns, err := HCLFunctions(hl.helmfileFunctions(dir))
+	if err != nil {
+		return nil, err
+	}
+	// Templates can't render other templates
+	templateFns := maps.Clone(fns)
+	fns["templatefile"] = hl.templateFileFunc(dir, templateFns)
+
+	if hl.functionsByDir == nil {
+		hl.functionsByDir = map[string]map[string]function.Function{}
+	}
+	hl.functionsByDir[dir] = fns
+	return fns, nil
+}
+
+// helmfileFunctions returns the functions accessing files, environment variables and secrets.
+func (hl *HCLLoader) helmfileFunctions(dir string) map[string]function.Function {
+	return map[string]function.Function{
+		"file": function.New(&function.Spec{
+			Params: []function.Parameter{{Name: "path", Type: cty.String}},
+			Type:   function.StaticReturnType(cty.String),
+			Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+				content, err := hl.readFile(dir, args[0].AsString())
+				if err != nil {
+					return cty.UnknownVal(cty.String), err
+				}
+				return cty.StringVal(string(content)), nil
+			},
+		}),
+		"fileexists": function.New(&function.Spec{
+			Params: []function.Parameter{{Name: "path", Type: cty.String}},
+			Type:   function.StaticReturnType(cty.Bool),
+			Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+				path := resolvePath(dir, args[0].AsString())
+				if err := hl.sandbox.CheckPath(hl.fs, path); err != nil {
+					return cty.UnknownVal(cty.Bool), err
+				}
+				return cty.BoolVal(hl.fs.FileExistsAt(path)), nil
+			},
+		}),
+		"env": function.New(&function.Spec{
+			Params: []function.Parameter{{Name: "name", Type: cty.String}},
+			Type:   function.StaticReturnType(cty.String),
+			Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+				name := args[0].AsString()
+				if err := hl.sandbox.CheckEnv(name); err != nil {
+					return cty.UnknownVal(cty.String), err
+				}
+				return cty.StringVal(os.Getenv(name)), nil
+			},
+		}),
+		"requiredenv": function.New(&function.Spec{
+			Params: []function.Parameter{{Name: "name", Type: cty.String}},
+			Type:   function.StaticReturnType(cty.String),
+			Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+				name := args[0].AsString()
+				if err := hl.sandbox.CheckEnv(name); err != nil {
+					return cty.UnknownVal(cty.String), err
+				}
+				val, err := tmpl.RequiredEnv(name)
+				if err != nil {
+					return cty.UnknownVal(cty.String), err
+				}
+				return cty.StringVal(val), nil
+			},
+		}),
+		"secret": function.New(&function.Spec{
+			Params: []function.Parameter{{Name: "ref", Type: cty.String}},
+			Type:   function.StaticReturnType(cty.String),
+			Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+				if err := hl.sandbox.CheckRefs(hl.fs, args[0].AsString()); err != nil {
+					return cty.UnknownVal(cty.String), err
+				}
+				val, err := hl.fetchSecretValue(args[0].AsString())
+				if err != nil {
+					return cty.UnknownVal(cty.String), err
+				}
+				return cty.StringVal(val), nil
+			},
+		}),
+	}
+}
+
+// templateFileFunc returns the templatefile function, which renders a file as a HCL
+// template with the given variables and the functions fns.
+func (hl *HCLLoader) templateFileFunc(dir string, fns map[string]function.Function) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "path", Type: cty.String},
+			{Name: "vars", Type: cty.DynamicPseudoType},
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			path := args[0].AsString()
+			vars := args[1]
+			if !vars.Type().IsObjectType() && !vars.Type().IsMapType() {
+				return cty.UnknownVal(cty.String), fmt.Errorf("invalid vars: an object or a map is required, but got %s", vars.Type().FriendlyName())
+			}
+
+			content, err := hl.readFile(dir, path)
+			if err != nil {
+				return cty.UnknownVal(cty.String), err
+			}
+			expr, diags := hclsyntax.ParseTemplate(content, path, hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				return cty.UnknownVal(cty.String), diags
+			}
+
+			ctx := &hcl.EvalContext{
+				Variables: vars.AsValueMap(),
+				Functions: fns,
+			}
+			val, diags := expr.Value(ctx)
+			if diags.HasErrors() {
+				return cty.UnknownVal(cty.String), diags
+			}
+			val, err = convert.Convert(val, cty.String)
+			if err != nil {
+				return cty.UnknownVal(cty.String), fmt.Errorf("template %s didn't render to a string: %v", path, err)
+			}
+			return val, nil
+		},
+	})
+}
+
+func (hl *HCLLoader) readFile(dir, path string) ([]byte, error) {
+	if insecureFunctionsDisabled() {
+		return nil, tmpl.DisableInsecureFunctionsErr
+	}
+	path = resolvePath(dir, path)
+	if err := hl.sandbox.CheckPath(hl.fs, path); err != nil {
+		return nil, err
+	}
+	return hl.fs.ReadFile(path)
+}
+
+func (hl *HCLLoader) fetchSecretValue(ref string) (string, error) {
+	if hl.valsRuntime == nil {
+		valsRuntime, err := plugins.ValsInstance()
 		if err != nil {
-			return nil, err
+			return "", err
 		}
+		hl.valsRuntime = valsRuntime
 	}
-	return hclFunctions, nil
+
+	result, err := hl.valsRuntime.Eval(map[string]any{"key": ref})
+	if err != nil {
+		return "", err
+	}
+	val, ok := result["key"].(string)
+	if !ok {
+		return "", fmt.Errorf("expected %v to be string", result["key"])
+	}
+	return val, nil
+}
+
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
 }
@@ -0,0 +1,453 @@
+package hcllang
+
+import (
+	nativejson "encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/variantdev/dag/pkg/dag"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/json"
+
+	"github.com/helmfile/helmfile/pkg/maputil"
+)
+
+const (
+	ReleaseBlockIdentifier     = "release"
+	RepositoryBlockIdentifier  = "repository"
+	EnvironmentBlockIdentifier = "environment"
+	// helmfileBlockType is the block type passed to createDAGGraph for the
+	// blocks of a helmfile, whose nodes are named like `release.foo`.
+	helmfileBlockType      = "helmfile"
+	helmfileAccessorPrefix = "helmfile"
+	envValuesAccessor      = "values"
+)
+
+// helmfileBlockKeys maps the block types of a helmfile to the keys of the
+// helmfile document they are rendered to.
+var helmfileBlockKeys = map[string]string{
+	ReleaseBlockIdentifier:     "releases",
+	RepositoryBlockIdentifier:  "repositories",
+	EnvironmentBlockIdentifier: "environments",
+}
+
+// envValuesKeys are the keys of the helmfile document the environment values are loaded from.
+var envValuesKeys = []string{"environments", "bases", "values"}
+
+// HelmfileContext is the context a helmfile written in HCL is rendered in.
+type HelmfileContext struct {
+	// Environment is the name of the environment, accessible via `helmfile.environment`.
+	Environment string
+	// Namespace is the namespace given by --namespace, accessible via `helmfile.namespace`.
+	Namespace string
+	// Values are the environment values, accessible via `values`.
+	// When nil, the environment values are not known yet, and only the parts of the helmfile
+	// the environment values are loaded from are rendered: the environment blocks,
+	// and the `bases` and `values` attributes. They can't refer to `values`.
+	Values map[string]any
+}
+
+// helmfileBlock is a release, repository or environment block of a helmfile.
+type helmfileBlock struct {
+	Type  string
+	Name  string
+	Attrs hcl.Attributes
+	Range hcl.Range
+}
+
+// blockExpr is the expression of a helmfileBlock.
+// It evaluates to an object of the attributes of the block, including its name.
+type blockExpr struct {
+	block *helmfileBlock
+}
+
+func (e *blockExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	attrs := map[string]cty.Value{"name": cty.StringVal(e.block.Name)}
+	for name, attr := range e.block.Attrs {
+		v, d := attr.Expr.Value(ctx)
+		diags = append(diags, d...)
+		attrs[name] = v
+	}
+	return cty.ObjectVal(attrs), diags
+}
+
+func (e *blockExpr) Variables() []hcl.Traversal {
+	var traversals []hcl.Traversal
+	for _, attr := range e.block.Attrs {
+		traversals = append(traversals, attr.Expr.Variables()...)
+	}
+	return traversals
+}
+
+func (e *blockExpr) Range() hcl.Range {
+	return e.block.Range
+}
+
+func (e *blockExpr) StartRange() hcl.Range {
+	return e.block.Range
+}
+
+// HelmfileRender renders the helmfile written in HCL in src to a document
+// with the same structure as a helmfile written in YAML.
+//
+// Releases, repositories and environments are written as `release`, `repository` and
+// `environment` blocks labeled with their names, whose attributes are the keys of
+// the corresponding YAML entries. Blocks can refer to each other like `release.foo.namespace`
+// and to locals like `local.foo`, and are evaluated in the order of their references.
+// A release in `needs` is replaced by its ID. The other keys of the document are
+// top-level attributes.
+func (hl *HCLLoader) HelmfileRender(file string, src []byte, hctx HelmfileContext) (map[string]any, error) {
+	blocks, nodes, attrs, diags := hl.readHelmfileHCL(file, src)
+	if len(diags) > 0 {
+		return nil, diags.Errs()[0]
+	}
+
+	dagPlan, err := hl.createDAGGraph(nodes, helmfileBlockType)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, evaluated, err := hl.decodeHelmfileGraph(file, dagPlan, nodes, hctx)
+	if err != nil {
+		return nil, err
+	}
+
+	known := hctx.Values != nil
+
+	doc := map[string]any{}
+	for name, attr := range attrs {
+		if !known && name != "bases" && name != "values" {
+			continue
+		}
+		v, diags := attr.Expr.Value(ctx)
+		if len(diags) > 0 {
+			return nil, fmt.Errorf("error when trying to evaluate attribute %s : %s", name, diags.Errs()[0])
+		}
+		if !v.IsWhollyKnown() {
+			return nil, fmt.Errorf("attribute %s at %s:%d can't refer to environment values, because they are loaded from it", name, attr.Range.Filename, attr.Range.Start.Line)
+		}
+		doc[name], err = ctyToGo(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	environments := map[string]any{}
+	var releases, repositories []any
+	for _, b := range blocks {
+		if !known && b.Type != EnvironmentBlockIdentifier {
+			continue
+		}
+		v := evaluated[b.Type][b.Name]
+		if !v.IsWhollyKnown() {
+			return nil, fmt.Errorf("%s %q at %s:%d can't refer to environment values, because they are loaded from it", b.Type, b.Name, b.Range.Filename, b.Range.Start.Line)
+		}
+		if b.Type == ReleaseBlockIdentifier {
+			v, err = releaseWithNeedIDs(v)
+			if err != nil {
+				return nil, fmt.Errorf("release %q at %s:%d: %v", b.Name, b.Range.Filename, b.Range.Start.Line, err)
+			}
+		}
+		entry, err := ctyToGo(v)
+		if err != nil {
+			return nil, err
+		}
+		switch b.Type {
+		case ReleaseBlockIdentifier:
+			releases = append(releases, entry)
+		case RepositoryBlockIdentifier:
+			repositories = append(repositories, entry)
+		case EnvironmentBlockIdentifier:
+			// Environments are keyed by their names instead
+			envSpec := entry.(map[string]any)
+			delete(envSpec, "name")
+			environments[b.Name] = envSpec
+		}
+	}
+	if len(environments) > 0 {
+		doc[helmfileBlockKeys[EnvironmentBlockIdentifier]] = environments
+	}
+	if len(releases) > 0 {
+		doc[helmfileBlockKeys[ReleaseBlockIdentifier]] = releases
+	}
+	if len(repositories) > 0 {
+		doc[helmfileBlockKeys[RepositoryBlockIdentifier]] = repositories
+	}
+
+	return doc, nil
+}
+
+// WithoutEnvValuesKeys returns doc without the keys the environment values are loaded from,
+// which are rendered by HelmfileRender even when the environment values are not known.
+func WithoutEnvValuesKeys(doc map[string]any) map[string]any {
+	rest := map[string]any{}
+	for k, v := range doc {
+		if !slices.Contains(envValuesKeys, k) {
+			rest[k] = v
+		}
+	}
+	return rest
+}
+
+// readHelmfileHCL parses a helmfile written in HCL.
+// It returns the release, repository and environment blocks in the order of their definitions,
+// the nodes of the blocks and the locals keyed by their references, and the top-level attributes.
+func (hl *HCLLoader) readHelmfileHCL(file string, src []byte) ([]*helmfileBlock, map[string]*HelmfileHCLValue, hcl.Attributes, hcl.Diagnostics) {
+	p := hclparse.NewParser()
+	hclFile, diags := p.ParseHCL(src, file)
+	if hclFile == nil || hclFile.Body == nil || diags != nil {
+		return nil, nil, nil, diags
+	}
+
+	schema := &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{
+				Type:       ReleaseBlockIdentifier,
+				LabelNames: []string{"name"},
+			},
+			{
+				Type:       RepositoryBlockIdentifier,
+				LabelNames: []string{"name"},
+			},
+			{
+				Type:       EnvironmentBlockIdentifier,
+				LabelNames: []string{"name"},
+			},
+			{
+				Type: LocalsBlockIdentifier,
+			},
+		},
+	}
+	// Any top-level attribute is a key of the helmfile
+	if body, ok := hclFile.Body.(*hclsyntax.Body); ok {
+		for name := range body.Attributes {
+			schema.Attributes = append(schema.Attributes, hcl.AttributeSchema{Name: name})
+		}
+	}
+	content, diags := hclFile.Body.Content(schema)
+	if diags != nil {
+		return nil, nil, nil, diags
+	}
+
+	attrs := content.Attributes
+	for name, attr := range attrs {
+		for blockType, key := range helmfileBlockKeys {
+			if name == key {
+				return nil, nil, nil, hcl.Diagnostics{
+					&hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  fmt.Sprintf("Unsupported attribute %q", name),
+						Detail:   fmt.Sprintf("Define each of the %s with a `%s` block instead.", key, blockType),
+						Subject:  &attr.NameRange,
+					}}
+			}
+		}
+	}
+
+	if len(content.Blocks.OfType(LocalsBlockIdentifier)) > 1 {
+		return nil, nil, nil, hcl.Diagnostics{
+			&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "A file can only support exactly 1 `locals` block",
+				Subject:  &content.Blocks.OfType(LocalsBlockIdentifier)[1].DefRange,
+			}}
+	}
+
+	var blocks []*helmfileBlock
+	nodes := map[string]*HelmfileHCLValue{}
+	for _, block := range content.Blocks {
+		if block.Type == LocalsBlockIdentifier {
+			locals, diags := hl.decodeHelmfileHCLValuesBlock(block)
+			if diags != nil {
+				return nil, nil, nil, diags
+			}
+			for name, local := range locals {
+				local.Name = localsAccessorPrefix + "." + name
+				nodes[local.Name] = local
+			}
+			continue
+		}
+
+		attrs, diags := block.Body.JustAttributes()
+		if diags != nil {
+			return nil, nil, nil, diags
+		}
+		if attr, ok := attrs["name"]; ok {
+			return nil, nil, nil, hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Unsupported attribute \"name\"",
+					Detail:   fmt.Sprintf("The name of a %s is the label of its block.", block.Type),
+					Subject:  &attr.NameRange,
+				}}
+		}
+
+		b := &helmfileBlock{
+			Type:  block.Type,
+			Name:  block.Labels[0],
+			Attrs: attrs,
+			Range: block.DefRange,
+		}
+		name := b.Type + "." + b.Name
+		if existing, ok := nodes[name]; ok {
+			return nil, nil, nil, hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  fmt.Sprintf("Duplicate %s %q", b.Type, b.Name),
+					Detail:   fmt.Sprintf("The %s %q was already defined at %s:%d.", b.Type, b.Name, existing.Range.Filename, existing.Range.Start.Line),
+					Subject:  &block.DefRange,
+				}}
+		}
+		blocks = append(blocks, b)
+		nodes[name] = &HelmfileHCLValue{
+			Name:  name,
+			Expr:  &blockExpr{block: b},
+			Range: block.DefRange,
+		}
+	}
+
+	return blocks, nodes, attrs, nil
+}
+
+// decodeHelmfileGraph evaluates the nodes of a helmfile in the order of the DAG.
+// It returns the context to evaluate the top-level attributes in,
+// and the evaluated nodes keyed by the type and the name of their blocks.
+func (hl *HCLLoader) decodeHelmfileGraph(file string, dagTopology *dag.Topology, nodes map[string]*HelmfileHCLValue, hctx HelmfileContext) (*hcl.EvalContext, map[string]map[string]cty.Value, error) {
+	hclFunctions, err := hl.functions(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	envValues := cty.DynamicVal
+	if hctx.Values != nil {
+		envValues, err = goToCty(hctx.Values)
+		if err != nil {
+			return nil, nil, fmt.Errorf("environment values: %v", err)
+		}
+	}
+
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			envValuesAccessor: envValues,
+			helmfileAccessorPrefix: cty.ObjectVal(map[string]cty.Value{
+				"environment": cty.StringVal(hctx.Environment),
+				"namespace":   cty.StringVal(hctx.Namespace),
+			}),
+		},
+		Functions: hclFunctions,
+	}
+
+	evaluated := map[string]map[string]cty.Value{}
+	for _, root := range []string{localsAccessorPrefix, ReleaseBlockIdentifier, RepositoryBlockIdentifier, EnvironmentBlockIdentifier} {
+		evaluated[root] = map[string]cty.Value{}
+		ctx.Variables[root] = cty.EmptyObjectVal
+	}
+
+	for groupIndex := 0; groupIndex < len(*dagTopology); groupIndex++ {
+		for _, node := range (*dagTopology)[groupIndex] {
+			v := nodes[node.String()]
+			val, diags := v.Expr.Value(ctx)
+			if len(diags) > 0 {
+				return nil, nil, fmt.Errorf("error when trying to evaluate %s at %s:%d : %s", v.Name, v.Range.Filename, v.Range.Start.Line, diags.Errs()[0])
+			}
+			root, name, _ := strings.Cut(v.Name, ".")
+			evaluated[root][name] = val
+			// Update the eval context for the next evaluation iteration
+			ctx.Variables[root] = cty.ObjectVal(evaluated[root])
+		}
+	}
+
+	return ctx, evaluated, nil
+}
+
+// releaseWithNeedIDs returns the release r with the releases in its needs replaced by their IDs.
+func releaseWithNeedIDs(r cty.Value) (cty.Value, error) {
+	attrs := r.AsValueMap()
+	needs, ok := attrs["needs"]
+	if !ok || needs.IsNull() {
+		return r, nil
+	}
+	if !needs.CanIterateElements() {
+		return cty.NilVal, fmt.Errorf("needs must be a list, but got %s", needs.Type().FriendlyName())
+	}
+
+	var ids []cty.Value
+	for it := needs.ElementIterator(); it.Next(); {
+		_, need := it.Element()
+		switch {
+		case need.Type() == cty.String:
+			ids = append(ids, need)
+		case need.Type().IsObjectType() && need.Type().HasAttribute("name"):
+			ids = append(ids, cty.StringVal(releaseNeedID(need)))
+		default:
+			return cty.NilVal, fmt.Errorf("needs must contain releases or release IDs, but got %s", need.Type().FriendlyName())
+		}
+	}
+	if len(ids) == 0 {
+		attrs["needs"] = cty.ListValEmpty(cty.String)
+	} else {
+		attrs["needs"] = cty.ListVal(ids)
+	}
+	return cty.ObjectVal(attrs), nil
+}
+
+// releaseNeedID returns the ID to need the release r with.
+// A release without namespace nor kube context is needed by its name, like
+// `needs: [bar]` in YAML, so that it gets the namespace given by --namespace.
+func releaseNeedID(r cty.Value) string {
+	str := func(name string) string {
+		if !r.Type().HasAttribute(name) {
+			return ""
+		}
+		v := r.GetAttr(name)
+		if v.IsNull() || v.Type() != cty.String {
+			return ""
+		}
+		return v.AsString()
+	}
+
+	namespace, kubeContext := str("namespace"), str("kubeContext")
+	switch {
+	case kubeContext != "":
+		return kubeContext + "/" + namespace + "/" + str("name")
+	case namespace != "":
+		return namespace + "/" + str("name")
+	default:
+		return str("name")
+	}
+}
+
+func goToCty(v map[string]any) (cty.Value, error) {
+	stringified, err := maputil.RecursivelyStringifyMapKey(v)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	b, err := nativejson.Marshal(stringified)
+	if err != nil {
+		return cty.NilVal, fmt.Errorf("could not marshal json : %s", err.Error())
+	}
+	t, err := json.ImpliedType(b)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return json.Unmarshal(b, t)
+}
+
+func ctyToGo(v cty.Value) (any, error) {
+	// Same workaround as in convertToGo
+	b, err := json.Marshal(v, cty.DynamicPseudoType)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal cty value : %s", err.Error())
+	}
+
+	var jsonunm map[string]any
+	if err := nativejson.Unmarshal(b, &jsonunm); err != nil {
+		return nil, fmt.Errorf("could not unmarshall json : %s", err.Error())
+	}
+	return jsonunm["value"], nil
+}
@@ -0,0 +1,175 @@
+package hcllang
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHCL_HelmfileRender(t *testing.T) {
+	l := newHCLLoader()
+	src, err := l.fs.ReadFile("testdata/helmfile.hcl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := l.HelmfileRender("testdata/helmfile.hcl", src, HelmfileContext{
+		Environment: "prod",
+		Values:      map[string]any{"replicas": 3},
+	})
+	if err != nil {
+		t.Fatalf("Render error: %s", err.Error())
+	}
+
+	expected := map[string]any{
+		"helmDefaults": map[string]any{"wait": true},
+		"environments": map[string]any{
+			"default": map[string]any{
+				"values": []any{"default.yaml"},
+			},
+			"prod": map[string]any{
+				"values":      []any{"prod.yaml", map[string]any{"replicas": float64(3)}},
+				"kubeContext": "prod",
+			},
+		},
+		"repositories": []any{
+			map[string]any{"name": "bitnami", "url": "https://charts.bitnami.com/bitnami"},
+		},
+		"releases": []any{
+			map[string]any{
+				"name":      "api",
+				"namespace": "app",
+				"chart":     "./charts/api",
+				"needs":     []any{"db/postgres", "app/cache"},
+				"values": []any{map[string]any{
+					"database": "postgres.db",
+					"replicas": float64(3),
+				}},
+			},
+			map[string]any{
+				"name":      "postgres",
+				"namespace": "db",
+				"chart":     "bitnami/postgresql",
+				"installed": true,
+			},
+		},
+	}
+
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestHCL_HelmfileRender_UnknownValues(t *testing.T) {
+	l := newHCLLoader()
+	src, err := l.fs.ReadFile("testdata/helmfile.hcl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := l.HelmfileRender("testdata/helmfile.hcl", src, HelmfileContext{Environment: "prod"})
+	if err != nil {
+		t.Fatalf("Render error: %s", err.Error())
+	}
+
+	expected := map[string]any{
+		"environments": map[string]any{
+			"default": map[string]any{
+				"values": []any{"default.yaml"},
+			},
+			"prod": map[string]any{
+				"values":      []any{"prod.yaml", map[string]any{"replicas": float64(3)}},
+				"kubeContext": "prod",
+			},
+		},
+	}
+
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestHCL_HelmfileRender_Errors(t *testing.T) {
+	testcases := []struct {
+		name string
+		src  string
+		err  string
+	}{
+		{
+			name: "undefined release",
+			src: `
+release "api" {
+  chart = "./charts/api"
+  needs = [release.db]
+}
+`,
+			err: `variables "release.api" depend(s) on undefined vars "release.db"`,
+		},
+		{
+			name: "cycle",
+			src: `
+release "api" {
+  chart = "./charts/api"
+  needs = [release.db]
+}
+release "db" {
+  chart = "./charts/db"
+  needs = [release.api]
+}
+`,
+			err: "error while building the DAG variable graph",
+		},
+		{
+			name: "environment referring to environment values",
+			src: `
+environment "default" {
+  values = [values.files]
+}
+`,
+			err: `environment "default" at helmfile.hcl:2 can't refer to environment values, because they are loaded from it`,
+		},
+		{
+			name: "duplicate release",
+			src: `
+release "api" {
+  chart = "./charts/api"
+}
+release "api" {
+  chart = "./charts/api"
+}
+`,
+			err: `Duplicate release "api"`,
+		},
+		{
+			name: "name attribute",
+			src: `
+release "api" {
+  name  = "api"
+  chart = "./charts/api"
+}
+`,
+			err: `Unsupported attribute "name"`,
+		},
+		{
+			name: "releases attribute",
+			src: `
+releases = []
+`,
+			err: `Unsupported attribute "releases"`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := newHCLLoader()
+			_, err := l.HelmfileRender("helmfile.hcl", []byte(tc.src), HelmfileContext{})
+			if err == nil {
+				t.Fatalf("expected error %q, got none", tc.err)
+			}
+			if !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected error containing %q, got %q", tc.err, err.Error())
+			}
+		})
+	}
+}
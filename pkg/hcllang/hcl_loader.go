@@ -10,12 +10,15 @@ import (
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/helmfile/vals"
 	"github.com/variantdev/dag/pkg/dag"
 	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
 	"github.com/zclconf/go-cty/cty/json"
 	"go.uber.org/zap"
 
 	"github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/tmpl"
 )
 
 const (
@@ -90,6 +93,9 @@ type HCLLoader struct {
 	fs              *filesystem.FileSystem
 	logger          *zap.SugaredLogger
 	allVariableDefs map[string][]*HelmfileHCLValue // Track all definitions for merging
+	valsRuntime     vals.Evaluator                 // Lazily initialized by the secret function
+	sandbox         *tmpl.Sandbox
+	functionsByDir  map[string]map[string]function.Function
 }
 
 func NewHCLLoader(fs *filesystem.FileSystem, logger *zap.SugaredLogger) *HCLLoader {
@@ -98,6 +104,12 @@ func NewHCLLoader(fs *filesystem.FileSystem, logger *zap.SugaredLogger) *HCLLoad
 		logger: logger,
 	}
 }
+
+// SetSandbox restricts the functions accessing files and environment variables with the sandbox, unless it is nil
+func (hl *HCLLoader) SetSandbox(sandbox *tmpl.Sandbox) {
+	hl.sandbox = sandbox
+}
+
 func (hl *HCLLoader) AddFile(file string) {
 	hl.hclFilesPath = append(hl.hclFilesPath, file)
 }
@@ -226,10 +238,6 @@ func (hl *HCLLoader) decodeGraph(dagTopology *dag.Topology, blocktype string, va
 	values := map[string]cty.Value{}
 	helmfileHCLValuesValues := map[string]cty.Value{}
 	var diags hcl.Diagnostics
-	hclFunctions, err := HCLFunctions(nil)
-	if err != nil {
-		return nil, err
-	}
 	for groupIndex := 0; groupIndex < len(*dagTopology); groupIndex++ {
 		dagNodesInGroup := (*dagTopology)[groupIndex]
 
@@ -238,6 +246,10 @@ func (hl *HCLLoader) decodeGraph(dagTopology *dag.Topology, blocktype string, va
 			if blocktype != LocalsBlockIdentifier && additionalLocalContext[v.Range.Filename] != nil {
 				values[localsAccessorPrefix] = additionalLocalContext[v.Range.Filename][localsAccessorPrefix]
 			}
+			hclFunctions, err := hl.functions(v.Range.Filename)
+			if err != nil {
+				return nil, err
+			}
 			ctx := &hcl.EvalContext{
 				Variables: values,
 				Functions: hclFunctions,
@@ -257,6 +269,10 @@ func (hl *HCLLoader) decodeGraph(dagTopology *dag.Topology, blocktype string, va
 							// Ensure locals from a previous definition/file do not leak into this evaluation
 							ctx.Variables[localsAccessorPrefix] = cty.NilVal
 						}
+						ctx.Functions, err = hl.functions(varDef.Range.Filename)
+						if err != nil {
+							return nil, err
+						}
 						evalValue, evalDiags := varDef.Expr.Value(ctx)
 						if len(evalDiags) > 0 {
 							return nil, fmt.Errorf("error when trying to evaluate variable %s at %s:%d : %s",
@@ -469,6 +485,13 @@ func (hl *HCLLoader) parseSingleAttrRef(traversal hcl.Traversal, blockType strin
 		}
 	}
 	root := traversal.RootName()
+	// In helmfiles, the environment values and the helmfile are known before any block is evaluated,
+	// and the references to anything else than blocks and locals are reported on evaluation
+	if blockType == helmfileBlockType {
+		if _, ok := helmfileBlockKeys[root]; !ok && root != localsAccessorPrefix {
+			return "", nil
+		}
+	}
 	// In `values` blocks, Locals are always precomputed, so they don't need to be in the graph
 	if root == localsAccessorPrefix && blockType != LocalsBlockIdentifier {
 		return "", nil
@@ -487,6 +510,9 @@ func (hl *HCLLoader) parseSingleAttrRef(traversal hcl.Traversal, blockType strin
 	}
 
 	if attrTrav, ok := traversal[1].(hcl.TraverseAttr); ok {
+		if blockType == helmfileBlockType {
+			return root + "." + attrTrav.Name, nil
+		}
 		return attrTrav.Name, nil
 	}
 
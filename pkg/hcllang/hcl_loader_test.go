@@ -2,6 +2,7 @@ package hcllang
 
 import (
 	"io"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -9,6 +10,7 @@ import (
 
 	ffs "github.com/helmfile/helmfile/pkg/filesystem"
 	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/tmpl"
 )
 
 func newHCLLoader() *HCLLoader {
@@ -568,3 +570,59 @@ func TestHCL_CIDRFunctions(t *testing.T) {
 		t.Error(diff)
 	}
 }
+
+func TestHCL_HelmfileFunctions(t *testing.T) {
+	t.Setenv("HCL_TEST_NAME", "helmfile")
+
+	l := newHCLLoader()
+	l.AddFiles([]string{"testdata/functions.hcl"})
+
+	actual, err := l.HCLRender()
+	if err != nil {
+		t.Fatalf("Render error: %s", err.Error())
+	}
+
+	expected := map[string]any{
+		"content": "hello",
+		"exists":  true,
+		"missing": false,
+		"decoded": map[string]any{
+			"replicas": float64(2),
+			"image":    map[string]any{"tag": "v1"},
+		},
+		"rendered": "Hello, helmfile!",
+		"required": "helmfile",
+		"unset":    "",
+		"secret":   "secret-value",
+	}
+
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestHCL_HelmfileFunctions_RequiredEnvNotSet(t *testing.T) {
+	l := newHCLLoader()
+	l.AddFiles([]string{"testdata/functions.hcl"})
+
+	_, err := l.HCLRender()
+	if err == nil || !strings.Contains(err.Error(), "required env var `HCL_TEST_NAME` is not set") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHCL_HelmfileFunctions_InsecureFeaturesDisabled(t *testing.T) {
+	t.Setenv("HCL_TEST_NAME", "helmfile")
+	insecureFunctionsDisabled = func() bool { return true }
+	defer func() {
+		insecureFunctionsDisabled = tmpl.InsecureFunctionsDisabled
+	}()
+
+	l := newHCLLoader()
+	l.AddFiles([]string{"testdata/functions.hcl"})
+
+	_, err := l.HCLRender()
+	if err == nil || !strings.Contains(err.Error(), tmpl.DisableInsecureFunctionsErr.Error()) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
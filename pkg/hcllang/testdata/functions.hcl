@@ -0,0 +1,10 @@
+values {
+  content  = file("functions/content.txt")
+  exists   = fileexists("functions/content.txt")
+  missing  = fileexists("functions/missing.txt")
+  decoded  = yamldecode(file("functions/values.yaml"))
+  rendered = templatefile("functions/greeting.tmpl", { name = env("HCL_TEST_NAME") })
+  required = requiredenv("HCL_TEST_NAME")
+  unset    = env("HCL_TEST_UNSET")
+  secret   = secret("ref+echo://secret-value")
+}
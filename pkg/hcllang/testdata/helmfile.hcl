@@ -0,0 +1,36 @@
+locals {
+  namespace = "db"
+}
+
+helmDefaults = {
+  wait = true
+}
+
+environment "default" {
+  values = ["default.yaml"]
+}
+
+environment "prod" {
+  values      = ["prod.yaml", { replicas = 3 }]
+  kubeContext = "prod"
+}
+
+repository "bitnami" {
+  url = "https://charts.bitnami.com/bitnami"
+}
+
+release "api" {
+  namespace = "app"
+  chart     = "./charts/api"
+  needs     = [release.postgres, "app/cache"]
+  values = [{
+    database = "${release.postgres.name}.${release.postgres.namespace}"
+    replicas = values.replicas
+  }]
+}
+
+release "postgres" {
+  namespace = local.namespace
+  chart     = "${repository.bitnami.name}/postgresql"
+  installed = helmfile.environment != "dev"
+}
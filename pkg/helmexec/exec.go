@@ -3,6 +3,7 @@ package helmexec
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -27,6 +28,7 @@ import (
 	chart "helm.sh/helm/v4/pkg/chart/v2"
 	cliv4 "helm.sh/helm/v4/pkg/cli"
 
+	"github.com/helmfile/helmfile/pkg/tracing"
 	"github.com/helmfile/helmfile/pkg/yaml"
 )
 
@@ -672,6 +674,23 @@ func (helm *execer) ReleaseStatus(context HelmContext, name string, flags ...str
 	return err
 }
 
+func (helm *execer) ReleaseStatusInfo(context HelmContext, name string, flags ...string) (*ReleaseStatusInfo, error) {
+	helm.logger.Infof("Getting status %v", name)
+	args := []string{"status", name, "--output", "json"}
+
+	enableLiveOutput := false
+	out, err := helm.exec(append(args, flags...), map[string]string{}, &enableLiveOutput)
+	if err != nil {
+		return nil, err
+	}
+
+	var status ReleaseStatusInfo
+	if err := json.Unmarshal(out, &status); err != nil {
+		return nil, fmt.Errorf("parsing the output of helm status: %w", err)
+	}
+	return &status, nil
+}
+
 func (helm *execer) List(context HelmContext, filter string, flags ...string) (string, error) {
 	helm.logger.Infof("Listing releases matching %v", filter)
 	preArgs := make([]string, 0)
@@ -693,6 +712,23 @@ func (helm *execer) List(context HelmContext, filter string, flags ...string) (s
 	return string(out), err
 }
 
+func (helm *execer) ListReleases(context HelmContext, flags ...string) ([]ReleaseInfo, error) {
+	helm.logger.Infof("Listing installed releases %v", strings.Join(flags, " "))
+	args := []string{"list", "--output", "json"}
+
+	enableLiveOutput := false
+	out, err := helm.exec(append(args, flags...), map[string]string{}, &enableLiveOutput)
+	if err != nil {
+		return nil, err
+	}
+
+	var releases []ReleaseInfo
+	if err := json.Unmarshal(out, &releases); err != nil {
+		return nil, fmt.Errorf("parsing the output of helm list: %w", err)
+	}
+	return releases, nil
+}
+
 func (helm *execer) DecryptSecret(context HelmContext, name string, flags ...string) (string, error) {
 	absPath, err := filepath.Abs(name)
 	if err != nil {
@@ -1221,7 +1257,9 @@ func (helm *execer) exec(args []string, env map[string]string, overrideEnableLiv
 	if overrideEnableLiveOutput != nil {
 		enableLiveOutput = *overrideEnableLiveOutput
 	}
+	span := helm.startSpan(cmdargs)
 	outBytes, err := helm.runner.Execute(helm.helmBinary, cmdargs, env, enableLiveOutput)
+	tracing.End(span, err)
 	return outBytes, err
 }
 
@@ -1238,7 +1276,9 @@ func (helm *execer) execStdIn(args []string, env map[string]string, stdin io.Rea
 	}
 	cmd := fmt.Sprintf("exec: %s %s", helm.helmBinary, strings.Join(cmdargs, " "))
 	helm.logger.Debug(cmd)
+	span := helm.startSpan(cmdargs)
 	outBytes, err := helm.runner.ExecuteStdIn(helm.helmBinary, cmdargs, env, stdin)
+	tracing.End(span, err)
 	return outBytes, err
 }
 
@@ -1174,6 +1174,75 @@ exec: helm --kubeconfig config --kube-context dev status myRelease
 	}
 }
 
+func Test_ReleaseStatusInfo(t *testing.T) {
+	var buffer bytes.Buffer
+	runner := &mockRunner{output: []byte(`{"name":"web","namespace":"app","version":4,"info":{"first_deployed":"2024-01-01T10:00:00Z","last_deployed":"2024-05-01T10:00:00.123456+02:00","description":"Upgrade complete","status":"deployed","notes":"Visit http://web"},"chart":{"metadata":{"name":"web","version":"1.2.3","appVersion":"2.0.0"}},"manifest":"---"}`)}
+	helm := &execer{
+		helmBinary:  "helm",
+		version:     semver.MustParse("3.3.2"),
+		logger:      NewLogger(&buffer, "debug"),
+		kubeContext: "dev",
+		runner:      runner,
+	}
+
+	status, err := helm.ReleaseStatusInfo(HelmContext{}, "web", "--namespace", "app")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.Name != "web" || status.Namespace != "app" || status.Revision != 4 {
+		t.Errorf("helmexec.ReleaseStatusInfo() - unexpected release: %+v", status)
+	}
+	if status.Info.Status != "deployed" || status.Info.LastDeployed != "2024-05-01T10:00:00.123456+02:00" || status.Info.Notes != "Visit http://web" {
+		t.Errorf("helmexec.ReleaseStatusInfo() - unexpected info: %+v", status.Info)
+	}
+	if m := status.Chart.Metadata; m.Name != "web" || m.Version != "1.2.3" || m.AppVersion != "2.0.0" {
+		t.Errorf("helmexec.ReleaseStatusInfo() - unexpected chart metadata: %+v", m)
+	}
+	expectedArgs := []string{"--kube-context", "dev", "status", "web", "--output", "json", "--namespace", "app"}
+	if !reflect.DeepEqual(runner.execArgs[0], expectedArgs) {
+		t.Errorf("helmexec.ReleaseStatusInfo()\nactual args = %v\nexpect args = %v", runner.execArgs[0], expectedArgs)
+	}
+}
+
+func Test_ListReleases(t *testing.T) {
+	var buffer bytes.Buffer
+	runner := &mockRunner{output: []byte(`[{"name":"web","namespace":"app","revision":"3","updated":"2024-05-01 10:00:00.000000 +0000 UTC","status":"deployed","chart":"web-1.2.3","app_version":"2.0.0"}]
+`)}
+	helm := &execer{
+		helmBinary:  "helm",
+		version:     semver.MustParse("3.3.2"),
+		logger:      NewLogger(&buffer, "debug"),
+		kubeContext: "dev",
+		runner:      runner,
+	}
+
+	releases, err := helm.ListReleases(HelmContext{}, "--namespace", "app")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []ReleaseInfo{{
+		Name:       "web",
+		Namespace:  "app",
+		Revision:   "3",
+		Updated:    "2024-05-01 10:00:00.000000 +0000 UTC",
+		Status:     "deployed",
+		Chart:      "web-1.2.3",
+		AppVersion: "2.0.0",
+	}}
+	if !reflect.DeepEqual(releases, expected) {
+		t.Errorf("helmexec.ListReleases()\nactual = %v\nexpect = %v", releases, expected)
+	}
+	expectedArgs := []string{"--kube-context", "dev", "list", "--output", "json", "--namespace", "app"}
+	if !reflect.DeepEqual(runner.execArgs[0], expectedArgs) {
+		t.Errorf("helmexec.ListReleases()\nactual args = %v\nexpect args = %v", runner.execArgs[0], expectedArgs)
+	}
+
+	runner.output = []byte("Error: Kubernetes cluster unreachable")
+	if _, err := helm.ListReleases(HelmContext{}); err == nil {
+		t.Error("helmexec.ListReleases() - expected an error on the output that isn't JSON")
+	}
+}
+
 func Test_exec(t *testing.T) {
 	var buffer bytes.Buffer
 	logger := NewLogger(&buffer, "debug")
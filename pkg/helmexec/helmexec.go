@@ -9,6 +9,37 @@ type Version struct {
 	Patch int
 }
 
+// ReleaseInfo is a release installed in the cluster, as listed by `helm list --output json`
+type ReleaseInfo struct {
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace"`
+	Revision   string `json:"revision"`
+	Updated    string `json:"updated"`
+	Status     string `json:"status"`
+	Chart      string `json:"chart"`
+	AppVersion string `json:"app_version"`
+}
+
+// ReleaseStatusInfo is the status of a release, as printed by `helm status --output json`
+type ReleaseStatusInfo struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Revision  int    `json:"version"`
+	Info      struct {
+		Status       string `json:"status"`
+		LastDeployed string `json:"last_deployed"`
+		Description  string `json:"description"`
+		Notes        string `json:"notes"`
+	} `json:"info"`
+	Chart struct {
+		Metadata struct {
+			Name       string `json:"name"`
+			Version    string `json:"version"`
+			AppVersion string `json:"appVersion"`
+		} `json:"metadata"`
+	} `json:"chart"`
+}
+
 // Interface for executing helm commands
 type Interface interface {
 	SetExtraArgs(args ...string)
@@ -30,9 +61,11 @@ type Interface interface {
 	Lint(name, chart string, flags ...string) error
 	Unittest(name, chart string, flags ...string) error
 	ReleaseStatus(context HelmContext, name string, flags ...string) error
+	ReleaseStatusInfo(context HelmContext, name string, flags ...string) (*ReleaseStatusInfo, error)
 	DeleteRelease(context HelmContext, name string, flags ...string) error
 	TestRelease(context HelmContext, name string, flags ...string) error
 	List(context HelmContext, filter string, flags ...string) (string, error)
+	ListReleases(context HelmContext, flags ...string) ([]ReleaseInfo, error)
 	DecryptSecret(context HelmContext, name string, flags ...string) (string, error)
 	IsHelm3() bool
 	IsHelm4() bool
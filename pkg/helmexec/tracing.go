@@ -0,0 +1,81 @@
+package helmexec
+
+import (
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/helmfile/helmfile/pkg/tracing"
+)
+
+// helmGroupCommands are the helm commands whose subcommand is part of the span name, like `helm repo add`
+var helmGroupCommands = map[string]bool{
+	"dependency": true,
+	"diff":       true,
+	"plugin":     true,
+	"registry":   true,
+	"repo":       true,
+	"secrets":    true,
+}
+
+// helmReleaseCommands are the helm commands whose first positional argument is the release name
+var helmReleaseCommands = map[string]bool{
+	"delete":    true,
+	"diff":      true,
+	"status":    true,
+	"template":  true,
+	"test":      true,
+	"uninstall": true,
+	"upgrade":   true,
+}
+
+// startSpan starts the span of the helm invocation with cmdargs.
+func (helm *execer) startSpan(cmdargs []string) trace.Span {
+	var positional []string
+	kubeContext := helm.kubeContext
+	var namespace string
+	for i := 0; i < len(cmdargs); i++ {
+		arg := cmdargs[i]
+		switch {
+		case arg == "--kube-context" && i+1 < len(cmdargs):
+			kubeContext = cmdargs[i+1]
+			i++
+		case arg == "--namespace" && i+1 < len(cmdargs):
+			namespace = cmdargs[i+1]
+			i++
+		case arg == "--kubeconfig" && i+1 < len(cmdargs):
+			i++
+		case strings.HasPrefix(arg, "-"):
+		default:
+			positional = append(positional, arg)
+		}
+	}
+
+	name := "helm"
+	var release string
+	if len(positional) > 0 {
+		command := positional[0]
+		name += " " + command
+		rest := positional[1:]
+		if helmGroupCommands[command] && len(rest) > 0 {
+			name += " " + rest[0]
+			rest = rest[1:]
+		}
+		if helmReleaseCommands[command] && len(rest) > 0 {
+			release = rest[0]
+		}
+	}
+
+	attrs := []attribute.KeyValue{tracing.Command.String(strings.TrimPrefix(name, "helm "))}
+	if release != "" {
+		attrs = append(attrs, tracing.Release.String(release))
+	}
+	if namespace != "" {
+		attrs = append(attrs, tracing.Namespace.String(namespace))
+	}
+	if kubeContext != "" {
+		attrs = append(attrs, tracing.KubeContext.String(kubeContext))
+	}
+	return tracing.StartSpan(name, attrs...)
+}
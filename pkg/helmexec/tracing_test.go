@@ -0,0 +1,61 @@
+package helmexec
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tj/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/helmfile/helmfile/pkg/tracing"
+)
+
+func Test_ExecSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(prev)
+
+	helm, err := MockExecer(NewLogger(&bytes.Buffer{}, "debug"), "config", "dev")
+	require.NoError(t, err)
+
+	require.NoError(t, helm.SyncRelease(HelmContext{}, "web", "stable/web", "app", "--namespace", "app", "--timeout 10"))
+	require.NoError(t, helm.AddRepo("stable", "https://charts.example.com", "", "", "", "", "", "", false, false))
+	_, err = helm.exec([]string{"diff", "upgrade", "--allow-unreleased", "db", "stable/db", "--kube-context", "prod"}, map[string]string{}, nil)
+	require.NoError(t, err)
+
+	attrs := func(s sdktrace.ReadOnlySpan) map[attribute.Key]string {
+		m := map[attribute.Key]string{}
+		for _, kv := range s.Attributes() {
+			m[kv.Key] = kv.Value.AsString()
+		}
+		return m
+	}
+
+	spans := recorder.Ended()
+	var names []string
+	for _, s := range spans {
+		names = append(names, s.Name())
+	}
+	assert.Equal(t, []string{"helm upgrade", "helm repo add", "helm diff upgrade"}, names)
+
+	assert.Equal(t, map[attribute.Key]string{
+		tracing.Command:     "upgrade",
+		tracing.Release:     "web",
+		tracing.Namespace:   "app",
+		tracing.KubeContext: "dev",
+	}, attrs(spans[0]))
+	assert.Equal(t, map[attribute.Key]string{
+		tracing.Command:     "repo add",
+		tracing.KubeContext: "dev",
+	}, attrs(spans[1]))
+	assert.Equal(t, map[attribute.Key]string{
+		tracing.Command:     "diff upgrade",
+		tracing.Release:     "db",
+		tracing.KubeContext: "prod",
+	}, attrs(spans[2]))
+}
@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/werf/kubedog/pkg/informer"
@@ -31,6 +32,7 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 
 	"github.com/helmfile/helmfile/pkg/resource"
+	"github.com/helmfile/helmfile/pkg/tracing"
 )
 
 type cacheKey struct {
@@ -62,6 +64,7 @@ type Tracker struct {
 	trackOptions  *TrackOptions
 	filter        *resource.ResourceFilter
 	namespace     string
+	kubeContext   string
 	releaseName   string
 
 	// upstreamDoneCh is closed when the calling code (e.g. helm.SyncRelease)
@@ -77,6 +80,25 @@ type Tracker struct {
 	// VerifyAllConverged so the safety valve doesn't keep polling for
 	// resources that will never exist.
 	skipped *skippedKeys
+
+	// stats are what the last TrackResources call tracked.
+	stats TrackStats
+}
+
+// TrackStats are what TrackResources tracked, for the metrics of a run.
+type TrackStats struct {
+	// Resources is the number of resources tracked.
+	Resources int
+	// Changed is the number of the resources that were observed changing, or -1
+	// when unknown, as no baselines were captured to compare the resources with.
+	Changed int
+	// Duration is how long the tracking took.
+	Duration time.Duration
+}
+
+// Stats returns what the last TrackResources call tracked.
+func (t *Tracker) Stats() TrackStats {
+	return t.stats
 }
 
 type TrackerConfig struct {
@@ -144,6 +166,7 @@ func NewTracker(config *TrackerConfig) (*Tracker, error) {
 		trackOptions:   options,
 		filter:         filter,
 		namespace:      config.Namespace,
+		kubeContext:    config.KubeContext,
 		releaseName:    config.ReleaseName,
 		upstreamDoneCh: make(chan struct{}),
 		skipped:        newSkippedKeys(),
@@ -372,7 +395,23 @@ func (t *Tracker) waitForFreshness(ctx context.Context, tgt trackTarget, baselin
 	}
 }
 
-func (t *Tracker) TrackResources(ctx context.Context, resources []*resource.Resource) error {
+func (t *Tracker) TrackResources(ctx context.Context, resources []*resource.Resource) (err error) {
+	span := tracing.StartSpan("kubedog track",
+		tracing.Release.String(t.releaseName),
+		tracing.Namespace.String(t.namespace),
+		tracing.KubeContext.String(t.kubeContext),
+	)
+	start := time.Now()
+	t.stats = TrackStats{Changed: -1}
+	var changed atomic.Int32
+	defer func() {
+		t.stats.Duration = time.Since(start)
+		if t.trackOptions.Baselines != nil {
+			t.stats.Changed = int(changed.Load())
+		}
+		tracing.End(span, err)
+	}()
+
 	if len(resources) == 0 {
 		t.logger.Info("No resources to track")
 		return nil
@@ -394,6 +433,7 @@ func (t *Tracker) TrackResources(ctx context.Context, resources []*resource.Reso
 	}
 
 	targets := t.buildTargets(filtered)
+	t.stats.Resources = len(targets)
 	if len(targets) == 0 {
 		t.logger.Info("No trackable resources found (only Deployment, StatefulSet, DaemonSet, Job, Canary, and PersistentVolumeClaim are supported)")
 		return nil
@@ -485,6 +525,7 @@ func (t *Tracker) TrackResources(ctx context.Context, resources []*resource.Reso
 				switch {
 				case err == nil:
 					// resource changed; proceed to attach the tracker
+					changed.Add(1)
 				case errors.Is(err, errUpstreamDoneNoChange):
 					t.logger.Debugf("kubedog: %s/%s/%s unchanged by upstream; skipping tracker", tgt.kind, tgt.namespace, tgt.name)
 					// Hide this task from the printer output: it never changed,
@@ -0,0 +1,133 @@
+// Package metrics exports the outcome of sync, apply and destroy runs as
+// Prometheus metrics, to a file for the textfile collector of node-exporter,
+// or to a Pushgateway.
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+
+	"github.com/helmfile/helmfile/pkg/audit"
+)
+
+// Job is the job the metrics are pushed to a Pushgateway as.
+const Job = "helmfile"
+
+// results are the results of releases counted by helmfile_releases, which are
+// all set, even to zero, so that dashboards don't miss the results of no release.
+var results = []string{
+	audit.ActionUpgraded,
+	audit.ActionReinstalled,
+	audit.ActionDeleted,
+	audit.ActionFailed,
+	audit.ActionDeleteFailed,
+}
+
+// Run is a sync, apply or destroy command, and the runs of the releases of each of its helmfiles.
+type Run struct {
+	Command     string
+	Environment string
+	Start       time.Time
+	Duration    time.Duration
+	Records     []audit.Record
+	// Err is the error the command failed with, if any.
+	Err error
+}
+
+// Succeeded reports whether the command and all of its releases succeeded.
+func (r Run) Succeeded() bool {
+	if r.Err != nil {
+		return false
+	}
+	for _, record := range r.Records {
+		if record.Failed() {
+			return false
+		}
+		for _, rel := range record.Releases {
+			if rel.Action == audit.ActionFailed || rel.Action == audit.ActionDeleteFailed {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Write exports the metrics of r to output: the Pushgateway at output when it's an
+// http(s) URL, and the file at output in the Prometheus text format otherwise.
+func Write(output string, r Run) error {
+	if strings.HasPrefix(output, "http://") || strings.HasPrefix(output, "https://") {
+		// The command and environment group the metrics, so that a run only replaces
+		// the metrics of the previous run of the same command in the same environment
+		pusher := push.New(output, Job).
+			Grouping("command", r.Command).
+			Grouping("environment", r.Environment).
+			Gatherer(r.registry(nil)).
+			Client(&http.Client{Timeout: 10 * time.Second})
+		if err := pusher.Push(); err != nil {
+			return fmt.Errorf("pushing the metrics to %s: %w", output, err)
+		}
+		return nil
+	}
+
+	labels := prometheus.Labels{"command": r.Command, "environment": r.Environment}
+	if err := prometheus.WriteToTextfile(output, r.registry(labels)); err != nil {
+		return fmt.Errorf("writing the metrics to %s: %w", output, err)
+	}
+	return nil
+}
+
+// registry returns the metrics of r, with the constant labels added to each of them.
+func (r Run) registry(labels prometheus.Labels) *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	factory := prometheus.WrapRegistererWith(labels, reg)
+
+	gauge := func(name, help string) prometheus.Gauge {
+		g := prometheus.NewGauge(prometheus.GaugeOpts{Name: name, Help: help})
+		factory.MustRegister(g)
+		return g
+	}
+	gaugeVec := func(name, help string, labels ...string) *prometheus.GaugeVec {
+		g := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: help}, labels)
+		factory.MustRegister(g)
+		return g
+	}
+
+	releaseLabels := []string{"helmfile", "release", "namespace", "kube_context", "chart"}
+
+	gauge("helmfile_run_timestamp_seconds", "Time the run started at, in seconds since the epoch.").Set(float64(r.Start.Unix()))
+	gauge("helmfile_run_duration_seconds", "How long the run took.").Set(r.Duration.Seconds())
+	success := gauge("helmfile_run_success", "Whether the run and all of its releases succeeded.")
+	releases := gaugeVec("helmfile_releases", "Number of releases of the run, by result.", "result")
+	duration := gaugeVec("helmfile_release_duration_seconds", "How long syncing or deleting the release took.", append(releaseLabels, "result")...)
+	resources := gaugeVec("helmfile_release_tracked_resources", "Number of resources of the release kubedog tracked.", releaseLabels...)
+	changed := gaugeVec("helmfile_release_changed_resources", "Number of resources of the release kubedog observed changing.", releaseLabels...)
+	tracking := gaugeVec("helmfile_release_tracking_duration_seconds", "How long kubedog waited for the resources of the release to be ready.", releaseLabels...)
+
+	if r.Succeeded() {
+		success.Set(1)
+	}
+	for _, result := range results {
+		releases.WithLabelValues(result)
+	}
+	for _, record := range r.Records {
+		for _, rel := range record.Releases {
+			values := []string{record.Helmfile, rel.Name, rel.Namespace, rel.KubeContext, rel.Chart}
+
+			releases.WithLabelValues(rel.Action).Inc()
+			duration.WithLabelValues(append(values, rel.Action)...).Set(rel.DurationSeconds)
+			if t := rel.Tracking; t != nil {
+				resources.WithLabelValues(values...).Set(float64(t.Resources))
+				tracking.WithLabelValues(values...).Set(t.DurationSeconds)
+				if t.ChangedResources != nil {
+					changed.WithLabelValues(values...).Set(float64(*t.ChangedResources))
+				}
+			}
+		}
+	}
+	return reg
+}
@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/helmfile/helmfile/pkg/audit"
+)
+
+func changed(n int) *int {
+	return &n
+}
+
+var run = Run{
+	Command:     "apply",
+	Environment: "prod",
+	Start:       time.Unix(1714557600, 0),
+	Duration:    45 * time.Second,
+	Records: []audit.Record{
+		{
+			Helmfile: "helmfile.yaml",
+			Releases: []audit.Release{
+				{
+					Name: "web", Namespace: "app", KubeContext: "prod", Chart: "stable/web", Action: audit.ActionUpgraded, DurationSeconds: 12.5,
+					Tracking: &audit.Tracking{Resources: 3, ChangedResources: changed(2), DurationSeconds: 8},
+				},
+				{Name: "db", Namespace: "db", KubeContext: "prod", Chart: "stable/db", Action: audit.ActionFailed, DurationSeconds: 30},
+			},
+		},
+	},
+}
+
+func TestWriteTextfile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "helmfile.prom")
+	require.NoError(t, Write(file, run))
+
+	bs, err := os.ReadFile(file)
+	require.NoError(t, err)
+	out := string(bs)
+
+	for _, line := range []string{
+		`helmfile_run_timestamp_seconds{command="apply",environment="prod"} 1.7145576e+09`,
+		`helmfile_run_duration_seconds{command="apply",environment="prod"} 45`,
+		`helmfile_run_success{command="apply",environment="prod"} 0`,
+		`helmfile_releases{command="apply",environment="prod",result="upgraded"} 1`,
+		`helmfile_releases{command="apply",environment="prod",result="failed"} 1`,
+		`helmfile_releases{command="apply",environment="prod",result="deleted"} 0`,
+		`helmfile_release_duration_seconds{chart="stable/web",command="apply",environment="prod",helmfile="helmfile.yaml",kube_context="prod",namespace="app",release="web",result="upgraded"} 12.5`,
+		`helmfile_release_duration_seconds{chart="stable/db",command="apply",environment="prod",helmfile="helmfile.yaml",kube_context="prod",namespace="db",release="db",result="failed"} 30`,
+		`helmfile_release_tracked_resources{chart="stable/web",command="apply",environment="prod",helmfile="helmfile.yaml",kube_context="prod",namespace="app",release="web"} 3`,
+		`helmfile_release_changed_resources{chart="stable/web",command="apply",environment="prod",helmfile="helmfile.yaml",kube_context="prod",namespace="app",release="web"} 2`,
+		`helmfile_release_tracking_duration_seconds{chart="stable/web",command="apply",environment="prod",helmfile="helmfile.yaml",kube_context="prod",namespace="app",release="web"} 8`,
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+	assert.NotContains(t, out, `release="db"} `, "db isn't tracked with kubedog")
+}
+
+func TestWritePushgateway(t *testing.T) {
+	var method, path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bs, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		method, path, body = r.Method, r.URL.Path, string(bs)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	succeeded := Run{Command: "sync", Environment: "default", Start: time.Now()}
+	require.NoError(t, Write(server.URL, succeeded))
+
+	assert.Equal(t, http.MethodPut, method)
+	// The grouping labels follow the job in any order
+	require.True(t, strings.HasPrefix(path, "/metrics/job/helmfile/"), path)
+	assert.ElementsMatch(t, []string{"command/sync", "environment/default"}, groupingLabels(strings.TrimPrefix(path, "/metrics/job/helmfile/")))
+	assert.Contains(t, body, "helmfile_run_success")
+	assert.NotContains(t, body, "environment", "the grouping labels are set by the Pushgateway")
+}
+
+func groupingLabels(path string) []string {
+	parts := strings.Split(path, "/")
+	var labels []string
+	for i := 0; i+1 < len(parts); i += 2 {
+		labels = append(labels, parts[i]+"/"+parts[i+1])
+	}
+	return labels
+}
+
+func TestWriteErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	require.ErrorContains(t, Write(server.URL, run), "pushing the metrics to "+server.URL)
+	require.ErrorContains(t, Write(filepath.Join(t.TempDir(), "missing", "helmfile.prom"), run), "writing the metrics to")
+}
+
+func TestSucceeded(t *testing.T) {
+	assert.False(t, run.Succeeded())
+	assert.True(t, Run{Records: []audit.Record{{Releases: []audit.Release{{Action: audit.ActionDeleted}}}}}.Succeeded())
+	assert.False(t, Run{Err: errors.New("loading helmfile.yaml")}.Succeeded())
+	assert.False(t, Run{Records: []audit.Record{{Errors: []string{"release web failed"}}}}.Succeeded())
+}
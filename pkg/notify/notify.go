@@ -0,0 +1,277 @@
+// Package notify sends the start, release failures and completion of sync,
+// apply and destroy runs to the endpoints of the `notifications:` block of
+// helmfile.yaml:
+//
+//	notifications:
+//	- format: slack
+//	  url: https://hooks.slack.com/services/T000/B000/XXXX
+//	  events: [releaseFailed, complete]
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Masterminds/sprig/v3"
+
+	"github.com/helmfile/helmfile/pkg/audit"
+	"github.com/helmfile/helmfile/pkg/webhook"
+)
+
+// Event types, as set in Config.Events and Event.Type
+const (
+	EventStart         = "start"
+	EventReleaseFailed = "releaseFailed"
+	EventComplete      = "complete"
+)
+
+// Payload formats, as set in Config.Format
+const (
+	FormatWebhook = "webhook"
+	FormatSlack   = "slack"
+	FormatTeams   = "teams"
+)
+
+// DefaultMessages are the messages of the events a notification doesn't set a message for.
+var DefaultMessages = map[string]string{
+	EventStart: `helmfile {{ .Command }} of {{ .Helmfile }} started in environment {{ .Environment }} by {{ .User }}`,
+	EventReleaseFailed: `helmfile {{ .Command }} of {{ .Helmfile }} in environment {{ .Environment }}: ` +
+		`release {{ .Release.Name }}{{ with .Release.Namespace }} in namespace {{ . }}{{ end }} failed after {{ .Release.Duration }}`,
+	EventComplete: `helmfile {{ .Command }} of {{ .Helmfile }} in environment {{ .Environment }} ` +
+		`{{ if .Succeeded }}succeeded{{ else }}failed{{ end }} in {{ .Duration }}` +
+		`{{ range .Upgraded }}{{ "\n" }}- {{ .Name }} upgraded to {{ .Chart }}{{ with .Version }} {{ . }}{{ end }} in {{ .Duration }}{{ end }}` +
+		`{{ range .Reinstalled }}{{ "\n" }}- {{ .Name }} reinstalled in {{ .Duration }}{{ end }}` +
+		`{{ range .Deleted }}{{ "\n" }}- {{ .Name }} deleted in {{ .Duration }}{{ end }}` +
+		`{{ range .Failed }}{{ "\n" }}- {{ .Name }} failed after {{ .Duration }}{{ end }}` +
+		`{{ range .DeleteFailed }}{{ "\n" }}- {{ .Name }} failed to delete after {{ .Duration }}{{ end }}`,
+}
+
+// Config is an endpoint of the `notifications:` block of helmfile.yaml.
+type Config struct {
+	// Format is the payload format the endpoint expects: webhook, the default, slack or teams.
+	Format string `yaml:"format,omitempty"`
+
+	webhook.Endpoint `yaml:",inline"`
+
+	// Events are the events sent to the endpoint. All of them are sent when empty.
+	Events []string `yaml:"events,omitempty"`
+
+	// Messages are the Go templates of the messages of each event, overriding DefaultMessages.
+	Messages map[string]string `yaml:"messages,omitempty"`
+}
+
+// Wants reports whether the event type t is sent to the endpoint.
+func (c Config) Wants(t string) bool {
+	return len(c.Events) == 0 || slices.Contains(c.Events, t)
+}
+
+// Validate returns an error when the format, the events or the messages of c are invalid.
+// It's called when the helmfile is loaded, while the url, which is often read from the
+// environment, is only required to send notifications.
+func (c Config) Validate() error {
+	switch c.Format {
+	case "", FormatWebhook, FormatSlack, FormatTeams:
+	default:
+		return fmt.Errorf("unknown notification format %q: must be one of %q, %q or %q", c.Format, FormatWebhook, FormatSlack, FormatTeams)
+	}
+	for _, e := range c.Events {
+		if _, ok := DefaultMessages[e]; !ok {
+			return fmt.Errorf("unknown notification event %q: must be one of %q, %q or %q", e, EventStart, EventReleaseFailed, EventComplete)
+		}
+	}
+	for e := range c.Messages {
+		if _, ok := DefaultMessages[e]; !ok {
+			return fmt.Errorf("message of unknown notification event %q", e)
+		}
+		if _, err := c.template(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Duration is printed rounded to the second in messages, and as seconds in webhook payloads.
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).Round(time.Second).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).Seconds())
+}
+
+// Release is a release affected by a run.
+type Release struct {
+	Name        string   `json:"name"`
+	Namespace   string   `json:"namespace,omitempty"`
+	KubeContext string   `json:"kubeContext,omitempty"`
+	Chart       string   `json:"chart,omitempty"`
+	Version     string   `json:"version,omitempty"`
+	Duration    Duration `json:"duration"`
+}
+
+// Event is the data of the message templates, and the payload of webhooks.
+type Event struct {
+	Type        string   `json:"event"`
+	Command     string   `json:"command"`
+	User        string   `json:"user"`
+	Helmfile    string   `json:"helmfile"`
+	Commit      string   `json:"commit,omitempty"`
+	Environment string   `json:"environment"`
+	Selectors   []string `json:"selectors,omitempty"`
+
+	// Release is the failed release of a releaseFailed event.
+	Release *Release `json:"release,omitempty"`
+
+	// The releases affected by the run, and how long the run took, are set on complete events.
+	Upgraded     []Release `json:"upgraded,omitempty"`
+	Reinstalled  []Release `json:"reinstalled,omitempty"`
+	Deleted      []Release `json:"deleted,omitempty"`
+	Failed       []Release `json:"failed,omitempty"`
+	DeleteFailed []Release `json:"deleteFailed,omitempty"`
+	Duration     Duration  `json:"duration,omitempty"`
+	Succeeded    bool      `json:"succeeded"`
+	Errors       []string  `json:"errors,omitempty"`
+}
+
+// NewEvent returns the event of type t of the run recorded as r.
+func NewEvent(t string, r audit.Record) Event {
+	e := Event{
+		Type:        t,
+		Command:     r.Command,
+		User:        r.User,
+		Helmfile:    r.Helmfile,
+		Commit:      r.Commit,
+		Environment: r.Environment,
+		Selectors:   r.Selectors,
+		Duration:    seconds(r.DurationSeconds),
+		Succeeded:   !r.Failed(),
+		Errors:      r.Errors,
+	}
+	for _, rel := range r.Releases {
+		release := Release{
+			Name:        rel.Name,
+			Namespace:   rel.Namespace,
+			KubeContext: rel.KubeContext,
+			Chart:       rel.Chart,
+			Version:     rel.Version,
+			Duration:    seconds(rel.DurationSeconds),
+		}
+		switch rel.Action {
+		case audit.ActionUpgraded:
+			e.Upgraded = append(e.Upgraded, release)
+		case audit.ActionReinstalled:
+			e.Reinstalled = append(e.Reinstalled, release)
+		case audit.ActionDeleted:
+			e.Deleted = append(e.Deleted, release)
+		case audit.ActionFailed:
+			e.Failed = append(e.Failed, release)
+		case audit.ActionDeleteFailed:
+			e.DeleteFailed = append(e.DeleteFailed, release)
+		}
+	}
+	// A run fails when any of its releases fails, even when the run itself ends without errors
+	if len(e.Failed) > 0 || len(e.DeleteFailed) > 0 {
+		e.Succeeded = false
+	}
+	return e
+}
+
+func seconds(s float64) Duration {
+	return Duration(time.Duration(s * float64(time.Second)))
+}
+
+// ReleaseFailedEvents returns a releaseFailed event for each release the run failed to sync or delete.
+func (e Event) ReleaseFailedEvents() []Event {
+	var events []Event
+	for _, r := range append(append([]Release{}, e.Failed...), e.DeleteFailed...) {
+		failed := Event{
+			Type:        EventReleaseFailed,
+			Command:     e.Command,
+			User:        e.User,
+			Helmfile:    e.Helmfile,
+			Commit:      e.Commit,
+			Environment: e.Environment,
+			Selectors:   e.Selectors,
+			Release:     &r,
+		}
+		events = append(events, failed)
+	}
+	return events
+}
+
+// Message renders the message of e for c.
+func (c Config) Message(e Event) (string, error) {
+	t, err := c.template(e.Type)
+	if err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	if err := t.Execute(&buf, e); err != nil {
+		return "", fmt.Errorf("rendering the %s message: %w", e.Type, err)
+	}
+	return buf.String(), nil
+}
+
+func (c Config) template(eventType string) (*template.Template, error) {
+	text, ok := c.Messages[eventType]
+	if !ok {
+		text = DefaultMessages[eventType]
+	}
+	t, err := template.New(eventType).Funcs(sprig.TxtFuncMap()).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the %s message: %w", eventType, err)
+	}
+	return t, nil
+}
+
+// Send sends e to the endpoint of c, in the payload format of c.
+func Send(c Config, e Event) error {
+	if c.URL == "" {
+		return errors.New("notification url is required")
+	}
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	msg, err := c.Message(e)
+	if err != nil {
+		return err
+	}
+	payload, err := c.payload(e, msg)
+	if err != nil {
+		return err
+	}
+
+	return c.Post(payload)
+}
+
+func (c Config) payload(e Event, msg string) ([]byte, error) {
+	switch c.Format {
+	case FormatSlack:
+		return json.Marshal(map[string]string{"text": msg})
+	case FormatTeams:
+		color := "2EB886"
+		if e.Type == EventReleaseFailed || e.Type == EventComplete && !e.Succeeded {
+			color = "D63333"
+		}
+		return json.Marshal(map[string]string{
+			"@type":      "MessageCard",
+			"@context":   "https://schema.org/extensions",
+			"summary":    fmt.Sprintf("helmfile %s %s", e.Command, e.Type),
+			"themeColor": color,
+			// Teams renders the text as markdown, which needs two spaces to break lines
+			"text": strings.ReplaceAll(msg, "\n", "  \n"),
+		})
+	default:
+		return json.Marshal(struct {
+			Message string `json:"message"`
+			Event
+		}{Message: msg, Event: e})
+	}
+}
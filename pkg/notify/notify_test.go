@@ -0,0 +1,132 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/helmfile/helmfile/pkg/audit"
+	"github.com/helmfile/helmfile/pkg/webhook"
+)
+
+var record = audit.Record{
+	Time:        time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+	Command:     "apply",
+	User:        "alice",
+	Helmfile:    "helmfile.yaml",
+	Environment: "prod",
+	Releases: []audit.Release{
+		{Name: "web", Namespace: "app", Chart: "stable/web", Version: "1.2.3", Action: audit.ActionUpgraded, DurationSeconds: 12.4},
+		{Name: "old", Namespace: "app", Action: audit.ActionDeleted, DurationSeconds: 2},
+		{Name: "db", Namespace: "db", Chart: "stable/db", Action: audit.ActionFailed, DurationSeconds: 30},
+	},
+	DurationSeconds: 45.2,
+}
+
+func TestMessage(t *testing.T) {
+	complete := NewEvent(EventComplete, record)
+	assert.False(t, complete.Succeeded)
+
+	msg, err := Config{}.Message(complete)
+	require.NoError(t, err)
+	assert.Equal(t, `helmfile apply of helmfile.yaml in environment prod failed in 45s
+- web upgraded to stable/web 1.2.3 in 12s
+- old deleted in 2s
+- db failed after 30s`, msg)
+
+	msg, err = Config{}.Message(NewEvent(EventStart, audit.Record{Command: "sync", User: "alice", Helmfile: "helmfile.yaml", Environment: "prod"}))
+	require.NoError(t, err)
+	assert.Equal(t, "helmfile sync of helmfile.yaml started in environment prod by alice", msg)
+
+	failed := complete.ReleaseFailedEvents()
+	require.Len(t, failed, 1)
+	msg, err = Config{}.Message(failed[0])
+	require.NoError(t, err)
+	assert.Equal(t, "helmfile apply of helmfile.yaml in environment prod: release db in namespace db failed after 30s", msg)
+
+	c := Config{Messages: map[string]string{
+		EventComplete: `{{ len .Upgraded }} upgraded, {{ .Deleted | len }} deleted by {{ .User | upper }}`,
+	}}
+	msg, err = c.Message(complete)
+	require.NoError(t, err)
+	assert.Equal(t, "1 upgraded, 1 deleted by ALICE", msg)
+
+	c.Messages[EventComplete] = `{{ .Unknown }}`
+	_, err = c.Message(complete)
+	require.ErrorContains(t, err, "rendering the complete message")
+}
+
+func TestSend(t *testing.T) {
+	var payloads []map[string]any
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		bs, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		var payload map[string]any
+		require.NoError(t, json.Unmarshal(bs, &payload))
+		payloads = append(payloads, payload)
+	}))
+	defer server.Close()
+
+	e := NewEvent(EventComplete, record)
+	messages := map[string]string{EventComplete: "{{ .Command }} done\n{{ len .Failed }} failed"}
+
+	require.NoError(t, Send(Config{Format: FormatSlack, Endpoint: webhook.Endpoint{URL: server.URL}, Messages: messages}, e))
+	require.NoError(t, Send(Config{Format: FormatTeams, Endpoint: webhook.Endpoint{URL: server.URL}, Messages: messages}, e))
+	require.NoError(t, Send(Config{Endpoint: webhook.Endpoint{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer token"}}, Messages: messages}, e))
+
+	require.Len(t, payloads, 3)
+	assert.Equal(t, map[string]any{"text": "apply done\n1 failed"}, payloads[0])
+	assert.Equal(t, map[string]any{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    "helmfile apply complete",
+		"themeColor": "D63333",
+		"text":       "apply done  \n1 failed",
+	}, payloads[1])
+
+	webhook := payloads[2]
+	assert.Equal(t, "Bearer token", auth)
+	assert.Equal(t, "apply done\n1 failed", webhook["message"])
+	assert.Equal(t, "complete", webhook["event"])
+	assert.Equal(t, false, webhook["succeeded"])
+	assert.Equal(t, 45.2, webhook["duration"])
+	assert.Equal(t, []any{map[string]any{"name": "db", "namespace": "db", "chart": "stable/db", "duration": 30.0}}, webhook["failed"])
+}
+
+func TestSendErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	e := NewEvent(EventStart, record)
+	require.EqualError(t, Send(Config{Endpoint: webhook.Endpoint{URL: server.URL}}, e), "unexpected status 500 Internal Server Error")
+	require.EqualError(t, Send(Config{}, e), "notification url is required")
+	require.EqualError(t, Send(Config{Endpoint: webhook.Endpoint{URL: server.URL}, Format: "email"}, e), `unknown notification format "email": must be one of "webhook", "slack" or "teams"`)
+	require.EqualError(t, Send(Config{Endpoint: webhook.Endpoint{URL: server.URL}, Events: []string{"done"}}, e), `unknown notification event "done": must be one of "start", "releaseFailed" or "complete"`)
+
+	// The token in the url isn't leaked into the error
+	err := Send(Config{Endpoint: webhook.Endpoint{URL: "http://127.0.0.1:1/services/SECRET"}}, e)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "SECRET")
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Config{Format: FormatSlack, Events: []string{EventReleaseFailed}}.Validate(), "the url is only required to send")
+	require.EqualError(t, Config{Events: []string{"compelte"}}.Validate(), `unknown notification event "compelte": must be one of "start", "releaseFailed" or "complete"`)
+	require.EqualError(t, Config{Messages: map[string]string{"complete": "{{ .Command"}}.Validate(), `parsing the complete message: template: complete:1: unclosed action`)
+}
+
+func TestWants(t *testing.T) {
+	assert.True(t, Config{}.Wants(EventStart))
+	assert.True(t, Config{Events: []string{EventComplete}}.Wants(EventComplete))
+	assert.False(t, Config{Events: []string{EventComplete}}.Wants(EventStart))
+}
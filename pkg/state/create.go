@@ -17,6 +17,7 @@ import (
 	"github.com/helmfile/helmfile/pkg/helmexec"
 	"github.com/helmfile/helmfile/pkg/maputil"
 	"github.com/helmfile/helmfile/pkg/remote"
+	"github.com/helmfile/helmfile/pkg/tmpl"
 	"github.com/helmfile/helmfile/pkg/yaml"
 )
 
@@ -64,6 +65,9 @@ type StateCreator struct {
 
 	LoadFile func(inheritedEnv, overrodeEnv *environment.Environment, baseDir, file string, evaluateBases bool) (*HelmState, error)
 
+	// Sandbox restricts the template functions of the loaded states
+	Sandbox *tmpl.Sandbox
+
 	getHelm func(*HelmState) (helmexec.Interface, error)
 
 	overrideHelmBinary string
@@ -120,7 +124,7 @@ func (c *StateCreator) Parse(content []byte, baseDir, file string) (*HelmState,
 		if err == io.EOF {
 			break
 		} else if err != nil {
-			if filepath.Ext(file) != ".gotmpl" {
+			if ext := filepath.Ext(file); ext != ".gotmpl" && ext != DefaultHCLFileExtension {
 				return nil, &StateLoadError{fmt.Sprintf("failed to read %s: reading document at index %d. Started seeing this since Helmfile v1? Add the .gotmpl file extension", file, i), err}
 			}
 			return nil, &StateLoadError{fmt.Sprintf("failed to read %s: reading document at index %d", file, i), err}
@@ -133,6 +137,7 @@ func (c *StateCreator) Parse(content []byte, baseDir, file string) (*HelmState,
 
 	state.logger = c.logger
 	state.valsRuntime = c.valsRuntime
+	state.Sandbox = c.Sandbox
 
 	return &state, nil
 }
@@ -169,7 +174,7 @@ func (c *StateCreator) LoadEnvValues(target *HelmState, env string, failOnMissin
 		return nil, &StateLoadError{fmt.Sprintf("failed to read %s", state.FilePath), err}
 	}
 
-	newDefaults, err := state.loadValuesEntries(nil, state.DefaultValues, c.remote, ctxEnv, env, "")
+	newDefaults, err := state.loadValuesEntries(nil, state.DefaultValues, c.remote, ctxEnv, env, "", false)
 	if err != nil {
 		return nil, err
 	}
@@ -217,6 +222,13 @@ func (c *StateCreator) ParseAndLoad(content []byte, baseDir, file string, envNam
 
 	state.FilePath = file
 
+	// Bases are validated as part of the helmfile loading them, once all the values are merged
+	if evaluateBases {
+		if err := c.validateEnvValues(state, envName, &state.Env, envValues, overrode); err != nil {
+			return nil, &StateLoadError{fmt.Sprintf("failed to read %s", state.FilePath), err}
+		}
+	}
+
 	vals, err := state.Env.GetMergedValues()
 	if err != nil {
 		return nil, fmt.Errorf("rendering values: %w", err)
@@ -274,6 +286,20 @@ func mergeEnvironments(dst, src map[string]EnvironmentSpec) {
 				merged.MergeStrategy = dstEnv.MergeStrategy
 			}
 
+			// Override LazyValues if src has it
+			if srcEnv.LazyValues != nil {
+				merged.LazyValues = srcEnv.LazyValues
+			} else {
+				merged.LazyValues = dstEnv.LazyValues
+			}
+
+			// Override ValuesSchema if src has it
+			if srcEnv.ValuesSchema != "" {
+				merged.ValuesSchema = srcEnv.ValuesSchema
+			} else {
+				merged.ValuesSchema = dstEnv.ValuesSchema
+			}
+
 			// Concatenate Values so the later layer (src, e.g. main helmfile)
 			// always takes precedence over the earlier one (dst, e.g. a base
 			// helmfile). Under override the natural append order achieves that
@@ -303,6 +329,15 @@ func (c *StateCreator) loadBases(envValues, overrodeEnv *environment.Environment
 	}
 	layers := []*HelmState{}
 	for _, b := range st.Bases {
+		if !remote.IsRemote(b) {
+			path := b
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(baseDir, path)
+			}
+			if err := c.Sandbox.CheckPath(c.fs, path); err != nil {
+				return nil, err
+			}
+		}
 		base, err := c.LoadFile(envValues, newOverrodeEnv, baseDir, b, false)
 		if err != nil {
 			return nil, err
@@ -409,7 +444,8 @@ func (c *StateCreator) loadEnvValues(st *HelmState, name string, failOnMissingEn
 		if err != nil {
 			return nil, err
 		}
-		valuesVals, err = st.loadValuesEntries(envSpec.MissingFileHandler, envValuesEntries, c.remote, loadValuesEntriesEnv, name, envSpec.MergeStrategy)
+		lazy := envSpec.LazyValues != nil && *envSpec.LazyValues
+		valuesVals, err = st.loadValuesEntries(envSpec.MissingFileHandler, envValuesEntries, c.remote, loadValuesEntriesEnv, name, envSpec.MergeStrategy, lazy)
 		if err != nil {
 			return nil, err
 		}
@@ -565,13 +601,18 @@ func (c *StateCreator) scatterGatherEnvSecretFiles(st *HelmState, envSecretFiles
 	return decryptedFilesKeeper, nil
 }
 
-func (st *HelmState) loadValuesEntries(missingFileHandler *string, entries []any, remote *remote.Remote, ctxEnv *environment.Environment, envName string, mergeStrategy string) (map[string]any, error) {
+func (st *HelmState) loadValuesEntries(missingFileHandler *string, entries []any, remote *remote.Remote, ctxEnv *environment.Environment, envName string, mergeStrategy string, lazy bool) (map[string]any, error) {
 	var envVals map[string]any
 
 	valuesEntries := append([]any{}, entries...)
 	ld := NewEnvironmentValuesLoader(st.storage(), st.fs, st.logger, remote)
+	ld.sandbox = st.Sandbox
 	var err error
-	envVals, err = ld.LoadEnvironmentValues(missingFileHandler, valuesEntries, ctxEnv, envName, mergeStrategy)
+	if lazy {
+		envVals, err = ld.LoadLazyEnvironmentValues(missingFileHandler, valuesEntries, ctxEnv, envName, mergeStrategy)
+	} else {
+		envVals, err = ld.LoadEnvironmentValues(missingFileHandler, valuesEntries, ctxEnv, envName, mergeStrategy)
+	}
 	if err != nil {
 		return nil, err
 	}
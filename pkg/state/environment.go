@@ -28,4 +28,13 @@ type EnvironmentSpec struct {
 	// helmfile's MergeMaps treats nil from the override side elsewhere). Subsequent .gotmpl
 	// values files can also reference values from earlier files via .Values.
 	MergeStrategy string `yaml:"mergeStrategy,omitempty"`
+
+	// LazyValues makes every .gotmpl values file listed under `values` see the values of all the
+	// other files via .Values, regardless of their order. Each file is rendered once, after the files
+	// defining the values it references, and referencing each other in a cycle is an error.
+	// The values are still merged in the order of the files, according to MergeStrategy.
+	LazyValues *bool `yaml:"lazyValues,omitempty"`
+
+	// ValuesSchema is the path to a JSON schema the merged values of the environment must conform to.
+	ValuesSchema string `yaml:"valuesSchema,omitempty"`
 }
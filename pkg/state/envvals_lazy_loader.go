@@ -0,0 +1,354 @@
+package state
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template/parse"
+
+	"dario.cat/mergo"
+
+	"github.com/helmfile/helmfile/pkg/environment"
+	"github.com/helmfile/helmfile/pkg/hcllang"
+	"github.com/helmfile/helmfile/pkg/maputil"
+)
+
+// lazyValuesKeyRegexp matches a line of a values file defining a top-level key, like `cluster:`.
+var lazyValuesKeyRegexp = regexp.MustCompile(`^["']?([^\s#"':{}\[\]]+)["']?\s*:(?:\s|$)`)
+
+// lazyValuesEntry is an entry of the values of an environment with lazyValues.
+type lazyValuesEntry struct {
+	// file is the values file of the entry, or empty for inline values
+	file string
+	vals map[string]any
+	done bool
+
+	// refs are the values the template references, like `cluster.domain`.
+	// An empty ref is a reference to the whole .Values.
+	refs []string
+	// defines are the top-level keys the template defines outside of its actions
+	defines map[string]bool
+	// needs are the templates defining the values the template references,
+	// along with the first of these references
+	needs []lazyValuesNeed
+}
+
+type lazyValuesNeed struct {
+	entry *lazyValuesEntry
+	ref   string
+}
+
+// LoadLazyEnvironmentValues loads the values like LoadEnvironmentValues, except that every template sees
+// the values of all the other entries regardless of their order.
+//
+// The references to .Values of the templates are parsed beforehand, and each template is rendered once,
+// after the templates defining the values it references. The values are then merged in the order of the entries.
+func (ld *EnvironmentValuesLoader) LoadLazyEnvironmentValues(missingFileHandler *string, valuesEntries []any, ctxEnv *environment.Environment, envName string, mergeStrategy string) (map[string]any, error) {
+	switch mergeStrategy {
+	case "", MergeStrategyOverride, MergeStrategyFallback:
+	default:
+		return nil, fmt.Errorf("environment %q: invalid mergeStrategy %q (must be %q or %q)",
+			envName, mergeStrategy, MergeStrategyOverride, MergeStrategyFallback)
+	}
+
+	env := *environment.New(envName)
+	if ctxEnv != nil {
+		env = *ctxEnv
+	}
+	envVals, err := env.GetMergedValues()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merged values for environment %q: %v", envName, err)
+	}
+
+	hclLoader := hcllang.NewHCLLoader(ld.fs, ld.logger)
+	hclLoader.SetSandbox(ld.sandbox)
+
+	var entries, templates []*lazyValuesEntry
+	for _, entry := range valuesEntries {
+		switch strOrMap := entry.(type) {
+		case string:
+			files, skipped, err := ld.storage.resolveFile(missingFileHandler, "environment values", strOrMap)
+			if err != nil {
+				return nil, err
+			}
+			if skipped {
+				continue
+			}
+			for _, f := range files {
+				if strings.HasSuffix(f, ".hcl") {
+					hclLoader.AddFile(f)
+					continue
+				}
+				e := &lazyValuesEntry{file: f}
+				if strings.HasSuffix(f, ".gotmpl") {
+					if err := ld.parseLazyValuesTemplate(e); err != nil {
+						return nil, err
+					}
+					templates = append(templates, e)
+				} else {
+					// Plain values files don't reference any value, so they are loaded right away
+					if e.vals, err = ld.loadValuesFile(f, env, nil); err != nil {
+						return nil, err
+					}
+					e.done = true
+				}
+				entries = append(entries, e)
+			}
+		case map[any]any, map[string]any:
+			vals, err := maputil.CastKeysToStrings(strOrMap)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, &lazyValuesEntry{vals: vals, done: true})
+		default:
+			return nil, fmt.Errorf("unexpected type of value: value=%v, type=%T", strOrMap, strOrMap)
+		}
+	}
+
+	for _, e := range templates {
+		e.needs = lazyValuesNeeds(templates, e)
+	}
+
+	for pending := len(templates); pending > 0; pending-- {
+		e := nextLazyValuesTemplate(templates)
+		if e == nil {
+			return nil, lazyValuesCycleError(envName, templates)
+		}
+
+		vals, err := lazyTemplateValues(entries, envVals, mergeStrategy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build template context for \"%s\": %v", e.file, err)
+		}
+		m, err := ld.loadValuesFile(e.file, env, vals)
+		if err != nil {
+			return nil, err
+		}
+		ld.logger.Debugf("envvals_loader: loaded %s:%v", e.file, m)
+		e.vals, e.done = m, true
+	}
+
+	result := map[string]any{}
+	for _, e := range entries {
+		result, err = mapMerge(result, []any{e.vals}, mergeStrategy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if hclLoader.Length() > 0 {
+		m, err := hclLoader.HCLRender()
+		if err != nil {
+			return nil, err
+		}
+		result, err = mapMerge(result, []any{m}, mergeStrategy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// parseLazyValuesTemplate parses the template of e to find the values it references and the top-level keys it defines.
+func (ld *EnvironmentValuesLoader) parseLazyValuesTemplate(e *lazyValuesEntry) error {
+	content, err := ld.fs.ReadFile(e.file)
+	if err != nil {
+		return fmt.Errorf("failed to load environment values file \"%s\": %v", e.file, err)
+	}
+
+	tree := parse.New(e.file)
+	tree.Mode = parse.SkipFuncCheck
+	trees := map[string]*parse.Tree{}
+	if _, err := tree.Parse(string(content), "", "", trees); err != nil {
+		return fmt.Errorf("failed to load environment values file \"%s\": %v", e.file, err)
+	}
+
+	e.defines = map[string]bool{}
+	for _, t := range trees {
+		walkLazyValuesNode(t.Root, string(content), e)
+	}
+	return nil
+}
+
+// walkLazyValuesNode collects the references to .Values and the top-level keys defined under node into e.
+func walkLazyValuesNode(node parse.Node, content string, e *lazyValuesEntry) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			walkLazyValuesNode(c, content, e)
+		}
+	case *parse.TextNode:
+		lines := strings.Split(string(n.Text), "\n")
+		// The first line of the text continues the line of the preceding action, if any
+		if n.Pos > 0 && content[n.Pos-1] != '\n' {
+			lines = lines[1:]
+		}
+		for _, l := range lines {
+			if m := lazyValuesKeyRegexp.FindStringSubmatch(l); m != nil {
+				e.defines[m[1]] = true
+			}
+		}
+	case *parse.ActionNode:
+		walkLazyValuesNode(n.Pipe, content, e)
+	case *parse.IfNode:
+		walkLazyValuesBranch(&n.BranchNode, content, e)
+	case *parse.RangeNode:
+		walkLazyValuesBranch(&n.BranchNode, content, e)
+	case *parse.WithNode:
+		walkLazyValuesBranch(&n.BranchNode, content, e)
+	case *parse.TemplateNode:
+		walkLazyValuesNode(n.Pipe, content, e)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		whole := false
+		var literals []string
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				switch a := arg.(type) {
+				case *parse.StringNode:
+					literals = append(literals, a.Text)
+				case *parse.FieldNode:
+					whole = addLazyValuesRef(a.Ident, e) || whole
+				case *parse.VariableNode:
+					if len(a.Ident) > 1 && a.Ident[0] == "$" {
+						whole = addLazyValuesRef(a.Ident[1:], e) || whole
+					}
+				case *parse.ChainNode:
+					walkLazyValuesNode(a.Node, content, e)
+				case *parse.PipeNode:
+					walkLazyValuesNode(a, content, e)
+				}
+			}
+		}
+		if !whole {
+			return
+		}
+		// A pipeline passing the whole .Values to functions like get, hasKey or dig references the keys
+		// its string literals name. Without any, it may reference any value.
+		if len(literals) == 0 {
+			e.refs = append(e.refs, "")
+		}
+		e.refs = append(e.refs, literals...)
+	}
+}
+
+func walkLazyValuesBranch(n *parse.BranchNode, content string, e *lazyValuesEntry) {
+	walkLazyValuesNode(n.Pipe, content, e)
+	walkLazyValuesNode(n.List, content, e)
+	walkLazyValuesNode(n.ElseList, content, e)
+}
+
+// addLazyValuesRef adds the value ident references to e, like `cluster.domain` for `.Values.cluster.domain`.
+// It returns true when ident is the whole .Values.
+func addLazyValuesRef(ident []string, e *lazyValuesEntry) bool {
+	if len(ident) == 0 || (ident[0] != "Values" && ident[0] != "StateValues") {
+		return false
+	}
+	if len(ident) == 1 {
+		return true
+	}
+	e.refs = append(e.refs, strings.Join(ident[1:], "."))
+	return false
+}
+
+// lazyValuesNeeds returns the other templates defining the top-level keys of the values e references.
+func lazyValuesNeeds(templates []*lazyValuesEntry, e *lazyValuesEntry) []lazyValuesNeed {
+	var needs []lazyValuesNeed
+	for _, t := range templates {
+		if t == e {
+			continue
+		}
+		for _, ref := range e.refs {
+			if ref == "" || t.defines[strings.SplitN(ref, ".", 2)[0]] {
+				needs = append(needs, lazyValuesNeed{entry: t, ref: ref})
+				break
+			}
+		}
+	}
+	return needs
+}
+
+// nextLazyValuesTemplate returns the first template not rendered yet whose needs are all rendered, if any.
+func nextLazyValuesTemplate(templates []*lazyValuesEntry) *lazyValuesEntry {
+	for _, t := range templates {
+		if t.done {
+			continue
+		}
+		ready := true
+		for _, n := range t.needs {
+			if !n.entry.done {
+				ready = false
+				break
+			}
+		}
+		if ready {
+			return t
+		}
+	}
+	return nil
+}
+
+// lazyValuesCycleError returns the error of the templates that can't be rendered. Every one of them
+// needs another one not rendered yet, so following their needs leads to a cycle.
+func lazyValuesCycleError(envName string, templates []*lazyValuesEntry) error {
+	var e *lazyValuesEntry
+	for _, t := range templates {
+		if !t.done {
+			e = t
+			break
+		}
+	}
+
+	// chain is the templates followed so far, along with the reference to the next one
+	var chain []lazyValuesNeed
+	for {
+		for i, c := range chain {
+			if c.entry != e {
+				continue
+			}
+			var b strings.Builder
+			for _, c := range chain[i:] {
+				ref := ".Values"
+				if c.ref != "" {
+					ref += "." + c.ref
+				}
+				fmt.Fprintf(&b, "%s (references %s) -> ", c.entry.file, ref)
+			}
+			b.WriteString(e.file)
+			return fmt.Errorf("environment %q: values files form a cycle: %s", envName, b.String())
+		}
+
+		for _, n := range e.needs {
+			if !n.entry.done {
+				chain = append(chain, lazyValuesNeed{entry: e, ref: n.ref})
+				e = n.entry
+				break
+			}
+		}
+	}
+}
+
+// lazyTemplateValues returns the .Values of the templates: the values of the loaded entries, merged
+// in their order, overridden by the values of the environment so that they still win like in LoadEnvironmentValues.
+func lazyTemplateValues(entries []*lazyValuesEntry, envVals map[string]any, mergeStrategy string) (map[string]any, error) {
+	vals := map[string]any{}
+	for _, e := range entries {
+		if !e.done {
+			continue
+		}
+		var err error
+		vals, err = mapMerge(vals, []any{e.vals}, mergeStrategy)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := mergo.Merge(&vals, envVals, mergo.WithOverride); err != nil {
+		return nil, err
+	}
+	return vals, nil
+}
@@ -25,6 +25,8 @@ type EnvironmentValuesLoader struct {
 	logger *zap.SugaredLogger
 
 	remote *remote.Remote
+
+	sandbox *tmpl.Sandbox
 }
 
 func NewEnvironmentValuesLoader(storage *Storage, fs *filesystem.FileSystem, logger *zap.SugaredLogger, remote *remote.Remote) *EnvironmentValuesLoader {
@@ -48,6 +50,7 @@ func (ld *EnvironmentValuesLoader) LoadEnvironmentValues(missingFileHandler *str
 		hclLoader = hcllang.NewHCLLoader(ld.fs, ld.logger)
 		err       error
 	)
+	hclLoader.SetSandbox(ld.sandbox)
 
 	for _, entry := range valuesEntries {
 		switch strOrMap := entry.(type) {
@@ -91,15 +94,9 @@ func (ld *EnvironmentValuesLoader) LoadEnvironmentValues(missingFileHandler *str
 					}
 					mergedVals = enriched
 				}
-				tmplData := NewEnvironmentTemplateData(env, "", mergedVals)
-				r := tmpl.NewFileRenderer(ld.fs, filepath.Dir(f), tmplData)
-				bytes, err := r.RenderToBytes(f)
+				m, err := ld.loadValuesFile(f, env, mergedVals)
 				if err != nil {
-					return nil, fmt.Errorf("failed to load environment values file \"%s\": %v", f, err)
-				}
-				m := map[string]any{}
-				if err := yaml.Unmarshal(bytes, &m); err != nil {
-					return nil, fmt.Errorf("failed to load environment values file \"%s\": %v\n\nOffending YAML:\n%s", f, err, bytes)
+					return nil, err
 				}
 				ld.logger.Debugf("envvals_loader: loaded %s:%v", strOrMap, m)
 				// Merge each file into result immediately so subsequent files in the same
@@ -134,6 +131,22 @@ func (ld *EnvironmentValuesLoader) LoadEnvironmentValues(missingFileHandler *str
 	return result, nil
 }
 
+// loadValuesFile loads the environment values file f. When f is a template, it is rendered with vals as .Values.
+func (ld *EnvironmentValuesLoader) loadValuesFile(f string, env environment.Environment, vals map[string]any) (map[string]any, error) {
+	tmplData := NewEnvironmentTemplateData(env, "", vals)
+	r := tmpl.NewFileRenderer(ld.fs, filepath.Dir(f), tmplData)
+	r.Context.SetSandbox(ld.sandbox)
+	bytes, err := r.RenderToBytes(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environment values file \"%s\": %v", f, err)
+	}
+	m := map[string]any{}
+	if err := yaml.Unmarshal(bytes, &m); err != nil {
+		return nil, fmt.Errorf("failed to load environment values file \"%s\": %v\n\nOffending YAML:\n%s", f, err, bytes)
+	}
+	return m, nil
+}
+
 func mapMerge(dest map[string]any, maps []any, mergeStrategy string) (map[string]any, error) {
 	for _, m := range maps {
 		// All the nested map key should be string. Otherwise we get strange errors due to that
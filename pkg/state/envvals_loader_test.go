@@ -537,3 +537,123 @@ func TestEnvValsLoad_InvalidStrategy_Errors(t *testing.T) {
 		}
 	}
 }
+
+// With lazy values, a template can reference values defined by files listed after it.
+func TestEnvValsLoad_LazyValues(t *testing.T) {
+	l := newLoader()
+
+	actual, err := l.LoadLazyEnvironmentValues(nil,
+		[]any{
+			"testdata/lazyvalues/ingress.yaml.gotmpl",
+			"testdata/lazyvalues/service.yaml.gotmpl",
+			"testdata/lazyvalues/cluster.yaml",
+			map[string]any{"cluster": map[string]any{"region": "us-east-1"}},
+		},
+		nil, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The values are still merged in order: the inline values override cluster.yaml,
+	// including the value service.yaml.gotmpl was rendered with.
+	expected := map[string]any{
+		"ingress": map[string]any{"host": "www.service.prod.example.com"},
+		"service": map[string]any{"domain": "service.prod.example.com", "region": "us-east-1"},
+		"cluster": map[string]any{"domain": "prod.example.com", "region": "us-east-1"},
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Error(diff)
+	}
+
+	// Without lazy values, the templates only see the environment values
+	_, err = l.LoadEnvironmentValues(nil,
+		[]any{"testdata/lazyvalues/service.yaml.gotmpl", "testdata/lazyvalues/cluster.yaml"},
+		nil, "", "")
+	if err == nil || !strings.Contains(err.Error(), `map has no entry for key "cluster"`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// The values of the environment win over the values of the files in the templates.
+func TestEnvValsLoad_LazyValues_EnvironmentValuesWin(t *testing.T) {
+	l := newLoader()
+
+	env := environment.New("test")
+	env.Values = map[string]any{"cluster": map[string]any{"domain": "staging.example.com"}}
+
+	actual, err := l.LoadLazyEnvironmentValues(nil,
+		[]any{"testdata/lazyvalues/service.yaml.gotmpl", "testdata/lazyvalues/cluster.yaml"},
+		env, "test", MergeStrategyFallback)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]any{
+		"service": map[string]any{"domain": "service.staging.example.com", "region": "eu-west-1"},
+		"cluster": map[string]any{"domain": "prod.example.com", "region": "eu-west-1"},
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Error(diff)
+	}
+}
+
+// Templates using defaults for missing values are rendered after the files defining them.
+func TestEnvValsLoad_LazyValues_Defaults(t *testing.T) {
+	l := newLoader()
+
+	actual, err := l.LoadLazyEnvironmentValues(nil,
+		[]any{
+			"testdata/lazyvalues/defaults.yaml.gotmpl",
+			"testdata/lazyvalues/service.yaml.gotmpl",
+			"testdata/lazyvalues/tls.yaml.gotmpl",
+			"testdata/lazyvalues/cluster.yaml",
+		},
+		nil, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]any{
+		"defaults": map[string]any{"domain": "service.prod.example.com", "tls": true},
+		"service":  map[string]any{"domain": "service.prod.example.com", "region": "eu-west-1"},
+		"tls":      map[string]any{"enabled": true},
+		"cluster":  map[string]any{"domain": "prod.example.com", "region": "eu-west-1"},
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestEnvValsLoad_LazyValues_Cycle(t *testing.T) {
+	l := newLoader()
+
+	_, err := l.LoadLazyEnvironmentValues(nil,
+		[]any{"testdata/lazyvalues/cluster.yaml", "testdata/lazyvalues/a.yaml.gotmpl", "testdata/lazyvalues/b.yaml.gotmpl", "testdata/lazyvalues/c.yaml.gotmpl"},
+		nil, "default", "")
+
+	expected := `environment "default": values files form a cycle: ` +
+		`testdata/lazyvalues/a.yaml.gotmpl (references .Values.b) -> ` +
+		`testdata/lazyvalues/b.yaml.gotmpl (references .Values.c) -> ` +
+		`testdata/lazyvalues/c.yaml.gotmpl (references .Values.a) -> ` +
+		`testdata/lazyvalues/a.yaml.gotmpl`
+	if err == nil || err.Error() != expected {
+		t.Errorf("unexpected error: want %q, got %v", expected, err)
+	}
+}
+
+// A value no file defines is reported with the error of the template referencing it.
+func TestEnvValsLoad_LazyValues_Undefined(t *testing.T) {
+	l := newLoader()
+
+	_, err := l.LoadLazyEnvironmentValues(nil,
+		[]any{"testdata/lazyvalues/ingress.yaml.gotmpl", "testdata/lazyvalues/undefined.yaml.gotmpl", "testdata/lazyvalues/service.yaml.gotmpl", "testdata/lazyvalues/cluster.yaml"},
+		nil, "default", "")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{`failed to load environment values file "testdata/lazyvalues/undefined.yaml.gotmpl"`, `map has no entry for key "undefined"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected the error to contain %q, got %v", want, err)
+		}
+	}
+}
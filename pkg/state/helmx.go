@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"slices"
@@ -61,9 +62,39 @@ func formatLabels(labels map[string]string) string {
 	return strings.Join(labelsList, ",")
 }
 
+const (
+	// OwnerProjectLabel and OwnerEnvironmentLabel are the labels helmfile sets on the releases it installs
+	// when helmDefaults.project is set, identifying the helmfile project and environment owning them
+	OwnerProjectLabel     = "helmfile.sh/project"
+	OwnerEnvironmentLabel = "helmfile.sh/environment"
+)
+
+// OwnerSelector returns the label selector of the releases owned by the project and environment of st,
+// or the empty string when the project is not set.
+func (st *HelmState) OwnerSelector() string {
+	if st.HelmDefaults.Project == "" {
+		return ""
+	}
+	return formatLabels(st.ownerLabels())
+}
+
+func (st *HelmState) ownerLabels() map[string]string {
+	if st.HelmDefaults.Project == "" {
+		return nil
+	}
+	return map[string]string{
+		OwnerProjectLabel:     st.HelmDefaults.Project,
+		OwnerEnvironmentLabel: st.Env.Name,
+	}
+}
+
 // append labels flags to helm flags, starting from helm v3.13.0
+// The labels identifying the owner of the release are always set, while the release labels are set only when they are synced.
 func (st *HelmState) appendLabelsFlags(flags []string, helm helmexec.Interface, release *ReleaseSpec, syncReleaseLabels bool) []string {
 	if !helm.IsVersionAtLeast("3.13.0") {
+		if st.HelmDefaults.Project != "" {
+			st.logger.Warnf("helmDefaults.project requires helm 3.13.0 or greater to label the release %q with its owner", release.Name)
+		}
 		return flags
 	}
 	isSyncReleaseLabels := false
@@ -78,11 +109,13 @@ func (st *HelmState) appendLabelsFlags(flags []string, helm helmexec.Interface,
 	case st.HelmDefaults.SyncReleaseLabels != nil && *st.HelmDefaults.SyncReleaseLabels:
 		isSyncReleaseLabels = true
 	}
+	labels := map[string]string{}
 	if isSyncReleaseLabels {
-		labels := formatLabels(release.Labels)
-		if labels != "" {
-			flags = append(flags, "--labels", labels)
-		}
+		maps.Copy(labels, release.Labels)
+	}
+	maps.Copy(labels, st.ownerLabels())
+	if formatted := formatLabels(labels); formatted != "" {
+		flags = append(flags, "--labels", formatted)
 	}
 	return flags
 }
@@ -156,6 +189,7 @@ func (st *HelmState) renderPostRendererArgs(release *ReleaseSpec, args []string)
 
 	tmplData := st.createReleaseTemplateData(release, vals)
 	renderer := tmpl.NewFileRenderer(fs, st.basePath, tmplData)
+	renderer.Context.SetSandbox(st.Sandbox)
 
 	result := make([]string, 0, len(args))
 	for _, arg := range args {
@@ -465,7 +499,10 @@ func (st *HelmState) startBackgroundKubedogTracking(
 	resultCh := make(chan error, 1)
 
 	go func() {
-		resultCh <- tracker.TrackResources(trackCtx, resources)
+		err := tracker.TrackResources(trackCtx, resources)
+		stats := tracker.Stats()
+		release.tracked = &stats
+		resultCh <- err
 	}()
 
 	// Two related safety valves run alongside the tracker. Both verify cluster
@@ -1150,7 +1187,10 @@ func (st *HelmState) trackWithKubedog(ctx context.Context, release *ReleaseSpec,
 		st.logger.Infof("Tracking breakdown: %s", breakdown)
 	}
 
-	if err := tracker.TrackResources(ctx, resources); err != nil {
+	err = tracker.TrackResources(ctx, resources)
+	stats := tracker.Stats()
+	release.tracked = &stats
+	if err != nil {
 		return fmt.Errorf("kubedog tracking failed for release %s: %w", release.Name, err)
 	}
 
@@ -6,6 +6,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/helmfile/helmfile/pkg/environment"
 	"github.com/helmfile/helmfile/pkg/helmexec"
 	"github.com/helmfile/helmfile/pkg/testutil"
 )
@@ -586,6 +587,62 @@ func TestFormatLabels(t *testing.T) {
 	}
 }
 
+func TestAppendLabelsFlags(t *testing.T) {
+	tests := []struct {
+		name              string
+		helmSpec          HelmSpec
+		syncReleaseLabels bool
+		version           string
+		expected          []string
+	}{
+		{
+			name:     "no project nor synced labels",
+			version:  "3.15.0",
+			expected: nil,
+		},
+		{
+			name:              "synced labels",
+			syncReleaseLabels: true,
+			version:           "3.15.0",
+			expected:          []string{"--labels", "tier=web"},
+		},
+		{
+			name:     "project",
+			helmSpec: HelmSpec{Project: "platform"},
+			version:  "3.15.0",
+			expected: []string{"--labels", "helmfile.sh/environment=prod,helmfile.sh/project=platform"},
+		},
+		{
+			name:              "project and synced labels",
+			helmSpec:          HelmSpec{Project: "platform"},
+			syncReleaseLabels: true,
+			version:           "3.15.0",
+			expected:          []string{"--labels", "helmfile.sh/environment=prod,helmfile.sh/project=platform,tier=web"},
+		},
+		{
+			name:     "project with helm not supporting labels",
+			helmSpec: HelmSpec{Project: "platform"},
+			version:  "3.12.0",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &HelmState{
+				logger: logger,
+				ReleaseSetSpec: ReleaseSetSpec{
+					HelmDefaults: tt.helmSpec,
+					Env:          environment.Environment{Name: "prod"},
+				},
+			}
+			release := &ReleaseSpec{Name: "web", Labels: map[string]string{"tier": "web"}}
+			got := st.appendLabelsFlags(nil, testutil.NewVersionHelmExec(tt.version), release, tt.syncReleaseLabels)
+			require.Equal(t, tt.expected, got)
+		})
+	}
+}
+
 func TestGetReleaseHardTimeout(t *testing.T) {
 	intPtr := func(i int) *int { return &i }
 	tests := []struct {
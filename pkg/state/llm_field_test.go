@@ -1,6 +1,7 @@
 package state
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -22,6 +23,7 @@ import (
 //   - Missing llm: block leaves ReleaseSetSpec.LLM as zero-value Config.
 //   - `llm: {}` (explicit empty) is equivalent to missing.
 //   - Partial llm: block (only APIKey + Model) sets only those fields.
+//   - llm.redaction rules unmarshal into Config.Redaction.
 //
 // We do NOT test template rendering ({{ env "KEY" }}) here — that is helmfile
 // core two-pass renderer behavior, exercised in pkg/app tests.
@@ -90,6 +92,24 @@ llm: {}
 			want:    llm.Config{},
 			wantSet: false,
 		},
+		{
+			name: "redaction rules",
+			yaml: `
+llm:
+  redaction:
+    patterns: ['[a-z]+\.corp\.example\.com']
+    keys: [customerId]
+    allowlist: ['^sha256:']
+`,
+			want: llm.Config{
+				Redaction: llm.RedactionRules{
+					Patterns:  []string{`[a-z]+\.corp\.example\.com`},
+					Keys:      []string{"customerId"},
+					Allowlist: []string{"^sha256:"},
+				},
+			},
+			wantSet: false, // redaction alone does not configure an endpoint
+		},
 	}
 
 	for _, tt := range tests {
@@ -101,7 +121,7 @@ llm: {}
 				t.Fatalf("Unmarshal failed: %v", err)
 			}
 			got := st.LLM
-			if got != tt.want {
+			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("LLM mismatch:\n got  = %+v\n want = %+v", got, tt.want)
 			}
 			// ReleaseSpec sanity: when the yaml contained releases, they must
@@ -0,0 +1,379 @@
+package state
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	v3 "go.yaml.in/yaml/v3"
+)
+
+// JSONSchemaID is the ID of the JSON schema of helmfile.yaml.
+const JSONSchemaID = "https://github.com/helmfile/helmfile/schema/helmfile.json"
+
+// schemaGenerator generates the JSON schema of helmfile.yaml from the types helmfiles are decoded to.
+type schemaGenerator struct {
+	defs map[string]any
+}
+
+// override returns the schema of t when it's decoded by its own UnmarshalYAML, which accepts
+// more than what its fields describe.
+func (g *schemaGenerator) override(t reflect.Type) (map[string]any, bool) {
+	switch t {
+	case reflect.TypeOf(time.Duration(0)):
+		return map[string]any{"type": []any{"string", "integer"}}, true
+	case reflect.TypeOf(Inherits{}):
+		inherit := g.schema(reflect.TypeOf(Inherit{}))
+		return map[string]any{"oneOf": []any{
+			map[string]any{"type": "array", "items": inherit},
+			inherit,
+		}}, true
+	case reflect.TypeOf(DefaultInherits{}):
+		return map[string]any{"oneOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		}}, true
+	case reflect.TypeOf(SubHelmfileSpec{}):
+		return map[string]any{"oneOf": []any{
+			map[string]any{"type": "string"},
+			g.structSchema(t),
+		}}, true
+	}
+	return nil, false
+}
+
+// JSONSchema returns the JSON schema of helmfile.yaml, generated from ReleaseSetSpec.
+func JSONSchema() map[string]any {
+	g := &schemaGenerator{defs: map[string]any{}}
+	schema := g.structSchema(reflect.TypeOf(ReleaseSetSpec{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["$id"] = JSONSchemaID
+	schema["title"] = "helmfile.yaml"
+	schema["$defs"] = g.defs
+	return schema
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]any {
+	if schema, ok := g.override(t); ok {
+		return schema
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.Struct:
+		name := g.defName(t)
+		if _, ok := g.defs[name]; !ok {
+			// Registered before being generated, for the types referencing themselves
+			g.defs[name] = nil
+			g.defs[name] = g.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/$defs/" + name}
+	case reflect.Map:
+		schema := map[string]any{"type": "object"}
+		if t.Elem().Kind() != reflect.Interface {
+			schema["additionalProperties"] = g.schema(t.Elem())
+		}
+		return schema
+	case reflect.Slice, reflect.Array:
+		schema := map[string]any{"type": "array"}
+		if t.Elem().Kind() != reflect.Interface {
+			schema["items"] = g.schema(t.Elem())
+		}
+		return schema
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	}
+	return map[string]any{}
+}
+
+// defName returns the name of the definition of the struct type t, prefixed with its package
+// when it's not a type of this package, like `event.Hook`.
+func (g *schemaGenerator) defName(t reflect.Type) string {
+	if t.PkgPath() == reflect.TypeOf(HelmState{}).PkgPath() {
+		return t.Name()
+	}
+	return path.Base(t.PkgPath()) + "." + t.Name()
+}
+
+// structSchema returns the schema of the struct type t, which has a property per field like go-yaml decodes them.
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	g.addProperties(props, t)
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+}
+
+func (g *schemaGenerator) addProperties(props map[string]any, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if strings.Contains(opts, "inline") {
+			g.addProperties(props, f.Type)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		props[name] = g.schema(f.Type)
+	}
+}
+
+// UnknownKey is a key of a helmfile that isn't in the schema of helmfile.yaml.
+type UnknownKey struct {
+	// File and Line are where the key is. Line is the line in the YAML the key was found in, until
+	// the caller maps it to the line of the file.
+	File string
+	Line int
+
+	// Path is the path of the key, like `releases[0].namepsace`
+	Path string
+	Key  string
+
+	// Suggestion is the allowed key the key is likely a typo of, like `namespace`
+	Suggestion string
+	// Locations are the paths the key is allowed at when it's misplaced, like `helmDefaults`
+	Locations []string
+}
+
+func (k UnknownKey) String() string {
+	msg := fmt.Sprintf("%s:%d: %s: ", k.File, k.Line, k.Path)
+	switch {
+	case k.Suggestion != "":
+		msg += fmt.Sprintf("unknown key, did you mean %q?", k.Suggestion)
+	case len(k.Locations) > 0:
+		msg += fmt.Sprintf("misplaced key, it is only allowed under %s", strings.Join(k.Locations, ", "))
+	default:
+		msg += "unknown key"
+	}
+	return msg
+}
+
+// UnknownKeysError is returned in strict mode when helmfiles have keys that aren't in the schema of helmfile.yaml.
+type UnknownKeysError struct {
+	Keys []UnknownKey
+}
+
+func (e *UnknownKeysError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "found %d unknown or misplaced keys:", len(e.Keys))
+	for _, k := range e.Keys {
+		fmt.Fprintf(&b, "\n  %s", k)
+	}
+	return b.String()
+}
+
+// FindUnknownKeys returns the keys of the YAML documents in content that aren't in the schema of helmfile.yaml,
+// in the order they appear in content.
+func FindUnknownKeys(content []byte) ([]UnknownKey, error) {
+	schema := JSONSchema()
+	c := &keyChecker{
+		defs:      schema["$defs"].(map[string]any),
+		locations: map[string][]string{},
+	}
+	c.indexLocations(schema, "", map[string]bool{})
+
+	decoder := v3.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc v3.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		c.check(&doc, schema, "")
+	}
+
+	return c.unknown, nil
+}
+
+type keyChecker struct {
+	defs map[string]any
+	// locations are the paths of the objects each key is allowed in, like `releases[]`
+	locations map[string][]string
+	unknown   []UnknownKey
+}
+
+func (c *keyChecker) resolve(schema map[string]any) map[string]any {
+	for {
+		ref, ok := schema["$ref"].(string)
+		if !ok {
+			return schema
+		}
+		schema = c.defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+	}
+}
+
+// indexLocations records the paths each key of the objects of schema is allowed at.
+// visiting are the definitions being visited, not to loop over the ones referencing themselves.
+func (c *keyChecker) indexLocations(schema map[string]any, at string, visiting map[string]bool) {
+	if ref, ok := schema["$ref"].(string); ok {
+		if visiting[ref] {
+			return
+		}
+		visiting[ref] = true
+		defer delete(visiting, ref)
+	}
+	schema = c.resolve(schema)
+
+	if branches, ok := schema["oneOf"].([]any); ok {
+		for _, b := range branches {
+			c.indexLocations(b.(map[string]any), at, visiting)
+		}
+		return
+	}
+
+	location := at
+	if location == "" {
+		location = "the top level"
+	}
+	if props, ok := schema["properties"].(map[string]any); ok {
+		for k, p := range props {
+			c.locations[k] = append(c.locations[k], location)
+			c.indexLocations(p.(map[string]any), joinKeyPath(at, k), visiting)
+		}
+	}
+	if ap, ok := schema["additionalProperties"].(map[string]any); ok {
+		c.indexLocations(ap, joinKeyPath(at, "<name>"), visiting)
+	}
+	if items, ok := schema["items"].(map[string]any); ok {
+		c.indexLocations(items, at+"[]", visiting)
+	}
+}
+
+func (c *keyChecker) check(node *v3.Node, schema map[string]any, at string) {
+	for node.Kind == v3.AliasNode {
+		node = node.Alias
+	}
+	schema = c.resolve(schema)
+
+	if branches, ok := schema["oneOf"].([]any); ok {
+		for _, b := range branches {
+			if branch := c.resolve(b.(map[string]any)); matchesKind(node, branch) {
+				c.check(node, branch, at)
+				return
+			}
+		}
+		return
+	}
+
+	switch node.Kind {
+	case v3.DocumentNode:
+		for _, n := range node.Content {
+			c.check(n, schema, at)
+		}
+	case v3.SequenceNode:
+		items, ok := schema["items"].(map[string]any)
+		if !ok {
+			return
+		}
+		for i, n := range node.Content {
+			c.check(n, items, fmt.Sprintf("%s[%d]", at, i))
+		}
+	case v3.MappingNode:
+		props, _ := schema["properties"].(map[string]any)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, v := node.Content[i], node.Content[i+1]
+			if k.Value == "<<" {
+				c.check(v, schema, at)
+				continue
+			}
+			if p, ok := props[k.Value]; ok {
+				c.check(v, p.(map[string]any), joinKeyPath(at, k.Value))
+				continue
+			}
+			switch ap := schema["additionalProperties"].(type) {
+			case map[string]any:
+				c.check(v, ap, joinKeyPath(at, k.Value))
+			case bool:
+				if !ap {
+					c.unknown = append(c.unknown, c.unknownKey(k, at, props))
+				}
+			}
+		}
+	}
+}
+
+func (c *keyChecker) unknownKey(k *v3.Node, at string, props map[string]any) UnknownKey {
+	key := UnknownKey{Line: k.Line, Path: joinKeyPath(at, k.Value), Key: k.Value}
+
+	allowed := make([]string, 0, len(props))
+	for p := range props {
+		allowed = append(allowed, p)
+	}
+	sort.Strings(allowed)
+	// Only the keys close enough not to be a different word are suggested
+	best := min(3, len(k.Value)/2+1)
+	for _, p := range allowed {
+		if d := editDistance(strings.ToLower(k.Value), strings.ToLower(p)); d < best {
+			best, key.Suggestion = d, p
+		}
+	}
+	if key.Suggestion == "" {
+		key.Locations = c.locations[k.Value]
+		sort.Strings(key.Locations)
+	}
+	return key
+}
+
+func matchesKind(node *v3.Node, schema map[string]any) bool {
+	switch schema["type"] {
+	case "object":
+		return node.Kind == v3.MappingNode
+	case "array":
+		return node.Kind == v3.SequenceNode
+	}
+	return node.Kind == v3.ScalarNode
+}
+
+func joinKeyPath(at, key string) string {
+	if at == "" {
+		return key
+	}
+	return at + "." + key
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,108 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/stretchr/testify/require"
+
+	"github.com/helmfile/helmfile/pkg/yaml"
+)
+
+func TestJSONSchema(t *testing.T) {
+	bs, err := json.Marshal(JSONSchema())
+	require.NoError(t, err)
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(bs))
+	require.NoError(t, err)
+
+	c := jsonschema.NewCompiler()
+	require.NoError(t, c.AddResource(JSONSchemaID, doc))
+	schema, err := c.Compile(JSONSchemaID)
+	require.NoError(t, err)
+
+	validate := func(t *testing.T, content string) error {
+		t.Helper()
+		vals := map[string]any{}
+		require.NoError(t, yaml.Unmarshal([]byte(content), &vals))
+		instance, err := toJSONValue(vals)
+		require.NoError(t, err)
+		return schema.Validate(instance)
+	}
+
+	require.NoError(t, validate(t, `helmDefaults:
+  wait: true
+  timeout: 600
+environments:
+  default:
+    values:
+    - values.yaml
+repositories:
+- name: stable
+  url: https://charts.example.com
+helmfiles:
+- sub.yaml
+- path: other.yaml
+  selectors:
+  - name=web
+  values:
+  - foo: bar
+releases:
+- name: web
+  chart: stable/web
+  namespace: web
+  inherit:
+    template: default
+  hooks:
+  - events: ["presync"]
+    command: echo
+templates:
+  default:
+    atomic: true
+`))
+
+	require.Error(t, validate(t, "releases:\n- name: web\n  atomc: true\n"))
+}
+
+func TestFindUnknownKeys(t *testing.T) {
+	keys, err := FindUnknownKeys([]byte(`helmDefaults:
+  wait: true
+  atomic: true
+releases:
+- name: web
+  chart: stable/web
+  namepsace: web
+  releases: []
+- name: db
+  chart: stable/db
+  hooks:
+  - events: ["presync"]
+    comand: echo
+wait: true
+helmfiles:
+- path: sub.yaml
+  environment:
+    values: []
+  nonsense: true
+---
+templates:
+  default: &default
+    atomc: true
+`))
+	require.NoError(t, err)
+
+	var got []string
+	for _, k := range keys {
+		got = append(got, k.String())
+	}
+	require.Equal(t, []string{
+		`:7: releases[0].namepsace: unknown key, did you mean "namespace"?`,
+		`:8: releases[0].releases: misplaced key, it is only allowed under the top level`,
+		`:13: releases[1].hooks[0].comand: unknown key, did you mean "command"?`,
+		`:14: wait: misplaced key, it is only allowed under helmDefaults, releases[], templates.<name>`,
+		`:17: helmfiles[0].environment: unknown key`,
+		`:19: helmfiles[0].nonsense: unknown key`,
+		`:23: templates.default.atomc: unknown key, did you mean "atomic"?`,
+	}, got)
+}
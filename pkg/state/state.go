@@ -29,6 +29,7 @@ import (
 	"github.com/helmfile/chartify"
 	"github.com/helmfile/vals"
 	"github.com/tatsushid/go-prettytable"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 	helmchart "helm.sh/helm/v3/pkg/chart"
 	cliv3 "helm.sh/helm/v3/pkg/cli"
@@ -36,6 +37,7 @@ import (
 
 	"github.com/helmfile/helmfile/pkg/agent/llm"
 	"github.com/helmfile/helmfile/pkg/argparser"
+	"github.com/helmfile/helmfile/pkg/audit"
 	"github.com/helmfile/helmfile/pkg/environment"
 	"github.com/helmfile/helmfile/pkg/envvar"
 	"github.com/helmfile/helmfile/pkg/event"
@@ -43,8 +45,10 @@ import (
 	"github.com/helmfile/helmfile/pkg/helmexec"
 	"github.com/helmfile/helmfile/pkg/kubedog"
 	"github.com/helmfile/helmfile/pkg/maputil"
+	"github.com/helmfile/helmfile/pkg/notify"
 	"github.com/helmfile/helmfile/pkg/remote"
 	"github.com/helmfile/helmfile/pkg/tmpl"
+	"github.com/helmfile/helmfile/pkg/tracing"
 	"github.com/helmfile/helmfile/pkg/yaml"
 )
 
@@ -93,6 +97,15 @@ type ReleaseSetSpec struct {
 	// This field is read at app layer; the state layer treats it as inert.
 	LLM llm.Config `yaml:"llm,omitempty"`
 
+	// Audit is the optional configuration of the sinks sync, apply and destroy
+	// runs are recorded to, and `helmfile history` reads them from.
+	// Like LLM, it is read at app layer.
+	Audit audit.Config `yaml:"audit,omitempty"`
+
+	// Notifications are the optional endpoints the start, release failures
+	// and completion of sync, apply and destroy runs are sent to.
+	Notifications []notify.Config `yaml:"notifications,omitempty"`
+
 	// Capabilities.APIVersions
 	ApiVersions []string `yaml:"apiVersions,omitempty"`
 
@@ -167,6 +180,21 @@ type HelmState struct {
 	// which is accessible from within the whole helmfile go template.
 	// Note that this is usually computed by DesiredStateLoader from ReleaseSetSpec.Env
 	RenderedValues map[string]any
+
+	// ExternalReleases maps the IDs of releases defined by other helmfiles
+	// processed by the same command to the helmfile defining each of them.
+	// Needs on these releases are ordered across helmfiles by the caller, so
+	// they are left out when planning the releases of this state.
+	ExternalReleases map[string]string `yaml:"-"`
+
+	// UndefinedNeedsExternal is set when the command loads other helmfiles
+	// without ordering releases across them. Needs on releases this state
+	// doesn't define are then taken to be on releases of other helmfiles, and
+	// are left out when planning the releases of this state.
+	UndefinedNeedsExternal bool `yaml:"-"`
+
+	// Sandbox restricts the template functions of this state, when it is a sandboxed sub-helmfile.
+	Sandbox *tmpl.Sandbox `yaml:"-"`
 }
 
 // chartifyTempDirTracker holds the set of chartify output directories to be
@@ -181,6 +209,16 @@ func (st *HelmState) SetKubeconfig(kubeconfig string) {
 	st.kubeconfig = kubeconfig
 }
 
+// SetLogger sets the logger of st, like the one of the output of a release in the terminal UI.
+func (st *HelmState) SetLogger(logger *zap.SugaredLogger) {
+	st.logger = logger
+}
+
+// Logger returns the logger of the state.
+func (st *HelmState) Logger() *zap.SugaredLogger {
+	return st.logger
+}
+
 // SubHelmfileSpec defines the subhelmfile path and options
 type SubHelmfileSpec struct {
 	//path or glob pattern for the sub helmfiles
@@ -198,7 +236,18 @@ type SubHelmfileSpec struct {
 	// where sub-helmfiles are independent.
 	Inherits []string `yaml:"inherits,omitempty"`
 
-	Environment SubhelmfileEnvironmentSpec
+	// Sandbox restricts the template functions of the sub helmfiles and their own sub helmfiles
+	Sandbox *SubHelmfileSandboxSpec `yaml:"sandbox,omitempty"`
+
+	Environment SubhelmfileEnvironmentSpec `yaml:",inline"`
+}
+
+// SubHelmfileSandboxSpec is the sandbox spec for a subhelmfile.
+// The templates of a sandboxed sub helmfile can't run commands or hooks, can only read files in the
+// directory of the sub helmfile, and can only read the environment variables in AllowedEnv.
+type SubHelmfileSandboxSpec struct {
+	// AllowedEnv is the list of the environment variables the templates can read, like `TEAM_A_*`
+	AllowedEnv []string `yaml:"allowedEnv,omitempty"`
 }
 
 // SubhelmfileEnvironmentSpec is the environment spec for a subhelmfile
@@ -209,6 +258,9 @@ type SubhelmfileEnvironmentSpec struct {
 
 // HelmSpec to defines helmDefault values
 type HelmSpec struct {
+	// Project identifies the helmfile project. When set, the releases are labeled with the project and the environment,
+	// so that `helmfile prune` finds the releases the project installed but no longer declares
+	Project     string   `yaml:"project,omitempty"`
 	KubeContext string   `yaml:"kubeContext,omitempty"`
 	Args        []string `yaml:"args,omitempty"`
 	DiffArgs    []string `yaml:"diffArgs,omitempty"`
@@ -429,6 +481,10 @@ type ReleaseSpec struct {
 	SetStringValues []SetValue        `yaml:"setString,omitempty"`
 	duration        time.Duration
 
+	// ValuesSchema is the path to a JSON schema the merged values of this release must conform to.
+	// The values are validated before any helm call, and are the same values `helmfile write-values` writes.
+	ValuesSchema string `yaml:"valuesSchema,omitempty"`
+
 	ValuesTemplate    []any      `yaml:"valuesTemplate,omitempty"`
 	SetValuesTemplate []SetValue `yaml:"setTemplate,omitempty"`
 
@@ -473,6 +529,12 @@ type ReleaseSpec struct {
 	//version of the chart that has really been installed cause desired version may be fuzzy (~2.0.0)
 	installedVersion string
 
+	// failedNeed is the ID of the failed release the release was skipped for, when it was skipped
+	failedNeed string
+
+	// tracked is what kubedog tracked of the release after it was synced, if it was tracked
+	tracked *kubedog.TrackStats
+
 	// ForceGoGetter forces the use of go-getter for fetching remote directory as maniefsts/chart/kustomization
 	// by parsing the url from `chart` field of the release.
 	// This is handy when getting the go-getter url parsing error when it doesn't work as expected.
@@ -652,6 +714,13 @@ type AffectedReleases struct {
 	Deleted      []*ReleaseSpec
 	Failed       []*ReleaseSpec
 	DeleteFailed []*ReleaseSpec
+	// Skipped are the releases that weren't synced or deleted, as a release they wait for failed
+	Skipped []*ReleaseSpec
+
+	// mu guards the lists above, which are appended to by concurrent
+	// SyncReleases, DeleteReleasesForSync and DeleteReleases calls when
+	// releases are scheduled one at a time.
+	mu sync.Mutex
 }
 
 // DefaultEnv is the default environment to use for helm commands
@@ -720,30 +789,40 @@ func (st *HelmState) reformat(spec *ReleaseSpec) []string {
 			kubecontext = spec.KubeContext
 		}
 
-		if spec.Desired() && !releaseInstalledInfo[fmt.Sprintf("%s/%s/%s", kubecontext, ns, name)] {
-			st.logger.Warnf("WARNING: %s", fmt.Sprintf("release %s needs %s, but %s is not installed due to installed: false. Either mark %s as installed or remove %s from %s's needs", spec.Name, name, name, name, name, spec.Name))
+		_, external := st.ExternalReleases[formatNeed(kubecontext, ns, name)]
+		if _, defined := releaseInstalledInfo[fmt.Sprintf("%s/%s/%s", kubecontext, ns, name)]; !defined && st.UndefinedNeedsExternal {
+			external = true
 		}
 
-		var componentsAfterOverride []string
-
-		if kubecontext != "" {
-			componentsAfterOverride = append(componentsAfterOverride, kubecontext)
+		if spec.Desired() && !external && !releaseInstalledInfo[fmt.Sprintf("%s/%s/%s", kubecontext, ns, name)] {
+			st.logger.Warnf("WARNING: %s", fmt.Sprintf("release %s needs %s, but %s is not installed due to installed: false. Either mark %s as installed or remove %s from %s's needs", spec.Name, name, name, name, name, spec.Name))
 		}
 
-		// This is intentionally `kubecontext != "" || ns != ""`, but "ns != ""
-		// To avoid conflating kubecontext=,namespace=foo,name=bar and kubecontext=foo,namespace=,name=bar
-		// as they are both `foo/bar`, we explicitly differentiate each with `foo//bar` and `foo/bar`.
-		// Note that `foo//bar` is not always a equivalent to `foo/default/bar` as the default namespace is depedent on
-		// the user's kubeconfig.
-		if kubecontext != "" || ns != "" {
-			componentsAfterOverride = append(componentsAfterOverride, ns)
-		}
+		needs = append(needs, formatNeed(kubecontext, ns, name))
+	}
+	return needs
+}
 
-		componentsAfterOverride = append(componentsAfterOverride, name)
+// formatNeed returns the release ID that a need on the release name in ns and kubecontext refers to.
+func formatNeed(kubecontext, ns, name string) string {
+	var components []string
 
-		needs = append(needs, strings.Join(componentsAfterOverride, "/"))
+	if kubecontext != "" {
+		components = append(components, kubecontext)
 	}
-	return needs
+
+	// This is intentionally `kubecontext != "" || ns != ""`, but "ns != ""
+	// To avoid conflating kubecontext=,namespace=foo,name=bar and kubecontext=foo,namespace=,name=bar
+	// as they are both `foo/bar`, we explicitly differentiate each with `foo//bar` and `foo/bar`.
+	// Note that `foo//bar` is not always a equivalent to `foo/default/bar` as the default namespace is depedent on
+	// the user's kubeconfig.
+	if kubecontext != "" || ns != "" {
+		components = append(components, ns)
+	}
+
+	components = append(components, name)
+
+	return strings.Join(components, "/")
 }
 
 func (st *HelmState) ApplyOverrides(spec *ReleaseSpec) {
@@ -781,7 +860,10 @@ func WithOCIOnly() SyncOption {
 	}
 }
 
-func (st *HelmState) SyncRepos(helm RepoUpdater, shouldSkip map[string]bool, opts ...SyncOption) ([]string, error) {
+func (st *HelmState) SyncRepos(helm RepoUpdater, shouldSkip map[string]bool, opts ...SyncOption) (_ []string, err error) {
+	span := tracing.StartSpan("sync repos", tracing.Helmfile.String(st.FilePath))
+	defer func() { tracing.End(span, err) }()
+
 	cfg := syncConfig{}
 	for _, o := range opts {
 		o(&cfg)
@@ -1130,7 +1212,7 @@ func (st *HelmState) DeleteReleasesForSync(affectedReleases *AffectedReleases, h
 		workerLimit = len(releases)
 	}
 
-	m := new(sync.Mutex)
+	m := &affectedReleases.mu
 
 	st.scatterGather(
 		workerLimit,
@@ -1236,7 +1318,7 @@ func (st *HelmState) SyncReleases(affectedReleases *AffectedReleases, helm helme
 		workerLimit = len(preps)
 	}
 
-	m := new(sync.Mutex)
+	m := &affectedReleases.mu
 
 	st.scatterGather(
 		workerLimit,
@@ -1482,6 +1564,53 @@ func (st *HelmState) listReleases(context helmexec.HelmContext, helm helmexec.In
 	return helm.List(context, "^"+release.Name+"$", flags...)
 }
 
+// LiveReleaseScope is the kube context and the namespace a release is installed in.
+// The empty namespace is the namespace of the kube context.
+type LiveReleaseScope struct {
+	KubeContext string
+	Namespace   string
+}
+
+// LiveReleaseScope returns the scope release is looked up in by `helmfile list --live`.
+func (st *HelmState) LiveReleaseScope(release *ReleaseSpec) LiveReleaseScope {
+	return LiveReleaseScope{KubeContext: st.getKubeContext(release), Namespace: release.Namespace}
+}
+
+// ListOwnedReleases lists the releases installed in kubeContext, in all the namespaces, by the project and environment of st.
+func (st *HelmState) ListOwnedReleases(helm helmexec.Interface, kubeContext string) ([]helmexec.ReleaseInfo, error) {
+	var flags []string
+	if kubeContext != "" {
+		flags = append(flags, "--kube-context", kubeContext)
+	}
+	// helm list returns 256 releases at most by default
+	flags = append(flags, "--all-namespaces", "--all", "--max", "0", "--selector", st.OwnerSelector())
+	return helm.ListReleases(helmexec.HelmContext{}, flags...)
+}
+
+// DeleteOwnedRelease deletes the release installed in kubeContext by the project of st, which st doesn't declare.
+func (st *HelmState) DeleteOwnedRelease(helm helmexec.Interface, kubeContext string, installed helmexec.ReleaseInfo) error {
+	release := ReleaseSpec{Name: installed.Name, Namespace: installed.Namespace, KubeContext: kubeContext}
+	flags := st.appendConnectionFlags([]string{}, &release)
+	flags = st.appendCascadeFlags(flags, helm, &release, "")
+	flags = st.appendDeleteWaitFlags(flags, &release)
+	flags = append(flags, "--namespace", release.Namespace)
+	return helm.DeleteRelease(st.createHelmContext(&release, 0), release.Name, flags...)
+}
+
+// ListLiveReleases lists the releases installed in scope, whatever their status is.
+func (st *HelmState) ListLiveReleases(helm helmexec.Interface, scope LiveReleaseScope) ([]helmexec.ReleaseInfo, error) {
+	var flags []string
+	if scope.KubeContext != "" {
+		flags = append(flags, "--kube-context", scope.KubeContext)
+	}
+	if scope.Namespace != "" {
+		flags = append(flags, "--namespace", scope.Namespace)
+	}
+	// helm list returns 256 releases at most by default
+	flags = append(flags, "--all", "--max", "0")
+	return helm.ListReleases(helmexec.HelmContext{}, flags...)
+}
+
 func (st *HelmState) getDeployedVersion(context helmexec.HelmContext, helm helmexec.Interface, release *ReleaseSpec) (string, error) {
 	//retrieve the version
 	if out, err := st.listReleases(context, helm, release); err == nil {
@@ -2212,7 +2341,10 @@ func (st *HelmState) prepareChartForRelease(release *ReleaseSpec, helm helmexec.
 // Note: OCI chart locks are acquired and released during chart download within this function.
 // The tempDir cleanup is deferred until after helm operations complete in the caller,
 // so charts remain available during helm commands even though locks are released.
-func (st *HelmState) PrepareCharts(helm helmexec.Interface, dir string, concurrency int, helmfileCommand string, opts ChartPrepareOptions) (map[PrepareChartKey]string, []error) {
+func (st *HelmState) PrepareCharts(helm helmexec.Interface, dir string, concurrency int, helmfileCommand string, opts ChartPrepareOptions) (_ map[PrepareChartKey]string, errs []error) {
+	span := tracing.StartSpan("prepare charts", tracing.Helmfile.String(st.FilePath))
+	defer func() { tracing.End(span, errors.Join(errs...)) }()
+
 	if !opts.SkipResolve {
 		updated, err := st.ResolveDeps()
 		if err != nil {
@@ -2309,7 +2441,7 @@ func (st *HelmState) PrepareCharts(helm helmexec.Interface, dir string, concurre
 
 	prepareChartInfo := make(map[PrepareChartKey]string, len(releases))
 
-	errs := []error{}
+	errs = []error{}
 
 	jobQueue := make(chan *ReleaseSpec, len(releases))
 	results := make(chan *chartPrepareResult, len(releases))
@@ -2331,7 +2463,13 @@ func (st *HelmState) PrepareCharts(helm helmexec.Interface, dir string, concurre
 				if sharedChartKeys[st.getChartCacheKey(release)] {
 					releaseOpts.ForceDownload = true
 				}
+				span := tracing.StartSpan("prepare chart",
+					tracing.Release.String(release.Name),
+					tracing.Namespace.String(release.Namespace),
+					tracing.KubeContext.String(release.KubeContext),
+				)
 				result := st.prepareChartForRelease(release, helm, dir, helmfileCommand, releaseOpts, workerIndex)
+				tracing.End(span, result.err)
 				results <- result
 			}
 		},
@@ -2451,6 +2589,16 @@ type TemplateOpts struct {
 	// TemplateArgs are extra args appended to "helm template" (e.g. "--dry-run=server"
 	// to enable the lookup() function for `helmfile template`).
 	TemplateArgs string
+	// SnapshotDir, when set, renders each release into a temporary directory and
+	// writes its normalized manifests to a stable per-release file under SnapshotDir.
+	SnapshotDir string
+	// CheckSnapshots compares the normalized manifests against the files in
+	// SnapshotDir instead of writing them, printing a unified diff on mismatch.
+	CheckSnapshots bool
+	// SnapshotBaseDir is the directory the snapshots of a state file are placed
+	// relative to, so that same-named state files in different directories don't
+	// share snapshots. It's usually the working directory helmfile was run from.
+	SnapshotBaseDir string
 }
 
 type TemplateOpt interface{ Apply(*TemplateOpts) }
@@ -2468,6 +2616,9 @@ func (st *HelmState) TemplateReleases(helm helmexec.Interface, outputDir string,
 	}
 
 	errs := []error{}
+	// Snapshot mismatches are collected separately so that every out-of-date
+	// release is rendered and diffed, not just the first one.
+	var snapshotErrs []error
 
 	for i := range st.Releases {
 		release := &st.Releases[i]
@@ -2520,6 +2671,24 @@ func (st *HelmState) TemplateReleases(helm helmexec.Interface, outputDir string,
 			}
 		}
 
+		// The temporary directory of the snapshot is removed at the end of the iteration,
+		// not to keep the renders of every release until all of them are rendered.
+		var snapshotTmp, snapshotRenderDir string
+		if opts.SnapshotDir != "" {
+			snapshotTmp, err = st.fs.MkdirTemp("", "helmfile-snapshot-")
+			if err != nil {
+				errs = append(errs, err)
+			} else {
+				snapshotRenderDir, err = st.GenerateOutputDir(snapshotTmp, release, "")
+				if err != nil {
+					errs = append(errs, err)
+				} else if err := st.fs.MkdirAll(snapshotRenderDir, 0755); err != nil {
+					errs = append(errs, err)
+				}
+				flags = append(flags, "--output-dir", snapshotRenderDir)
+			}
+		}
+
 		if validate {
 			flags = append(flags, "--validate")
 		}
@@ -2539,14 +2708,29 @@ func (st *HelmState) TemplateReleases(helm helmexec.Interface, outputDir string,
 		if len(errs) == 0 {
 			if err := helm.TemplateRelease(release.Name, release.ChartPathOrName(), flags...); err != nil {
 				errs = append(errs, err)
+			} else if snapshotRenderDir != "" {
+				var mismatch *SnapshotMismatchError
+				if err := st.snapshotRelease(release, snapshotRenderDir, opts); errors.As(err, &mismatch) {
+					snapshotErrs = append(snapshotErrs, err)
+				} else if err != nil {
+					errs = append(errs, err)
+				}
 			}
 		}
 
 		if _, err := st.TriggerCleanupEvent(release, "template"); err != nil {
 			st.logger.Warnf("warn: %v\n", err)
 		}
+
+		if snapshotTmp != "" {
+			if err := st.fs.RemoveAll(snapshotTmp); err != nil {
+				st.logger.Warnf("warn: removing %s: %v\n", snapshotTmp, err)
+			}
+		}
 	}
 
+	errs = append(errs, snapshotErrs...)
+
 	if len(errs) != 0 {
 		return errs
 	}
@@ -2737,6 +2921,12 @@ type UnittestOpts struct {
 	FailFast    bool
 	Color       bool
 	DebugPlugin bool
+	// UpdateSnapshot passes --update-snapshot to helm-unittest. Snapshot files
+	// written or changed by the run are recorded per release.
+	UpdateSnapshot bool
+	// Report, when non-nil, collects per-release results. helm-unittest is
+	// asked for JUnit output which is parsed into the report.
+	Report *UnittestReport
 }
 
 // UnittestOpt is a functional option for UnittestOpts
@@ -2749,10 +2939,13 @@ func (o *UnittestOpts) Apply(opts *UnittestOpts) {
 	*opts = *o
 }
 
-// UnittestReleases runs helm unittest on each release that has unitTests defined.
-// The workerLimit parameter is currently unused but kept for API consistency with
-// similar methods (e.g., LintReleases).
-func (st *HelmState) UnittestReleases(helm helmexec.Interface, additionalValues []string, args []string, _ int, opt ...UnittestOpt) []error {
+// UnittestReleases runs helm unittest on each release that has unitTests defined,
+// with up to workerLimit releases tested concurrently.
+//
+// Errors returned by helm unittest itself are returned unwrapped so callers can
+// tell failed tests (a helmexec.ExitError) from other failures. With FailFast,
+// no new release is started once one has failed.
+func (st *HelmState) UnittestReleases(helm helmexec.Interface, additionalValues []string, args []string, workerLimit int, opt ...UnittestOpt) []error {
 	opts := &UnittestOpts{}
 	for _, o := range opt {
 		o.Apply(opts)
@@ -2760,125 +2953,232 @@ func (st *HelmState) UnittestReleases(helm helmexec.Interface, additionalValues
 
 	helm.SetExtraArgs()
 
-	errs := []error{}
-
 	if len(args) > 0 {
 		helm.SetExtraArgs(args...)
 	}
 
-	for i := range st.Releases {
-		release := st.Releases[i]
+	var reportDir string
+	if opts.Report != nil {
+		dir, err := st.fs.MkdirTemp("", "helmfile-unittest-")
+		if err != nil {
+			return []error{fmt.Errorf("creating unittest report directory: %w", err)}
+		}
+		reportDir = dir
+		if !opts.SkipCleanup {
+			defer func() {
+				if err := st.fs.RemoveAll(dir); err != nil {
+					st.logger.Warnf("warn: failed to remove %s: %v\n", dir, err)
+				}
+			}()
+		}
+	}
 
-		if !release.Desired() {
-			continue
+	var (
+		mu     sync.Mutex
+		errs   []error
+		failed bool
+	)
+
+	_ = st.scatterGatherReleases(helm, workerLimit, func(release ReleaseSpec, workerIndex int) error {
+		if !release.Desired() || len(release.UnitTests) == 0 {
+			return nil
 		}
 
-		if len(release.UnitTests) == 0 {
-			continue
+		mu.Lock()
+		skip := opts.FailFast && failed
+		mu.Unlock()
+		if skip {
+			st.logger.Debugf("skipping unittest of release %q: a previous release failed and --fail-fast is set", release.Name)
+			return nil
 		}
 
-		flags, files, err := st.flagsForLint(helm, &release, 0)
+		releaseErrs := st.unittestRelease(helm, &release, workerIndex, additionalValues, reportDir, opts)
 
-		if !opts.SkipCleanup {
-			defer st.removeFiles(files)
+		if len(releaseErrs) > 0 {
+			mu.Lock()
+			errs = append(errs, releaseErrs...)
+			failed = true
+			mu.Unlock()
 		}
 
-		releaseErr := false
+		return nil
+	})
+
+	if len(errs) != 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (st *HelmState) unittestRelease(helm helmexec.Interface, release *ReleaseSpec, workerIndex int, additionalValues []string, reportDir string, opts *UnittestOpts) []error {
+	var errs []error
+
+	flags, files, err := st.flagsForLint(helm, release, workerIndex)
+
+	if !opts.SkipCleanup {
+		defer st.removeFiles(files)
+	}
+
+	if err != nil {
+		return append(errs, err)
+	}
+
+	for _, value := range additionalValues {
+		valfile, err := filepath.Abs(value)
 		if err != nil {
-			errs = append(errs, err)
-			releaseErr = true
+			return append(errs, err)
 		}
-		for _, value := range additionalValues {
-			valfile, err := filepath.Abs(value)
-			if err != nil {
-				errs = append(errs, err)
-				releaseErr = true
-				break
-			}
 
-			// Check for any stat error (not just IsNotExist) to also catch
-			// permission denied, I/O errors, etc. before passing to helm.
-			// This intentionally differs from LintReleases which only checks IsNotExist.
-			if _, err := os.Stat(valfile); err != nil {
-				errs = append(errs, err)
-				releaseErr = true
-				break
-			}
-			flags = append(flags, "--values", valfile)
+		// Check for any stat error (not just IsNotExist) to also catch
+		// permission denied, I/O errors, etc. before passing to helm.
+		// This intentionally differs from LintReleases which only checks IsNotExist.
+		if _, err := os.Stat(valfile); err != nil {
+			return append(errs, err)
 		}
+		flags = append(flags, "--values", valfile)
+	}
 
-		if releaseErr {
-			continue
+	if opts.Set != nil {
+		for _, s := range opts.Set {
+			flags = append(flags, "--set", s)
 		}
+	}
 
-		if opts.Set != nil {
-			for _, s := range opts.Set {
-				flags = append(flags, "--set", s)
-			}
-		}
+	if opts.FailFast {
+		flags = append(flags, "--failfast")
+	}
 
-		if opts.FailFast {
-			flags = append(flags, "--failfast")
+	if opts.Color {
+		// In Helm 4, --color is parsed by Helm itself before reaching the plugin.
+		// See https://github.com/helmfile/helmfile/issues/2280 for details.
+		// Skip the flag with a warning since helm-unittest does not currently
+		// support an env var alternative for colored output.
+		if helm.IsHelm4() {
+			st.logger.Warnf("warn: --color flag is not supported with Helm 4 due to flag parsing issues, ignoring\n")
+		} else {
+			flags = append(flags, "--color")
 		}
+	}
 
-		if opts.Color {
-			// In Helm 4, --color is parsed by Helm itself before reaching the plugin.
-			// See https://github.com/helmfile/helmfile/issues/2280 for details.
-			// Skip the flag with a warning since helm-unittest does not currently
-			// support an env var alternative for colored output.
-			if helm.IsHelm4() {
-				st.logger.Warnf("warn: --color flag is not supported with Helm 4 due to flag parsing issues, ignoring\n")
-			} else {
-				flags = append(flags, "--color")
+	if opts.DebugPlugin {
+		flags = append(flags, "--debugPlugin")
+	}
+
+	if opts.UpdateSnapshot {
+		flags = append(flags, "--update-snapshot")
+	}
+
+	// Add unit test file/directory paths as glob patterns for --file flag.
+	// Paths are relative to the chart directory (matching helm-unittest conventions).
+	// If the path has no glob characters and does not look like a YAML file,
+	// treat it as a directory and append a glob suffix.
+	// Reject absolute paths and paths that escape the chart directory via "..".
+	testGlobs := make([]string, 0, len(release.UnitTests))
+	for _, testPath := range release.UnitTests {
+		cleanPath := filepath.Clean(testPath)
+		if filepath.IsAbs(cleanPath) || cleanPath == ".." || strings.HasPrefix(cleanPath, ".."+string(filepath.Separator)) {
+			return append(errs, fmt.Errorf("release %q: unitTests path %q must be a relative path within the chart directory", release.Name, testPath))
+		}
+		if !strings.ContainsAny(testPath, "*?[") {
+			lowerPath := strings.ToLower(testPath)
+			if !strings.HasSuffix(lowerPath, ".yaml") && !strings.HasSuffix(lowerPath, ".yml") {
+				testPath = strings.TrimRight(testPath, "/") + "/*_test.yaml"
 			}
 		}
+		testGlobs = append(testGlobs, testPath)
+		flags = append(flags, "--file", testPath)
+	}
 
-		if opts.DebugPlugin {
-			flags = append(flags, "--debugPlugin")
-		}
+	var reportFile string
+	if reportDir != "" {
+		reportFile = filepath.Join(reportDir, fmt.Sprintf("%s.xml", strings.NewReplacer("/", "_", ":", "_").Replace(ReleaseToID(release))))
+		flags = append(flags, "--output-type", "JUnit", "--output-file", reportFile)
+	}
 
-		// Add unit test file/directory paths as glob patterns for --file flag.
-		// Paths are relative to the chart directory (matching helm-unittest conventions).
-		// If the path has no glob characters and does not look like a YAML file,
-		// treat it as a directory and append a glob suffix.
-		// Validate and add unit test file/directory paths.
-		// Reject absolute paths and paths that escape the chart directory via "..".
-		for _, testPath := range release.UnitTests {
-			cleanPath := filepath.Clean(testPath)
-			if filepath.IsAbs(cleanPath) || cleanPath == ".." || strings.HasPrefix(cleanPath, ".."+string(filepath.Separator)) {
-				errs = append(errs, fmt.Errorf("release %q: unitTests path %q must be a relative path within the chart directory", release.Name, testPath))
-				releaseErr = true
-				break
-			}
-			if !strings.ContainsAny(testPath, "*?[") {
-				lowerPath := strings.ToLower(testPath)
-				if !strings.HasSuffix(lowerPath, ".yaml") && !strings.HasSuffix(lowerPath, ".yml") {
-					testPath = strings.TrimRight(testPath, "/") + "/*_test.yaml"
-				}
-			}
-			flags = append(flags, "--file", testPath)
+	chart := release.ChartPathOrName()
+
+	var snapshotsBefore map[string]time.Time
+	if opts.UpdateSnapshot {
+		snapshotsBefore = st.unittestSnapshots(chart, testGlobs)
+	}
+
+	start := time.Now()
+	unittestErr := helm.Unittest(release.Name, chart, flags...)
+	elapsed := time.Since(start)
+
+	if unittestErr != nil {
+		errs = append(errs, unittestErr)
+	}
+
+	var updatedSnapshots []string
+	if opts.UpdateSnapshot {
+		updatedSnapshots = changedSnapshots(snapshotsBefore, st.unittestSnapshots(chart, testGlobs))
+		for _, s := range updatedSnapshots {
+			st.logger.Infof("release %q: wrote snapshot %s", release.Name, s)
 		}
+	}
 
-		if len(errs) == 0 || !opts.FailFast {
-			if err := helm.Unittest(release.Name, release.ChartPathOrName(), flags...); err != nil {
-				errs = append(errs, err)
+	if opts.Report != nil {
+		res := UnittestReleaseResult{
+			Release:          release.Name,
+			Namespace:        release.Namespace,
+			KubeContext:      release.KubeContext,
+			Chart:            release.Chart,
+			Duration:         elapsed,
+			UpdatedSnapshots: updatedSnapshots,
+		}
+		if data, err := st.fs.ReadFile(reportFile); err == nil {
+			if err := res.addJUnit(data); err != nil {
+				st.logger.Warnf("warn: release %q: unable to parse helm-unittest report: %v\n", release.Name, err)
 			}
 		}
-
-		if _, err := st.TriggerCleanupEvent(&release, "unittest"); err != nil {
-			st.logger.Warnf("warn: %v\n", err)
+		var exitErr helmexec.ExitError
+		if unittestErr != nil && (!errors.As(unittestErr, &exitErr) || len(res.Cases) == 0) {
+			res.Error = unittestErr.Error()
 		}
+		opts.Report.Add(res)
+	}
 
-		if opts.FailFast && len(errs) > 0 {
-			break
-		}
+	if _, err := st.TriggerCleanupEvent(release, "unittest"); err != nil {
+		st.logger.Warnf("warn: %v\n", err)
 	}
 
-	if len(errs) != 0 {
-		return errs
+	return errs
+}
+
+// unittestSnapshots returns the modification times of the helm-unittest
+// snapshot files belonging to the test files matched by testGlobs.
+// helm-unittest stores the snapshot of `dir/foo_test.yaml` in
+// `dir/__snapshot__/foo_test.yaml.snap`, i.e. next to the test file.
+func (st *HelmState) unittestSnapshots(chart string, testGlobs []string) map[string]time.Time {
+	snapshots := map[string]time.Time{}
+	for _, g := range testGlobs {
+		matches, err := st.fs.Glob(filepath.Join(chart, g))
+		if err != nil {
+			continue
+		}
+		for _, testFile := range matches {
+			snap := filepath.Join(filepath.Dir(testFile), "__snapshot__", filepath.Base(testFile)+".snap")
+			if info, err := st.fs.Stat(snap); err == nil {
+				snapshots[snap] = info.ModTime()
+			}
+		}
 	}
+	return snapshots
+}
 
-	return nil
+// changedSnapshots returns the sorted snapshot paths that are new in after or
+// whose modification time differs from before.
+func changedSnapshots(before, after map[string]time.Time) []string {
+	var changed []string
+	for path, mtime := range after {
+		if prev, ok := before[path]; !ok || !prev.Equal(mtime) {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
 }
 
 type diffResult struct {
@@ -3158,6 +3458,37 @@ type DiffOpts struct {
 	// DetectedKubeVersion is the Kubernetes version detected from the cluster.
 	// This is used when kubeVersion is not specified in helmfile.yaml
 	DetectedKubeVersion string
+	// Outputs, when not nil, is set to the diff output of each release
+	Outputs *DiffOutputs
+}
+
+// DiffOutputs are the diff outputs of releases, by release ID.
+// The releases may be diffed concurrently, so they are safe for concurrent use.
+type DiffOutputs struct {
+	mu      sync.Mutex
+	outputs map[string]string
+}
+
+func NewDiffOutputs() *DiffOutputs {
+	return &DiffOutputs{outputs: map[string]string{}}
+}
+
+// Set sets the diff output of the release with ID id.
+func (o *DiffOutputs) Set(id, output string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.outputs[id] = output
+}
+
+// Get returns the diff output of the release with ID id, if it was diffed.
+func (o *DiffOutputs) Get(id string) (string, bool) {
+	if o == nil {
+		return "", false
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	output, ok := o.outputs[id]
+	return output, ok
 }
 
 func (o *DiffOpts) Apply(opts *DiffOpts) {
@@ -3274,6 +3605,9 @@ func (st *HelmState) DiffReleases(helm helmexec.Interface, additionalValues []st
 		id := ReleaseToID(p.release)
 		if stdout, ok := outputs[id]; ok {
 			fmt.Print(stdout.String())
+			if opts.Outputs != nil {
+				opts.Outputs.Set(id, stdout.String())
+			}
 		} else {
 			panic(fmt.Sprintf("missing output for release %s", id))
 		}
@@ -3300,8 +3634,50 @@ func (st *HelmState) ReleaseStatuses(helm helmexec.Interface, workerLimit int) [
 	})
 }
 
+// ReleaseStatus is the status of a release of the state, as reported by `helm status`.
+// Info is nil when the release is not installed.
+type ReleaseStatus struct {
+	Release     ReleaseSpec
+	KubeContext string
+	Info        *helmexec.ReleaseStatusInfo
+}
+
+// CollectReleaseStatuses gets the status of the releases like ReleaseStatuses, but returns them instead of printing them.
+func (st *HelmState) CollectReleaseStatuses(helm helmexec.Interface, workerLimit int) ([]ReleaseStatus, []error) {
+	var mu sync.Mutex
+	var statuses []ReleaseStatus
+
+	errs := st.scatterGatherReleases(helm, workerLimit, func(release ReleaseSpec, workerIndex int) error {
+		if !release.Desired() {
+			return nil
+		}
+
+		st.ApplyOverrides(&release)
+
+		flags := []string{}
+		if release.Namespace != "" {
+			flags = append(flags, "--namespace", release.Namespace)
+		}
+		flags = st.appendConnectionFlags(flags, &release)
+
+		info, err := helm.ReleaseStatusInfo(st.createHelmContext(&release, workerIndex), release.Name, flags...)
+		if err != nil && !strings.Contains(err.Error(), "Error: release: not found") {
+			return err
+		}
+
+		mu.Lock()
+		statuses = append(statuses, ReleaseStatus{Release: release, KubeContext: st.getKubeContext(&release), Info: info})
+		mu.Unlock()
+		return nil
+	})
+
+	return statuses, errs
+}
+
 // DeleteReleases wrapper for executing helm delete on the releases
 func (st *HelmState) DeleteReleases(affectedReleases *AffectedReleases, helm helmexec.Interface, concurrency int, purge bool, cascade string) []error {
+	m := &affectedReleases.mu
+
 	return st.scatterGatherReleases(helm, concurrency, func(release ReleaseSpec, workerIndex int) error {
 		st.ApplyOverrides(&release)
 
@@ -3318,7 +3694,9 @@ func (st *HelmState) DeleteReleases(affectedReleases *AffectedReleases, helm hel
 		if _, err := st.triggerReleaseEvent("preuninstall", nil, &release, "delete"); err != nil {
 			release.duration = time.Since(start)
 
+			m.Lock()
 			affectedReleases.DeleteFailed = append(affectedReleases.Failed, &release)
+			m.Unlock()
 
 			return err
 		}
@@ -3326,19 +3704,25 @@ func (st *HelmState) DeleteReleases(affectedReleases *AffectedReleases, helm hel
 		if err := helm.DeleteRelease(context, release.Name, flags...); err != nil {
 			release.duration = time.Since(start)
 
+			m.Lock()
 			affectedReleases.DeleteFailed = append(affectedReleases.Failed, &release)
+			m.Unlock()
 			return err
 		}
 
 		if _, err := st.triggerReleaseEvent("postuninstall", nil, &release, "delete"); err != nil {
 			release.duration = time.Since(start)
 
+			m.Lock()
 			affectedReleases.DeleteFailed = append(affectedReleases.Failed, &release)
+			m.Unlock()
 			return err
 		}
 		release.duration = time.Since(start)
 
+		m.Lock()
 		affectedReleases.Deleted = append(affectedReleases.Deleted, &release)
+		m.Unlock()
 		return nil
 	})
 }
@@ -3613,6 +3997,7 @@ func (st *HelmState) triggerGlobalReleaseEvent(evt string, evtErr error, helmfil
 		Env:           st.Env,
 		Logger:        st.logger,
 		Fs:            st.fs,
+		Sandbox:       st.Sandbox,
 	}
 	data := map[string]any{
 		"HelmfileCommand": helmfileCmd,
@@ -3650,6 +4035,12 @@ func (st *HelmState) triggerReleaseEvent(evt string, evtErr error, r *ReleaseSpe
 		Env:           st.Env,
 		Logger:        st.logger,
 		Fs:            st.fs,
+		Sandbox:       st.Sandbox,
+		SpanAttributes: []attribute.KeyValue{
+			tracing.Release.String(r.Name),
+			tracing.Namespace.String(r.Namespace),
+			tracing.KubeContext.String(st.getKubeContext(r)),
+		},
 	}
 	vals := st.Values()
 	data := map[string]any{
@@ -4600,6 +4991,10 @@ func (st *HelmState) prepareReleaseValuesEntries(release *ReleaseSpec) ([]any, e
 		}
 	}
 
+	if err := st.Sandbox.CheckRefs(st.fs, values); err != nil {
+		return nil, err
+	}
+
 	valuesMapSecretsRendered, err := st.valsRuntime.Eval(map[string]any{"values": values})
 	if err != nil {
 		return nil, err
@@ -4613,6 +5008,12 @@ func (st *HelmState) prepareReleaseValuesEntries(release *ReleaseSpec) ([]any, e
 	return valuesSecretsRendered, nil
 }
 
+// ResolveReleaseValues returns the release's values merged in declaration order,
+// with values files rendered and vals references evaluated, without invoking helm.
+func (st *HelmState) ResolveReleaseValues(release *ReleaseSpec) (map[string]any, error) {
+	return st.resolveReleaseValues(release)
+}
+
 func (st *HelmState) resolveReleaseValues(release *ReleaseSpec) (map[string]any, error) {
 	merged := map[string]any{}
 
@@ -4673,6 +5074,7 @@ func (st *HelmState) resolveReleaseValues(release *ReleaseSpec) (map[string]any,
 
 func (st *HelmState) renderValuesFileToBytesWithData(path string, templateData releaseTemplateData) ([]byte, error) {
 	r := tmpl.NewFileRenderer(st.fs, filepath.Dir(path), templateData)
+	r.Context.SetSandbox(st.Sandbox)
 	rawBytes, err := r.RenderToBytes(path)
 	if err != nil {
 		return nil, err
@@ -4691,6 +5093,10 @@ func (st *HelmState) renderValuesFileToBytesWithData(path string, templateData r
 			return nil, err
 		}
 
+		if err := st.Sandbox.CheckRefs(st.fs, rawYaml); err != nil {
+			return nil, err
+		}
+
 		parsedYaml, err := st.valsRuntime.Eval(rawYaml)
 		if err != nil {
 			return nil, err
@@ -4703,17 +5109,19 @@ func (st *HelmState) renderValuesFileToBytesWithData(path string, templateData r
 }
 
 func (st *HelmState) generateTemporaryReleaseValuesFilesWithData(release *ReleaseSpec, values []any, getTemplateData func() (releaseTemplateData, error)) ([]string, error) {
-	return st.generateTemporaryReleaseValuesFilesCore(release, values, func(path string) ([]byte, error) {
+	generatedFiles, _, err := st.generateTemporaryReleaseValuesFilesCore(release, values, func(path string) ([]byte, error) {
 		templateData, err := getTemplateData()
 		if err != nil {
 			return nil, err
 		}
 		return st.renderValuesFileToBytesWithData(path, templateData)
 	})
+	return generatedFiles, err
 }
 
 func (st *HelmState) newReleaseTemplateFuncMap(dir string) template.FuncMap {
 	r := tmpl.NewFileRenderer(st.fs, dir, nil)
+	r.Context.SetSandbox(st.Sandbox)
 
 	return r.Context.CreateFuncMap()
 }
@@ -4729,6 +5137,7 @@ func (st *HelmState) storage() *Storage {
 		basePath: st.basePath,
 		logger:   st.logger,
 		fs:       st.fs,
+		sandbox:  st.Sandbox,
 	}
 }
 
@@ -4906,7 +5315,7 @@ func (st *HelmState) getMissingFileHandler() *string {
 	}
 }
 
-func (st *HelmState) generateTemporaryReleaseValuesFiles(release *ReleaseSpec, values []any) ([]string, error) {
+func (st *HelmState) generateTemporaryReleaseValuesFiles(release *ReleaseSpec, values []any) ([]string, []string, error) {
 	return st.generateTemporaryReleaseValuesFilesCore(release, values, func(path string) ([]byte, error) {
 		return st.RenderReleaseValuesFileToBytes(release, path)
 	})
@@ -4914,28 +5323,30 @@ func (st *HelmState) generateTemporaryReleaseValuesFiles(release *ReleaseSpec, v
 
 // generateTemporaryReleaseValuesFilesCore is the shared implementation for generating temporary values files.
 // renderStringValue is called for each string value entry after the file path has been resolved.
-func (st *HelmState) generateTemporaryReleaseValuesFilesCore(release *ReleaseSpec, values []any, renderStringValue func(path string) ([]byte, error)) ([]string, error) {
+// It returns the generated files along with what each of them was generated from, like the values file users write.
+func (st *HelmState) generateTemporaryReleaseValuesFilesCore(release *ReleaseSpec, values []any, renderStringValue func(path string) ([]byte, error)) ([]string, []string, error) {
 	generatedFiles := []string{}
+	sources := []string{}
 
 	for _, value := range values {
 		switch typedValue := value.(type) {
 		case string:
 			paths, skip, err := st.storage().resolveFile(st.getReleaseMissingFileHandler(release), "values", typedValue, st.getReleaseMissingFileHandlerConfig(release).resolveFileOptions()...)
 			if err != nil {
-				return generatedFiles, err
+				return generatedFiles, sources, err
 			}
 			if skip {
 				continue
 			}
 
 			if len(paths) > 1 {
-				return generatedFiles, fmt.Errorf("glob patterns in release values and secrets is not supported yet. please submit a feature request if necessary")
+				return generatedFiles, sources, fmt.Errorf("glob patterns in release values and secrets is not supported yet. please submit a feature request if necessary")
 			}
 			path := paths[0]
 
 			yamlBytes, err := renderStringValue(path)
 			if err != nil {
-				return generatedFiles, fmt.Errorf("failed to render values files \"%s\": %v", typedValue, err)
+				return generatedFiles, sources, fmt.Errorf("failed to render values files \"%s\": %v", typedValue, err)
 			}
 
 			if err := func() error {
@@ -4952,17 +5363,17 @@ func (st *HelmState) generateTemporaryReleaseValuesFilesCore(release *ReleaseSpe
 				}
 
 				st.logger.Debugf("Successfully generated the value file from %s to %s", path, valfile.Name())
-
 				generatedFiles = append(generatedFiles, valfile.Name())
+				sources = append(sources, path)
 
 				return nil
 			}(); err != nil {
-				return generatedFiles, err
+				return generatedFiles, sources, err
 			}
 		case map[any]any:
 			strMap, err := maputil.CastKeysToStrings(typedValue)
 			if err != nil {
-				return generatedFiles, err
+				return generatedFiles, sources, err
 			}
 			if err := func() error {
 				valfile, err := createTempValuesFile(release, strMap)
@@ -4983,10 +5394,11 @@ func (st *HelmState) generateTemporaryReleaseValuesFilesCore(release *ReleaseSpe
 				}
 
 				generatedFiles = append(generatedFiles, valfile.Name())
+				sources = append(sources, fmt.Sprintf("inline values of release %q in %s", release.Name, st.FilePath))
 
 				return nil
 			}(); err != nil {
-				return generatedFiles, err
+				return generatedFiles, sources, err
 			}
 		case map[string]any:
 			if err := func() error {
@@ -5008,34 +5420,37 @@ func (st *HelmState) generateTemporaryReleaseValuesFilesCore(release *ReleaseSpe
 				}
 
 				generatedFiles = append(generatedFiles, valfile.Name())
+				sources = append(sources, fmt.Sprintf("inline values of release %q in %s", release.Name, st.FilePath))
 
 				return nil
 			}(); err != nil {
-				return generatedFiles, err
+				return generatedFiles, sources, err
 			}
 		default:
-			return generatedFiles, fmt.Errorf("unexpected type of value: value=%v, type=%T", typedValue, typedValue)
+			return generatedFiles, sources, fmt.Errorf("unexpected type of value: value=%v, type=%T", typedValue, typedValue)
 		}
 	}
-	return generatedFiles, nil
+	return generatedFiles, sources, nil
 }
 
-func (st *HelmState) generateVanillaValuesFiles(release *ReleaseSpec) ([]string, error) {
+func (st *HelmState) generateVanillaValuesFiles(release *ReleaseSpec) ([]string, []string, error) {
 	valuesSecretsRendered, err := st.prepareReleaseValuesEntries(release)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	generatedFiles, err := st.generateTemporaryReleaseValuesFiles(release, valuesSecretsRendered)
+	generatedFiles, sources, err := st.generateTemporaryReleaseValuesFiles(release, valuesSecretsRendered)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return generatedFiles, nil
+	return generatedFiles, sources, nil
 }
 
-func (st *HelmState) generateSecretValuesFiles(helm helmexec.Interface, release *ReleaseSpec, workerIndex int) ([]string, error) {
+func (st *HelmState) generateSecretValuesFiles(helm helmexec.Interface, release *ReleaseSpec, workerIndex int) ([]string, []string, error) {
 	var generatedDecryptedFiles []any
+	// encryptedFiles maps the decrypted files to the secrets files they were decrypted from
+	encryptedFiles := map[string]string{}
 
 	for _, v := range release.Secrets {
 		var (
@@ -5048,17 +5463,17 @@ func (st *HelmState) generateSecretValuesFiles(helm helmexec.Interface, release
 		case string:
 			paths, skip, err = st.storage().resolveFile(release.MissingFileHandler, "secrets", release.ValuesPathPrefix+value, st.MissingFileHandlerConfig.resolveFileOptions()...)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 		default:
 			bs, err := yaml.Marshal(value)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 
 			path, err := os.CreateTemp(os.TempDir(), "helmfile-embdedded-secrets-*.yaml.enc")
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			_ = path.Close()
 			defer func() {
@@ -5066,7 +5481,7 @@ func (st *HelmState) generateSecretValuesFiles(helm helmexec.Interface, release
 			}()
 
 			if err := os.WriteFile(path.Name(), bs, 0644); err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 
 			paths = []string{path.Name()}
@@ -5077,42 +5492,53 @@ func (st *HelmState) generateSecretValuesFiles(helm helmexec.Interface, release
 		}
 
 		if len(paths) > 1 {
-			return nil, fmt.Errorf("glob patterns in release secret file is not supported yet. please submit a feature request if necessary")
+			return nil, nil, fmt.Errorf("glob patterns in release secret file is not supported yet. please submit a feature request if necessary")
 		}
 		path := paths[0]
 
 		valfile, err := helm.DecryptSecret(st.createHelmContext(release, workerIndex), path)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		defer func() {
 			_ = os.Remove(valfile)
 		}()
 
 		generatedDecryptedFiles = append(generatedDecryptedFiles, valfile)
+		encryptedFiles[valfile] = path
 	}
 
-	generatedFiles, err := st.generateTemporaryReleaseValuesFiles(release, generatedDecryptedFiles)
+	generatedFiles, sources, err := st.generateTemporaryReleaseValuesFiles(release, generatedDecryptedFiles)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	for i, source := range sources {
+		if f, ok := encryptedFiles[source]; ok {
+			sources[i] = f
+		}
 	}
 
-	return generatedFiles, nil
+	return generatedFiles, sources, nil
 }
 
 func (st *HelmState) generateValuesFiles(helm helmexec.Interface, release *ReleaseSpec, workerIndex int) ([]string, error) {
-	valuesFiles, err := st.generateVanillaValuesFiles(release)
+	valuesFiles, valuesSources, err := st.generateVanillaValuesFiles(release)
 	if err != nil {
 		return nil, err
 	}
 
-	secretValuesFiles, err := st.generateSecretValuesFiles(helm, release, workerIndex)
+	secretValuesFiles, secretSources, err := st.generateSecretValuesFiles(helm, release, workerIndex)
 	if err != nil {
 		return nil, err
 	}
 
 	files := append(valuesFiles, secretValuesFiles...)
 
+	if err := st.validateReleaseValues(release, files, append(valuesSources, secretSources...)); err != nil {
+		st.removeFiles(files)
+		return nil, err
+	}
+
 	return files, nil
 }
 
@@ -5411,6 +5837,132 @@ func (ar *AffectedReleases) DisplayAffectedReleases(logger *zap.SugaredLogger, u
 		logger.Infof("\n%s", kubedog.HeaderDividerCenteredStyled("Failed to Delete Releases", kubedog.TableVisualWidth(tableStr), useColor))
 		logger.Info(tableStr)
 	}
+	if len(ar.Skipped) > 0 {
+		tbl, _ := prettytable.NewTable(prettytable.Column{Header: "NAME"},
+			prettytable.Column{Header: "NAMESPACE", MinWidth: 6},
+			prettytable.Column{Header: "FAILED DEPENDENCY"},
+		)
+		tbl.Separator = "   "
+		for _, release := range ar.Skipped {
+			err := tbl.AddRow(release.Name, release.Namespace, release.failedNeed)
+			if err != nil {
+				logger.Warn("Could not add row, %v", err)
+			}
+		}
+		tableStr := tbl.String()
+		logger.Infof("\n%s", kubedog.HeaderDividerCenteredStyled("Skipped Releases", kubedog.TableVisualWidth(tableStr), useColor))
+		logger.Info(tableStr)
+	}
+}
+
+// Skip records that release was skipped, as the release failedNeed, which it waits for, failed.
+func (ar *AffectedReleases) Skip(release *ReleaseSpec, failedNeed string) {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+
+	skipped := *release
+	skipped.failedNeed = failedNeed
+	ar.Skipped = append(ar.Skipped, &skipped)
+}
+
+// GitCommit returns the commit checked out in the git repository containing the helmfile, if any.
+func (st *HelmState) GitCommit() string {
+	return audit.GitCommit(st.storage().JoinBase("."))
+}
+
+// AuditRecord returns the record of command run on the releases of st, for the audit log.
+// The caller sets the time, user, commit, selectors, duration and errors of the run.
+func (st *HelmState) AuditRecord(command string, ar *AffectedReleases) audit.Record {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+
+	record := audit.Record{
+		Command:     command,
+		Helmfile:    st.FilePath,
+		Environment: st.Env.Name,
+		Releases:    []audit.Release{},
+	}
+	add := func(action string, releases []*ReleaseSpec) {
+		for _, r := range releases {
+			record.Releases = append(record.Releases, audit.Release{
+				Name:            r.Name,
+				Namespace:       r.Namespace,
+				KubeContext:     st.getKubeContext(r),
+				Chart:           r.Chart,
+				Version:         r.installedVersion,
+				Action:          action,
+				DurationSeconds: r.duration.Seconds(),
+				Tracking:        auditTracking(r.tracked),
+			})
+		}
+	}
+	add(audit.ActionDeleted, ar.Deleted)
+	add(audit.ActionDeleteFailed, ar.DeleteFailed)
+	add(audit.ActionUpgraded, ar.Upgraded)
+	add(audit.ActionReinstalled, ar.Reinstalled)
+	add(audit.ActionFailed, ar.Failed)
+	return record
+}
+
+func auditTracking(stats *kubedog.TrackStats) *audit.Tracking {
+	if stats == nil {
+		return nil
+	}
+	t := &audit.Tracking{
+		Resources:       stats.Resources,
+		DurationSeconds: stats.Duration.Seconds(),
+	}
+	if stats.Changed >= 0 {
+		t.ChangedResources = &stats.Changed
+	}
+	return t
+}
+
+// ReleaseTiming is how long syncing or deleting a release took, and the releases it needs.
+type ReleaseTiming struct {
+	ID          string
+	Name        string
+	Namespace   string
+	KubeContext string
+	// Needs are the IDs of the releases the release needs
+	Needs    []string
+	Duration time.Duration
+}
+
+// ReleaseTimings returns the timings of the releases synced or deleted by ar, once per release.
+func (st *HelmState) ReleaseTimings(ar *AffectedReleases) []ReleaseTiming {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+
+	var timings []ReleaseTiming
+	seen := map[string]bool{}
+	for _, releases := range [][]*ReleaseSpec{ar.Upgraded, ar.Reinstalled, ar.Deleted, ar.Failed, ar.DeleteFailed} {
+		for _, r := range releases {
+			id := ReleaseToID(r)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			timings = append(timings, ReleaseTiming{
+				ID:          id,
+				Name:        r.Name,
+				Namespace:   r.Namespace,
+				KubeContext: st.getKubeContext(r),
+				Needs:       r.Needs,
+				Duration:    r.duration,
+			})
+		}
+	}
+	return timings
+}
+
+// AuditConfig returns the audit block of st, with the path of the audit file relative to the helmfile.
+func (st *HelmState) AuditConfig() audit.Config {
+	c := st.Audit
+	if c.File != "" {
+		c.File = st.storage().normalizePath(c.File)
+	}
+	return c
 }
 
 func escape(value string) string {
@@ -5423,17 +5975,19 @@ func escape(value string) string {
 // future time
 func (p SubHelmfileSpec) MarshalYAML() (any, error) {
 	type SubHelmfileSpecTmp struct {
-		Path               string   `yaml:"path,omitempty"`
-		Selectors          []string `yaml:"selectors,omitempty"`
-		SelectorsInherited bool     `yaml:"selectorsInherited,omitempty"`
-		Inherits           []string `yaml:"inherits,omitempty"`
-		OverrideValues     []any    `yaml:"values,omitempty"`
+		Path               string                  `yaml:"path,omitempty"`
+		Selectors          []string                `yaml:"selectors,omitempty"`
+		SelectorsInherited bool                    `yaml:"selectorsInherited,omitempty"`
+		Inherits           []string                `yaml:"inherits,omitempty"`
+		Sandbox            *SubHelmfileSandboxSpec `yaml:"sandbox,omitempty"`
+		OverrideValues     []any                   `yaml:"values,omitempty"`
 	}
 	return &SubHelmfileSpecTmp{
 		Path:               p.Path,
 		Selectors:          p.Selectors,
 		SelectorsInherited: p.SelectorsInherited,
 		Inherits:           p.Inherits,
+		Sandbox:            p.Sandbox,
 		OverrideValues:     p.Environment.OverrideValues,
 	}, nil
 }
@@ -5451,10 +6005,11 @@ func (hf *SubHelmfileSpec) UnmarshalYAML(unmarshal func(any) error) error {
 		hf.Path = i
 	case map[any]any, map[string]any: // helmfile path with sub section
 		var subHelmfileSpecTmp struct {
-			Path               string   `yaml:"path"`
-			Selectors          []string `yaml:"selectors"`
-			SelectorsInherited bool     `yaml:"selectorsInherited"`
-			Inherits           []string `yaml:"inherits"`
+			Path               string                  `yaml:"path"`
+			Selectors          []string                `yaml:"selectors"`
+			SelectorsInherited bool                    `yaml:"selectorsInherited"`
+			Inherits           []string                `yaml:"inherits"`
+			Sandbox            *SubHelmfileSandboxSpec `yaml:"sandbox"`
 
 			Environment SubhelmfileEnvironmentSpec `yaml:",inline"`
 		}
@@ -5465,6 +6020,7 @@ func (hf *SubHelmfileSpec) UnmarshalYAML(unmarshal func(any) error) error {
 		hf.Selectors = subHelmfileSpecTmp.Selectors
 		hf.SelectorsInherited = subHelmfileSpecTmp.SelectorsInherited
 		hf.Inherits = subHelmfileSpecTmp.Inherits
+		hf.Sandbox = subHelmfileSpecTmp.Sandbox
 		hf.Environment = subHelmfileSpecTmp.Environment
 	}
 	// since we cannot make sur the "console" string can be red after the "path" we must check we don't have
@@ -5482,6 +6038,9 @@ func (hf *SubHelmfileSpec) UnmarshalYAML(unmarshal func(any) error) error {
 	if len(hf.Inherits) > 0 && hf.Path == "" {
 		return fmt.Errorf("found 'inherits' definition without path: %v", hf.Inherits)
 	}
+	if hf.Sandbox != nil && hf.Path == "" {
+		return fmt.Errorf("found 'sandbox' definition without path: %v", *hf.Sandbox)
+	}
 	// validate inherits: entries against the allowed set, failing fast on typos
 	// (an unknown key would otherwise silently do nothing)
 	for _, key := range hf.Inherits {
@@ -9,6 +9,7 @@ import (
 	"dario.cat/mergo"
 
 	"github.com/helmfile/helmfile/pkg/tmpl"
+	"github.com/helmfile/helmfile/pkg/tracing"
 	"github.com/helmfile/helmfile/pkg/yaml"
 )
 
@@ -88,7 +89,10 @@ func updateBoolTemplatedValues(r *ReleaseSpec) error {
 	return nil
 }
 
-func (st *HelmState) ExecuteTemplates() (*HelmState, error) {
+func (st *HelmState) ExecuteTemplates() (_ *HelmState, err error) {
+	span := tracing.StartSpan("execute release templates", tracing.Helmfile.String(st.FilePath))
+	defer func() { tracing.End(span, err) }()
+
 	r := *st
 
 	vals := st.Values()
@@ -126,6 +130,7 @@ func (st *HelmState) ExecuteTemplates() (*HelmState, error) {
 		for it, prev := 0, release; it < 6; it++ {
 			tmplData := st.createReleaseTemplateData(prev, vals)
 			renderer := tmpl.NewFileRenderer(st.fs, st.basePath, tmplData)
+			renderer.Context.SetSandbox(st.Sandbox)
 			r, err := release.ExecuteTemplateExpressions(renderer)
 			if err != nil {
 				return nil, fmt.Errorf("failed executing templates in release \"%s\".\"%s\": %v", st.FilePath, release.Name, err)
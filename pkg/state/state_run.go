@@ -96,6 +96,13 @@ type PlanOptions struct {
 	IncludeTransitiveNeeds bool
 	SkipNeeds              bool
 	SelectedReleases       []ReleaseSpec
+
+	// externalReleases are the IDs of releases defined by other helmfiles.
+	// Needs on them are not part of the plan.
+	externalReleases map[string]string
+	// undefinedNeedsExternal leaves needs on releases that aren't planned out
+	// of the plan, as they may be on releases of other helmfiles.
+	undefinedNeedsExternal bool
 }
 
 func (st *HelmState) PlanReleases(opts PlanOptions) ([][]Release, error) {
@@ -104,6 +111,9 @@ func (st *HelmState) PlanReleases(opts PlanOptions) ([][]Release, error) {
 		return nil, err
 	}
 
+	opts.externalReleases = st.ExternalReleases
+	opts.undefinedNeedsExternal = st.UndefinedNeedsExternal
+
 	groups, err := SortedReleaseGroups(marked, opts)
 	if err != nil {
 		return nil, err
@@ -133,6 +143,11 @@ func GroupReleasesByDependency(releases []Release, opts PlanOptions) ([][]Releas
 	idToReleases := map[string][]Release{}
 	idToIndex := map[string]int{}
 
+	planned := map[string]bool{}
+	for _, r := range releases {
+		planned[ReleaseToID(&r.ReleaseSpec)] = true
+	}
+
 	d := dag.New()
 	for i, r := range releases {
 		id := ReleaseToID(&r.ReleaseSpec)
@@ -143,6 +158,12 @@ func GroupReleasesByDependency(releases []Release, opts PlanOptions) ([][]Releas
 		var needs []string
 		for i := 0; i < len(r.Needs); i++ {
 			n := r.Needs[i]
+			if _, external := opts.externalReleases[n]; external {
+				continue
+			}
+			if opts.undefinedNeedsExternal && !planned[n] {
+				continue
+			}
 			needs = append(needs, n)
 		}
 		d.Add(id, dag.Dependencies(needs))
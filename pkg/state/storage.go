@@ -11,6 +11,7 @@ import (
 
 	"github.com/helmfile/helmfile/pkg/filesystem"
 	"github.com/helmfile/helmfile/pkg/remote"
+	"github.com/helmfile/helmfile/pkg/tmpl"
 )
 
 type Storage struct {
@@ -20,6 +21,9 @@ type Storage struct {
 
 	basePath string
 	fs       *filesystem.FileSystem
+
+	// sandbox, when set, restricts the local files that can be resolved.
+	sandbox *tmpl.Sandbox
 }
 
 func NewStorage(forFile string, logger *zap.SugaredLogger, fs *filesystem.FileSystem) *Storage {
@@ -74,6 +78,14 @@ func (st *Storage) resolveFile(missingFileHandler *string, tpe, path string, opt
 		}
 	} else {
 		files, err = st.ExpandPaths(path)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, f := range files {
+			if err := st.sandbox.CheckPath(st.fs, f); err != nil {
+				return nil, false, err
+			}
+		}
 	}
 
 	if err != nil {
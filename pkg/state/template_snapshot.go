@@ -0,0 +1,232 @@
+package state
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/helmfile/helmfile/pkg/yaml"
+)
+
+// DefaultSnapshotFileTemplate is the output-file template used to place
+// the template snapshot of a release within the snapshot directory of its
+// state file. Unlike the write-values default it leaves out the hash of the
+// state file's absolute path, so that snapshot paths are the same in every
+// checkout.
+const DefaultSnapshotFileTemplate = "{{ if .Release.KubeContext }}{{ .Release.KubeContext }}/{{ end }}" +
+	"{{ if .Release.Namespace }}{{ .Release.Namespace }}/{{ end }}" +
+	"{{ .Release.Name }}.yaml"
+
+// SnapshotMismatchError is returned by TemplateReleases in CheckSnapshots mode
+// when the fresh render of a release differs from its snapshot file.
+type SnapshotMismatchError struct {
+	Release string
+	Path    string
+	Missing bool
+}
+
+func (e *SnapshotMismatchError) Error() string {
+	if e.Missing {
+		return fmt.Sprintf("snapshot %s for release %q does not exist", e.Path, e.Release)
+	}
+	return fmt.Sprintf("snapshot %s for release %q is out of date", e.Path, e.Release)
+}
+
+var manifestDocumentSeparator = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
+// snapshotRelease normalizes the manifests helm rendered into renderDir and
+// either writes them to the release's snapshot file or, with CheckSnapshots,
+// prints a unified diff against it and returns a *SnapshotMismatchError.
+func (st *HelmState) snapshotRelease(release *ReleaseSpec, renderDir string, opts *TemplateOpts) error {
+	rendered, err := st.normalizeManifests(renderDir)
+	if err != nil {
+		return fmt.Errorf("normalizing rendered manifests of release %q: %w", release.Name, err)
+	}
+
+	stateDir, err := st.snapshotStateDir(opts.SnapshotBaseDir)
+	if err != nil {
+		return err
+	}
+	file, err := st.GenerateOutputFilePath(release, DefaultSnapshotFileTemplate)
+	if err != nil {
+		return err
+	}
+	rel := filepath.Join(stateDir, file)
+	path := filepath.Join(opts.SnapshotDir, rel)
+
+	existing, err := st.fs.ReadFile(path)
+	missing := errors.Is(err, fs.ErrNotExist)
+	if err != nil && !missing {
+		return err
+	}
+
+	if bytes.Equal(existing, rendered) && !missing {
+		st.logger.Debugf("snapshot %s is up to date", path)
+		return nil
+	}
+
+	if opts.CheckSnapshots {
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(existing)),
+			B:        difflib.SplitLines(string(rendered)),
+			FromFile: filepath.ToSlash(filepath.Join("a", rel)),
+			ToFile:   filepath.ToSlash(filepath.Join("b", rel)),
+			Context:  3,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Print(diff)
+		return &SnapshotMismatchError{Release: release.Name, Path: path, Missing: missing}
+	}
+
+	if err := st.fs.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := st.fs.WriteFile(path, rendered, 0644); err != nil {
+		return err
+	}
+	st.logger.Infof("Wrote snapshot %s", path)
+
+	return nil
+}
+
+// snapshotStateDir returns the directory the snapshots of the releases of the
+// state file are placed in, which is named after the state file, within its
+// directory relative to baseDir. State files outside of baseDir, like remote
+// ones, are placed in "external" by the name of their directory.
+func (st *HelmState) snapshotStateDir(baseDir string) (string, error) {
+	name := strings.TrimSuffix(filepath.Base(st.FilePath), filepath.Ext(st.FilePath))
+	if baseDir == "" {
+		return name, nil
+	}
+
+	abs, err := st.fs.Abs(st.FilePath)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Dir(abs)
+
+	rel, err := filepath.Rel(baseDir, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		rel = filepath.Join("external", filepath.Base(dir))
+	}
+	return filepath.Join(rel, name), nil
+}
+
+type manifestDocument struct {
+	source, kind, namespace, name string
+	text                          string
+}
+
+// normalizeManifests concatenates every manifest helm wrote under dir into a
+// single YAML stream. Documents are ordered by source template, kind,
+// namespace and name, so that snapshots don't depend on the order in which
+// helm renders templates or lists resources within a file.
+func (st *HelmState) normalizeManifests(dir string) ([]byte, error) {
+	var docs []manifestDocument
+
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		entries, err := st.fs.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			path := filepath.Join(dir, e.Name())
+			if e.IsDir() {
+				if err := walk(path); err != nil {
+					return err
+				}
+				continue
+			}
+
+			bs, err := st.fs.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			for _, text := range manifestDocumentSeparator.Split(string(bs), -1) {
+				text = strings.TrimSpace(text)
+				if !hasManifestContent(text) {
+					continue
+				}
+				docs = append(docs, parseManifestDocument(text))
+			}
+		}
+		return nil
+	}
+	if err := walk(dir); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool {
+		a, b := docs[i], docs[j]
+		switch {
+		case a.source != b.source:
+			return a.source < b.source
+		case a.kind != b.kind:
+			return a.kind < b.kind
+		case a.namespace != b.namespace:
+			return a.namespace < b.namespace
+		case a.name != b.name:
+			return a.name < b.name
+		}
+		return a.text < b.text
+	})
+
+	var buf bytes.Buffer
+	for _, d := range docs {
+		buf.WriteString("---\n")
+		buf.WriteString(d.text)
+		buf.WriteString("\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// hasManifestContent reports whether the document has anything besides
+// comments and blank lines, e.g. a template that rendered to nothing.
+func hasManifestContent(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return true
+		}
+	}
+	return false
+}
+
+func parseManifestDocument(text string) manifestDocument {
+	doc := manifestDocument{text: text}
+
+	for _, line := range strings.Split(text, "\n") {
+		if src, ok := strings.CutPrefix(line, "# Source: "); ok {
+			doc.source = strings.TrimSpace(src)
+			break
+		}
+	}
+
+	var meta struct {
+		Kind     string `yaml:"kind"`
+		Metadata struct {
+			Name      string `yaml:"name"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"metadata"`
+	}
+	// Documents that aren't valid YAML are still snapshotted verbatim and
+	// ordered by their text only.
+	if err := yaml.Unmarshal([]byte(text), &meta); err == nil {
+		doc.kind = meta.Kind
+		doc.name = meta.Metadata.Name
+		doc.namespace = meta.Metadata.Namespace
+	}
+
+	return doc
+}
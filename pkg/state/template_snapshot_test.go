@@ -0,0 +1,166 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/testutil"
+)
+
+func writeRendered(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func TestNormalizeManifests_SortsDocuments(t *testing.T) {
+	dir := t.TempDir()
+	writeRendered(t, dir, map[string]string{
+		"web/templates/service.yaml": `---
+# Source: web/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`,
+		"web/templates/all.yaml": `---
+# Source: web/templates/all.yaml
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: web
+---
+# Source: web/templates/all.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web-b
+---
+# Source: web/templates/all.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web-a
+---
+# Source: web/templates/empty.yaml
+`,
+	})
+
+	st := &HelmState{fs: filesystem.DefaultFileSystem()}
+	got, err := st.normalizeManifests(dir)
+	require.NoError(t, err)
+	require.Equal(t, `---
+# Source: web/templates/all.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web-a
+---
+# Source: web/templates/all.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web-b
+---
+# Source: web/templates/all.yaml
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: web
+---
+# Source: web/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`, string(got))
+}
+
+func TestSnapshotRelease_WriteThenCheck(t *testing.T) {
+	snapshots := t.TempDir()
+	st := &HelmState{
+		FilePath: "helmfile.yaml",
+		fs:       filesystem.DefaultFileSystem(),
+		logger:   helmexec.NewLogger(os.Stderr, "warn"),
+	}
+	release := &ReleaseSpec{Name: "web", Namespace: "apps"}
+
+	render := func(replicas string) string {
+		dir := t.TempDir()
+		writeRendered(t, dir, map[string]string{
+			"web/templates/deployment.yaml": "---\n# Source: web/templates/deployment.yaml\nkind: Deployment\nmetadata:\n  name: web\nspec:\n  replicas: " + replicas + "\n",
+		})
+		return dir
+	}
+
+	// A missing snapshot fails the check.
+	var err error
+	out, captureErr := testutil.CaptureStdout(func() {
+		err = st.snapshotRelease(release, render("1"), &TemplateOpts{SnapshotDir: snapshots, CheckSnapshots: true})
+	})
+	require.NoError(t, captureErr)
+	var mismatch *SnapshotMismatchError
+	require.True(t, errors.As(err, &mismatch))
+	require.True(t, mismatch.Missing)
+	require.Contains(t, out, "+++ b/helmfile/apps/web.yaml")
+
+	require.NoError(t, st.snapshotRelease(release, render("1"), &TemplateOpts{SnapshotDir: snapshots}))
+	written, err := os.ReadFile(filepath.Join(snapshots, "helmfile", "apps", "web.yaml"))
+	require.NoError(t, err)
+	require.Contains(t, string(written), "replicas: 1")
+
+	require.NoError(t, st.snapshotRelease(release, render("1"), &TemplateOpts{SnapshotDir: snapshots, CheckSnapshots: true}))
+
+	out, captureErr = testutil.CaptureStdout(func() {
+		err = st.snapshotRelease(release, render("3"), &TemplateOpts{SnapshotDir: snapshots, CheckSnapshots: true})
+	})
+	require.NoError(t, captureErr)
+	require.True(t, errors.As(err, &mismatch))
+	require.False(t, mismatch.Missing)
+	require.Contains(t, out, "--- a/helmfile/apps/web.yaml")
+	require.Contains(t, out, "-  replicas: 1\n+  replicas: 3\n")
+}
+
+func TestSnapshotRelease_StateDirectories(t *testing.T) {
+	base := t.TempDir()
+	snapshots := t.TempDir()
+	render := t.TempDir()
+	writeRendered(t, render, map[string]string{
+		"web/templates/service.yaml": "---\n# Source: web/templates/service.yaml\nkind: Service\nmetadata:\n  name: web\n",
+	})
+	release := &ReleaseSpec{Name: "web", Namespace: "apps"}
+	opts := &TemplateOpts{SnapshotDir: snapshots, SnapshotBaseDir: base}
+
+	// Same-named sub-helmfiles in different directories get snapshots of their own
+	for _, file := range []string{
+		filepath.Join(base, "helmfile.yaml"),
+		filepath.Join(base, "team-a", "helmfile.yaml"),
+		filepath.Join(base, "team-b", "helmfile.yaml"),
+		filepath.Join(t.TempDir(), "remote", "helmfile.yaml"),
+	} {
+		st := &HelmState{
+			FilePath: file,
+			fs:       filesystem.DefaultFileSystem(),
+			logger:   helmexec.NewLogger(os.Stderr, "warn"),
+		}
+		require.NoError(t, st.snapshotRelease(release, render, opts))
+	}
+
+	for _, path := range []string{
+		"helmfile/apps/web.yaml",
+		"team-a/helmfile/apps/web.yaml",
+		"team-b/helmfile/apps/web.yaml",
+		"external/remote/helmfile/apps/web.yaml",
+	} {
+		require.FileExists(t, filepath.Join(snapshots, path))
+	}
+}
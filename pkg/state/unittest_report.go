@@ -0,0 +1,298 @@
+package state
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// UnittestReport aggregates helm-unittest results across all releases of a
+// `helmfile unittest` run, possibly spanning several helmfiles. It is safe for
+// concurrent use by the workers of UnittestReleases.
+type UnittestReport struct {
+	mu       sync.Mutex
+	releases []UnittestReleaseResult
+}
+
+// UnittestReleaseResult is the outcome of running helm-unittest for a single release.
+type UnittestReleaseResult struct {
+	Release     string `json:"release"`
+	Namespace   string `json:"namespace,omitempty"`
+	KubeContext string `json:"kubeContext,omitempty"`
+	Chart       string `json:"chart"`
+	// Duration is the wall-clock time of the helm unittest invocation.
+	Duration time.Duration `json:"-"`
+	// Cases are the individual test cases parsed from helm-unittest's JUnit output.
+	Cases []UnittestCase `json:"cases"`
+	// UpdatedSnapshots lists snapshot files written or changed by --update-snapshot.
+	UpdatedSnapshots []string `json:"updatedSnapshots,omitempty"`
+	// Error is set when helm unittest could not run the tests at all, e.g. the
+	// plugin is missing or the chart failed to render.
+	Error string `json:"error,omitempty"`
+}
+
+// UnittestCase is a single helm-unittest test case.
+type UnittestCase struct {
+	// Suite is the helm-unittest suite (test file) the case belongs to.
+	Suite   string        `json:"suite"`
+	Name    string        `json:"name"`
+	Time    time.Duration `json:"-"`
+	Failure string        `json:"failure,omitempty"`
+	Skipped bool          `json:"skipped,omitempty"`
+}
+
+// Failed reports whether the release had failing test cases or could not be tested.
+func (r UnittestReleaseResult) Failed() bool {
+	if r.Error != "" {
+		return true
+	}
+	for _, c := range r.Cases {
+		if c.Failure != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (r UnittestReleaseResult) counts() (tests, failures, errs, skipped int) {
+	for _, c := range r.Cases {
+		tests++
+		if c.Failure != "" {
+			failures++
+		}
+		if c.Skipped {
+			skipped++
+		}
+	}
+	if r.Error != "" {
+		tests++
+		errs++
+	}
+	return
+}
+
+// Add records the result of one release.
+func (r *UnittestReport) Add(res UnittestReleaseResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.releases = append(r.releases, res)
+}
+
+// Results returns the recorded results sorted by kube context, namespace and
+// release name, so that reports are stable regardless of execution order.
+func (r *UnittestReport) Results() []UnittestReleaseResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := append([]UnittestReleaseResult(nil), r.releases...)
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].KubeContext != out[j].KubeContext {
+			return out[i].KubeContext < out[j].KubeContext
+		}
+		if out[i].Namespace != out[j].Namespace {
+			return out[i].Namespace < out[j].Namespace
+		}
+		return out[i].Release < out[j].Release
+	})
+	return out
+}
+
+// WriteJUnit writes the report as a single JUnit XML document with one
+// <testsuite> per release. The helm-unittest suite of each case is kept as
+// the testcase classname.
+func (r *UnittestReport) WriteJUnit(w io.Writer) error {
+	doc := junitTestSuites{Name: "helmfile unittest"}
+	var total time.Duration
+
+	for _, res := range r.Results() {
+		tests, failures, errs, skipped := res.counts()
+		suite := junitTestSuite{
+			Name:     res.Release,
+			Tests:    tests,
+			Failures: failures,
+			Errors:   errs,
+			Skipped:  skipped,
+			Time:     formatJUnitSeconds(res.Duration),
+		}
+		for _, p := range []struct{ name, value string }{
+			{"namespace", res.Namespace},
+			{"kubeContext", res.KubeContext},
+			{"chart", res.Chart},
+		} {
+			if p.value != "" {
+				suite.Properties = append(suite.Properties, junitProperty{Name: p.name, Value: p.value})
+			}
+		}
+		for _, c := range res.Cases {
+			tc := junitTestCase{Classname: c.Suite, Name: c.Name, Time: formatJUnitSeconds(c.Time)}
+			if c.Failure != "" {
+				tc.Failure = &junitMessage{Message: "test failed", Content: c.Failure}
+			}
+			if c.Skipped {
+				tc.Skipped = &junitMessage{}
+			}
+			suite.TestCases = append(suite.TestCases, tc)
+		}
+		if res.Error != "" {
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				Classname: res.Release,
+				Name:      "helm unittest",
+				Error:     &junitMessage{Message: "helm unittest failed", Content: res.Error},
+			})
+		}
+
+		doc.Tests += tests
+		doc.Failures += failures
+		doc.Errors += errs
+		total += res.Duration
+		doc.Suites = append(doc.Suites, suite)
+	}
+	doc.Time = formatJUnitSeconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *UnittestReport) WriteJSON(w io.Writer) error {
+	type jsonCase struct {
+		UnittestCase
+		Seconds float64 `json:"time"`
+	}
+	type jsonRelease struct {
+		UnittestReleaseResult
+		Cases    []jsonCase `json:"cases"`
+		Tests    int        `json:"tests"`
+		Failures int        `json:"failures"`
+		Errors   int        `json:"errors"`
+		Skipped  int        `json:"skipped"`
+		Seconds  float64    `json:"time"`
+	}
+
+	out := struct {
+		Releases []jsonRelease `json:"releases"`
+	}{Releases: []jsonRelease{}}
+
+	for _, res := range r.Results() {
+		tests, failures, errs, skipped := res.counts()
+		jr := jsonRelease{
+			UnittestReleaseResult: res,
+			Cases:                 []jsonCase{},
+			Tests:                 tests,
+			Failures:              failures,
+			Errors:                errs,
+			Skipped:               skipped,
+			Seconds:               res.Duration.Seconds(),
+		}
+		for _, c := range res.Cases {
+			jr.Cases = append(jr.Cases, jsonCase{UnittestCase: c, Seconds: c.Time.Seconds()})
+		}
+		out.Releases = append(out.Releases, jr)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// addJUnit parses the JUnit document helm-unittest wrote for the release and
+// appends its test cases.
+func (r *UnittestReleaseResult) addJUnit(data []byte) error {
+	var doc junitTestSuites
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parsing JUnit report: %w", err)
+	}
+	for _, s := range doc.Suites {
+		for _, tc := range s.TestCases {
+			c := UnittestCase{
+				Suite: s.Name,
+				Name:  tc.Name,
+				Time:  parseJUnitSeconds(tc.Time),
+			}
+			if tc.Failure != nil {
+				c.Failure = firstNonEmpty(tc.Failure.Content, tc.Failure.Message, "failed")
+			}
+			if tc.Error != nil {
+				c.Failure = firstNonEmpty(tc.Error.Content, tc.Error.Message, "error")
+			}
+			c.Skipped = tc.Skipped != nil
+			r.Cases = append(r.Cases, c)
+		}
+	}
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func formatJUnitSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+func parseJUnitSeconds(s string) time.Duration {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(f * float64(time.Second))
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr,omitempty"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Time     string           `xml:"time,attr,omitempty"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Classname string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr,omitempty"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Content string `xml:",chardata"`
+}
@@ -0,0 +1,104 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const helmUnittestJUnit = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="deployment" tests="2" failures="1" errors="0" time="0.012">
+    <testcase classname="deployment" name="sets replicas" time="0.004"></testcase>
+    <testcase classname="deployment" name="sets image" time="0.008">
+      <failure message="failed" type=""><![CDATA[expected "nginx:1.25" got "nginx:latest"]]></failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="service" tests="1" failures="0" errors="0" time="0.002">
+    <testcase classname="service" name="exposes port" time="0.002"></testcase>
+  </testsuite>
+</testsuites>
+`
+
+func TestUnittestReport_AggregatesReleasesAsSuites(t *testing.T) {
+	web := UnittestReleaseResult{Release: "web", Namespace: "default", Chart: "./charts/web", Duration: 1500 * time.Millisecond}
+	require.NoError(t, web.addJUnit([]byte(helmUnittestJUnit)))
+	require.Len(t, web.Cases, 3)
+	require.True(t, web.Failed())
+
+	report := &UnittestReport{}
+	// Added out of order on purpose: output must be sorted.
+	report.Add(web)
+	report.Add(UnittestReleaseResult{Release: "api", Namespace: "default", Chart: "./charts/api", Error: "Error: plugin \"unittest\" exited with error"})
+
+	var junit bytes.Buffer
+	require.NoError(t, report.WriteJUnit(&junit))
+
+	var doc junitTestSuites
+	require.NoError(t, xml.Unmarshal(junit.Bytes(), &doc))
+	require.Len(t, doc.Suites, 2)
+	require.Equal(t, "api", doc.Suites[0].Name)
+	require.Equal(t, 1, doc.Suites[0].Errors)
+	require.Equal(t, "web", doc.Suites[1].Name)
+	require.Equal(t, 3, doc.Suites[1].Tests)
+	require.Equal(t, 1, doc.Suites[1].Failures)
+	require.Equal(t, "1.500", doc.Suites[1].Time)
+	require.Equal(t, "deployment", doc.Suites[1].TestCases[1].Classname)
+	require.NotNil(t, doc.Suites[1].TestCases[1].Failure)
+	require.Contains(t, doc.Suites[1].TestCases[1].Failure.Content, "nginx:latest")
+	require.Equal(t, 4, doc.Tests)
+	require.Equal(t, 1, doc.Failures)
+	require.Equal(t, 1, doc.Errors)
+
+	var js bytes.Buffer
+	require.NoError(t, report.WriteJSON(&js))
+
+	var out struct {
+		Releases []struct {
+			Release  string  `json:"release"`
+			Tests    int     `json:"tests"`
+			Failures int     `json:"failures"`
+			Error    string  `json:"error"`
+			Time     float64 `json:"time"`
+			Cases    []struct {
+				Suite   string `json:"suite"`
+				Name    string `json:"name"`
+				Failure string `json:"failure"`
+			} `json:"cases"`
+		} `json:"releases"`
+	}
+	require.NoError(t, json.Unmarshal(js.Bytes(), &out))
+	require.Len(t, out.Releases, 2)
+	require.Equal(t, "api", out.Releases[0].Release)
+	require.True(t, strings.HasPrefix(out.Releases[0].Error, "Error:"))
+	require.Equal(t, "web", out.Releases[1].Release)
+	require.Equal(t, 3, out.Releases[1].Tests)
+	require.Equal(t, 1, out.Releases[1].Failures)
+	require.InDelta(t, 1.5, out.Releases[1].Time, 0.001)
+	require.Equal(t, "service", out.Releases[1].Cases[2].Suite)
+}
+
+func TestChangedSnapshots(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	t1 := t0.Add(time.Second)
+
+	before := map[string]time.Time{
+		"tests/__snapshot__/a_test.yaml.snap": t0,
+		"tests/__snapshot__/b_test.yaml.snap": t0,
+	}
+	after := map[string]time.Time{
+		"tests/__snapshot__/a_test.yaml.snap": t0,
+		"tests/__snapshot__/b_test.yaml.snap": t1,
+		"tests/__snapshot__/c_test.yaml.snap": t1,
+	}
+
+	require.Equal(t, []string{
+		"tests/__snapshot__/b_test.yaml.snap",
+		"tests/__snapshot__/c_test.yaml.snap",
+	}, changedSnapshots(before, after))
+}
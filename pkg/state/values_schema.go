@@ -0,0 +1,304 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"dario.cat/mergo"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+
+	"github.com/helmfile/helmfile/pkg/environment"
+	"github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/maputil"
+	"github.com/helmfile/helmfile/pkg/yaml"
+)
+
+var (
+	valuesSchemaPrinter = message.NewPrinter(language.English)
+
+	indexTokenRegexp = regexp.MustCompile(`^\d+$`)
+)
+
+// ValuesSchemaError is returned when values don't conform to their valuesSchema.
+type ValuesSchemaError struct {
+	// Subject is what the values are of, like `environment "prod"` or `release "web"`
+	Subject string
+	Schema  string
+
+	Violations []ValuesSchemaViolation
+}
+
+// ValuesSchemaViolation is a value that doesn't conform to the schema.
+type ValuesSchemaViolation struct {
+	// Path is the path of the value, like `ingress.hosts[0]`
+	Path string
+	// Source is the file setting the value, if known
+	Source  string
+	Message string
+}
+
+func (e *ValuesSchemaError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: values do not conform to the schema %s:", e.Subject, e.Schema)
+	for _, v := range e.Violations {
+		fmt.Fprintf(&b, "\n  at %s", v.Path)
+		if v.Source != "" {
+			fmt.Fprintf(&b, " (%s)", v.Source)
+		}
+		fmt.Fprintf(&b, ": %s", v.Message)
+	}
+	return b.String()
+}
+
+// valuesLayer is a set of values merged into the validated values, along with where it comes from.
+type valuesLayer struct {
+	source string
+	vals   map[string]any
+}
+
+// validateValues validates vals against the JSON schema at schemaPath.
+// layers returns the values vals were merged from, in the order of their precedence, to find
+// the source of the offending values. It is only called when vals don't conform to the schema.
+func (st *HelmState) validateValues(subject, schemaPath string, vals map[string]any, layers func() []valuesLayer) error {
+	files, _, err := st.storage().resolveFile(nil, "values schema", schemaPath)
+	if err != nil {
+		return fmt.Errorf("%s: %v", subject, err)
+	}
+	if len(files) > 1 {
+		return fmt.Errorf("%s: glob patterns in valuesSchema are not supported", subject)
+	}
+
+	c := jsonschema.NewCompiler()
+	c.UseLoader(valuesSchemaLoader{fs: st.fs})
+	schema, err := c.Compile(files[0])
+	if err != nil {
+		return fmt.Errorf("%s: failed to compile the values schema %s: %v", subject, schemaPath, err)
+	}
+
+	instance, err := toJSONValue(vals)
+	if err != nil {
+		return fmt.Errorf("%s: %v", subject, err)
+	}
+
+	err = schema.Validate(instance)
+	if err == nil {
+		return nil
+	}
+	verr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return fmt.Errorf("%s: %v", subject, err)
+	}
+
+	ls := layers()
+	schemaErr := &ValuesSchemaError{Subject: subject, Schema: schemaPath}
+	for _, l := range leafValidationErrors(verr) {
+		paths := [][]string{l.InstanceLocation}
+		if k, ok := l.ErrorKind.(*kind.AdditionalProperties); ok {
+			// Point at the offending properties rather than the object having them
+			paths = paths[:0]
+			for _, p := range k.Properties {
+				paths = append(paths, append(append([]string{}, l.InstanceLocation...), p))
+			}
+		}
+		for _, p := range paths {
+			schemaErr.Violations = append(schemaErr.Violations, ValuesSchemaViolation{
+				Path:    formatValuesPath(p),
+				Source:  valuesSource(ls, p),
+				Message: l.ErrorKind.LocalizedString(valuesSchemaPrinter),
+			})
+		}
+	}
+	return schemaErr
+}
+
+// validateReleaseValues validates the values files generated for release, merged like `helmfile write-values` does,
+// against the valuesSchema of the release. sources are the files users write the generated files were generated from,
+// so that the errors point at them.
+func (st *HelmState) validateReleaseValues(release *ReleaseSpec, generatedFiles, sources []string) error {
+	if release.ValuesSchema == "" {
+		return nil
+	}
+
+	var layers []valuesLayer
+	merged := map[string]any{}
+	for i, f := range generatedFiles {
+		vals := map[string]any{}
+		bs, err := st.fs.ReadFile(f)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", f, err)
+		}
+		if err := yaml.Unmarshal(bs, &vals); err != nil {
+			return fmt.Errorf("unmarshalling yaml %s: %w", f, err)
+		}
+		if err := mergo.Merge(&merged, &vals, mergo.WithOverride); err != nil {
+			return fmt.Errorf("merging %s: %w", f, err)
+		}
+		layers = append(layers, valuesLayer{source: sources[i], vals: vals})
+	}
+
+	return st.validateValues(fmt.Sprintf("release %q", release.Name), release.ValuesSchema, merged, func() []valuesLayer {
+		return layers
+	})
+}
+
+// leafValidationErrors returns the errors of err that have no causes, which are the actual violations.
+func leafValidationErrors(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+	var leaves []*jsonschema.ValidationError
+	for _, c := range err.Causes {
+		leaves = append(leaves, leafValidationErrors(c)...)
+	}
+	return leaves
+}
+
+// valuesSource returns the source of the last layer setting the value at path, or of the closest parent value.
+func valuesSource(layers []valuesLayer, path []string) string {
+	for n := len(path); n >= 0; n-- {
+		for i := len(layers) - 1; i >= 0; i-- {
+			if hasValueAt(layers[i].vals, path[:n]) {
+				return layers[i].source
+			}
+		}
+	}
+	return ""
+}
+
+func hasValueAt(v any, path []string) bool {
+	if len(path) == 0 {
+		return v != nil
+	}
+	switch typed := v.(type) {
+	case map[string]any:
+		child, ok := typed[path[0]]
+		return ok && hasValueAt(child, path[1:])
+	case map[any]any:
+		child, ok := typed[path[0]]
+		return ok && hasValueAt(child, path[1:])
+	case []any:
+		i, err := strconv.Atoi(path[0])
+		return err == nil && i >= 0 && i < len(typed) && hasValueAt(typed[i], path[1:])
+	}
+	return false
+}
+
+// formatValuesPath formats the location of a value like `ingress.hosts[0]`.
+func formatValuesPath(path []string) string {
+	if len(path) == 0 {
+		return "(root)"
+	}
+	var b strings.Builder
+	for _, p := range path {
+		if indexTokenRegexp.MatchString(p) {
+			fmt.Fprintf(&b, "[%s]", p)
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(p)
+	}
+	return b.String()
+}
+
+// toJSONValue converts vals to the types the validator expects, like JSON numbers for YAML integers.
+func toJSONValue(vals map[string]any) (any, error) {
+	casted, err := maputil.CastKeysToStrings(vals)
+	if err != nil {
+		return nil, err
+	}
+	bs, err := json.Marshal(casted)
+	if err != nil {
+		return nil, err
+	}
+	return jsonschema.UnmarshalJSON(bytes.NewReader(bs))
+}
+
+// valuesSchemaLoader loads the schemas, including the ones referenced via $ref, from the helmfile filesystem.
+type valuesSchemaLoader struct {
+	fs *filesystem.FileSystem
+}
+
+func (l valuesSchemaLoader) Load(url string) (any, error) {
+	path, err := jsonschema.FileLoader{}.ToFile(url)
+	if err != nil {
+		return nil, err
+	}
+	bs, err := l.fs.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return jsonschema.UnmarshalJSON(bytes.NewReader(bs))
+}
+
+// validateEnvValues validates the merged values of the environment e against the valuesSchema of the environment.
+func (c *StateCreator) validateEnvValues(st *HelmState, name string, e *environment.Environment, ctxEnv, overrode *environment.Environment) error {
+	envSpec, ok := st.Environments[name]
+	if !ok || envSpec.ValuesSchema == "" {
+		return nil
+	}
+
+	vals, err := e.GetMergedValues()
+	if err != nil {
+		return err
+	}
+
+	return st.validateValues(fmt.Sprintf("environment %q", name), envSpec.ValuesSchema, vals, func() []valuesLayer {
+		return c.envValuesLayers(st, envSpec, name, ctxEnv, overrode)
+	})
+}
+
+// envValuesLayers loads the values files of the environment one by one, in the order they are merged.
+// The files that can't be loaded on their own, like lazy values files referencing the others, are left out.
+func (c *StateCreator) envValuesLayers(st *HelmState, envSpec EnvironmentSpec, name string, ctxEnv, overrode *environment.Environment) []valuesLayer {
+	load := func(missingFileHandler *string, entries []any, inline string) []valuesLayer {
+		var layers []valuesLayer
+		for _, entry := range entries {
+			path, ok := entry.(string)
+			if !ok {
+				if vals, err := maputil.CastKeysToStrings(entry); err == nil {
+					layers = append(layers, valuesLayer{source: inline, vals: vals})
+				}
+				continue
+			}
+			files, _, err := st.storage().resolveFile(missingFileHandler, "environment values", path)
+			if err != nil {
+				continue
+			}
+			for _, f := range files {
+				vals, err := st.loadValuesEntries(missingFileHandler, []any{f}, c.remote, ctxEnv, name, "", false)
+				if err != nil {
+					continue
+				}
+				layers = append(layers, valuesLayer{source: f, vals: vals})
+			}
+		}
+		return layers
+	}
+
+	layers := load(nil, st.DefaultValues, fmt.Sprintf("inline values in %s", st.FilePath))
+
+	envLayers := load(envSpec.MissingFileHandler, envSpec.Values, fmt.Sprintf("inline values of environment %q in %s", name, st.FilePath))
+	if envSpec.MergeStrategy == MergeStrategyFallback {
+		slices.Reverse(envLayers)
+	}
+	layers = append(layers, envLayers...)
+
+	if overrode != nil {
+		layers = append(layers,
+			valuesLayer{source: "--state-values-file", vals: overrode.Values},
+			valuesLayer{source: "--state-values-set", vals: overrode.CLIOverrides},
+		)
+	}
+
+	return layers
+}
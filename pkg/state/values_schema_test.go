@@ -0,0 +1,156 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/helmfile/helmfile/pkg/environment"
+	"github.com/helmfile/helmfile/pkg/exectest"
+	"github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/helmexec"
+	"github.com/helmfile/helmfile/pkg/remote"
+	"github.com/helmfile/helmfile/pkg/testhelper"
+)
+
+const testValuesSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "properties": {
+    "replicaCount": {"type": "integer"},
+    "image": {
+      "type": "object",
+      "properties": {
+        "tag": {"type": "string"}
+      }
+    },
+    "hosts": {
+      "type": "array",
+      "items": {"type": "string"}
+    }
+  }
+}
+`
+
+func TestEnvironmentValuesSchema(t *testing.T) {
+	load := func(t *testing.T, prod string) (*HelmState, error) {
+		t.Helper()
+
+		files := map[string]string{
+			"/example/path/to/helmfile.yaml": `environments:
+  production:
+    valuesSchema: schema.json
+    values:
+    - default.yaml
+    - production.yaml
+    - image:
+        tag: v1
+`,
+			"/example/path/to/schema.json":     testValuesSchema,
+			"/example/path/to/default.yaml":    "replicaCount: 1\nhosts:\n- a.example.com\n",
+			"/example/path/to/production.yaml": prod,
+		}
+		testFs := testhelper.NewTestFs(files)
+		r := remote.NewRemote(logger, testFs.Cwd, testFs.ToFileSystem())
+		return NewCreator(logger, testFs.ToFileSystem(), nil, nil, "", "", r, false, "").
+			ParseAndLoad([]byte(files["/example/path/to/helmfile.yaml"]), "/example/path/to", "/example/path/to/helmfile.yaml", "production", true, true, true, nil, nil)
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		st, err := load(t, "replicaCount: 3\n")
+		require.NoError(t, err)
+		require.Equal(t, 3, st.Env.Values["replicaCount"])
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := load(t, "replicaCont: 3\nhosts:\n- 1\n")
+		require.EqualError(t, err, `failed to read /example/path/to/helmfile.yaml: environment "production": values do not conform to the schema schema.json:
+  at hosts[0] (/example/path/to/production.yaml): got number, want string
+  at replicaCont (/example/path/to/production.yaml): additional properties 'replicaCont' not allowed`)
+	})
+}
+
+// decryptingHelm decrypts secrets files by copying them, like helm-secrets does for unencrypted files.
+type decryptingHelm struct {
+	exectest.Helm
+}
+
+func (helm *decryptingHelm) DecryptSecret(context helmexec.HelmContext, name string, flags ...string) (string, error) {
+	bs, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp("", "decrypted-*.yaml")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	_, err = f.Write(bs)
+	return f.Name(), err
+}
+
+func TestReleaseValuesSchema(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	writeFile("schema.json", testValuesSchema)
+	writeFile("values.yaml", "replicaCount: 1\nimage:\n  tag: v1\n")
+	writeFile("secrets.yaml", "replicaCount: many\n")
+
+	run := func(release *ReleaseSpec) error {
+		st := &HelmState{
+			basePath: dir,
+			FilePath: "helmfile.yaml",
+			fs:       filesystem.DefaultFileSystem(),
+			logger:   logger,
+			ReleaseSetSpec: ReleaseSetSpec{
+				Releases: []ReleaseSpec{*release},
+				Env:      environment.Environment{Name: "default"},
+			},
+			RenderedValues: map[string]any{},
+			valsRuntime:    valsRuntime,
+		}
+		_, files, err := st.flagsForTemplate(&decryptingHelm{}, release, 0, &TemplateOpts{})
+		st.removeFiles(files)
+		return err
+	}
+
+	require.NoError(t, run(&ReleaseSpec{
+		Name:         "web",
+		Chart:        "stable/web",
+		ValuesSchema: "schema.json",
+		Values:       []any{"values.yaml", map[string]any{"replicaCount": 2}},
+	}))
+
+	err := run(&ReleaseSpec{
+		Name:         "web",
+		Chart:        "stable/web",
+		ValuesSchema: "schema.json",
+		Values:       []any{"values.yaml", map[string]any{"image": map[string]any{"tag": 2}}},
+	})
+	require.EqualError(t, err, `release "web": values do not conform to the schema schema.json:
+  at image.tag (inline values of release "web" in helmfile.yaml): got number, want string`)
+
+	err = run(&ReleaseSpec{
+		Name:         "web",
+		Chart:        "stable/web",
+		ValuesSchema: "schema.json",
+		Values:       []any{map[string]any{"replicaCount": "3"}, "values.yaml"},
+	})
+	require.NoError(t, err, "values.yaml overrides the invalid inline value")
+
+	err = run(&ReleaseSpec{
+		Name:         "web",
+		Chart:        "stable/web",
+		ValuesSchema: "schema.json",
+		Values:       []any{"values.yaml"},
+		Secrets:      []any{"secrets.yaml"},
+	})
+	require.EqualError(t, err, `release "web": values do not conform to the schema schema.json:
+  at replicaCount (`+filepath.Join(dir, "secrets.yaml")+`): got string, want integer`)
+}
@@ -126,6 +126,16 @@ func (helm *noCallHelmExec) List(context helmexec.HelmContext, filter string, fl
 	return "", nil
 }
 
+func (helm *noCallHelmExec) ReleaseStatusInfo(context helmexec.HelmContext, name string, flags ...string) (*helmexec.ReleaseStatusInfo, error) {
+	helm.doPanic()
+	return nil, nil
+}
+
+func (helm *noCallHelmExec) ListReleases(context helmexec.HelmContext, flags ...string) ([]helmexec.ReleaseInfo, error) {
+	helm.doPanic()
+	return nil, nil
+}
+
 func (helm *noCallHelmExec) DecryptSecret(context helmexec.HelmContext, name string, flags ...string) (string, error) {
 	helm.doPanic()
 	return "", nil
@@ -8,6 +8,7 @@ type Context struct {
 	preRender bool
 	basePath  string
 	fs        *filesystem.FileSystem
+	sandbox   *Sandbox
 }
 
 // SetBasePath sets the base path for the template
@@ -18,3 +19,8 @@ func (c *Context) SetBasePath(path string) {
 func (c *Context) SetFileSystem(fs *filesystem.FileSystem) {
 	c.fs = fs
 }
+
+// SetSandbox restricts the template functions with the sandbox, unless it is nil
+func (c *Context) SetSandbox(sandbox *Sandbox) {
+	c.sandbox = sandbox
+}
@@ -43,6 +43,12 @@ func init() {
 	disableInsecureTemplateFunctions, _ = strconv.ParseBool(os.Getenv(envvar.DisableInsecureTemplateFunctions))
 }
 
+// InsecureFunctionsDisabled returns true when insecure functions like exec and readFile
+// are disabled by HELMFILE_DISABLE_INSECURE_FEATURES or HELMFILE_DISABLE_INSECURE_TEMPLATE_FUNCTIONS.
+func InsecureFunctionsDisabled() bool {
+	return disableInsecureFeatures || disableInsecureTemplateFunctions
+}
+
 func (c *Context) createFuncMap() template.FuncMap {
 	funcMap := template.FuncMap{
 		"envExec":          c.EnvExec,
@@ -63,6 +69,11 @@ func (c *Context) createFuncMap() template.FuncMap {
 		"fetchSecretValue": fetchSecretValue,
 		"expandSecretRefs": fetchSecretValues,
 	}
+	if c.sandbox != nil {
+		for name, f := range c.sandboxFuncs() {
+			funcMap[name] = f
+		}
+	}
 	if c.preRender {
 		// disable potential side-effect template calls
 		funcMap["exec"] = func(string, []any, ...string) (string, error) {
@@ -81,7 +92,7 @@ func (c *Context) createFuncMap() template.FuncMap {
 			return []fs.DirEntry{}, nil
 		}
 	}
-	if disableInsecureFeatures || disableInsecureTemplateFunctions {
+	if InsecureFunctionsDisabled() {
 		// disable insecure functions
 		funcMap["exec"] = func(string, []any, ...string) (string, error) {
 			return "", DisableInsecureFunctionsErr
@@ -0,0 +1,232 @@
+package tmpl
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/helmfile/helmfile/pkg/filesystem"
+)
+
+// Sandbox restricts the template functions available to the templates of a helmfile
+// that isn't trusted, like a remote sub-helmfile maintained by another team.
+//
+// exec and envExec are denied, the functions reading files can only read files in Dir,
+// and the functions reading environment variables can only read the ones in AllowedEnv.
+// The same goes for the files read by vals refs, like ref+file://, and for the
+// values, secrets and bases files of the helmfile.
+type Sandbox struct {
+	// Dir is the absolute path to the directory the templates can read files from.
+	Dir string
+	// AllowedEnv is the list of environment variables the templates can read.
+	// Entries can be patterns like `TEAM_A_*`.
+	AllowedEnv []string
+	// Parent is the sandbox of the helmfile this sandbox is nested in, if any.
+	// The templates are restricted by both sandboxes.
+	Parent *Sandbox
+}
+
+// SandboxError is returned by the template functions a Sandbox denies.
+type SandboxError struct {
+	err string
+}
+
+func (e SandboxError) Error() string {
+	return e.err
+}
+
+// Deny returns the error of the function name, which is denied by the sandbox.
+func (s *Sandbox) Deny(name string) error {
+	return SandboxError{fmt.Sprintf("%s is not allowed in sandboxed helmfiles", name)}
+}
+
+// CheckPath returns an error when the file at filename is outside of the sandbox directory.
+// Symbolic links are resolved so that they can't point outside of it.
+func (s *Sandbox) CheckPath(fs *filesystem.FileSystem, filename string) error {
+	if s == nil {
+		return nil
+	}
+
+	resolved := resolveSandboxPath(fs, filename)
+	for sb := s; sb != nil; sb = sb.Parent {
+		dir := resolveSandboxPath(fs, sb.Dir)
+		rel, err := filepath.Rel(dir, resolved)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return SandboxError{fmt.Sprintf("%s is outside of the sandbox directory %s", filename, sb.Dir)}
+		}
+	}
+	return nil
+}
+
+// CheckEnv returns an error when the environment variable name isn't allowed by the sandbox.
+func (s *Sandbox) CheckEnv(name string) error {
+	if s == nil {
+		return nil
+	}
+
+	for sb := s; sb != nil; sb = sb.Parent {
+		if !sb.allowsEnv(name) {
+			return SandboxError{fmt.Sprintf("env var `%s` is not allowed in the sandbox", name)}
+		}
+	}
+	return nil
+}
+
+// sandboxRefPattern matches the vals refs of the providers reading local files,
+// and of envsubst, which reads environment variables.
+var sandboxRefPattern = regexp.MustCompile(`ref\+(file|sops|tfstate|envsubst)://([^#?\s]*)`)
+
+// CheckRefs returns an error when the vals refs in v read files outside of the
+// sandbox directory, or environment variables through envsubst.
+// v is a string or values containing strings, as passed to vals.
+func (s *Sandbox) CheckRefs(fs *filesystem.FileSystem, v any) error {
+	if s == nil {
+		return nil
+	}
+
+	switch typed := v.(type) {
+	case string:
+		for _, m := range sandboxRefPattern.FindAllStringSubmatch(typed, -1) {
+			if m[1] == "envsubst" {
+				return s.Deny("ref+envsubst://")
+			}
+			if err := s.CheckPath(fs, m[2]); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		for _, e := range typed {
+			if err := s.CheckRefs(fs, e); err != nil {
+				return err
+			}
+		}
+	case map[any]any:
+		for _, e := range typed {
+			if err := s.CheckRefs(fs, e); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, e := range typed {
+			if err := s.CheckRefs(fs, e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Sandbox) allowsEnv(name string) bool {
+	for _, pattern := range s.AllowedEnv {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func resolveSandboxPath(fs *filesystem.FileSystem, filename string) string {
+	if abs, err := fs.Abs(filename); err == nil {
+		filename = abs
+	}
+	if fs.EvalSymlinks != nil {
+		if resolved, err := fs.EvalSymlinks(filename); err == nil {
+			filename = resolved
+		}
+	}
+	return filepath.Clean(filename)
+}
+
+// sandboxFuncs returns the template functions restricted by the sandbox of the context.
+func (c *Context) sandboxFuncs() map[string]any {
+	resolve := func(filename string) string {
+		if filepath.IsAbs(filename) {
+			return filename
+		}
+		return filepath.Join(c.basePath, filename)
+	}
+	getenv := func(name string) (string, error) {
+		if err := c.sandbox.CheckEnv(name); err != nil {
+			return "", err
+		}
+		return os.Getenv(name), nil
+	}
+
+	return map[string]any{
+		"exec": func(string, []any, ...string) (string, error) {
+			return "", c.sandbox.Deny("exec")
+		},
+		"envExec": func(map[string]any, string, []any, ...string) (string, error) {
+			return "", c.sandbox.Deny("envExec")
+		},
+		"isFile": func(filename string) (bool, error) {
+			if err := c.sandbox.CheckPath(c.fs, resolve(filename)); err != nil {
+				return false, err
+			}
+			return c.IsFile(filename)
+		},
+		"isDir": func(filename string) (bool, error) {
+			if err := c.sandbox.CheckPath(c.fs, resolve(filename)); err != nil {
+				return false, err
+			}
+			return c.IsDir(filename)
+		},
+		"readFile": func(filename string) (string, error) {
+			if err := c.sandbox.CheckPath(c.fs, resolve(filename)); err != nil {
+				return "", err
+			}
+			return c.ReadFile(filename)
+		},
+		"readDir": func(path string) ([]string, error) {
+			if err := c.sandbox.CheckPath(c.fs, resolve(path)); err != nil {
+				return nil, err
+			}
+			return c.ReadDir(path)
+		},
+		"readDirEntries": func(path string) ([]os.DirEntry, error) {
+			if err := c.sandbox.CheckPath(c.fs, resolve(path)); err != nil {
+				return nil, err
+			}
+			return c.ReadDirEntries(path)
+		},
+		"fetchSecretValue": func(ref string) (string, error) {
+			if err := c.sandbox.CheckRefs(c.fs, ref); err != nil {
+				return "", err
+			}
+			return fetchSecretValue(ref)
+		},
+		"expandSecretRefs": func(values map[string]any) (map[string]any, error) {
+			if err := c.sandbox.CheckRefs(c.fs, values); err != nil {
+				return nil, err
+			}
+			return fetchSecretValues(values)
+		},
+		"env": func(name string) (string, error) {
+			return getenv(name)
+		},
+		"requiredEnv": func(name string) (string, error) {
+			val, err := getenv(name)
+			if err != nil {
+				return "", err
+			}
+			if val == "" {
+				return RequiredEnv(name)
+			}
+			return val, nil
+		},
+		"expandenv": func(s string) (string, error) {
+			var err error
+			expanded := os.Expand(s, func(name string) string {
+				val, getErr := getenv(name)
+				if getErr != nil && err == nil {
+					err = getErr
+				}
+				return val
+			})
+			return expanded, err
+		},
+	}
+}
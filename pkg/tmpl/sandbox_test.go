@@ -0,0 +1,125 @@
+package tmpl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/helmfile/helmfile/pkg/filesystem"
+)
+
+func TestSandbox_FuncMap(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "team-a")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "values"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "values", "a.yaml"), []byte("a: 1\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "secrets.yaml"), []byte("password: x\n"), 0644))
+	require.NoError(t, os.Symlink(filepath.Join(root, "secrets.yaml"), filepath.Join(dir, "link.yaml")))
+
+	ctx := &Context{basePath: dir, fs: filesystem.DefaultFileSystem()}
+	ctx.SetSandbox(&Sandbox{Dir: dir, AllowedEnv: []string{"TEAM_A_*", "HOME"}})
+	funcMap := ctx.createFuncMap()
+
+	t.Run("exec is denied", func(t *testing.T) {
+		_, err := funcMap["exec"].(func(string, []any, ...string) (string, error))("echo", []any{"foo"})
+		require.EqualError(t, err, "exec is not allowed in sandboxed helmfiles")
+		_, err = funcMap["envExec"].(func(map[string]any, string, []any, ...string) (string, error))(nil, "echo", []any{"foo"})
+		require.EqualError(t, err, "envExec is not allowed in sandboxed helmfiles")
+	})
+
+	t.Run("files in the sandbox directory can be read", func(t *testing.T) {
+		out, err := funcMap["readFile"].(func(string) (string, error))("values/a.yaml")
+		require.NoError(t, err)
+		require.Equal(t, "a: 1\n", out)
+
+		files, err := funcMap["readDir"].(func(string) ([]string, error))("values")
+		require.NoError(t, err)
+		require.Equal(t, []string{filepath.Join("values", "a.yaml")}, files)
+	})
+
+	t.Run("files outside of the sandbox directory can't be read", func(t *testing.T) {
+		readFile := funcMap["readFile"].(func(string) (string, error))
+		for _, f := range []string{"../secrets.yaml", filepath.Join(root, "secrets.yaml"), "link.yaml"} {
+			_, err := readFile(f)
+			require.Error(t, err, f)
+			require.ErrorAs(t, err, &SandboxError{}, f)
+		}
+
+		_, err := funcMap["isFile"].(func(string) (bool, error))("../secrets.yaml")
+		require.Error(t, err)
+		_, err = funcMap["readDir"].(func(string) ([]string, error))("..")
+		require.Error(t, err)
+	})
+
+	t.Run("env vars are restricted to the allowlist", func(t *testing.T) {
+		t.Setenv("TEAM_A_REGION", "eu-west-1")
+		t.Setenv("TEAM_B_TOKEN", "secret")
+
+		getenv := funcMap["env"].(func(string) (string, error))
+		out, err := getenv("TEAM_A_REGION")
+		require.NoError(t, err)
+		require.Equal(t, "eu-west-1", out)
+
+		_, err = getenv("TEAM_B_TOKEN")
+		require.EqualError(t, err, "env var `TEAM_B_TOKEN` is not allowed in the sandbox")
+
+		_, err = funcMap["requiredEnv"].(func(string) (string, error))("TEAM_A_MISSING")
+		require.EqualError(t, err, "required env var `TEAM_A_MISSING` is not set")
+
+		_, err = funcMap["expandenv"].(func(string) (string, error))("$TEAM_A_REGION/$TEAM_B_TOKEN")
+		require.EqualError(t, err, "env var `TEAM_B_TOKEN` is not allowed in the sandbox")
+	})
+}
+
+func TestSandbox_CheckPath_Nested(t *testing.T) {
+	fs := filesystem.DefaultFileSystem()
+	parent := &Sandbox{Dir: "/team-a"}
+	child := &Sandbox{Dir: "/team-a/apps", Parent: parent}
+
+	require.NoError(t, child.CheckPath(fs, "/team-a/apps/values.yaml"))
+	require.EqualError(t, child.CheckPath(fs, "/team-a/values.yaml"), "/team-a/values.yaml is outside of the sandbox directory /team-a/apps")
+
+	// A child can't widen the directory of its parent
+	widened := &Sandbox{Dir: "/", Parent: parent}
+	require.EqualError(t, widened.CheckPath(fs, "/team-b/values.yaml"), "/team-b/values.yaml is outside of the sandbox directory /team-a")
+
+	var none *Sandbox
+	require.NoError(t, none.CheckPath(fs, "/etc/passwd"))
+}
+
+func TestSandbox_CheckEnv_Nested(t *testing.T) {
+	parent := &Sandbox{AllowedEnv: []string{"TEAM_A_*"}}
+	child := &Sandbox{AllowedEnv: []string{"TEAM_A_REGION", "HOME"}, Parent: parent}
+
+	require.NoError(t, child.CheckEnv("TEAM_A_REGION"))
+	require.Error(t, child.CheckEnv("TEAM_A_ZONE"))
+	require.Error(t, child.CheckEnv("HOME"))
+}
+
+func TestSandbox_CheckRefs(t *testing.T) {
+	fs := filesystem.DefaultFileSystem()
+	sandbox := &Sandbox{Dir: "/team-a"}
+
+	require.NoError(t, sandbox.CheckRefs(fs, map[string]any{
+		"password": "ref+file:///team-a/secrets.yaml#/password",
+		"region":   "ref+awssecrets://team-a/region",
+		"list":     []any{"ref+sops:///team-a/secrets.enc.yaml#/token"},
+	}))
+
+	for _, v := range []any{
+		"ref+file:///team-b/secrets.yaml#/password",
+		map[string]any{"nested": map[any]any{"token": "ref+sops:///secrets.enc.yaml"}},
+		[]any{"prefix-ref+tfstate:///terraform.tfstate/output.token"},
+	} {
+		err := sandbox.CheckRefs(fs, v)
+		require.Error(t, err, v)
+		require.ErrorAs(t, err, &SandboxError{}, v)
+	}
+
+	require.EqualError(t, sandbox.CheckRefs(fs, "ref+envsubst://$TOKEN"), "ref+envsubst:// is not allowed in sandboxed helmfiles")
+
+	var unsandboxed *Sandbox
+	require.NoError(t, unsandboxed.CheckRefs(fs, "ref+envsubst://$TOKEN"))
+}
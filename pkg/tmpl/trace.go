@@ -0,0 +1,251 @@
+package tmpl
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+// The markers are inserted into the output of the traced templates to find which template line
+// an output line was rendered from. They are in the Unicode private use area so that they
+// never collide with the rendered text.
+const (
+	traceMarkerStart = "\uE000"
+	traceMarkerEnd   = "\uE001"
+)
+
+// tracedFuncs are the template functions that render content from elsewhere than the template.
+var tracedFuncs = map[string]bool{
+	"tpl":              true,
+	"include":          true,
+	"readFile":         true,
+	"exec":             true,
+	"envExec":          true,
+	"fetchSecretValue": true,
+}
+
+// TraceSource is the template line an output line was rendered from.
+type TraceSource struct {
+	File string
+	Line int
+	// Via is the inclusion the line was rendered by, like `readFile "values.yaml"` or `tpl`, if any.
+	Via string
+}
+
+func (s TraceSource) String() string {
+	loc := fmt.Sprintf("%s:%d", s.File, s.Line)
+	if s.Via != "" {
+		loc += " (" + s.Via + ")"
+	}
+	return loc
+}
+
+// TracedLine is a rendered line along with the template line it was rendered from.
+type TracedLine struct {
+	Text   string
+	Source TraceSource
+}
+
+// RenderTrace is the trace of a template rendered by RenderTemplateToBufferWithTrace.
+type RenderTrace struct {
+	Lines []TracedLine
+	// ErrorLine is the line of the template the rendering failed at, or 0 when unknown.
+	ErrorLine int
+}
+
+// RenderTemplateToBufferWithTrace renders the template s like RenderTemplateToBuffer, and traces the
+// template line each output line was rendered from.
+// file is the name of the template file and firstLine the line of the file s starts at.
+func (c *Context) RenderTemplateToBufferWithTrace(s, file string, firstLine int, data any) (*bytes.Buffer, *RenderTrace, error) {
+	// A comment spanning the lines before s makes the lines in the errors the lines of the file
+	if firstLine > 1 {
+		s = "{{/*" + strings.Repeat("\n", firstLine-1) + "*/}}" + s
+	}
+	tr := &tracer{file: file, text: s}
+	trace := &RenderTrace{}
+
+	t, err := c.newTemplate()
+	if err != nil {
+		return nil, trace, err
+	}
+	t, err = t.New(file).Parse(s)
+	if err != nil {
+		trace.ErrorLine = tr.errorLine(err)
+		return nil, trace, err
+	}
+	if t.Tree != nil {
+		tr.instrument(t.Tree.Root)
+	}
+
+	var out bytes.Buffer
+	execErr := t.Execute(&out, data)
+
+	var rendered string
+	rendered, trace.Lines = tr.resolve(out.String())
+	if execErr != nil {
+		trace.ErrorLine = tr.errorLine(execErr)
+	}
+
+	return bytes.NewBufferString(rendered), trace, execErr
+}
+
+type tracer struct {
+	file    string
+	text    string
+	sources []TraceSource
+}
+
+func (tr *tracer) line(pos parse.Pos) int {
+	return 1 + strings.Count(tr.text[:pos], "\n")
+}
+
+func (tr *tracer) marker(line int, via string) string {
+	tr.sources = append(tr.sources, TraceSource{File: tr.file, Line: line, Via: via})
+	return traceMarkerStart + strconv.Itoa(len(tr.sources)-1) + traceMarkerEnd
+}
+
+// instrument inserts a marker before every action and at the start of every line of text in list.
+func (tr *tracer) instrument(list *parse.ListNode) {
+	if list == nil {
+		return
+	}
+
+	nodes := make([]parse.Node, 0, len(list.Nodes))
+	for _, n := range list.Nodes {
+		switch n := n.(type) {
+		case *parse.TextNode:
+			line := tr.line(n.Pos)
+			var b bytes.Buffer
+			b.WriteString(tr.marker(line, ""))
+			for i, c := range n.Text {
+				b.WriteByte(c)
+				if c == '\n' && i < len(n.Text)-1 {
+					line++
+					b.WriteString(tr.marker(line, ""))
+				}
+			}
+			n.Text = b.Bytes()
+		case *parse.ActionNode:
+			nodes = append(nodes, tr.markerNode(n.Pos, via(n.Pipe)))
+		case *parse.TemplateNode:
+			nodes = append(nodes, tr.markerNode(n.Pos, fmt.Sprintf("template %q", n.Name)))
+		case *parse.IfNode:
+			tr.instrument(n.List)
+			tr.instrument(n.ElseList)
+		case *parse.RangeNode:
+			tr.instrument(n.List)
+			tr.instrument(n.ElseList)
+		case *parse.WithNode:
+			tr.instrument(n.List)
+			tr.instrument(n.ElseList)
+		}
+		nodes = append(nodes, n)
+	}
+	list.Nodes = nodes
+}
+
+func (tr *tracer) markerNode(pos parse.Pos, via string) *parse.TextNode {
+	return &parse.TextNode{NodeType: parse.NodeText, Pos: pos, Text: []byte(tr.marker(tr.line(pos), via))}
+}
+
+// via returns the first inclusion in pipe, like `readFile "values.yaml"`.
+func via(pipe *parse.PipeNode) string {
+	if pipe == nil {
+		return ""
+	}
+	for _, cmd := range pipe.Cmds {
+		for i, arg := range cmd.Args {
+			switch arg := arg.(type) {
+			case *parse.IdentifierNode:
+				if i > 0 || !tracedFuncs[arg.Ident] {
+					continue
+				}
+				if len(cmd.Args) > 1 {
+					switch a := cmd.Args[1].(type) {
+					case *parse.StringNode:
+						return arg.Ident + " " + a.Quoted
+					case *parse.PipeNode:
+						if v := via(a); v != "" {
+							return arg.Ident + " (" + v + ")"
+						}
+					}
+				}
+				return arg.Ident
+			case *parse.PipeNode:
+				if v := via(arg); v != "" {
+					return v
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// resolve removes the markers from out, and returns it along with the source of each line.
+func (tr *tracer) resolve(out string) (string, []TracedLine) {
+	var (
+		rendered strings.Builder
+		line     strings.Builder
+		lines    []TracedLine
+	)
+
+	current := TraceSource{File: tr.file, Line: 1}
+	var lineSource *TraceSource
+
+	flush := func() {
+		src := current
+		if lineSource != nil {
+			src = *lineSource
+		}
+		lines = append(lines, TracedLine{Text: line.String(), Source: src})
+		line.Reset()
+		lineSource = nil
+	}
+
+	for i := 0; i < len(out); {
+		if strings.HasPrefix(out[i:], traceMarkerStart) {
+			if end := strings.Index(out[i:], traceMarkerEnd); end > 0 {
+				id, _ := strconv.Atoi(out[i+len(traceMarkerStart) : i+end])
+				current = tr.sources[id]
+				if line.Len() == 0 {
+					src := current
+					lineSource = &src
+				}
+				i += end + len(traceMarkerEnd)
+				continue
+			}
+		}
+
+		c := out[i]
+		rendered.WriteByte(c)
+		if c == '\n' {
+			flush()
+		} else {
+			if lineSource == nil {
+				src := current
+				lineSource = &src
+			}
+			line.WriteByte(c)
+		}
+		i++
+	}
+	if line.Len() > 0 {
+		flush()
+	}
+
+	return rendered.String(), lines
+}
+
+// errorLine returns the line of the template err occurred at, or 0 when unknown.
+func (tr *tracer) errorLine(err error) int {
+	re := regexp.MustCompile(`template: ` + regexp.QuoteMeta(tr.file) + `:(\d+)`)
+	m := re.FindStringSubmatch(err.Error())
+	if m == nil {
+		return 0
+	}
+	line, _ := strconv.Atoi(m[1])
+	return line
+}
@@ -0,0 +1,67 @@
+package tmpl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/helmfile/helmfile/pkg/filesystem"
+)
+
+func TestRenderTemplateToBufferWithTrace(t *testing.T) {
+	ctx := &Context{basePath: ".", fs: filesystem.DefaultFileSystem()}
+
+	text := `releases:
+{{- range $i := list 1 2 }}
+- name: app-{{ $i }}
+{{- end }}
+{{ $labels := "team: a\ntier: web" }}
+  labels:
+{{ tpl $labels . | indent 4 }}
+`
+	buf, trace, err := ctx.RenderTemplateToBufferWithTrace(text, "helmfile.yaml.gotmpl", 5, nil)
+	require.NoError(t, err)
+	require.Equal(t, "releases:\n- name: app-1\n- name: app-2\n\n  labels:\n    team: a\n    tier: web\n", buf.String())
+
+	src := func(line int, via string) TraceSource {
+		return TraceSource{File: "helmfile.yaml.gotmpl", Line: line, Via: via}
+	}
+	require.Equal(t, []TracedLine{
+		{Text: "releases:", Source: src(5, "")},
+		{Text: "- name: app-1", Source: src(7, "")},
+		{Text: "- name: app-2", Source: src(7, "")},
+		{Text: "", Source: src(9, "")},
+		{Text: "  labels:", Source: src(10, "")},
+		{Text: "    team: a", Source: src(11, "tpl")},
+		{Text: "    tier: web", Source: src(11, "tpl")},
+	}, trace.Lines)
+	require.Zero(t, trace.ErrorLine)
+}
+
+func TestRenderTemplateToBufferWithTrace_Error(t *testing.T) {
+	ctx := &Context{basePath: ".", fs: filesystem.DefaultFileSystem()}
+
+	_, trace, err := ctx.RenderTemplateToBufferWithTrace("releases:\n- name: {{ required \"name is required\" .name }}\n", "helmfile.yaml.gotmpl", 3, map[string]any{"name": ""})
+	require.ErrorContains(t, err, "template: helmfile.yaml.gotmpl:4:")
+	require.Equal(t, 4, trace.ErrorLine)
+
+	_, trace, err = ctx.RenderTemplateToBufferWithTrace("releases:\n{{ if }}\n", "helmfile.yaml.gotmpl", 1, nil)
+	require.Error(t, err)
+	require.Equal(t, 2, trace.ErrorLine)
+}
+
+func TestTraceVia(t *testing.T) {
+	ctx := &Context{basePath: ".", fs: newFSExpecting("values.yaml", "a: 1")}
+
+	_, trace, err := ctx.RenderTemplateToBufferWithTrace(`{{ readFile "values.yaml" }}
+{{ tpl (readFile "values.yaml") . }}
+{{ "values.yaml" | readFile }}
+`, "helmfile.yaml.gotmpl", 1, nil)
+	require.NoError(t, err)
+
+	var vias []string
+	for _, l := range trace.Lines {
+		vias = append(vias, l.Source.Via)
+	}
+	require.Equal(t, []string{`readFile "values.yaml"`, `tpl (readFile "values.yaml")`, `readFile`}, vias)
+}
@@ -0,0 +1,236 @@
+// Package tracing instruments helmfile runs with OpenTelemetry spans, exported
+// via OTLP over HTTP, e.g. to a local collector, and/or to a JSON file.
+//
+// Every span is a child of the root span of the run, started by Start, as
+// helmfile doesn't thread a context through its loading and deployment code.
+// The spans carry the release, kube context and batch of the work they time
+// as attributes instead.
+package tracing
+
+import (
+	goContext "context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Span attributes
+const (
+	Helmfile    = attribute.Key("helmfile.file")
+	Environment = attribute.Key("helmfile.environment")
+	Release     = attribute.Key("helmfile.release")
+	Namespace   = attribute.Key("helmfile.namespace")
+	KubeContext = attribute.Key("helmfile.kube_context")
+	Batch       = attribute.Key("helmfile.batch")
+	// Releases are the IDs of the releases of a group of releases
+	Releases = attribute.Key("helmfile.releases")
+	Hook     = attribute.Key("helmfile.hook")
+	Event    = attribute.Key("helmfile.event")
+	Command  = attribute.Key("helm.command")
+)
+
+const instrumentationName = "github.com/helmfile/helmfile"
+
+// shutdownTimeout bounds the time Shutdown takes to flush the spans, not to hang
+// helmfile on exit when the OTLP endpoint is unreachable.
+const shutdownTimeout = 5 * time.Second
+
+// Config is where the spans are exported to. Tracing is disabled when none is set.
+type Config struct {
+	// Endpoint is the URL of the OTLP/HTTP endpoint, like http://localhost:4318.
+	Endpoint string
+	// File is the file the spans are written to as JSON, one span per line.
+	File string
+}
+
+// Enabled reports whether the spans are exported anywhere.
+func (c Config) Enabled() bool {
+	return c.Endpoint != "" || c.File != ""
+}
+
+var (
+	mu       sync.Mutex
+	provider *sdktrace.TracerProvider
+	// local is true when provider only processes the spans for the processors registered with Register
+	local      bool
+	registered int
+	root       = goContext.Background()
+	rootSpan   trace.Span
+	shutdown   []func(goContext.Context) error
+)
+
+// Start exports the spans to c, and starts the root span of the run, named name.
+// Tracing is a no-op unless Start is called with a config that is enabled.
+func Start(c Config, name string, attrs ...attribute.KeyValue) error {
+	if !c.Enabled() {
+		return nil
+	}
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName("helmfile"))),
+	}
+	var closers []func(goContext.Context) error
+	if c.Endpoint != "" {
+		exporter, err := otlptracehttp.New(goContext.Background(), otlptracehttp.WithEndpointURL(c.Endpoint))
+		if err != nil {
+			return fmt.Errorf("creating the otlp trace exporter: %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	if c.File != "" {
+		f, err := os.Create(c.File)
+		if err != nil {
+			return fmt.Errorf("creating the trace file: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			_ = f.Close()
+			return fmt.Errorf("creating the trace file exporter: %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+		closers = append(closers, func(goContext.Context) error { return f.Close() })
+	}
+
+	tp := sdktrace.NewTracerProvider(opts...)
+	start(tp, name, attrs...)
+
+	mu.Lock()
+	defer mu.Unlock()
+	// The provider flushes the spans to the exporters before the file is closed
+	shutdown = append([]func(goContext.Context) error{tp.Shutdown}, closers...)
+	return nil
+}
+
+// start starts the root span of the run with tp, which is set as the global tracer provider.
+func start(tp *sdktrace.TracerProvider, name string, attrs ...attribute.KeyValue) {
+	otel.SetTracerProvider(tp)
+
+	mu.Lock()
+	defer mu.Unlock()
+	provider = tp
+	local = false
+	root, rootSpan = tp.Tracer(instrumentationName).Start(goContext.Background(), name, trace.WithAttributes(attrs...))
+}
+
+// Shutdown ends the root span of the run, recording err, and flushes the spans to the exporters,
+// giving up after shutdownTimeout.
+func Shutdown(err error) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if rootSpan != nil {
+		End(rootSpan, err)
+		rootSpan = nil
+	}
+	root = goContext.Background()
+	provider = nil
+	local = false
+
+	ctx, cancel := goContext.WithTimeout(goContext.Background(), shutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	for _, f := range shutdown {
+		if err := f(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	shutdown = nil
+	return errors.Join(errs...)
+}
+
+// StartSpan starts a span named name, as a child of the root span of the run.
+// The span is a no-op unless tracing is started.
+func StartSpan(name string, attrs ...attribute.KeyValue) trace.Span {
+	mu.Lock()
+	ctx := root
+	mu.Unlock()
+
+	_, span := otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+	return span
+}
+
+// End ends span, recording err as its status when it's not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// collector is a span processor keeping the spans that end in memory.
+type collector struct {
+	mu    sync.Mutex
+	spans []sdktrace.ReadOnlySpan
+}
+
+func (c *collector) OnStart(goContext.Context, sdktrace.ReadWriteSpan) {}
+
+func (c *collector) OnEnd(s sdktrace.ReadOnlySpan) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.spans = append(c.spans, s)
+}
+
+func (c *collector) Shutdown(goContext.Context) error { return nil }
+
+func (c *collector) ForceFlush(goContext.Context) error { return nil }
+
+// Register calls the span processor p with the spans that start and end from now on, whether
+// tracing is started or not, until the returned function is called.
+func Register(p sdktrace.SpanProcessor) func() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if provider == nil {
+		// The spans are only processed, not exported, and have no root span as tracing isn't started
+		provider = sdktrace.NewTracerProvider()
+		local = true
+		otel.SetTracerProvider(provider)
+	}
+	tp := provider
+	tp.RegisterSpanProcessor(p)
+	registered++
+
+	return func() {
+		tp.UnregisterSpanProcessor(p)
+
+		mu.Lock()
+		defer mu.Unlock()
+		registered--
+		if local && registered == 0 && provider == tp {
+			otel.SetTracerProvider(noop.NewTracerProvider())
+			provider = nil
+			local = false
+		}
+	}
+}
+
+// Collect keeps the spans that end from now on in memory, whether tracing is started or not,
+// until the returned function is called, which returns them.
+func Collect() func() []sdktrace.ReadOnlySpan {
+	c := &collector{}
+	unregister := Register(c)
+
+	return func() []sdktrace.ReadOnlySpan {
+		unregister()
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.spans
+	}
+}
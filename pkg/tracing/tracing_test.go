@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+type exportedSpan struct {
+	Name        string
+	SpanContext struct{ TraceID, SpanID string }
+	Parent      struct{ SpanID string }
+	Attributes  []struct {
+		Key   string
+		Value struct{ Value any }
+	}
+	Status struct{ Code, Description string }
+}
+
+func TestStartWithFile(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(prev)
+
+	file := filepath.Join(t.TempDir(), "trace.json")
+	require.NoError(t, Start(Config{File: file}, "helmfile apply", Environment.String("prod")))
+
+	span := StartSpan("helm upgrade", Release.String("web"), Batch.Int(2))
+	End(span, errors.New("upgrade failed"))
+	require.NoError(t, Shutdown(nil))
+
+	bs, err := os.ReadFile(file)
+	require.NoError(t, err)
+	var spans []exportedSpan
+	dec := json.NewDecoder(strings.NewReader(string(bs)))
+	for dec.More() {
+		var s exportedSpan
+		require.NoError(t, dec.Decode(&s))
+		spans = append(spans, s)
+	}
+
+	require.Len(t, spans, 2)
+	helm, root := spans[0], spans[1]
+	assert.Equal(t, "helm upgrade", helm.Name)
+	assert.Equal(t, "helmfile apply", root.Name)
+	assert.Equal(t, root.SpanContext.TraceID, helm.SpanContext.TraceID)
+	assert.Equal(t, root.SpanContext.SpanID, helm.Parent.SpanID)
+	assert.Equal(t, "Error", helm.Status.Code)
+	assert.Equal(t, "upgrade failed", helm.Status.Description)
+	assert.Equal(t, "Unset", root.Status.Code)
+
+	attrs := map[string]any{}
+	for _, a := range helm.Attributes {
+		attrs[a.Key] = a.Value.Value
+	}
+	assert.Equal(t, map[string]any{"helmfile.release": "web", "helmfile.batch": 2.0}, attrs)
+}
+
+func TestDisabled(t *testing.T) {
+	require.NoError(t, Start(Config{}, "helmfile apply"))
+
+	span := StartSpan("helm upgrade")
+	assert.False(t, span.SpanContext().IsValid())
+	End(span, nil)
+	require.NoError(t, Shutdown(nil))
+}
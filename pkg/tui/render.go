@@ -0,0 +1,196 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/tatsushid/go-prettytable"
+
+	"github.com/helmfile/helmfile/pkg/kubedog"
+)
+
+// ANSI escape codes, like the ones of the kubedog progress printer.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiRed    = "\x1b[31m"
+	ansiCyan   = "\x1b[36m"
+	ansiGray   = "\x1b[90m"
+	ansiBold   = "\x1b[1m"
+)
+
+// expandedLines is the number of the last lines of output shown for an expanded release
+const expandedLines = 12
+
+const help = "↑/↓ select · enter expand · ctrl-c interrupt"
+
+func (u *UI) colorize(s, code string) string {
+	if !u.useColor || code == "" {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+func statusColor(s Status) string {
+	switch s {
+	case Done:
+		return ansiGreen
+	case Failed:
+		return ansiRed
+	case Syncing, Deleting:
+		return ansiYellow
+	case Diffing, Tracking:
+		return ansiCyan
+	case Queued, Unchanged:
+		return ansiGray
+	}
+	return ""
+}
+
+// elapsed returns the time row took, or has taken so far.
+func (row *row) elapsed(now time.Time) string {
+	if row.started.IsZero() {
+		return "-"
+	}
+	if !row.finished.IsZero() {
+		now = row.finished
+	}
+	return now.Sub(row.started).Round(time.Second).String()
+}
+
+func (row *row) batchString() string {
+	if row.batch == 0 {
+		return "-"
+	}
+	return fmt.Sprint(row.batch)
+}
+
+// render returns the lines of the screen of the UI, which is width columns wide and height lines high.
+//
+// The releases are listed first, scrolled so that the selected one is shown, followed by the last
+// lines of the log of the run.
+func (u *UI) render(width, height int) []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	now := u.now()
+	var done, failed int
+	idWidth := len("RELEASE")
+	for _, row := range u.rows {
+		switch row.status {
+		case Done, Unchanged:
+			done++
+		case Failed:
+			failed++
+		}
+		idWidth = max(idWidth, runewidth.StringWidth(row.ID))
+	}
+	// The release IDs are truncated to leave room for the other columns
+	idWidth = min(idWidth, max(width-32, 10))
+
+	title := fmt.Sprintf("%d/%d done", done, len(u.rows))
+	if failed > 0 {
+		title += fmt.Sprintf(", %d failed", failed)
+	}
+	title = fmt.Sprintf("%s · %s · %s", u.title, title, now.Sub(u.started).Round(time.Second))
+
+	columns := func(marker, id string, status Status, batch, elapsed string) string {
+		prefix := fmt.Sprintf("%s %s  ", marker, runewidth.FillRight(runewidth.Truncate(id, idWidth, "…"), idWidth))
+		line := runewidth.Truncate(fmt.Sprintf("%s%-9s  %5s  %8s", prefix, status, batch, elapsed), width, "")
+		if len(line) <= len(prefix) {
+			return line
+		}
+		// The status is colored once the line fits the width, not to count the escape codes
+		padded := fmt.Sprintf("%-9s", status)
+		return prefix + strings.Replace(line[len(prefix):], padded, u.colorize(padded, statusColor(status)), 1)
+	}
+
+	var rows []string
+	var selectedStart, selectedEnd int
+	for i, row := range u.rows {
+		marker := " "
+		if i == u.selected {
+			marker = ">"
+			selectedStart = len(rows)
+		}
+		rows = append(rows, columns(marker, row.ID, row.status, row.batchString(), row.elapsed(now)))
+
+		if row.expanded {
+			output := row.output.all()
+			if len(output) == 0 {
+				output = []string{"(no output yet)"}
+			}
+			for _, line := range output[max(len(output)-expandedLines, 0):] {
+				rows = append(rows, "    │ "+runewidth.Truncate(line, max(width-6, 0), ""))
+			}
+		}
+		if i == u.selected {
+			selectedEnd = len(rows) - 1
+		}
+	}
+
+	log := u.log.all()
+	// The title, the header of the columns, the divider of the log and the help take a line each
+	available := max(height-4, 0)
+	logHeight := min(len(log), max(available/3, min(3, available)))
+	rowsHeight := available - logHeight
+	if len(rows) < rowsHeight {
+		rowsHeight = len(rows)
+		logHeight = min(len(log), available-rowsHeight)
+	}
+
+	// The selected release, and its output when it's expanded, are scrolled into the view
+	start := min(max(selectedEnd-rowsHeight+1, 0), selectedStart)
+	start = min(start, max(len(rows)-rowsHeight, 0))
+
+	screen := []string{
+		u.colorize(runewidth.Truncate(title, width, "…"), ansiBold),
+		columns(" ", "RELEASE", "STATUS", "BATCH", "ELAPSED"),
+	}
+	screen = append(screen, rows[start:min(start+rowsHeight, len(rows))]...)
+	screen = append(screen, u.colorize(runewidth.Truncate("── log "+strings.Repeat("─", width), width, ""), ansiGray))
+	for _, line := range log[len(log)-logHeight:] {
+		screen = append(screen, runewidth.Truncate(line, width, ""))
+	}
+	screen = append(screen, u.colorize(runewidth.Truncate(help, width, ""), ansiGray))
+	return screen
+}
+
+// report returns what's printed when the UI is stopped: the log of the run,
+// the output of the failed releases, and a summary of the releases.
+func (u *UI) report(now time.Time) string {
+	var b strings.Builder
+	for _, line := range u.log.all() {
+		b.WriteString(line + "\n")
+	}
+
+	for _, row := range u.rows {
+		if row.status != Failed {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s\n", kubedog.HeaderDividerStyled(fmt.Sprintf("Output of release '%s'", row.ID), u.useColor))
+		for _, line := range row.output.all() {
+			b.WriteString(line + "\n")
+		}
+	}
+
+	if len(u.rows) == 0 {
+		return b.String()
+	}
+	tbl, _ := prettytable.NewTable(
+		prettytable.Column{Header: "RELEASE"},
+		prettytable.Column{Header: "STATUS"},
+		prettytable.Column{Header: "BATCH", AlignRight: true},
+		prettytable.Column{Header: "ELAPSED", AlignRight: true},
+	)
+	tbl.Separator = "   "
+	for _, row := range u.rows {
+		_ = tbl.AddRow(row.ID, string(row.status), row.batchString(), row.elapsed(now))
+	}
+	table := tbl.String()
+	fmt.Fprintf(&b, "\n%s\n%s", kubedog.HeaderDividerCenteredStyled("Releases", kubedog.TableVisualWidth(table), u.useColor), table)
+	return b.String()
+}
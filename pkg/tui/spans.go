@@ -0,0 +1,117 @@
+package tui
+
+import (
+	goContext "context"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/helmfile/helmfile/pkg/tracing"
+)
+
+// spanStatus returns the status of the release a span named name is about while it runs, if any.
+func spanStatus(name string) Status {
+	switch {
+	case strings.HasPrefix(name, "helm diff "):
+		return Diffing
+	case name == "helm upgrade" || name == "helm install":
+		return Syncing
+	case name == "helm uninstall" || name == "helm delete":
+		return Deleting
+	case name == "kubedog track":
+		return Tracking
+	}
+	return ""
+}
+
+// spanProcessor follows the status and the batch of the releases through the spans of the run.
+type spanProcessor struct {
+	u *UI
+}
+
+func (p spanProcessor) OnStart(_ goContext.Context, s sdktrace.ReadWriteSpan) {
+	p.u.onSpan(s.Name(), s.Attributes(), true)
+}
+
+func (p spanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	p.u.onSpan(s.Name(), s.Attributes(), false)
+}
+
+func (p spanProcessor) Shutdown(goContext.Context) error { return nil }
+
+func (p spanProcessor) ForceFlush(goContext.Context) error { return nil }
+
+// onSpan updates the releases the span named name with attrs is about, when it starts or ends.
+func (u *UI) onSpan(name string, attrs []attribute.KeyValue, started bool) {
+	var release, namespace, kubeContext string
+	var batch int
+	var ids []string
+	for _, a := range attrs {
+		switch a.Key {
+		case tracing.Release:
+			release = a.Value.AsString()
+		case tracing.Namespace:
+			namespace = a.Value.AsString()
+		case tracing.KubeContext:
+			kubeContext = a.Value.AsString()
+		case tracing.Batch:
+			batch = int(a.Value.AsInt64())
+		case tracing.Releases:
+			ids = a.Value.AsStringSlice()
+		}
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	switch {
+	case name == "release group" && started:
+		for _, row := range u.rows {
+			for _, id := range ids {
+				if row.ID == id {
+					row.batch = batch
+				}
+			}
+		}
+	case name == "release" && started:
+		for _, row := range u.matching(release, namespace, kubeContext) {
+			row.batch = batch
+		}
+	default:
+		s := spanStatus(name)
+		if s == "" {
+			return
+		}
+		for _, row := range u.matching(release, namespace, kubeContext) {
+			switch {
+			case row.status.finished():
+			case started:
+				u.setStatus(row, s)
+			case s == Diffing && row.status == Diffing:
+				// The release waits to be synced, if it has changes
+				u.setStatus(row, Queued)
+			case s == Tracking && row.status == Tracking:
+				// The release still has to be finished, like its postsync hooks to be run
+				u.setStatus(row, Syncing)
+			}
+		}
+	}
+}
+
+// matching returns the rows of the release named name in namespace and kubeContext. The namespace
+// and kube context of a release are only compared when both are known, as the spans of helm have
+// the default ones, while a release may not. It must be called with u.mu held.
+func (u *UI) matching(name, namespace, kubeContext string) []*row {
+	if name == "" {
+		return nil
+	}
+	known := func(a, b string) bool { return a == "" || b == "" || a == b }
+	var rows []*row
+	for _, row := range u.rows {
+		if row.Name == name && known(row.Namespace, namespace) && known(row.KubeContext, kubeContext) {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
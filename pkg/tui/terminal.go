@@ -0,0 +1,204 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/helmfile/helmfile/pkg/tracing"
+)
+
+const (
+	// The UI is drawn on the alternate screen, without the cursor, which are restored when it's stopped
+	enterScreen = "\x1b[?1049h\x1b[?25l"
+	leaveScreen = "\x1b[?25h\x1b[?1049l"
+
+	redrawInterval = 200 * time.Millisecond
+)
+
+// Enabled reports whether the UI can be shown, which needs stdout to be a terminal.
+func Enabled() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// terminal is the terminal the UI is shown on, while it's started.
+type terminal struct {
+	// out is the terminal, which is stdout before the UI is started
+	out    *os.File
+	stdout *os.File
+	stderr *os.File
+	// pipe replaces stdout and stderr while the UI is shown, so that what's written to them goes to the log of the run
+	pipe   *os.File
+	copied chan struct{}
+	// input is the state of stdin to restore, when it's a terminal that's read for the keys of the UI
+	input *term.State
+
+	unregister func()
+	redraw     chan struct{}
+	stop       chan struct{}
+	drawn      sync.WaitGroup
+	once       sync.Once
+
+	// after is where the output written to the UI once it's stopped goes
+	after io.Writer
+}
+
+// Start shows the UI on the terminal, until Stop is called.
+//
+// What's written to stdout and stderr while the UI is shown goes to the log of the run,
+// unless it was written to them before they were swapped, like the loggers created before.
+func (u *UI) Start() error {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("creating the pipe of the output of the ui: %w", err)
+	}
+
+	t := &terminal{
+		out:    os.Stdout,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+		pipe:   w,
+		copied: make(chan struct{}),
+		redraw: make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+	}
+	u.mu.Lock()
+	u.term = t
+	u.mu.Unlock()
+
+	go func() {
+		_, _ = io.Copy(u.Log(), r)
+		_ = r.Close()
+		close(t.copied)
+	}()
+	os.Stdout, os.Stderr = w, w
+
+	// The keys are read as they are typed, which also turns ctrl-c into a key
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		if state, err := term.MakeRaw(fd); err == nil {
+			t.input = state
+			go u.read(os.Stdin)
+		}
+	}
+
+	_, _ = io.WriteString(t.out, enterScreen)
+	t.unregister = tracing.Register(spanProcessor{u})
+
+	t.drawn.Add(1)
+	go u.draw()
+	return nil
+}
+
+// Stop stops showing the UI, and prints the log of the run, the output of the failed releases
+// and a summary of the releases instead. What's written to the UI afterwards is printed as is.
+// It's a no-op when the UI isn't started or already stopped.
+func (u *UI) Stop() {
+	u.mu.Lock()
+	t := u.term
+	u.mu.Unlock()
+	if t == nil {
+		return
+	}
+
+	t.once.Do(func() {
+		close(t.stop)
+		t.drawn.Wait()
+		t.unregister()
+
+		os.Stdout, os.Stderr = t.stdout, t.stderr
+		_ = t.pipe.Close()
+		<-t.copied
+
+		_, _ = io.WriteString(t.out, leaveScreen)
+		if t.input != nil {
+			_ = term.Restore(int(os.Stdin.Fd()), t.input)
+		}
+
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		t.after = t.stderr
+		_, _ = io.WriteString(t.stderr, u.report(u.now()))
+	})
+}
+
+// draw draws the UI on the terminal, every redrawInterval and when a key is pressed, until the UI is stopped.
+func (u *UI) draw() {
+	t := u.term
+	defer t.drawn.Done()
+
+	ticker := time.NewTicker(redrawInterval)
+	defer ticker.Stop()
+	for {
+		width, height, err := term.GetSize(int(t.out.Fd()))
+		if err != nil || width <= 0 || height <= 0 {
+			width, height = 80, 24
+		}
+
+		var b strings.Builder
+		b.WriteString("\x1b[H")
+		for i, line := range u.render(width, height) {
+			if i > 0 {
+				// The terminal is in raw mode, where a line feed doesn't return the cursor to the first column
+				b.WriteString("\r\n")
+			}
+			b.WriteString(line + "\x1b[K")
+		}
+		b.WriteString("\x1b[J")
+		_, _ = io.WriteString(t.out, b.String())
+
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+		case <-t.redraw:
+		}
+	}
+}
+
+// read handles the keys typed in, until the UI is stopped.
+func (u *UI) read(in io.Reader) {
+	t := u.term
+	buf := make([]byte, 16)
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			return
+		}
+		select {
+		case <-t.stop:
+			return
+		default:
+		}
+
+		switch string(buf[:n]) {
+		case "\x1b[A", "k":
+			u.move(-1)
+		case "\x1b[B", "j":
+			u.move(1)
+		case "\r", " ":
+			u.toggle()
+		case "\x03":
+			u.interrupt()
+			return
+		}
+
+		select {
+		case t.redraw <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// interrupt stops the UI, which restores the terminal, and interrupts helmfile like ctrl-c does without the UI.
+// When helmfile can't be interrupted this way, the run goes on with its plain output, where ctrl-c works again.
+func (u *UI) interrupt() {
+	u.Stop()
+	if p, err := os.FindProcess(os.Getpid()); err == nil {
+		_ = p.Signal(os.Interrupt)
+	}
+}
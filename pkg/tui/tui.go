@@ -0,0 +1,223 @@
+// Package tui is the terminal UI of `--ui`, which shows the releases of a sync,
+// apply or destroy as one row each, with their status, the time they took and
+// the batch they are in, instead of the interleaved output of helm and kubedog.
+//
+// A release can be expanded to show its own output. The status of the releases
+// is followed through the spans of the run, see the tracing package, while the
+// output of each release is written to UI.Output by the caller.
+package tui
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status is the status of a release in the UI.
+type Status string
+
+const (
+	Queued    Status = "queued"
+	Diffing   Status = "diffing"
+	Syncing   Status = "syncing"
+	Deleting  Status = "deleting"
+	Tracking  Status = "tracking"
+	Done      Status = "done"
+	Failed    Status = "failed"
+	Unchanged Status = "unchanged"
+)
+
+// finished reports whether the release is done with, successfully or not.
+func (s Status) finished() bool {
+	return s == Done || s == Failed || s == Unchanged
+}
+
+// Release is a release shown in the UI.
+type Release struct {
+	// ID is the ID of the release, as in state.ReleaseToID
+	ID          string
+	Name        string
+	Namespace   string
+	KubeContext string
+}
+
+// maxLines is the number of lines of output kept for each release, and for the log of the run
+const maxLines = 1000
+
+// lines is the last lines of an output.
+type lines struct {
+	done    []string
+	partial []byte
+}
+
+var escapeSequence = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+func (l *lines) write(p []byte) {
+	l.partial = append(l.partial, p...)
+	for {
+		i := bytes.IndexByte(l.partial, '\n')
+		if i < 0 {
+			break
+		}
+		l.done = append(l.done, sanitize(string(l.partial[:i])))
+		l.partial = l.partial[i+1:]
+	}
+	if len(l.done) > maxLines {
+		l.done = append([]string(nil), l.done[len(l.done)-maxLines:]...)
+	}
+}
+
+// all returns the lines, including the one being written.
+func (l *lines) all() []string {
+	if len(l.partial) == 0 {
+		return l.done
+	}
+	return append(l.done[:len(l.done):len(l.done)], sanitize(string(l.partial)))
+}
+
+// sanitize removes the colors and cursor movements of a line of output, which
+// would break the layout of the UI, keeping what's written after the last carriage return.
+func sanitize(line string) string {
+	line = escapeSequence.ReplaceAllString(line, "")
+	if i := strings.LastIndexByte(line, '\r'); i >= 0 {
+		line = line[i+1:]
+	}
+	return strings.ReplaceAll(line, "\t", "    ")
+}
+
+// row is the row of a release.
+type row struct {
+	Release
+	status   Status
+	batch    int
+	started  time.Time
+	finished time.Time
+	expanded bool
+	output   lines
+}
+
+// UI shows the releases of a run. The zero value isn't usable, see New.
+type UI struct {
+	title    string
+	useColor bool
+	now      func() time.Time
+
+	mu       sync.Mutex
+	started  time.Time
+	rows     []*row
+	log      lines
+	selected int
+
+	term *terminal
+}
+
+// New returns the UI of the run of command, like `apply`.
+func New(command string, useColor bool) *UI {
+	return &UI{
+		title:    "helmfile " + command,
+		useColor: useColor,
+		now:      time.Now,
+		started:  time.Now(),
+	}
+}
+
+// row returns the row of r, adding it when it isn't shown yet. It must be called with u.mu held.
+func (u *UI) row(r Release) *row {
+	for _, existing := range u.rows {
+		if existing.ID == r.ID {
+			return existing
+		}
+	}
+	added := &row{Release: r, status: Queued}
+	u.rows = append(u.rows, added)
+	return added
+}
+
+// Queue shows the releases rs as queued, until they are started.
+func (u *UI) Queue(rs ...Release) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, r := range rs {
+		u.row(r)
+	}
+}
+
+// Set sets the status of r to s. The elapsed time of r starts when it's first synced or deleted,
+// and stops when it's finished.
+func (u *UI) Set(r Release, s Status) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.setStatus(u.row(r), s)
+}
+
+func (u *UI) setStatus(row *row, s Status) {
+	now := u.now()
+	switch {
+	case s.finished():
+		if row.finished.IsZero() {
+			row.finished = now
+		}
+	case s != Queued && s != Diffing:
+		if row.started.IsZero() {
+			row.started = now
+		}
+		row.finished = time.Time{}
+	}
+	row.status = s
+}
+
+// Finish marks r as done, or failed when err isn't nil.
+func (u *UI) Finish(r Release, err error) {
+	s := Done
+	if err != nil {
+		s = Failed
+	}
+	u.Set(r, s)
+}
+
+// Output returns the writer of the output of r, which is shown when r is expanded.
+func (u *UI) Output(r Release) io.Writer {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return &writer{u: u, lines: &u.row(r).output}
+}
+
+// Log returns the writer of the output of the run that isn't about a single release.
+func (u *UI) Log() io.Writer {
+	return &writer{u: u, lines: &u.log}
+}
+
+// writer writes to lines of output of u, or as is once u is stopped.
+type writer struct {
+	u     *UI
+	lines *lines
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	w.u.mu.Lock()
+	defer w.u.mu.Unlock()
+	if t := w.u.term; t != nil && t.after != nil {
+		return t.after.Write(p)
+	}
+	w.lines.write(p)
+	return len(p), nil
+}
+
+// move moves the selection by delta rows.
+func (u *UI) move(delta int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.selected = min(max(u.selected+delta, 0), max(len(u.rows)-1, 0))
+}
+
+// toggle expands the selected release, or collapses it.
+func (u *UI) toggle() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.selected < len(u.rows) {
+		u.rows[u.selected].expanded = !u.rows[u.selected].expanded
+	}
+}
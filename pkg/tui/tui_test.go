@@ -0,0 +1,170 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/helmfile/helmfile/pkg/tracing"
+)
+
+var (
+	db  = Release{ID: "prod/db/db", Name: "db", Namespace: "db", KubeContext: "prod"}
+	api = Release{ID: "app/api", Name: "api", Namespace: "app"}
+	web = Release{ID: "app/web", Name: "web", Namespace: "app"}
+)
+
+func newTestUI(now *time.Time) *UI {
+	u := New("apply", false)
+	u.now = func() time.Time { return *now }
+	u.started = *now
+	return u
+}
+
+func (u *UI) rowOf(r Release) *row {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.row(r)
+}
+
+func attrs(r Release, kubeContext string) []attribute.KeyValue {
+	return []attribute.KeyValue{tracing.Release.String(r.Name), tracing.Namespace.String(r.Namespace), tracing.KubeContext.String(kubeContext)}
+}
+
+func TestSpans(t *testing.T) {
+	now := time.Unix(1714557600, 0)
+	u := newTestUI(&now)
+	u.Queue(db, api, web)
+
+	u.onSpan("helm diff upgrade", attrs(db, "prod"), true)
+	u.onSpan("helm diff upgrade", attrs(api, "prod"), true)
+	assert.Equal(t, Diffing, u.rowOf(db).status)
+	assert.Equal(t, Diffing, u.rowOf(api).status, "the kube context of a release is only compared when it's known")
+
+	u.onSpan("helm diff upgrade", attrs(db, "staging"), false)
+	assert.Equal(t, Diffing, u.rowOf(db).status, "db in another kube context isn't the release")
+	u.onSpan("helm diff upgrade", attrs(db, "prod"), false)
+	assert.Equal(t, Queued, u.rowOf(db).status)
+	assert.True(t, u.rowOf(db).started.IsZero(), "diffing doesn't start the elapsed time")
+
+	u.onSpan("release group", []attribute.KeyValue{tracing.Batch.Int(1), tracing.Releases.StringSlice([]string{db.ID})}, true)
+	u.onSpan("release", append(attrs(api, ""), tracing.Batch.Int(2)), true)
+	assert.Equal(t, 1, u.rowOf(db).batch)
+	assert.Equal(t, 2, u.rowOf(api).batch)
+
+	u.Set(db, Syncing)
+	start := now
+	now = now.Add(5 * time.Second)
+	u.onSpan("kubedog track", attrs(db, "prod"), true)
+	assert.Equal(t, Tracking, u.rowOf(db).status)
+	u.onSpan("kubedog track", attrs(db, "prod"), false)
+	assert.Equal(t, Syncing, u.rowOf(db).status)
+
+	now = now.Add(5 * time.Second)
+	u.Finish(db, nil)
+	u.onSpan("helm upgrade", attrs(db, "prod"), true)
+	assert.Equal(t, Done, u.rowOf(db).status, "a finished release isn't started again")
+	assert.Equal(t, start, u.rowOf(db).started)
+	assert.Equal(t, "10s", u.rowOf(db).elapsed(now.Add(time.Minute)))
+
+	u.onSpan("helm uninstall", attrs(web, "prod"), true)
+	assert.Equal(t, Deleting, u.rowOf(web).status)
+	u.Finish(web, errors.New("uninstall failed"))
+	assert.Equal(t, Failed, u.rowOf(web).status)
+}
+
+func TestLines(t *testing.T) {
+	var l lines
+	l.write([]byte("\x1b[32mdeployment/web\x1b[0m\tready\nprogress 10%"))
+	l.write([]byte("\rprogress 100%\n"))
+	l.write([]byte("partial"))
+	assert.Equal(t, []string{"deployment/web    ready", "progress 100%", "partial"}, l.all())
+
+	for i := range maxLines + 10 {
+		l.write(fmt.Appendf(nil, "line %d\n", i))
+	}
+	all := l.all()
+	assert.Len(t, all, maxLines)
+	assert.Equal(t, fmt.Sprintf("line %d", maxLines+9), all[len(all)-1])
+}
+
+func TestRender(t *testing.T) {
+	now := time.Unix(1714557600, 0)
+	u := newTestUI(&now)
+	u.Queue(db, api, web)
+	u.Set(db, Syncing)
+	u.onSpan("release", append(attrs(db, "prod"), tracing.Batch.Int(1)), true)
+	now = now.Add(12 * time.Second)
+	u.Finish(db, nil)
+	u.Set(api, Syncing)
+	now = now.Add(3 * time.Second)
+	fmt.Fprint(u.Output(api), "Release \"api\" has been upgraded.\nSTATUS: deployed\n")
+	fmt.Fprint(u.Log(), "Building dependency release=db\n")
+
+	screen := strings.Join(u.render(80, 24), "\n")
+	assert.Contains(t, screen, "helmfile apply · 1/3 done · 15s")
+	assert.Regexp(t, `>\s+prod/db/db\s+done\s+1\s+12s`, screen)
+	assert.Regexp(t, `\s+app/api\s+syncing\s+-\s+3s`, screen)
+	assert.Regexp(t, `\s+app/web\s+queued\s+-\s+-`, screen)
+	assert.Contains(t, screen, "Building dependency release=db")
+	assert.NotContains(t, screen, "has been upgraded", "the output of a release is shown when it's expanded")
+
+	u.move(1)
+	u.toggle()
+	screen = strings.Join(u.render(80, 24), "\n")
+	assert.Contains(t, screen, "    │ Release \"api\" has been upgraded.\n    │ STATUS: deployed\n  app/web")
+	assert.Regexp(t, `>\s+app/api`, screen)
+
+	// The rows are scrolled to show the selected one
+	u.move(-1)
+	u.toggle()
+	u.Queue(Release{ID: "app/a"}, Release{ID: "app/b"}, Release{ID: "app/c"}, Release{ID: "app/d"})
+	u.move(10)
+	lines := u.render(80, 8)
+	assert.Len(t, lines, 8)
+	assert.Regexp(t, `>\s+app/d`, strings.Join(lines, "\n"))
+	assert.NotContains(t, strings.Join(lines, "\n"), "prod/db/db")
+
+	for _, line := range u.render(20, 24) {
+		assert.LessOrEqual(t, len([]rune(line)), 20)
+	}
+}
+
+func TestReport(t *testing.T) {
+	now := time.Unix(1714557600, 0)
+	u := newTestUI(&now)
+	u.Queue(db, api)
+	u.Set(db, Syncing)
+	u.Set(api, Syncing)
+	now = now.Add(2 * time.Second)
+	u.Finish(db, nil)
+	u.Finish(api, errors.New("upgrade failed"))
+	fmt.Fprint(u.Output(db), "db output\n")
+	fmt.Fprint(u.Output(api), "Error: UPGRADE FAILED\n")
+	fmt.Fprint(u.Log(), "Affected releases are:\n")
+
+	report := u.report(now)
+	require.Contains(t, report, "Affected releases are:\n")
+	assert.Contains(t, report, "Output of release 'app/api'")
+	assert.Contains(t, report, "Error: UPGRADE FAILED")
+	assert.NotContains(t, report, "db output", "only the output of the failed releases is printed")
+	assert.Regexp(t, `prod/db/db\s+done\s+-\s+2s`, report)
+	assert.Regexp(t, `app/api\s+failed\s+-\s+2s`, report)
+	assert.Less(t, strings.Index(report, "Affected releases"), strings.Index(report, "RELEASE"))
+}
+
+func TestWriteAfterStop(t *testing.T) {
+	var out strings.Builder
+	u := New("sync", false)
+	u.term = &terminal{after: &out}
+	fmt.Fprint(u.Log(), "after\n")
+	fmt.Fprint(u.Output(db), "db after\n")
+	assert.Equal(t, "after\ndb after\n", out.String())
+	assert.Empty(t, u.log.all())
+}
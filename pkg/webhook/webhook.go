@@ -0,0 +1,67 @@
+// Package webhook posts JSON payloads to the HTTP endpoints that runs are
+// reported to, like the audit webhook and the notifications.
+package webhook
+
+import (
+	"bytes"
+	goContext "context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultTimeout is the per-request timeout of endpoints that don't set one.
+const DefaultTimeout = 10 * time.Second
+
+// Endpoint is an HTTP endpoint JSON payloads are posted to.
+type Endpoint struct {
+	URL string `yaml:"url,omitempty"`
+
+	// Headers are added to each request, e.g. to authenticate against URL.
+	Headers map[string]string `yaml:"headers,omitempty"`
+
+	// Timeout is the per-request timeout. Defaults to 10s when zero.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// Post posts the JSON payload to e, and fails unless e responds with a 2xx status.
+func (e Endpoint) Post(payload []byte) error {
+	timeout := e.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := goContext.WithTimeout(goContext.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(payload))
+	if err != nil {
+		return redact(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.Headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return redact(err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return nil
+}
+
+// redact drops the url from err, as it may carry the token of the endpoint,
+// like the url of slack webhooks.
+func redact(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s request failed: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}